@@ -0,0 +1,63 @@
+package solacevaultplugin
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// auditTagsKey is the response key a role's audit tags go under. Audit
+// devices HMAC response strings unless the mount is tuned with
+// audit_non_hmac_response_keys=audit_tags, which leaves them readable to
+// whatever routes audit entries.
+const auditTagsKey = "audit_tags"
+
+// Audit tags are kept small: they ride along on every creds/ and rotation
+// response of the role.
+const (
+	maxAuditTags           = 16
+	maxAuditTagValueLength = 256
+)
+
+// auditTagNamePattern is what an audit tag's name must look like, so that
+// SIEM pipelines can key on it without escaping.
+var auditTagNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// validateAuditTags checks a role's audit_tags.
+func validateAuditTags(tags map[string]string) error {
+	if len(tags) > maxAuditTags {
+		return fmt.Errorf("audit_tags may have at most %d tags, got %d", maxAuditTags, len(tags))
+	}
+	for name, value := range tags {
+		if !auditTagNamePattern.MatchString(name) {
+			return fmt.Errorf("audit tag %q must be 1 to 64 lowercase letters, digits, '_', '.' or '-', starting with a letter or digit", name)
+		}
+		if value == "" || len(value) > maxAuditTagValueLength {
+			return fmt.Errorf("audit tag %q must have a value of 1 to %d characters", name, maxAuditTagValueLength)
+		}
+		for _, c := range value {
+			if c < ' ' || c > '~' {
+				return fmt.Errorf("audit tag %q may only contain printable ASCII characters, got %q", name, c)
+			}
+		}
+	}
+	return nil
+}
+
+// addAuditTags puts a role's audit tags on a successful response about it.
+// Error responses are left alone: Vault only treats a response as an error
+// while error is its sole field.
+func addAuditTags(resp *logical.Response, role *RoleEntry) {
+	if resp == nil || resp.IsError() || role == nil || len(role.AuditTags) == 0 {
+		return
+	}
+	tags := make(map[string]interface{}, len(role.AuditTags))
+	for name, value := range role.AuditTags {
+		tags[name] = value
+	}
+	if resp.Data == nil {
+		resp.Data = make(map[string]interface{})
+	}
+	resp.Data[auditTagsKey] = tags
+}
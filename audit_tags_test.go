@@ -0,0 +1,108 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestAuditTags(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"audit_tags": []string{"app=billing", "team=payments"}},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("set audit_tags: err=%v, resp=%v", err, resp)
+	}
+	want := map[string]interface{}{"app": "billing", "team": "payments"}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-role/test-role",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("rotate: err=%v, resp=%v", err, resp)
+	}
+	if got := resp.Data["audit_tags"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("rotate audit_tags = %v, want %v", got, want)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test-role",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read creds: err=%v, resp=%v", err, resp)
+	}
+	if got := resp.Data["audit_tags"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("creds audit_tags = %v, want %v", got, want)
+	}
+
+	// A patch that leaves audit_tags alone keeps them.
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"password_length": 32},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("patch role: err=%v, resp=%v", err, resp)
+	}
+	role, _ := getRole(ctx, storage, "test-role")
+	if len(role.AuditTags) != 2 {
+		t.Errorf("audit_tags lost by a patch: %v", role.AuditTags)
+	}
+
+	// Roles without tags return none.
+	role.AuditTags = nil
+	if err := putRole(ctx, storage, "test-role", role); err != nil {
+		t.Fatalf("putRole: %v", err)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test-role",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read creds: err=%v, resp=%v", err, resp)
+	}
+	if _, ok := resp.Data["audit_tags"]; ok {
+		t.Error("expected no audit_tags for a role without them")
+	}
+}
+
+func TestValidateAuditTags(t *testing.T) {
+	tooMany := make(map[string]string, maxAuditTags+1)
+	for i := 0; i <= maxAuditTags; i++ {
+		tooMany[string(rune('a'+i))] = "x"
+	}
+	for name, tc := range map[string]struct {
+		tags  map[string]string
+		valid bool
+	}{
+		"none":          {nil, true},
+		"simple":        {map[string]string{"app": "billing", "cost-center": "4711", "env.tier": "prod"}, true},
+		"uppercase":     {map[string]string{"App": "billing"}, false},
+		"leading dash":  {map[string]string{"-app": "billing"}, false},
+		"long name":     {map[string]string{strings.Repeat("a", 65): "billing"}, false},
+		"empty value":   {map[string]string{"app": ""}, false},
+		"long value":    {map[string]string{"app": strings.Repeat("b", maxAuditTagValueLength+1)}, false},
+		"control value": {map[string]string{"app": "bill\ning"}, false},
+		"too many":      {tooMany, false},
+	} {
+		if err := validateAuditTags(tc.tags); (err == nil) != tc.valid {
+			t.Errorf("%s: expected valid=%v, got %v", name, tc.valid, err)
+		}
+	}
+}
@@ -2,18 +2,87 @@ package solacevaultplugin
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
 const backendHelp = "The Solace secrets engine rotates CLI user passwords on Solace PubSub+ brokers."
 
+const pluginVersion = "v0.1.0"
+
+// operationPrefixSolace prefixes the OpenAPI operation IDs of every path.
+const operationPrefixSolace = "solace"
+
+// listResponseFields describes the response of a list operation.
+var listResponseFields = map[string]*framework.FieldSchema{
+	"keys": {
+		Type:        framework.TypeStringSlice,
+		Description: "Names of the entries.",
+	},
+}
+
+// noContentResponses describes an operation that returns no data.
+var noContentResponses = map[int][]framework.Response{
+	http.StatusNoContent: {{Description: "No Content"}},
+}
+
 type solaceBackend struct {
 	*framework.Backend
-	roleMutex sync.RWMutex
+
+	// roleLocks serialize rotation of, and credential reads for, a role.
+	roleLocks []*locksutil.LockEntry
+
+	clientMutex sync.Mutex
+	clients     map[string]*cachedHTTPClient
+
+	// entries caches stored roles and brokers; see entry_cache.go.
+	entries *entryCache
+
+	stateMutex   sync.Mutex
+	brokerStates map[string]*brokerState
+	configLocks  map[string]*configLock
+
+	periodicMutex  sync.Mutex
+	lastPeriodic   periodicRun
+	periodicCursor string
+
+	// writableSince is when this node last became able to rotate: when the
+	// mount was set up, or when it stopped being a replication secondary
+	// or standby. It is zero while the node cannot rotate.
+	writableSince time.Time
+
+	// schedule holds when each role is next due for automatic rotation;
+	// see periodic.go.
+	schedule map[string]time.Time
+
+	// passwordExpiry holds, for scheduled roles with a max_password_age,
+	// when their credential exceeds it; see periodic.go.
+	passwordExpiry map[string]time.Time
+
+	// localRotations holds, for roles this node rotated, when they are due
+	// by this node's monotonic clock; see periodic.go.
+	localRotations map[string]localRotation
+
+	// generation is the highest storage generation this node has written,
+	// and rotatedAt the generation of each role's rotation; see restore.go.
+	generationMutex sync.Mutex
+	generation      uint64
+	rotatedAt       map[string]uint64
+
+	// restoreCheckPending is set when the mount starts, until periodic
+	// passes have checked every role's stored password on its brokers, and
+	// restoreCheckCursor is the last role checked; see restore.go. Both are
+	// guarded by generationMutex.
+	restoreCheckPending bool
+	restoreCheckCursor  string
 }
 
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
@@ -25,52 +94,127 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 }
 
 func backend() *solaceBackend {
-	b := &solaceBackend{}
+	b := &solaceBackend{
+		roleLocks:     locksutil.CreateLocks(),
+		entries:       newEntryCache(entryCacheSize),
+		writableSince: time.Now(),
+	}
 
 	b.Backend = &framework.Backend{
-		Help:        backendHelp,
-		BackendType: logical.TypeLogical,
-		RunningVersion: "v0.1.0",
+		Help:           backendHelp,
+		BackendType:    logical.TypeLogical,
+		RunningVersion: pluginVersion,
 		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"health",
+			},
 			SealWrapStorage: []string{
 				"config/brokers/*",
-				"roles/*",
+				"secrets/*",
+				"recovery/*",
+				"retained/*",
+				"activity/*",
 			},
 		},
-		PeriodicFunc: b.periodicFunc,
+		InitializeFunc: b.initialize,
+		PeriodicFunc:   b.periodicFunc,
+		Invalidate:     b.invalidate,
+		Clean:          b.clean,
+		// Every operation that writes storage or changes a broker sets
+		// ForwardPerformanceStandby and ForwardPerformanceSecondary, so
+		// performance standbys and secondaries hand it whole to the node
+		// that owns role storage. All storage is mount-wide, so there is no
+		// LocalStorage or WriteForwardedStorage to declare.
 		Paths: framework.PathAppend(
-            pathConfigBrokers(b),
-            pathRoles(b),
-            pathCreds(b),
-            pathRotateRole(b),
-       ),
+			pathConfigBrokerActivity(b),
+			pathConfigBrokerPublic(b),
+			pathConfigBrokers(b),
+			pathConfigBrokerGroups(b),
+			pathConfigSettings(b),
+			pathRolesBulkDelete(b),
+			pathRolesSpec(b),
+			pathRoles(b),
+			pathRoleTransferOwnership(b),
+			pathCreds(b),
+			pathRotateRole(b),
+			pathSync(b),
+			pathRecovery(b),
+			pathRetained(b),
+			pathVerify(b),
+			pathVerifyPassword(b),
+			pathDecommission(b),
+			pathTidy(b),
+			pathStatus(b),
+			pathHealth(b),
+			pathInfo(b),
+		),
 	}
 
 	return b
 }
 
-func (b *solaceBackend) periodicFunc(ctx context.Context, req *logical.Request) error {
-	roles, err := listRoles(ctx, req.Storage)
-	if err != nil {
-		b.Logger().Error("periodic: failed to list roles", "error", err)
-		return nil
+// roleLock returns the lock guarding the named role.
+func (b *solaceBackend) roleLock(name string) *locksutil.LockEntry {
+	return locksutil.LockForKey(b.roleLocks, name)
+}
+
+// canWriteBrokers reports whether this node may change passwords on brokers.
+// Performance standbys and performance secondaries share (or replicate) the
+// primary's role storage, so a password set from there could never be
+// persisted; DR secondaries serve no requests at all. Local mounts on a
+// performance secondary keep their own storage and are writable.
+func (b *solaceBackend) canWriteBrokers() bool {
+	state := b.System().ReplicationState()
+	if state.HasState(consts.ReplicationDRSecondary | consts.ReplicationPerformanceStandby) {
+		return false
 	}
+	if state.HasState(consts.ReplicationPerformanceSecondary) && !b.System().LocalMount() {
+		return false
+	}
+	return true
+}
 
-	for _, name := range roles {
-		role, err := getRole(ctx, req.Storage, name)
-		if err != nil {
-			b.Logger().Error("periodic: failed to read role", "role", name, "error", err)
-			continue
-		}
-		if role == nil || role.RotationPeriod == 0 || role.LastRotated.IsZero() {
-			continue
+func (b *solaceBackend) initialize(ctx context.Context, req *logical.InitializationRequest) error {
+	// Vault calls this directly rather than through HandleRequest, so the
+	// entries it writes are dropped from the cache here.
+	req.Storage = b.cachedStorage(req.Storage)
+
+	// Nodes that cannot write storage see the index and migrated secrets
+	// once the node that can has written them.
+	if b.WriteSafeReplicationState() {
+		if err := buildBrokerRoleIndex(ctx, req.Storage); err != nil {
+			return fmt.Errorf("building broker-to-role index: %w", err)
 		}
-		if time.Now().UTC().After(role.LastRotated.Add(role.RotationPeriod)) {
-			if _, err := b.rotateRole(ctx, req.Storage, name); err != nil {
-				b.Logger().Error("periodic: failed to rotate role", "role", name, "error", err)
-			}
+		if err := migrateRoleSecrets(ctx, req.Storage); err != nil {
+			return fmt.Errorf("migrating role passwords to secret storage: %w", err)
 		}
 	}
-
+	if err := b.loadGeneration(ctx, req.Storage); err != nil {
+		return fmt.Errorf("loading storage generation: %w", err)
+	}
+	// Names are only reported, so failing to check them does not keep the
+	// mount from starting.
+	if err := b.flagProblemNames(ctx, req.Storage); err != nil {
+		b.Logger().Warn("failed to check stored names", "error", err)
+	}
 	return nil
 }
+
+// invalidate is called when storage is changed out from under this node, e.g.
+// on performance standbys, so cached entries and broker clients are rebuilt
+// and changed roles are read again by the next periodic pass.
+func (b *solaceBackend) invalidate(_ context.Context, key string) {
+	b.entries.remove(key)
+	switch {
+	case strings.HasPrefix(key, brokerStoragePrefix):
+		b.invalidateClient(strings.TrimPrefix(key, brokerStoragePrefix))
+	case strings.HasPrefix(key, roleStoragePrefix):
+		b.unscheduleRole(strings.TrimPrefix(key, roleStoragePrefix))
+	}
+}
+
+func (b *solaceBackend) clean(_ context.Context) {
+	b.resetClients()
+	b.resetSchedule()
+	b.entries.reset()
+}
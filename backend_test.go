@@ -4,9 +4,13 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -56,10 +60,12 @@ func TestPeriodicFunc_RotatesDueRoles(t *testing.T) {
 
 	// Get password after initial rotation
 	role, _ := getRole(ctx, storage, "fast-role")
-	firstPassword := role.Password
+	secret, _ := getRoleSecret(ctx, storage, "fast-role")
+	firstPassword := secret.Password
 
-	// Backdate last_rotated to trigger periodic rotation
-	role.LastRotated = time.Now().Add(-2 * time.Second)
+	// Backdate last_rotated to trigger periodic rotation. A stored time
+	// this node did not write must be past due by the clock skew tolerance.
+	role.LastRotated = time.Now().Add(-time.Hour)
 	putRole(ctx, storage, "fast-role", role)
 
 	// Run periodic function
@@ -70,8 +76,8 @@ func TestPeriodicFunc_RotatesDueRoles(t *testing.T) {
 	}
 
 	// Verify password changed
-	role, _ = getRole(ctx, storage, "fast-role")
-	if role.Password == firstPassword {
+	secret, _ = getRoleSecret(ctx, storage, "fast-role")
+	if secret.Password == firstPassword {
 		t.Error("password should have changed after periodic rotation")
 	}
 }
@@ -120,8 +126,8 @@ func TestPeriodicFunc_SkipsNotDueRoles(t *testing.T) {
 	}
 	b.HandleRequest(ctx, req)
 
-	role, _ := getRole(ctx, storage, "slow-role")
-	firstPassword := role.Password
+	secret, _ := getRoleSecret(ctx, storage, "slow-role")
+	firstPassword := secret.Password
 
 	// Run periodic — should NOT rotate (not due yet)
 	periodicReq := &logical.Request{Storage: storage}
@@ -130,8 +136,8 @@ func TestPeriodicFunc_SkipsNotDueRoles(t *testing.T) {
 		t.Fatalf("periodicFunc: %v", err)
 	}
 
-	role, _ = getRole(ctx, storage, "slow-role")
-	if role.Password != firstPassword {
+	secret, _ = getRoleSecret(ctx, storage, "slow-role")
+	if secret.Password != firstPassword {
 		t.Error("password should NOT have changed — not due for rotation")
 	}
 }
@@ -161,3 +167,574 @@ func TestPeriodicFunc_SkipsNoRotationPeriod(t *testing.T) {
 		t.Fatalf("periodicFunc: %v", err)
 	}
 }
+
+func TestPeriodicFunc_DefersThrottledBroker(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":       server.URL,
+			"admin_username": "admin",
+			"admin_password": "secret",
+		},
+	}
+	b.HandleRequest(ctx, req)
+
+	role := &RoleEntry{
+		Broker:         "test-broker",
+		CLIUsername:    "monitor",
+		RotationPeriod: time.Second,
+		PasswordLength: defaultPasswordLength,
+		LastRotated:    time.Now().Add(-time.Hour),
+	}
+	putRole(ctx, storage, "throttled", role)
+
+	sb := b.(*solaceBackend)
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if _, deferred := sb.brokerDeferredUntil("test-broker"); !deferred {
+		t.Fatal("expected broker to be deferred after Retry-After")
+	}
+
+	// The second pass must not contact the broker while it is backing off.
+	before := attempts
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if attempts != before {
+		t.Errorf("broker contacted %d more times while deferred", attempts-before)
+	}
+}
+
+// roleReadCountingStorage counts reads of role entries.
+type roleReadCountingStorage struct {
+	logical.Storage
+	roleReads int
+}
+
+func (s *roleReadCountingStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	if strings.HasPrefix(key, roleStoragePrefix) {
+		s.roleReads++
+	}
+	return s.Storage.Get(ctx, key)
+}
+
+func TestPeriodicFunc_ReadsOnlyUnscheduledRoles(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	for _, name := range []string{"a", "b", "c"} {
+		putRole(ctx, storage, name, &RoleEntry{
+			Broker:         "test-broker",
+			CLIUsername:    name,
+			RotationPeriod: time.Hour,
+			LastRotated:    time.Now(),
+		})
+	}
+
+	sb := b.(*solaceBackend)
+	counting := &roleReadCountingStorage{Storage: storage}
+	pass := func() int {
+		counting.roleReads = 0
+		if err := sb.periodicFunc(ctx, &logical.Request{Storage: counting}); err != nil {
+			t.Fatalf("periodicFunc: %v", err)
+		}
+		return counting.roleReads
+	}
+
+	if reads := pass(); reads != 3 {
+		t.Errorf("first pass read %d roles, want 3", reads)
+	}
+	if reads := pass(); reads != 0 {
+		t.Errorf("second pass read %d roles, want 0 as none is due", reads)
+	}
+
+	// A role changed elsewhere is read again.
+	sb.invalidate(ctx, roleStoragePrefix+"b")
+	if reads := pass(); reads != 1 {
+		t.Errorf("pass after invalidation read %d roles, want 1", reads)
+	}
+}
+
+func TestPeriodicFunc_PeriodicInterval(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	settings, _ := getSettings(ctx, storage)
+	settings.PeriodicInterval = time.Hour
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+
+	pass := func() time.Time {
+		t.Helper()
+		if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+			t.Fatalf("periodicFunc: %v", err)
+		}
+		return sb.lastPeriodicRun().Started
+	}
+
+	first := pass()
+	if first.IsZero() {
+		t.Fatal("expected the first call to run a pass")
+	}
+	if second := pass(); !second.Equal(first) {
+		t.Error("expected calls within periodic_interval to be skipped")
+	}
+
+	sb.periodicMutex.Lock()
+	sb.lastPeriodic.Started = time.Now().Add(-time.Hour + periodicIntervalSlack)
+	sb.periodicMutex.Unlock()
+	if third := pass(); !third.After(first) {
+		t.Error("expected a pass once periodic_interval has elapsed, less the slack")
+	}
+}
+
+func TestPeriodicFunc_ClockSkew(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	pass := func() {
+		t.Helper()
+		if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+			t.Fatalf("periodicFunc: %v", err)
+		}
+	}
+	password := func(name string) string {
+		t.Helper()
+		secret, _ := getRoleSecret(ctx, storage, name)
+		if secret == nil {
+			return ""
+		}
+		return secret.Password
+	}
+
+	// Another node, whose clock is behind this one's, rotated the role:
+	// it looks 30 seconds past due here.
+	putRole(ctx, storage, "elsewhere", &RoleEntry{
+		Broker:         "test-broker",
+		CLIUsername:    "monitor",
+		RotationPeriod: time.Hour,
+		PasswordLength: defaultPasswordLength,
+		LastRotated:    time.Now().Add(-time.Hour - 30*time.Second),
+	})
+	pass()
+	if password("elsewhere") != "" {
+		t.Error("role within clock_skew_tolerance of its due time was rotated")
+	}
+
+	settings, _ := getSettings(ctx, storage)
+	settings.ClockSkewTolerance = 0
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+	sb.unscheduleRole("elsewhere")
+	pass()
+	rotated := password("elsewhere")
+	if rotated == "" {
+		t.Fatal("role past due was not rotated with clock_skew_tolerance 0")
+	}
+
+	// A role this node rotated is timed by its monotonic clock, whatever
+	// the wall clock says.
+	sb.periodicMutex.Lock()
+	local := sb.localRotations["elsewhere"]
+	local.dueAt = time.Now().Add(-time.Second)
+	sb.localRotations["elsewhere"] = local
+	sb.periodicMutex.Unlock()
+	pass()
+	if password("elsewhere") == rotated {
+		t.Error("role due by this node's monotonic clock was not rotated")
+	}
+}
+
+func TestPeriodicFunc_StartupCooldown(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	config := logical.TestBackendConfig()
+	sys := config.System.(*logical.StaticSystemView)
+	sys.ReplicationStateVal = consts.ReplicationDRSecondary
+	b, storage := newTestBackend(t, config)
+	sb := b.(*solaceBackend)
+
+	settings := defaultSettings()
+	settings.AllowInsecureTransport = true
+	settings.StartupCooldown = time.Hour
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+	if err := putBroker(ctx, storage, "test-broker", &BrokerConfig{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "secret",
+	}); err != nil {
+		t.Fatalf("putBroker: %v", err)
+	}
+	putRole(ctx, storage, "due", &RoleEntry{
+		Broker:         "test-broker",
+		CLIUsername:    "monitor",
+		RotationPeriod: time.Second,
+		PasswordLength: defaultPasswordLength,
+		LastRotated:    time.Now().Add(-time.Hour),
+	})
+	pass := func() {
+		t.Helper()
+		if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+			t.Fatalf("periodicFunc: %v", err)
+		}
+	}
+
+	// The mount was set up long ago, but the cool-down starts when the
+	// node is promoted.
+	sb.periodicMutex.Lock()
+	sb.writableSince = time.Now().Add(-2 * time.Hour)
+	sb.periodicMutex.Unlock()
+	pass()
+	sys.ReplicationStateVal = consts.ReplicationPerformancePrimary
+	pass()
+	if attempts != 0 {
+		t.Fatalf("broker contacted %d times during the startup cool-down", attempts)
+	}
+
+	sb.periodicMutex.Lock()
+	sb.writableSince = time.Now().Add(-time.Hour)
+	sb.periodicMutex.Unlock()
+	pass()
+	if attempts == 0 {
+		t.Error("expected the due role to be rotated once the cool-down is over")
+	}
+}
+
+func TestPeriodicFunc_SkipsReplicatedSecondaries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		state   consts.ReplicationState
+		local   bool
+		canSEMP bool
+	}{
+		{"performance standby", consts.ReplicationPerformanceStandby, false, false},
+		{"performance secondary", consts.ReplicationPerformanceSecondary, false, false},
+		{"local mount on performance secondary", consts.ReplicationPerformanceSecondary, true, true},
+		{"DR secondary", consts.ReplicationDRSecondary, false, false},
+		{"primary", consts.ReplicationPerformancePrimary, false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			config := logical.TestBackendConfig()
+			sys := config.System.(*logical.StaticSystemView)
+			sys.ReplicationStateVal = tc.state
+			sys.LocalMountVal = tc.local
+			b, storage := newTestBackend(t, config)
+
+			if err := putBroker(ctx, storage, "test-broker", &BrokerConfig{
+				SEMPURL:       server.URL,
+				AdminUsername: "admin",
+				AdminPassword: "secret",
+			}); err != nil {
+				t.Fatalf("putBroker: %v", err)
+			}
+			putRole(ctx, storage, "due", &RoleEntry{
+				Broker:         "test-broker",
+				CLIUsername:    "monitor",
+				RotationPeriod: time.Second,
+				PasswordLength: defaultPasswordLength,
+				LastRotated:    time.Now().Add(-time.Hour),
+			})
+
+			before := attempts
+			if err := b.(*solaceBackend).periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+				t.Fatalf("periodicFunc: %v", err)
+			}
+			if contacted := attempts != before; contacted != tc.canSEMP {
+				t.Errorf("broker contacted = %v, want %v", contacted, tc.canSEMP)
+			}
+
+			if !tc.canSEMP {
+				_, err := b.HandleRequest(ctx, &logical.Request{
+					Operation: logical.UpdateOperation,
+					Path:      "rotate-role/due",
+					Storage:   storage,
+				})
+				if err != logical.ErrReadOnly {
+					t.Errorf("rotate-role err = %v, want ErrReadOnly so Vault forwards it", err)
+				}
+			}
+		})
+	}
+}
+
+// readOnlyStorage refuses writes, as storage does on a performance standby.
+type readOnlyStorage struct {
+	logical.Storage
+}
+
+func (s *readOnlyStorage) Put(context.Context, *logical.StorageEntry) error {
+	return logical.ErrReadOnly
+}
+
+func (s *readOnlyStorage) Delete(context.Context, string) error {
+	return logical.ErrReadOnly
+}
+
+func TestWriteOperations_ForwardedFromPerformanceNodes(t *testing.T) {
+	for name, state := range map[string]consts.ReplicationState{
+		"performance standby":   consts.ReplicationPerformanceStandby,
+		"performance secondary": consts.ReplicationPerformanceSecondary,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			config := logical.TestBackendConfig()
+			config.System.(*logical.StaticSystemView).ReplicationStateVal = state
+			b, storage := newTestBackend(t, config)
+			putBroker(ctx, storage, "test-broker", &BrokerConfig{SEMPURL: "https://broker:8080", AdminUsername: "admin", AdminPassword: "secret"})
+			putRole(ctx, storage, "test-role", &RoleEntry{Broker: "test-broker", CLIUsername: "monitor", PasswordLength: defaultPasswordLength})
+
+			// Setting up the mount must not fail on storage it cannot write.
+			if err := b.Initialize(ctx, &logical.InitializationRequest{Storage: &readOnlyStorage{storage}}); err != nil {
+				t.Fatalf("Initialize: %v", err)
+			}
+
+			for _, req := range []*logical.Request{
+				{Operation: logical.UpdateOperation, Path: "rotate-role/test-role"},
+				{Operation: logical.UpdateOperation, Path: "sync/test-role"},
+				{Operation: logical.UpdateOperation, Path: "decommission/test-role"},
+				{Operation: logical.UpdateOperation, Path: "roles/test-role", Data: map[string]interface{}{"rotation_period": 3600}},
+				{Operation: logical.DeleteOperation, Path: "roles/test-role"},
+				{Operation: logical.DeleteOperation, Path: "config/brokers/test-broker"},
+				{Operation: logical.UpdateOperation, Path: "config/settings", Data: map[string]interface{}{"periodic_concurrency": 2}},
+				{Operation: logical.UpdateOperation, Path: "tidy", Data: map[string]interface{}{"cleanup": true}},
+			} {
+				req.Storage = storage
+				if _, err := b.HandleRequest(ctx, req); err != logical.ErrReadOnly {
+					t.Errorf("%s %s: err = %v, want ErrReadOnly so Vault forwards it", req.Operation, req.Path, err)
+				}
+			}
+			if role, _ := getRole(ctx, storage, "test-role"); role == nil || role.RotationPeriod != 0 {
+				t.Errorf("role changed on a performance node: %+v", role)
+			}
+
+			// Reads are still served locally.
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      "roles/test-role",
+				Storage:   storage,
+			})
+			if err != nil || resp == nil {
+				t.Errorf("read role: err=%v, resp=%v", err, resp)
+			}
+		})
+	}
+}
+
+func TestPeriodicFunc_TimeBudgetCarriesOver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	putBroker(ctx, storage, "test-broker", &BrokerConfig{SEMPURL: server.URL, AdminUsername: "admin", AdminPassword: "secret"})
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, name := range names {
+		putRole(ctx, storage, name, &RoleEntry{
+			Broker:         "test-broker",
+			CLIUsername:    name,
+			RotationPeriod: time.Hour,
+			PasswordLength: defaultPasswordLength,
+			LastRotated:    time.Now().Add(-2 * time.Hour),
+		})
+	}
+	settings := defaultSettings()
+	settings.PeriodicTimeBudget = 50 * time.Millisecond
+	putSettings(ctx, storage, settings)
+
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	run := sb.lastPeriodicRun()
+	if run.CarriedOver == 0 || run.Rotated+run.CarriedOver != len(names) {
+		t.Fatalf("rotated=%d carried_over=%d, want some roles carried over", run.Rotated, run.CarriedOver)
+	}
+
+	// Later passes pick up where the previous one stopped until every role
+	// has been rotated.
+	for i := 0; i < len(names) && sb.lastPeriodicRun().CarriedOver > 0; i++ {
+		if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+			t.Fatalf("periodicFunc: %v", err)
+		}
+	}
+	for _, name := range names {
+		role, _ := getRole(ctx, storage, name)
+		if time.Since(role.LastRotated) > time.Minute {
+			t.Errorf("role %q was never rotated", name)
+		}
+	}
+}
+
+func TestPeriodicFunc_TimeBudgetCancelsWaitingRotations(t *testing.T) {
+	// The broker never answers the rotation lock lookup that comes before
+	// any change, so only the budget's deadline ends the pass.
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	putBroker(ctx, storage, "test-broker", &BrokerConfig{SEMPURL: server.URL, AdminUsername: "admin", AdminPassword: "secret", RotationLockTTL: time.Minute})
+	for _, name := range []string{"a", "b"} {
+		putRole(ctx, storage, name, &RoleEntry{
+			Broker:         "test-broker",
+			CLIUsername:    name,
+			RotationPeriod: time.Hour,
+			PasswordLength: defaultPasswordLength,
+			LastRotated:    time.Now().Add(-2 * time.Hour),
+		})
+	}
+	settings := defaultSettings()
+	settings.PeriodicTimeBudget = 100 * time.Millisecond
+	settings.PeriodicConcurrency = 1
+	putSettings(ctx, storage, settings)
+
+	start := time.Now()
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("pass took %s, want it ended by its time budget", elapsed)
+	}
+	run := sb.lastPeriodicRun()
+	if run.Failed != 1 || run.CarriedOver != 1 {
+		t.Errorf("failed=%d carried_over=%d, want the started rotation cancelled and the other carried over", run.Failed, run.CarriedOver)
+	}
+}
+
+func TestResumeAfterCursor(t *testing.T) {
+	b := backend()
+	due := []string{"c", "a", "d", "b"}
+
+	if got := b.resumeAfterCursor(append([]string(nil), due...)); strings.Join(got, ",") != "a,b,c,d" {
+		t.Errorf("without cursor = %v, want [a b c d]", got)
+	}
+	b.setPeriodicCursor("b")
+	if got := b.resumeAfterCursor(append([]string(nil), due...)); strings.Join(got, ",") != "c,d,a,b" {
+		t.Errorf("after b = %v, want [c d a b]", got)
+	}
+	b.setPeriodicCursor("bb")
+	if got := b.resumeAfterCursor(append([]string(nil), due...)); strings.Join(got, ",") != "c,d,a,b" {
+		t.Errorf("after removed role bb = %v, want [c d a b]", got)
+	}
+}
+
+func TestBackend_OpenAPI(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.System.(*logical.StaticSystemView).PluginEnvironment = &logical.PluginEnvironment{VaultVersion: "1.15.0"}
+	b, storage := newTestBackend(t, config)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.HelpOperation,
+		Path:      "",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("help: err=%v, resp=%v", err, resp)
+	}
+	doc, ok := resp.Data["openapi"].(*framework.OASDocument)
+	if !ok {
+		t.Fatalf("unexpected openapi document %T", resp.Data["openapi"])
+	}
+
+	ids := make(map[string]string)
+	for path, item := range doc.Paths {
+		for method, op := range map[string]*framework.OASOperation{
+			"get": item.Get, "post": item.Post, "delete": item.Delete, "patch": item.Patch,
+		} {
+			if op == nil {
+				continue
+			}
+			if !strings.HasPrefix(op.OperationID, operationPrefixSolace+"-") {
+				t.Errorf("%s %s: operation ID %q lacks the %s prefix", method, path, op.OperationID, operationPrefixSolace)
+			}
+			if other, dup := ids[op.OperationID]; dup {
+				t.Errorf("%s %s: operation ID %q is also used by %s", method, path, op.OperationID, other)
+			}
+			ids[op.OperationID] = method + " " + path
+		}
+	}
+	for _, id := range []string{"solace-configure-broker", "solace-read-broker", "solace-list-roles", "solace-rotate-role", "solace-read-credentials", "solace-tidy"} {
+		if _, ok := ids[id]; !ok {
+			t.Errorf("missing operation %s", id)
+		}
+	}
+}
+
+func TestBackend_ResponseSchemas(t *testing.T) {
+	b, storage, _ := setupRotationTest(t)
+	ctx := context.Background()
+
+	if resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-role/test-role",
+		Storage:   storage,
+	}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("rotate: err=%v, resp=%v", err, resp)
+	}
+
+	for _, path := range []string{"config/brokers/test-broker", "config/settings", "roles/test-role", "creds/test-role", "status", "status/overdue"} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("read %s: err=%v, resp=%v", path, err, resp)
+		}
+		route := b.(*solaceBackend).Route(path)
+		schema.ValidateResponse(t, schema.GetResponseSchema(t, route, logical.ReadOperation), resp, true)
+	}
+}
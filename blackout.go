@@ -0,0 +1,328 @@
+package solacevaultplugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A rotation blackout holds back automatic rotations during a change freeze.
+// It is made of whole days, given as dates or as days of the week in the
+// blackout time zone, and of periods taken from an iCalendar feed. Manual
+// rotations are not held back, so a compromised credential can still be
+// replaced during a freeze.
+
+// blackoutDateLayout is how blackout_dates are written.
+const blackoutDateLayout = "2006-01-02"
+
+// maxICalSize bounds the iCalendar feed read from blackout_ical_url.
+const maxICalSize = 1 << 20
+
+// icalFetchTimeout bounds fetching blackout_ical_url.
+const icalFetchTimeout = 30 * time.Second
+
+// blackoutPeriod is an event from the blackout calendar feed.
+type blackoutPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// blackoutLocation returns the time zone blackout dates and weekdays are in.
+func (s *Settings) blackoutLocation() *time.Location {
+	if s.BlackoutTimezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.BlackoutTimezone)
+	if err != nil {
+		// The time zone was checked when it was set, so this only happens
+		// if the host lost its zone database; UTC is the safer guess than
+		// no blackout at all.
+		return time.UTC
+	}
+	return loc
+}
+
+// inBlackout reports whether automatic rotations are held back at now.
+func (s *Settings) inBlackout(now time.Time) bool {
+	if len(s.BlackoutDates) == 0 && len(s.BlackoutWeekdays) == 0 && len(s.BlackoutPeriods) == 0 {
+		return false
+	}
+	local := now.In(s.blackoutLocation())
+	date := local.Format(blackoutDateLayout)
+	for _, d := range s.BlackoutDates {
+		if d == date {
+			return true
+		}
+	}
+	for _, name := range s.BlackoutWeekdays {
+		if weekdays[name] == local.Weekday() {
+			return true
+		}
+	}
+	for _, p := range s.BlackoutPeriods {
+		if !now.Before(p.Start) && now.Before(p.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeBlackoutDates checks blackout_dates and returns them sorted and
+// without repeats.
+func normalizeBlackoutDates(dates []string) ([]string, error) {
+	seen := make(map[string]bool, len(dates))
+	var out []string
+	for _, d := range dates {
+		d = strings.TrimSpace(d)
+		if _, err := time.Parse(blackoutDateLayout, d); err != nil {
+			return nil, fmt.Errorf("blackout_dates must be dates like 2026-12-24, got %q", d)
+		}
+		if !seen[d] {
+			seen[d] = true
+			out = append(out, d)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// normalizeBlackoutWeekdays checks blackout_weekdays and returns them in
+// lowercase, in week order.
+func normalizeBlackoutWeekdays(names []string) ([]string, error) {
+	var days [7]bool
+	for _, name := range names {
+		day, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("blackout_weekdays must be days of the week like saturday, got %q", name)
+		}
+		days[day] = true
+	}
+	var out []string
+	for day, set := range days {
+		if set {
+			out = append(out, strings.ToLower(time.Weekday(day).String()))
+		}
+	}
+	if len(out) == len(days) {
+		return nil, errors.New("blackout_weekdays cannot cover the whole week; automatic rotation would never run")
+	}
+	return out, nil
+}
+
+// fetchICalBlackouts downloads an iCalendar feed and returns its events that
+// have not yet ended.
+func fetchICalBlackouts(ctx context.Context, feedURL string, allowInsecure bool, loc *time.Location) ([]blackoutPeriod, error) {
+	parsed, err := url.Parse(feedURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return nil, errors.New("blackout_ical_url must be an http or https URL with a host")
+	}
+	if parsed.Scheme != "https" && !allowInsecure {
+		return nil, errors.New("blackout_ical_url must use https; set allow_insecure_transport to permit http")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, icalFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "text/calendar")
+	req.Header.Set("User-Agent", sempUserAgent)
+	// The client brokers are reached with, so the feed gets the same
+	// timeouts and, with redirects refused, is read only from the host the
+	// settings name.
+	client := newHTTPClient(&BrokerConfig{RequestTimeout: icalFetchTimeout})
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching blackout calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching blackout calendar: server returned HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxICalSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading blackout calendar: %w", err)
+	}
+	if len(body) > maxICalSize {
+		return nil, fmt.Errorf("blackout calendar is larger than %d bytes", maxICalSize)
+	}
+	return parseICalBlackouts(string(body), loc, time.Now())
+}
+
+// errICalRecurrence is returned for a blackout calendar with a recurring
+// event. Recurrences are not expanded, and counting only their first
+// occurrence would silently leave the others out of the freeze.
+var errICalRecurrence = errors.New("blackout calendar has recurring events (RRULE or RDATE), which are not supported; use a feed that lists each occurrence")
+
+// parseICalBlackouts returns the events of an iCalendar feed that end after
+// now. An event ends at its DTEND, or its DURATION after it starts. All-day
+// events cover their days in loc, as do times without a zone. Cancelled
+// events are left out, and a feed with a recurring event is refused.
+func parseICalBlackouts(feed string, loc *time.Location, now time.Time) ([]blackoutPeriod, error) {
+	lines := unfoldICal(feed)
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, errors.New("blackout calendar is not an iCalendar feed")
+	}
+
+	var periods []blackoutPeriod
+	var inEvent, cancelled, allDay, recurring, hasDuration bool
+	var start, end time.Time
+	var durationDays int
+	var duration time.Duration
+	for _, line := range lines {
+		name, params, value := splitICalLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			inEvent, cancelled, allDay, recurring, hasDuration = true, false, false, false, false
+			start, end = time.Time{}, time.Time{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			inEvent = false
+			if start.IsZero() || cancelled {
+				continue
+			}
+			if recurring {
+				return nil, errICalRecurrence
+			}
+			if end.IsZero() && hasDuration {
+				end = start.AddDate(0, 0, durationDays).Add(duration)
+			}
+			if end.IsZero() && allDay {
+				end = start.AddDate(0, 0, 1)
+			}
+			if end.After(start) && end.After(now) {
+				periods = append(periods, blackoutPeriod{Start: start.UTC(), End: end.UTC()})
+			}
+		case !inEvent:
+		case name == "DTSTART" || name == "DTEND":
+			t, date, err := parseICalTime(params, value, loc)
+			if err != nil {
+				return nil, fmt.Errorf("blackout calendar: %s: %w", name, err)
+			}
+			if name == "DTSTART" {
+				start, allDay = t, date
+			} else {
+				end = t
+			}
+		case name == "DURATION":
+			days, d, err := parseICalDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("blackout calendar: DURATION: %w", err)
+			}
+			durationDays, duration, hasDuration = days, d, true
+		case name == "RRULE" || name == "RDATE":
+			recurring = true
+		case name == "STATUS":
+			cancelled = strings.EqualFold(value, "CANCELLED")
+		}
+	}
+	return periods, nil
+}
+
+// icalDurationPattern matches a positive iCalendar DURATION: weeks, or days
+// and a time.
+var icalDurationPattern = regexp.MustCompile(`^\+?P(?:(\d+)W|(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?)$`)
+
+// parseICalDuration parses a DURATION value such as P1D, PT4H30M or P1W
+// into whole days, which follow the calendar across daylight saving
+// changes, and a time.
+func parseICalDuration(value string) (days int, d time.Duration, err error) {
+	m := icalDurationPattern.FindStringSubmatch(value)
+	// P alone, or a T without a time, matches the pattern but says nothing.
+	if m == nil || strings.HasSuffix(value, "P") || strings.HasSuffix(value, "T") {
+		return 0, 0, fmt.Errorf("must be a positive duration like P1D or PT2H, got %q", value)
+	}
+	n := func(i int) int {
+		v, _ := strconv.Atoi(m[i])
+		return v
+	}
+	days = 7*n(1) + n(2)
+	d = time.Duration(n(3))*time.Hour + time.Duration(n(4))*time.Minute + time.Duration(n(5))*time.Second
+	return days, d, nil
+}
+
+// unfoldICal splits an iCalendar feed into its logical lines, joining
+// continuation lines, which start with a space or tab, to the line before.
+func unfoldICal(feed string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(feed))
+	scanner.Buffer(make([]byte, 0, 4096), maxICalSize)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitICalLine splits a content line into its upper-cased name, its
+// parameters and its value. Colons inside quoted parameter values do not end
+// the name.
+func splitICalLine(line string) (name string, params map[string]string, value string) {
+	quoted := false
+	sep := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+	parts := strings.Split(line[:sep], ";")
+	params = make(map[string]string, len(parts)-1)
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[sep+1:]
+}
+
+// parseICalTime parses a DTSTART or DTEND value, reporting whether it is a
+// date rather than a time. Dates and times without a zone are in loc, as
+// are times in a TZID this host does not know, such as Windows zone names.
+func parseICalTime(params map[string]string, value string, loc *time.Location) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
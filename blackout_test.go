@@ -0,0 +1,297 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const testBlackoutFeed = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Berlin\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19701025T030000\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Year-end\r\n" +
+	"  freeze\r\n" +
+	"DTSTART;VALUE=DATE:20261224\r\n" +
+	"DTEND;VALUE=DATE:20270102\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=\"Europe/Berlin\":20261110T180000\r\n" +
+	"DTEND;TZID=\"Europe/Berlin\":20261110T220000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20261111T080000Z\r\n" +
+	"DTEND:20261111T090000Z\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20260101\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261201\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICalBlackouts(t *testing.T) {
+	now := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	periods, err := parseICalBlackouts(testBlackoutFeed, time.UTC, now)
+	if err != nil {
+		t.Fatalf("parseICalBlackouts: %v", err)
+	}
+
+	// The cancelled event and the one already over are left out, and an
+	// all-day event without an end lasts one day.
+	want := []blackoutPeriod{
+		{time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 11, 10, 17, 0, 0, 0, time.UTC), time.Date(2026, 11, 10, 21, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(periods) != len(want) {
+		t.Fatalf("periods = %v, want %v", periods, want)
+	}
+	for i := range want {
+		if !periods[i].Start.Equal(want[i].Start) || !periods[i].End.Equal(want[i].End) {
+			t.Errorf("period %d = %v, want %v", i, periods[i], want[i])
+		}
+	}
+
+	if _, err := parseICalBlackouts("not a calendar", time.UTC, now); err == nil {
+		t.Error("parsing a feed that is not iCalendar succeeded")
+	}
+	if _, err := parseICalBlackouts("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\nEND:VCALENDAR\n", time.UTC, now); err == nil {
+		t.Error("parsing a malformed DTSTART succeeded")
+	}
+}
+
+func TestParseICalBlackouts_Duration(t *testing.T) {
+	now := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	feed := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nDTSTART:20261110T170000Z\nDURATION:PT4H30M\nEND:VEVENT\n" +
+		// A day spans the change back from summer time, in the feed's zone.
+		"BEGIN:VEVENT\nDTSTART;TZID=Europe/Berlin:20271030T120000\nDURATION:P1D\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nDTSTART;VALUE=DATE:20261201\nDURATION:P1W\nEND:VEVENT\n" +
+		"END:VCALENDAR\n"
+	periods, err := parseICalBlackouts(feed, time.UTC, now)
+	if err != nil {
+		t.Fatalf("parseICalBlackouts: %v", err)
+	}
+	want := []blackoutPeriod{
+		{time.Date(2026, 11, 10, 17, 0, 0, 0, time.UTC), time.Date(2026, 11, 10, 21, 30, 0, 0, time.UTC)},
+		{time.Date(2027, 10, 30, 12, 0, 0, 0, berlin), time.Date(2027, 10, 31, 12, 0, 0, 0, berlin)},
+		{time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 8, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(periods) != len(want) {
+		t.Fatalf("periods = %v, want %v", periods, want)
+	}
+	for i := range want {
+		if !periods[i].Start.Equal(want[i].Start) || !periods[i].End.Equal(want[i].End) {
+			t.Errorf("period %d = %v, want %v", i, periods[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"", "P", "-P1D", "PT", "P1H", "PT1D", "P1DT", "P1.5D"} {
+		if _, _, err := parseICalDuration(bad); err == nil {
+			t.Errorf("parseICalDuration(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseICalBlackouts_RejectsRecurrence(t *testing.T) {
+	now := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	for _, rule := range []string{"RRULE:FREQ=WEEKLY;BYDAY=FR", "RDATE;VALUE=DATE:20261204"} {
+		feed := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20261127\n" + rule + "\nEND:VEVENT\nEND:VCALENDAR\n"
+		if _, err := parseICalBlackouts(feed, time.UTC, now); !errors.Is(err, errICalRecurrence) {
+			t.Errorf("%s: expected the feed to be refused, got %v", rule, err)
+		}
+	}
+	// A cancelled recurring event holds nothing back, so it is no reason
+	// to refuse the feed.
+	feed := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20261127\nRRULE:FREQ=DAILY\nSTATUS:CANCELLED\nEND:VEVENT\nEND:VCALENDAR\n"
+	if _, err := parseICalBlackouts(feed, time.UTC, now); err != nil {
+		t.Errorf("cancelled recurring event: %v", err)
+	}
+}
+
+func TestSettings_InBlackout(t *testing.T) {
+	settings := &Settings{
+		BlackoutDates:    []string{"2026-12-24"},
+		BlackoutWeekdays: []string{"sunday"},
+		BlackoutTimezone: "Europe/Berlin",
+		BlackoutPeriods: []blackoutPeriod{
+			{time.Date(2026, 11, 10, 17, 0, 0, 0, time.UTC), time.Date(2026, 11, 10, 21, 0, 0, 0, time.UTC)},
+		},
+	}
+	for _, tc := range []struct {
+		at   time.Time
+		want bool
+	}{
+		// 23:30 UTC on 23 December is already the 24th in Berlin.
+		{time.Date(2026, 12, 23, 23, 30, 0, 0, time.UTC), true},
+		{time.Date(2026, 12, 23, 22, 30, 0, 0, time.UTC), false},
+		// 25 October 2026 is a Sunday.
+		{time.Date(2026, 10, 25, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 10, 26, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 11, 10, 17, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 11, 10, 21, 0, 0, 0, time.UTC), false},
+	} {
+		if got := settings.inBlackout(tc.at); got != tc.want {
+			t.Errorf("inBlackout(%s) = %v, want %v", tc.at, got, tc.want)
+		}
+	}
+	if (&Settings{}).inBlackout(time.Now()) {
+		t.Error("settings without a blackout report one")
+	}
+}
+
+func TestPathConfigSettings_Blackout(t *testing.T) {
+	var feed string
+	calendar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if feed == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar")
+		w.Write([]byte(feed))
+	}))
+	defer calendar.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	write := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/settings",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("write settings: %v", err)
+		}
+		return resp
+	}
+
+	for name, data := range map[string]map[string]interface{}{
+		"bad date":     {"blackout_dates": "24.12.2026"},
+		"bad weekday":  {"blackout_weekdays": "caturday"},
+		"whole week":   {"blackout_weekdays": "mon,tuesday,wednesday,thursday,friday,saturday,sunday,monday"},
+		"bad timezone": {"blackout_timezone": "Mars/Olympus_Mons"},
+		"bad url":      {"blackout_ical_url": "ftp://calendar"},
+		"feed missing": {"blackout_ical_url": calendar.URL + "/freeze.ics"},
+	} {
+		if resp := write(data); resp == nil || !resp.IsError() {
+			t.Errorf("%s: expected an error, got %v", name, resp)
+		}
+	}
+
+	end := time.Now().UTC().Add(time.Hour)
+	feed = "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20200101T000000Z\r\nDTEND:" + end.Format("20060102T150405Z") + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	if resp := write(map[string]interface{}{
+		"blackout_dates":    "2026-12-31,2026-12-24,2026-12-31",
+		"blackout_weekdays": "Sunday,saturday",
+		"blackout_ical_url": calendar.URL + "/freeze.ics",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("write settings: %v", resp.Error())
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/settings",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read settings: err=%v, resp=%v", err, resp)
+	}
+	if got := strings.Join(resp.Data["blackout_dates"].([]string), ","); got != "2026-12-24,2026-12-31" {
+		t.Errorf("blackout_dates = %s", got)
+	}
+	if got := strings.Join(resp.Data["blackout_weekdays"].([]string), ","); got != "sunday,saturday" {
+		t.Errorf("blackout_weekdays = %s", got)
+	}
+	if resp.Data["blackout_ical_events"] != 1 || resp.Data["in_blackout"] != true {
+		t.Errorf("blackout_ical_events = %v, in_blackout = %v", resp.Data["blackout_ical_events"], resp.Data["in_blackout"])
+	}
+
+	// The stored events outlive the feed until it is fetched again.
+	feed = ""
+	if resp := write(map[string]interface{}{"periodic_concurrency": 2}); resp != nil && resp.IsError() {
+		t.Fatalf("write settings: %v", resp.Error())
+	}
+	settings, err := getSettings(ctx, storage)
+	if err != nil || len(settings.BlackoutPeriods) != 1 {
+		t.Fatalf("settings = %+v, err = %v", settings, err)
+	}
+	if resp := write(map[string]interface{}{"blackout_ical_url": ""}); resp != nil && resp.IsError() {
+		t.Fatalf("write settings: %v", resp.Error())
+	}
+	if settings, _ := getSettings(ctx, storage); len(settings.BlackoutPeriods) != 0 || !settings.BlackoutICalFetched.IsZero() {
+		t.Errorf("clearing the feed left %+v", settings)
+	}
+}
+
+func TestPeriodicFunc_Blackout(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	sb := b.(*solaceBackend)
+	ctx := context.Background()
+
+	if resp, err := sb.rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	role, _ := getRole(ctx, storage, "test-role")
+	role.RotationPeriod = time.Hour
+	role.LastRotated = time.Now().Add(-2 * time.Hour)
+	putRole(ctx, storage, "test-role", role)
+	before, _ := getRoleSecret(ctx, storage, "test-role")
+
+	settings, _ := getSettings(ctx, storage)
+	settings.BlackoutDates = []string{time.Now().UTC().Format(blackoutDateLayout)}
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if after, _ := getRoleSecret(ctx, storage, "test-role"); after.Password != before.Password {
+		t.Error("role was rotated during a blackout")
+	}
+	if run := sb.lastPeriodicRun(); run.Skipped != 1 {
+		t.Errorf("skipped = %d, want 1", run.Skipped)
+	}
+
+	// Manual rotations go ahead.
+	settings.MinRotationInterval = 0
+	putSettings(ctx, storage, settings)
+	if resp, err := sb.rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("manual rotation during a blackout: err=%v, resp=%v", err, resp)
+	}
+}
+
+func TestFetchICalBlackouts_RefusesRedirect(t *testing.T) {
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testBlackoutFeed))
+	}))
+	defer feed.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(feed.URL, http.StatusFound))
+	defer redirect.Close()
+
+	if _, err := fetchICalBlackouts(context.Background(), feed.URL, true, time.UTC); err != nil {
+		t.Fatalf("fetching the feed: %v", err)
+	}
+	if _, err := fetchICalBlackouts(context.Background(), redirect.URL, true, time.UTC); err == nil {
+		t.Error("expected a redirect to another host to be refused")
+	}
+}
@@ -0,0 +1,140 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// maxBrokerActivity bounds how many configuration changes are kept per
+// broker; the oldest are dropped first.
+const maxBrokerActivity = 200
+
+// Results of a configuration change as recorded in a broker's activity.
+const (
+	activityResultSuccess = "success"
+	activityResultFailed  = "failed"
+)
+
+func pathConfigBrokerActivity(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "config/brokers/" + framework.GenericNameRegex("name") + "/activity$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "broker-activity",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the broker configuration.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathConfigBrokerActivityRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"entries": {Type: framework.TypeSlice, Description: "Configuration changes sent to the broker, oldest first. Each has time, operation, target, result, and, when known, error_class and request_id."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Read the configuration changes the plugin sent to a broker.",
+			HelpDescription: "Returns the most recent SEMP configuration changes sent to the broker: what was changed, on which object, when, and whether the broker accepted it. Credentials are never recorded. The log is seal-wrapped, keeps the last 200 changes, and is removed with the broker.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathConfigBrokerActivityRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	config, err := getBroker(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+	activity, err := getBrokerActivity(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]map[string]interface{}, 0, len(activity))
+	for _, entry := range activity {
+		data := map[string]interface{}{
+			"time":      entry.Time.Format(time.RFC3339),
+			"operation": entry.Operation,
+			"target":    entry.Target,
+			"result":    entry.Result,
+		}
+		if entry.ErrorClass != "" {
+			data["error_class"] = entry.ErrorClass
+		}
+		if entry.RequestID != "" {
+			data["request_id"] = entry.RequestID
+		}
+		entries = append(entries, data)
+	}
+	return &logical.Response{Data: map[string]interface{}{"entries": entries}}, nil
+}
+
+type sempActivityKey struct{}
+
+// withSEMPActivity returns a context whose SEMP configuration changes are
+// recorded in the activity log of their broker in s.
+func withSEMPActivity(ctx context.Context, s logical.Storage) context.Context {
+	return context.WithValue(ctx, sempActivityKey{}, s)
+}
+
+// recordActivity appends a configuration change to the broker's activity
+// log, if the context asks for one. It is called with the broker's
+// configuration lock held, which keeps concurrent changes from losing each
+// other's entries. The change has already been made, so failing to record
+// it is logged rather than returned.
+func (c *SEMPClient) recordActivity(ctx context.Context, operation, target string, err error) {
+	s, _ := ctx.Value(sempActivityKey{}).(logical.Storage)
+	if s == nil {
+		return
+	}
+
+	entry := brokerActivity{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Target:    target,
+		Result:    activityResultSuccess,
+		RequestID: sempRequestID(ctx),
+	}
+	if err != nil {
+		entry.Result = activityResultFailed
+		entry.ErrorClass = sempErrorClass(err)
+	}
+
+	// The change may have been cancelled with ctx; its record should still
+	// be written.
+	ctx = context.WithoutCancel(ctx)
+	activity, err := getBrokerActivity(ctx, s, c.Broker)
+	if err == nil {
+		activity = append(activity, entry)
+		if len(activity) > maxBrokerActivity {
+			activity = activity[len(activity)-maxBrokerActivity:]
+		}
+		err = putBrokerActivity(ctx, s, c.Broker, activity)
+	}
+	if err != nil && c.Logger != nil {
+		c.Logger.Warn("failed to record SEMP configuration change",
+			"broker", c.Broker,
+			"operation", operation,
+			"target", target,
+			"error", err,
+		)
+	}
+}
@@ -0,0 +1,86 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathConfigBrokerActivity(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		ID:        "rotate-request",
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-role/test-role",
+		Storage:   storage,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("rotate: err=%v, resp=%v", err, resp)
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/brokers/test-broker/activity",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read activity: err=%v, resp=%v", err, resp)
+	}
+	entries := resp.Data["entries"].([]map[string]interface{})
+	if len(entries) != 1 {
+		t.Fatalf("entries = %v, want one", entries)
+	}
+	entry := entries[0]
+	if entry["operation"] != "change_password" || entry["target"] != "monitor" ||
+		entry["result"] != activityResultSuccess || entry["request_id"] != "rotate-request" {
+		t.Errorf("entry = %v", entry)
+	}
+
+	raw, _ := storage.Get(ctx, activityPrefix+"test-broker")
+	if strings.Contains(string(raw.Value), secret.Password) {
+		t.Error("activity log holds the password")
+	}
+}
+
+func TestRecordActivity_Bounded(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	ctx := withSEMPActivity(context.Background(), storage)
+	client := &SEMPClient{Broker: "prod"}
+
+	for i := 0; i < maxBrokerActivity+5; i++ {
+		target := "first"
+		if i > 0 {
+			target = "later"
+		}
+		client.recordActivity(ctx, "change_password", target, nil)
+	}
+	client.recordActivity(ctx, "delete_username", "last", &SEMPError{Class: sempErrCommand})
+
+	activity, err := getBrokerActivity(ctx, storage, "prod")
+	if err != nil {
+		t.Fatalf("getBrokerActivity: %v", err)
+	}
+	if len(activity) != maxBrokerActivity {
+		t.Fatalf("kept %d entries, want %d", len(activity), maxBrokerActivity)
+	}
+	if activity[0].Target == "first" {
+		t.Error("oldest entry was not dropped")
+	}
+	last := activity[len(activity)-1]
+	if last.Target != "last" || last.Result != activityResultFailed || last.ErrorClass != sempErrCommand {
+		t.Errorf("last entry = %+v", last)
+	}
+
+	// Without a log in the context nothing is recorded.
+	client.Broker = "other"
+	client.recordActivity(context.Background(), "change_password", "user", nil)
+	if activity, _ := getBrokerActivity(ctx, storage, "other"); activity != nil {
+		t.Errorf("recorded %v without an activity log", activity)
+	}
+}
@@ -0,0 +1,387 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// groupMember is a broker of a group together with its configuration, and
+// once rotation has prepared it, the client for its active node and the
+// release of the rotation lock taken there.
+type groupMember struct {
+	name   string
+	config *BrokerConfig
+	client *SEMPClient
+	unlock func()
+}
+
+// releaseGroupLocks releases the rotation locks taken on members.
+func releaseGroupLocks(members []groupMember) {
+	for _, member := range members {
+		if member.unlock != nil {
+			member.unlock()
+		}
+	}
+}
+
+// roleBrokers returns the names of the brokers a role's credential lives on:
+// its broker, or every member of its broker group.
+func roleBrokers(ctx context.Context, s logical.Storage, role *RoleEntry) ([]string, error) {
+	if role.BrokerGroup == "" {
+		return []string{role.Broker}, nil
+	}
+	group, err := getBrokerGroup(ctx, s, role.BrokerGroup)
+	if err != nil || group == nil {
+		return nil, err
+	}
+	return group.Brokers, nil
+}
+
+// groupMembers loads every member of a role's broker group. It returns an
+// error response if the group, or one of its brokers, no longer exists.
+func groupMembers(ctx context.Context, s logical.Storage, name string, role *RoleEntry) ([]groupMember, *logical.Response, error) {
+	group, err := getBrokerGroup(ctx, s, role.BrokerGroup)
+	if err != nil {
+		return nil, nil, err
+	}
+	if group == nil {
+		return nil, logical.ErrorResponse("broker group %q not found for role %q", role.BrokerGroup, name), nil
+	}
+	members := make([]groupMember, 0, len(group.Brokers))
+	for _, broker := range group.Brokers {
+		config, err := getSessionBroker(ctx, s, broker)
+		if err != nil {
+			return nil, nil, err
+		}
+		if config == nil {
+			return nil, logical.ErrorResponse("broker %q of group %q not found for role %q", broker, role.BrokerGroup, name), nil
+		}
+		members = append(members, groupMember{name: broker, config: config})
+	}
+	return members, nil, nil
+}
+
+// groupExcludedPasswordChars returns the characters a group role's password
+// must not contain: those any member rejects.
+func groupExcludedPasswordChars(members []groupMember) string {
+	var excluded strings.Builder
+	for _, member := range members {
+		for _, c := range member.config.excludedPasswordChars() {
+			if !strings.ContainsRune(excluded.String(), c) {
+				excluded.WriteRune(c)
+			}
+		}
+	}
+	return excluded.String()
+}
+
+// Per-broker outcomes of a group rotation, as reported to the caller.
+const (
+	groupMemberChanged     = "changed"
+	groupMemberFailed      = "failed"
+	groupMemberRolledBack  = "rolled back"
+	groupMemberNewPassword = "left with new password"
+	groupMemberUnchanged   = "not changed"
+	groupMemberReady       = "ready"
+)
+
+// errCLIUserMissing is returned when a group member lacks the role's CLI user
+// and the role may not create it.
+var errCLIUserMissing = errors.New("CLI user does not exist")
+
+// rotateGroupRole gives a group role's CLI user one new password on every
+// member of its broker group, in two phases. First every member must be
+// reachable, with an active node, and hold the CLI user or be allowed to
+// create it; otherwise nothing is changed. Then the password is applied a
+// member at a time. If a member fails, the members already changed are set
+// back to the stored password, so that Vault's password keeps working across
+// the group. Either way the result reports each member's outcome.
+func (b *solaceBackend) rotateGroupRole(ctx context.Context, s logical.Storage, name string, role *RoleEntry, opts rotationOptions) (*logical.Response, error) {
+	members, resp, err := groupMembers(ctx, s, name, role)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	defer releaseGroupLocks(members)
+	settings, err := getSettings(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string, len(members))
+	var prepareErr error
+	failed := ""
+	for i := range members {
+		member := &members[i]
+		statuses[member.name] = groupMemberReady
+		if err := b.prepareGroupMember(ctx, role, member); err != nil {
+			b.Logger().Error("broker group member not ready for rotation",
+				"role", name,
+				"cli_username", role.CLIUsername,
+				"broker_group", role.BrokerGroup,
+				"broker", member.name,
+				"error", err,
+			)
+			statuses[member.name] = groupMemberFailed
+			if prepareErr == nil {
+				prepareErr, failed = err, member.name
+			}
+		}
+	}
+	if prepareErr != nil {
+		b.recordGroupFailure(ctx, name, role, failed, prepareErr)
+		return logical.ErrorResponse("rotation of role %q was not attempted because not every broker of group %q is ready: %s",
+			name, role.BrokerGroup, groupStatusSummary(members, statuses)), nil
+	}
+
+	stored, err := getRoleSecret(ctx, s, name)
+	if err != nil {
+		return nil, err
+	}
+	var current *credential
+	var oldPassword []byte
+	if !stored.empty() {
+		current = credentialFromSecret(stored)
+		defer current.wipe()
+		oldPassword = current.password
+	}
+	cred, err := b.generateCredential(ctx, name, role, settings, groupExcludedPasswordChars(members), current, opts)
+	if errors.Is(err, errPasswordRejected) {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("generating credential: %w", err)
+	}
+	defer cred.wipe()
+
+	// From here members are changed, and rolled back if one fails; neither
+	// may be cut short by the caller's deadline.
+	ctx = context.WithoutCancel(ctx)
+	for _, member := range members {
+		statuses[member.name] = groupMemberUnchanged
+	}
+	for _, member := range members {
+		// A password one member refuses under its password history is not
+		// regenerated, since others may already hold it; the rotation is
+		// rolled back and the next one offers another.
+		err := classifyPasswordRefusal(b.applyPassword(ctx, member.client, role, cred.password))
+		if err == nil {
+			statuses[member.name] = groupMemberChanged
+			if settings.VerifyRotation {
+				err = verifyRotation(ctx, member.client, role.CLIUsername, oldPassword, cred.password)
+			}
+		}
+		if err != nil {
+			if statuses[member.name] != groupMemberChanged {
+				statuses[member.name] = groupMemberFailed
+			}
+			if errors.Is(err, errPasswordMinAge) {
+				b.postponeRole(name, time.Now().Add(passwordMinAgeRetryDelay))
+			}
+			return b.failGroupRotation(ctx, s, name, role, members, statuses, member.name, err, oldPassword, cred)
+		}
+	}
+
+	secret := cred.secret()
+	secret.Version = stored.nextVersion()
+	resp, err = b.storeRotatedSecret(ctx, s, name, role, secret, opts.actor)
+	if err != nil {
+		return nil, err
+	}
+	brokers := make(map[string]interface{}, len(statuses))
+	for member, status := range statuses {
+		brokers[member] = status
+	}
+	resp.Data["broker_group"] = role.BrokerGroup
+	resp.Data["brokers"] = brokers
+	if role.TerminateSessions {
+		terminated := true
+		for _, member := range members {
+			if !b.terminateSessions(ctx, member.client, name, role, resp) {
+				terminated = false
+			}
+		}
+		resp.Data["sessions_terminated"] = terminated
+	}
+	return resp, nil
+}
+
+// prepareGroupMember finds the active node of a group member, checks that
+// the role's password can be applied there, and takes the member's rotation
+// lock.
+func (b *solaceBackend) prepareGroupMember(ctx context.Context, role *RoleEntry, member *groupMember) error {
+	client, err := b.activeSEMPClient(ctx, member.name, member.config)
+	if err != nil {
+		return err
+	}
+	user, err := client.ShowUsername(ctx, role.CLIUsername)
+	if err != nil {
+		return err
+	}
+	switch {
+	case user == nil && !role.CreateIfMissing && !role.Monitor:
+		return errCLIUserMissing
+	case user != nil && role.Monitor && !monitorAccessLevels[user.GlobalAccessLevel]:
+		return fmt.Errorf("%w: %s", errMonitorAccessLevel, user.GlobalAccessLevel)
+	}
+	unlock, err := b.acquireRotationLock(ctx, client, role, member.config.RotationLockTTL)
+	if err != nil {
+		return err
+	}
+	member.client = client
+	member.unlock = unlock
+	return nil
+}
+
+// recordGroupFailure reports a group rotation that failed on broker failed.
+func (b *solaceBackend) recordGroupFailure(ctx context.Context, name string, role *RoleEntry, failed string, cause error) {
+	forgetSEMPSessionBroker(ctx, failed)
+	if retryAfter := sempRetryAfter(cause); retryAfter > 0 {
+		b.deferBroker(failed, time.Now().Add(retryAfter))
+	}
+	recordRotation(ctx, role.BrokerGroup, false)
+	b.sendEvent(ctx, eventRotateFail, "role", name, "broker", failed, "broker_group", role.BrokerGroup,
+		"cli_username", role.CLIUsername, "reason", rotationFailureReason(cause))
+}
+
+// failGroupRotation handles a group rotation that failed on broker failed
+// after some members may have been changed. It sets the previous password
+// back on those members; any left with the new password have it kept under
+// recovery/.
+func (b *solaceBackend) failGroupRotation(ctx context.Context, s logical.Storage, name string, role *RoleEntry, members []groupMember, statuses map[string]string, failed string, cause error, oldPassword []byte, cred *credential) (*logical.Response, error) {
+	b.recordGroupFailure(ctx, name, role, failed, cause)
+	b.Logger().Error("password change failed on broker group member",
+		"role", name,
+		"cli_username", role.CLIUsername,
+		"broker_group", role.BrokerGroup,
+		"broker", failed,
+		"error", cause,
+	)
+
+	// Without a stored password there is nothing to roll back to.
+	stranded := false
+	for _, member := range members {
+		if statuses[member.name] != groupMemberChanged {
+			continue
+		}
+		if oldPassword == nil {
+			statuses[member.name] = groupMemberNewPassword
+			stranded = true
+			continue
+		}
+		if err := member.client.ChangePassword(ctx, role.CLIUsername, oldPassword); err != nil {
+			b.Logger().Error("rolling back password on broker group member failed",
+				"role", name,
+				"cli_username", role.CLIUsername,
+				"broker_group", role.BrokerGroup,
+				"broker", member.name,
+				"error", err,
+			)
+			statuses[member.name] = groupMemberNewPassword
+			stranded = true
+			continue
+		}
+		statuses[member.name] = groupMemberRolledBack
+	}
+
+	msg := fmt.Sprintf("failed to rotate password for role %q on broker %q of group %q: %s", name, failed, role.BrokerGroup, groupStatusSummary(members, statuses))
+	if !stranded {
+		return logical.ErrorResponse(msg), nil
+	}
+	if err := b.saveRecovery(ctx, s, name, role, cred.secret()); err != nil {
+		b.Logger().Error("failed to save new password left on broker group members for recovery",
+			"role", name,
+			"broker_group", role.BrokerGroup,
+			"error", err,
+		)
+		return logical.ErrorResponse("%s; the new password could not be saved for recovery, so manual recovery may be required", msg), nil
+	}
+	return logical.ErrorResponse("%s; the new password was saved to %s%s", msg, recoveryPrefix, name), nil
+}
+
+// groupStatusSummary lists each member's outcome in group order.
+func groupStatusSummary(members []groupMember, statuses map[string]string) string {
+	parts := make([]string, len(members))
+	for i, member := range members {
+		parts[i] = member.name + " " + statuses[member.name]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// syncGroupRole pushes a group role's stored password to every member of its
+// broker group. Members that fail do not stop the others.
+func (b *solaceBackend) syncGroupRole(ctx context.Context, s logical.Storage, name string, role *RoleEntry, cred *credential) (*logical.Response, error) {
+	members, resp, err := groupMembers(ctx, s, name, role)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	var failed []string
+	for _, member := range members {
+		err := b.syncGroupMember(ctx, role, member, cred)
+		if err != nil {
+			b.Logger().Error("SEMP password sync failed",
+				"role", name,
+				"cli_username", role.CLIUsername,
+				"broker_group", role.BrokerGroup,
+				"broker", member.name,
+				"error", err,
+			)
+			failed = append(failed, member.name)
+		}
+	}
+	if len(failed) > 0 {
+		return logical.ErrorResponse("failed to sync password for role %q on brokers of group %q: %s", name, role.BrokerGroup, strings.Join(failed, ", ")), nil
+	}
+	return nil, nil
+}
+
+// syncGroupMember sets the stored password on one member of a group role's
+// broker group, under the member's rotation lock.
+func (b *solaceBackend) syncGroupMember(ctx context.Context, role *RoleEntry, member groupMember, cred *credential) error {
+	client, err := b.activeSEMPClient(ctx, member.name, member.config)
+	if err != nil {
+		return err
+	}
+	unlock, err := b.acquireRotationLock(ctx, client, role, member.config.RotationLockTTL)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return b.applyPassword(ctx, client, role, cred.password)
+}
+
+// verifyGroupRole confirms a group role's CLI user exists on every member of
+// its broker group, reporting each member's view of it.
+func (b *solaceBackend) verifyGroupRole(ctx context.Context, s logical.Storage, name string, role *RoleEntry) (*logical.Response, error) {
+	members, resp, err := groupMembers(ctx, s, name, role)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	brokers := make(map[string]interface{}, len(members))
+	result := &logical.Response{
+		Data: map[string]interface{}{
+			"broker_group": role.BrokerGroup,
+			"cli_username": role.CLIUsername,
+			"brokers":      brokers,
+		},
+	}
+	for _, member := range members {
+		memberRole := *role
+		memberRole.Broker = member.name
+		resp, err := b.verifyCLIUser(ctx, b.sempClient(member.name, member.config), name, &memberRole)
+		if err != nil || resp.IsError() {
+			return resp, err
+		}
+		delete(resp.Data, "broker")
+		delete(resp.Data, "cli_username")
+		brokers[member.name] = resp.Data
+		for _, warning := range resp.Warnings {
+			result.AddWarning(member.name + ": " + warning)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,204 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// brokerState is runtime health information about a broker. It lives only in
+// memory on the node doing the work and is not replicated.
+type brokerState struct {
+	// retryNotBefore is set when the broker asked us to back off.
+	retryNotBefore time.Time
+
+	breaker *circuitBreaker
+	admin   *adminUsage
+}
+
+// brokerStateLocked returns the state for a broker, creating it if needed.
+// b.stateMutex must be held.
+func (b *solaceBackend) brokerStateLocked(name string) *brokerState {
+	if b.brokerStates == nil {
+		b.brokerStates = make(map[string]*brokerState)
+	}
+	state, ok := b.brokerStates[name]
+	if !ok {
+		state = &brokerState{
+			breaker: newCircuitBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+			admin:   &adminUsage{},
+		}
+		b.brokerStates[name] = state
+	}
+	return state
+}
+
+// brokerBreaker returns the circuit breaker guarding SEMP calls to a broker.
+func (b *solaceBackend) brokerBreaker(name string) *circuitBreaker {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	return b.brokerStateLocked(name).breaker
+}
+
+// brokerAdminUsage returns the record of how a broker last answered its
+// admin credential.
+func (b *solaceBackend) brokerAdminUsage(name string) *adminUsage {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	return b.brokerStateLocked(name).admin
+}
+
+// brokerConfigLock returns the lock serializing configuration changes on a
+// broker. Unlike the rest of the broker's state it survives resetBrokerState,
+// so a change still in flight when the broker is reconfigured holds back the
+// ones that follow.
+func (b *solaceBackend) brokerConfigLock(name string) *configLock {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	if b.configLocks == nil {
+		b.configLocks = make(map[string]*configLock)
+	}
+	lock, ok := b.configLocks[name]
+	if !ok {
+		lock = newConfigLock()
+		b.configLocks[name] = lock
+	}
+	return lock
+}
+
+// resetBrokerState forgets all runtime state for a broker, e.g. after its
+// configuration changed.
+func (b *solaceBackend) resetBrokerState(name string) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	delete(b.brokerStates, name)
+}
+
+// deferBroker records that automatic rotations against a broker should not
+// be attempted before until.
+func (b *solaceBackend) deferBroker(name string, until time.Time) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	state := b.brokerStateLocked(name)
+	if until.After(state.retryNotBefore) {
+		state.retryNotBefore = until
+	}
+}
+
+// brokerDeferredUntil reports whether a broker is currently backing off, and
+// until when.
+func (b *solaceBackend) brokerDeferredUntil(name string) (time.Time, bool) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+
+	state, ok := b.brokerStates[name]
+	if !ok || !time.Now().Before(state.retryNotBefore) {
+		return time.Time{}, false
+	}
+	return state.retryNotBefore, true
+}
+
+// firstDeferredBroker returns the first of brokers that asked to be left
+// alone, and until when.
+func (b *solaceBackend) firstDeferredBroker(brokers []string) (string, time.Time, bool) {
+	for _, name := range brokers {
+		if until, deferred := b.brokerDeferredUntil(name); deferred {
+			return name, until, true
+		}
+	}
+	return "", time.Time{}, false
+}
+
+// Outcomes of the last use of a broker's admin credential, as reported on
+// broker reads.
+const (
+	adminOutcomeSuccess  = "success"
+	adminOutcomeRejected = "rejected"
+	adminOutcomeFailed   = "failed"
+)
+
+// adminUsage tracks when a broker's admin credential was last presented and
+// how the broker answered, so a credential changed outside Vault shows up
+// before it fails a batch of rotations. Calls that never reached the broker,
+// such as transport failures and fast-failed calls, are not uses. All
+// methods are safe on a nil adminUsage.
+type adminUsage struct {
+	mu          sync.Mutex
+	lastUsed    time.Time
+	lastSuccess time.Time
+	lastOutcome string
+}
+
+// record notes the outcome of a SEMP call.
+func (u *adminUsage) record(err error) {
+	if u == nil {
+		return
+	}
+
+	outcome := adminOutcomeSuccess
+	if err != nil {
+		switch sempErrorClass(err) {
+		case sempErrTransport, sempErrCircuitOpen, "":
+			return
+		}
+		outcome = adminOutcomeFailed
+		if status := sempStatusCode(err); status == http.StatusUnauthorized || status == http.StatusForbidden {
+			outcome = adminOutcomeRejected
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.lastUsed = time.Now().UTC()
+	u.lastOutcome = outcome
+	if outcome == adminOutcomeSuccess {
+		u.lastSuccess = u.lastUsed
+	}
+}
+
+// last returns when the credential was last used and with what outcome, and
+// when it last succeeded. Times are zero if it has not been used on this
+// node since the broker was configured.
+func (u *adminUsage) last() (used time.Time, outcome string, success time.Time) {
+	if u == nil {
+		return time.Time{}, "", time.Time{}
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.lastUsed, u.lastOutcome, u.lastSuccess
+}
+
+// configLock lets one configuration change at a time through to a broker.
+// Solace appliances answer "configuration database busy" to a change that
+// arrives while another is being committed, which periodic passes,
+// concurrent rotations and manual requests would otherwise provoke. Unlike
+// a sync.Mutex, waiting for it gives up when the caller's context ends. All
+// methods are safe on a nil configLock, which does not serialize anything.
+type configLock struct {
+	ch chan struct{}
+}
+
+func newConfigLock() *configLock {
+	return &configLock{ch: make(chan struct{}, 1)}
+}
+
+// acquire waits for the lock and returns the function that releases it.
+func (l *configLock) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.ch <- struct{}{}:
+		return func() { <-l.ch }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
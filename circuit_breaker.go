@@ -0,0 +1,107 @@
+package solacevaultplugin
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 5 * time.Minute
+
+	sempErrCircuitOpen = "circuit_open"
+)
+
+// Circuit breaker states as reported on broker reads.
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// circuitBreaker fast-fails SEMP calls to a broker after repeated transport
+// failures, so a dead appliance cannot stall every rotation that targets it.
+// After the cooldown a single trial call is let through; its outcome closes
+// or re-opens the circuit. All methods are safe on a nil breaker.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns an error if calls to the broker should currently fail fast.
+func (cb *circuitBreaker) allow(broker string) error {
+	if cb == nil {
+		return nil
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.failures < cb.threshold {
+		return nil
+	}
+	if time.Now().Before(cb.openUntil) || cb.trial {
+		return &SEMPError{
+			Class: sempErrCircuitOpen,
+			Err:   fmt.Errorf("circuit open for broker %q after %d consecutive failures; retrying after %s", broker, cb.failures, cb.openUntil.Format(time.RFC3339)),
+		}
+	}
+	cb.trial = true
+	return nil
+}
+
+// record updates the breaker with the outcome of a call. Only failures to
+// reach the broker count; any answer from it, even an HTTP error or a reply
+// that cannot be parsed, proves it is alive. A call the breaker itself
+// failed fast never reached the broker, so it leaves the breaker, and any
+// trial in flight, as they are.
+func (cb *circuitBreaker) record(err error) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	class := sempErrorClass(err)
+	if class == sempErrCircuitOpen {
+		return
+	}
+	cb.trial = false
+	switch class {
+	case sempErrTransport:
+		cb.failures++
+		if cb.failures >= cb.threshold {
+			cb.openUntil = time.Now().Add(cb.cooldown)
+		}
+	default:
+		cb.failures = 0
+		cb.openUntil = time.Time{}
+	}
+}
+
+// state reports the breaker state and, when open, until when.
+func (cb *circuitBreaker) state() (string, time.Time) {
+	if cb == nil {
+		return circuitClosed, time.Time{}
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch {
+	case cb.failures < cb.threshold:
+		return circuitClosed, time.Time{}
+	case time.Now().Before(cb.openUntil):
+		return circuitOpen, cb.openUntil
+	default:
+		return circuitHalfOpen, time.Time{}
+	}
+}
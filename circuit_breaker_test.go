@@ -0,0 +1,110 @@
+package solacevaultplugin
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_OpensAfterThreshold(t *testing.T) {
+	cb := newCircuitBreaker(3, time.Hour)
+	transportErr := &SEMPError{Class: sempErrTransport, Err: errors.New("connection refused")}
+
+	for i := 0; i < 3; i++ {
+		if err := cb.allow("prod"); err != nil {
+			t.Fatalf("allow before threshold: %v", err)
+		}
+		cb.record(transportErr)
+	}
+
+	err := cb.allow("prod")
+	if err == nil {
+		t.Fatal("expected circuit to be open after threshold failures")
+	}
+	if sempErrorClass(err) != sempErrCircuitOpen {
+		t.Errorf("error class = %q, want %q", sempErrorClass(err), sempErrCircuitOpen)
+	}
+	if state, until := cb.state(); state != circuitOpen || until.IsZero() {
+		t.Errorf("state = %q until %v, want open", state, until)
+	}
+}
+
+func TestCircuitBreaker_CommandFailureKeepsClosed(t *testing.T) {
+	cb := newCircuitBreaker(2, time.Hour)
+	commandErr := &SEMPError{Class: sempErrCommand, Err: errors.New("invalid username")}
+
+	for i := 0; i < 5; i++ {
+		cb.record(commandErr)
+	}
+	if err := cb.allow("prod"); err != nil {
+		t.Errorf("command failures should not open the circuit: %v", err)
+	}
+}
+
+func TestCircuitBreaker_BrokerAnswerKeepsClosed(t *testing.T) {
+	cb := newCircuitBreaker(2, time.Hour)
+	for _, err := range []error{
+		&SEMPError{Class: sempErrHTTP, Err: errors.New("SEMP returned HTTP 500")},
+		&SEMPError{Class: sempErrParse, Err: errors.New("unexpected reply")},
+		&SEMPError{Class: sempErrHTTP, Err: errors.New("SEMP returned HTTP 503")},
+	} {
+		cb.record(err)
+	}
+	if err := cb.allow("prod"); err != nil {
+		t.Errorf("a broker that answers should not open the circuit: %v", err)
+	}
+}
+
+func TestCircuitBreaker_FastFailKeepsTrial(t *testing.T) {
+	cb := newCircuitBreaker(1, time.Millisecond)
+	cb.record(&SEMPError{Class: sempErrTransport, Err: errors.New("timeout")})
+	time.Sleep(5 * time.Millisecond)
+
+	if err := cb.allow("prod"); err != nil {
+		t.Fatalf("trial call should be allowed: %v", err)
+	}
+	// A concurrent caller fails fast and records that; the trial must
+	// still be the only call let through.
+	fastFail := cb.allow("prod")
+	if fastFail == nil {
+		t.Fatal("second call during trial should fail fast")
+	}
+	cb.record(fastFail)
+	if err := cb.allow("prod"); err == nil {
+		t.Error("a fast-failed call ended the trial and let another call through")
+	}
+}
+
+func TestCircuitBreaker_HalfOpenTrial(t *testing.T) {
+	cb := newCircuitBreaker(1, time.Millisecond)
+	cb.record(&SEMPError{Class: sempErrTransport, Err: errors.New("timeout")})
+
+	time.Sleep(5 * time.Millisecond)
+	if state, _ := cb.state(); state != circuitHalfOpen {
+		t.Fatalf("state = %q, want half-open", state)
+	}
+
+	// A single trial is allowed; concurrent callers still fail fast.
+	if err := cb.allow("prod"); err != nil {
+		t.Fatalf("trial call should be allowed: %v", err)
+	}
+	if err := cb.allow("prod"); err == nil {
+		t.Error("second call during trial should fail fast")
+	}
+
+	cb.record(nil)
+	if state, _ := cb.state(); state != circuitClosed {
+		t.Errorf("state after successful trial = %q, want closed", state)
+	}
+}
+
+func TestCircuitBreaker_NilSafe(t *testing.T) {
+	var cb *circuitBreaker
+	if err := cb.allow("prod"); err != nil {
+		t.Errorf("nil breaker allow: %v", err)
+	}
+	cb.record(errors.New("ignored"))
+	if state, _ := cb.state(); state != circuitClosed {
+		t.Errorf("nil breaker state = %q, want closed", state)
+	}
+}
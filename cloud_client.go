@@ -0,0 +1,84 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultCloudAPIURL = "https://api.solace.cloud"
+
+// cloudTokenRegeneratePath is the Solace Cloud REST API endpoint that issues
+// a new value for an existing API token, invalidating the old value.
+const cloudTokenRegeneratePath = "/api/v2/platform/apiTokens/%s/regenerate"
+
+// CloudClient talks to the Solace Cloud REST API of the organization that
+// hosts a Cloud broker. Its errors are SEMPErrors with the same classes as
+// SEMP calls, so rotation failures are reported alike.
+type CloudClient struct {
+	APIURL     string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewCloudClient creates a client for the Cloud API configured on a broker.
+// The broker's tls_skip_verify does not carry over: the Cloud API is always
+// verified.
+func NewCloudClient(config *BrokerConfig) *CloudClient {
+	transport := *config
+	transport.TLSSkipVerify = false
+	return &CloudClient{
+		APIURL:     strings.TrimSuffix(config.CloudAPIURL, "/"),
+		Token:      config.CloudAPIToken,
+		HTTPClient: newHTTPClient(&transport),
+	}
+}
+
+type cloudTokenResponse struct {
+	Data struct {
+		Token string `json:"token"`
+	} `json:"data"`
+}
+
+// RegenerateToken issues a new value for the API token with the given ID and
+// returns it. The caller owns the returned buffer and should wipe it.
+func (c *CloudClient) RegenerateToken(ctx context.Context, tokenID string) ([]byte, error) {
+	endpoint := c.APIURL + fmt.Sprintf(cloudTokenRegeneratePath, url.PathEscape(tokenID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("building request: %w", err)}
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", sempUserAgent)
+	if id := sempRequestID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("Solace Cloud request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("reading Solace Cloud response: %w", err)}
+	}
+	defer wipe(body)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, &SEMPError{Class: sempErrHTTP, StatusCode: resp.StatusCode, Err: fmt.Errorf("Solace Cloud returned HTTP %d", resp.StatusCode)}
+	}
+	var parsed cloudTokenResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("parsing Solace Cloud response: %w", err)}
+	}
+	if parsed.Data.Token == "" {
+		return nil, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("Solace Cloud response did not include a token")}
+	}
+	return []byte(parsed.Data.Token), nil
+}
@@ -0,0 +1,130 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCloudClient_RegenerateToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/platform/apiTokens/tok-1/regenerate" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer org-token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"data":{"id":"tok-1","token":"new-value"}}`))
+	}))
+	defer server.Close()
+
+	client := NewCloudClient(&BrokerConfig{CloudAPIURL: server.URL + "/", CloudAPIToken: "org-token"})
+	token, err := client.RegenerateToken(context.Background(), "tok-1")
+	if err != nil {
+		t.Fatalf("RegenerateToken: %v", err)
+	}
+	if string(token) != "new-value" {
+		t.Errorf("token = %q, want new-value", token)
+	}
+}
+
+func TestCloudClient_RegenerateToken_Errors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		status int
+		body   string
+		class  string
+	}{
+		"unauthorized": {http.StatusUnauthorized, `{}`, sempErrHTTP},
+		"no token":     {http.StatusOK, `{"data":{}}`, sempErrParse},
+		"bad json":     {http.StatusOK, `not json`, sempErrParse},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+			w.Write([]byte(tc.body))
+		}))
+		client := NewCloudClient(&BrokerConfig{CloudAPIURL: server.URL, CloudAPIToken: "org-token"})
+		_, err := client.RegenerateToken(context.Background(), "tok-1")
+		if sempErrorClass(err) != tc.class {
+			t.Errorf("%s: error class = %q, want %q (err=%v)", name, sempErrorClass(err), tc.class, err)
+		}
+		server.Close()
+	}
+}
+
+func TestPathRotate_CloudToken(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		fmt.Fprintf(w, `{"data":{"token":"value-%d"}}`, n)
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	writeBroker(t, b, storage, "cloud")
+
+	writeRole := func() *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/ci-token",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"broker":         "cloud",
+				"target":         roleTargetCloudToken,
+				"cloud_token_id": "tok-1",
+			},
+		})
+		if err != nil {
+			t.Fatalf("write role: %v", err)
+		}
+		return resp
+	}
+	if resp := writeRole(); resp == nil || !resp.IsError() {
+		t.Error("expected error for a broker without cloud_api_token")
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "config/brokers/cloud",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"cloud_api_url":   server.URL,
+			"cloud_api_token": "org-token",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("patch broker: err=%v, resp=%v", err, resp)
+	}
+	if resp := writeRole(); resp != nil && resp.IsError() {
+		t.Fatalf("write role: %v", resp)
+	}
+
+	for i := 1; i <= 2; i++ {
+		resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "ci-token")
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+		}
+		creds := readCreds(t, b, storage, "ci-token")
+		if want := fmt.Sprintf("value-%d", i); creds["token"] != want {
+			t.Errorf("token = %v, want %s", creds["token"], want)
+		}
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/brokers/cloud",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("read broker: err=%v, resp=%v", err, resp)
+	}
+	if _, ok := resp.Data["cloud_api_token"]; ok {
+		t.Error("broker read must not return cloud_api_token")
+	}
+}
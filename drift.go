@@ -0,0 +1,152 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Reasons a drift check flags a role.
+const (
+	driftUserMissing      = "user_missing"
+	driftPasswordRejected = "password_rejected"
+
+	// driftRotationUnverified flags a role whose rotation the broker
+	// acknowledged but could not be verified by logging in.
+	driftRotationUnverified = "rotation_unverified"
+)
+
+// Brokers restored from their own configuration backups, or edited by hand,
+// silently stop accepting the password Vault holds. The drift check finds
+// such roles before their applications do: it looks up each CLI user role's
+// user on its brokers and logs in with the stored password, and flags the
+// role under state/drift/ if either fails. A rotation or sync clears the
+// flag, as does a later check that finds the role in step again.
+
+// checkDrift runs the drift check if drift_check_interval says one is due,
+// or carries on with one an earlier pass left unfinished. It stops at
+// deadline, if set, and the next pass continues after the last role it
+// checked.
+func (b *solaceBackend) checkDrift(ctx context.Context, s logical.Storage, settings *Settings, deadline time.Time) error {
+	if settings.DriftCheckInterval <= 0 {
+		return nil
+	}
+	state, err := getDriftCheckState(ctx, s)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &driftCheckState{}
+	}
+	if state.Cursor == "" && !state.LastCompleted.IsZero() && time.Since(state.LastCompleted) < settings.DriftCheckInterval {
+		return nil
+	}
+
+	roles, err := listRoles(ctx, s)
+	if err != nil {
+		return err
+	}
+	sort.Strings(roles)
+	for _, name := range roles {
+		if name <= state.Cursor {
+			continue
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			b.Logger().Debug("periodic: time budget exhausted, continuing the drift check on the next pass", "after", state.Cursor)
+			return putDriftCheckState(ctx, s, state)
+		}
+		if err := b.checkRoleDrift(ctx, s, name); err != nil {
+			b.Logger().Warn("periodic: failed to check role for drift", "role", name, "error", err)
+		}
+		state.Cursor = name
+	}
+
+	state.Cursor = ""
+	state.LastCompleted = time.Now().UTC()
+	return putDriftCheckState(ctx, s, state)
+}
+
+// checkRoleDrift checks one role against its brokers and flags or clears its
+// drift. Roles that do not rotate a CLI user, or were never rotated, have
+// nothing to check. If a broker cannot be asked, the role's flag is left as
+// it was.
+func (b *solaceBackend) checkRoleDrift(ctx context.Context, s logical.Storage, name string) error {
+	// Hold the role still so a rotation cannot change the password between
+	// reading it and trying it.
+	lock := b.roleLock(name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	role, err := getRole(ctx, s, name)
+	if err != nil || role == nil || !role.isCLIUser() {
+		return err
+	}
+	secret, err := getRoleSecret(ctx, s, name)
+	if err != nil || secret.empty() {
+		return err
+	}
+	brokers, err := roleBrokers(ctx, s, role)
+	if err != nil {
+		return err
+	}
+	if _, _, deferred := b.firstDeferredBroker(brokers); deferred {
+		return nil
+	}
+
+	password := []byte(secret.Password)
+	defer wipe(password)
+
+	var drift *driftEntry
+	for _, broker := range brokers {
+		config, err := getSessionBroker(ctx, s, broker)
+		if err != nil {
+			return err
+		}
+		if config == nil {
+			return nil
+		}
+		client := b.sempClient(broker, config)
+		user, err := client.ShowUsername(ctx, role.CLIUsername)
+		if err != nil {
+			return fmt.Errorf("looking up CLI user on broker %q: %w", broker, err)
+		}
+		if user == nil {
+			drift = &driftEntry{Reason: driftUserMissing, Broker: broker}
+			break
+		}
+		accepted, err := client.CheckLogin(ctx, role.CLIUsername, password)
+		if err != nil {
+			return fmt.Errorf("checking password on broker %q: %w", broker, err)
+		}
+		if !accepted {
+			drift = &driftEntry{Reason: driftPasswordRejected, Broker: broker}
+			break
+		}
+	}
+
+	if drift == nil {
+		return deleteDrift(ctx, s, name)
+	}
+	existing, err := getDrift(ctx, s, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Reason == drift.Reason && existing.Broker == drift.Broker {
+		return nil
+	}
+	drift.DetectedAt = time.Now().UTC()
+	if err := putDrift(ctx, s, name, drift); err != nil {
+		return err
+	}
+	b.Logger().Warn("role has drifted from its broker; rotate or sync it to reconcile",
+		"role", name,
+		"broker", drift.Broker,
+		"cli_username", role.CLIUsername,
+		"reason", drift.Reason,
+	)
+	b.sendEvent(ctx, eventDriftDetected, "role", name, "broker", drift.Broker, "reason", drift.Reason)
+	return nil
+}
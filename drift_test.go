@@ -0,0 +1,106 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCheckDrift(t *testing.T) {
+	pb := &passwordBroker{}
+	server := httptest.NewServer(pb)
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	b, storage, _ = setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+	settings := &Settings{DriftCheckInterval: time.Hour}
+
+	if resp, err := sb.rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	if err := sb.checkDrift(ctx, storage, settings, time.Time{}); err != nil {
+		t.Fatalf("checkDrift: %v", err)
+	}
+	if drift, _ := getDrift(ctx, storage, "test-role"); drift != nil {
+		t.Fatalf("role in step with its broker flagged: %+v", drift)
+	}
+
+	// The broker is restored from a backup with an older password.
+	pb.mu.Lock()
+	pb.password = "restored-from-backup"
+	pb.mu.Unlock()
+
+	// The check is not due again within drift_check_interval.
+	if err := sb.checkDrift(ctx, storage, settings, time.Time{}); err != nil {
+		t.Fatalf("checkDrift: %v", err)
+	}
+	if drift, _ := getDrift(ctx, storage, "test-role"); drift != nil {
+		t.Fatal("check ran again before drift_check_interval passed")
+	}
+
+	if err := putDriftCheckState(ctx, storage, &driftCheckState{LastCompleted: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sb.checkDrift(ctx, storage, settings, time.Time{}); err != nil {
+		t.Fatalf("checkDrift: %v", err)
+	}
+	drift, _ := getDrift(ctx, storage, "test-role")
+	if drift == nil || drift.Reason != driftPasswordRejected || drift.Broker != "test-broker" {
+		t.Fatalf("drift = %+v, want password_rejected on test-broker", drift)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "status/drift",
+		Storage:   storage,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("status/drift: err=%v, resp=%v", err, resp)
+	}
+	info := resp.Data["key_info"].(map[string]interface{})["test-role"].(map[string]interface{})
+	if info["reason"] != driftPasswordRejected {
+		t.Errorf("key_info = %v", info)
+	}
+
+	// Rotating the role reconciles it.
+	if resp, err := sb.rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	if drift, _ := getDrift(ctx, storage, "test-role"); drift != nil {
+		t.Errorf("drift not cleared by rotation: %+v", drift)
+	}
+}
+
+func TestCheckDrift_ResumesAfterDeadline(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+	settings := &Settings{DriftCheckInterval: time.Hour}
+
+	if err := putDriftCheckState(ctx, storage, &driftCheckState{Cursor: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		putRole(ctx, storage, name, &RoleEntry{Broker: "missing", CLIUsername: name, PasswordLength: defaultPasswordLength})
+	}
+
+	// A pass out of time keeps its place.
+	if err := sb.checkDrift(ctx, storage, settings, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("checkDrift: %v", err)
+	}
+	if state, _ := getDriftCheckState(ctx, storage); state.Cursor != "b" || !state.LastCompleted.IsZero() {
+		t.Fatalf("state = %+v, want the check still after b", state)
+	}
+
+	if err := sb.checkDrift(ctx, storage, settings, time.Time{}); err != nil {
+		t.Fatalf("checkDrift: %v", err)
+	}
+	if state, _ := getDriftCheckState(ctx, storage); state.Cursor != "" || state.LastCompleted.IsZero() {
+		t.Errorf("state = %+v, want a completed check", state)
+	}
+}
@@ -0,0 +1,187 @@
+package solacevaultplugin
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// entryCacheSize bounds how many storage entries the entry cache holds.
+const entryCacheSize = 4096
+
+// entryCache is an LRU cache of stored role and broker entries, keyed by
+// storage path, so hot paths such as creds/ stop reading them from storage
+// on every request. It holds the raw JSON, so every read decodes a fresh
+// copy that callers are free to change.
+//
+// Writes through cachingStorage drop the written entry. Requests, the
+// periodic pass and mount initialization all reach storage through it, so
+// every write this node makes does; Vault's
+// invalidation of keys changed by another node, such as writes a
+// performance standby forwards to the active node, drops them on the node
+// that did not make the write. An entry is only added if no write has
+// happened since its read began, so a read racing a write cannot cache the
+// old value. All methods are safe on a nil cache.
+type entryCache struct {
+	mu         sync.Mutex
+	size       int
+	order      *list.List
+	entries    map[string]*list.Element
+	generation uint64
+}
+
+type cachedEntry struct {
+	key   string
+	value []byte
+}
+
+func newEntryCache(size int) *entryCache {
+	return &entryCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// entryCacheable reports whether the entry at key is kept in the cache.
+func entryCacheable(key string) bool {
+	return strings.HasPrefix(key, roleStoragePrefix) || strings.HasPrefix(key, brokerStoragePrefix)
+}
+
+// get returns a copy of the cached value at key.
+func (c *entryCache) get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return bytes.Clone(elem.Value.(*cachedEntry).value), true
+}
+
+// currentGeneration returns a token to pass to add for a read about to
+// start.
+func (c *entryCache) currentGeneration() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// add caches value at key, unless anything was removed from the cache since
+// generation was taken.
+func (c *entryCache) add(key string, value []byte, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cachedEntry).value = bytes.Clone(value)
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cachedEntry{key: key, value: bytes.Clone(value)})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedEntry).key)
+	}
+}
+
+// remove drops the entry at key.
+func (c *entryCache) remove(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// reset drops every entry.
+func (c *entryCache) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// cachingStorage serves reads of role and broker entries from an entry
+// cache and drops the entries written or deleted through it.
+type cachingStorage struct {
+	logical.Storage
+	cache *entryCache
+}
+
+func (s *cachingStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	if !entryCacheable(key) {
+		return s.Storage.Get(ctx, key)
+	}
+	if value, ok := s.cache.get(key); ok {
+		return &logical.StorageEntry{Key: key, Value: value}, nil
+	}
+	generation := s.cache.currentGeneration()
+	entry, err := s.Storage.Get(ctx, key)
+	if err != nil || entry == nil {
+		return entry, err
+	}
+	s.cache.add(key, entry.Value, generation)
+	return entry, nil
+}
+
+func (s *cachingStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if entryCacheable(entry.Key) {
+		defer s.cache.remove(entry.Key)
+	}
+	return s.Storage.Put(ctx, entry)
+}
+
+func (s *cachingStorage) Delete(ctx context.Context, key string) error {
+	if entryCacheable(key) {
+		defer s.cache.remove(key)
+	}
+	return s.Storage.Delete(ctx, key)
+}
+
+// cachedStorage returns s with its role and broker entries going through the
+// backend's entry cache, unless they already do.
+func (b *solaceBackend) cachedStorage(s logical.Storage) logical.Storage {
+	if _, ok := s.(*cachingStorage); ok || s == nil {
+		return s
+	}
+	return &cachingStorage{Storage: s, cache: b.entries}
+}
+
+// HandleRequest serves a request with its reads of role and broker entries
+// going through the backend's entry cache.
+func (b *solaceBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	req.Storage = b.cachedStorage(req.Storage)
+	return b.Backend.HandleRequest(ctx, req)
+}
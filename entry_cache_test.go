@@ -0,0 +1,130 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestEntryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newEntryCache(2)
+	c.add("roles/a", []byte("a"), c.currentGeneration())
+	c.add("roles/b", []byte("b"), c.currentGeneration())
+	c.get("roles/a")
+	c.add("roles/c", []byte("c"), c.currentGeneration())
+
+	if _, ok := c.get("roles/b"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"roles/a", "roles/c"} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+}
+
+func TestEntryCache_SkipsAddAfterRemove(t *testing.T) {
+	c := newEntryCache(8)
+	generation := c.currentGeneration()
+	c.remove("roles/a")
+	c.add("roles/a", []byte("old"), generation)
+
+	if _, ok := c.get("roles/a"); ok {
+		t.Error("a value read before a write must not be cached after it")
+	}
+}
+
+func TestEntryCache_CredsReadsRoleOnce(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	if _, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil {
+		t.Fatalf("rotateRole: %v", err)
+	}
+	counting := &roleReadCountingStorage{Storage: storage}
+	readCreds := func() {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/test-role",
+			Storage:   counting,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("read creds: err=%v, resp=%v", err, resp)
+		}
+	}
+
+	readCreds()
+	readCreds()
+	if counting.roleReads != 1 {
+		t.Errorf("role read from storage %d times, want 1", counting.roleReads)
+	}
+
+	// A role write through the backend drops the cached entry.
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test-role",
+		Storage:   counting,
+		Data:      map[string]interface{}{"broker": "test-broker", "cli_username": "monitor", "rotation_period": 3600},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("write role: err=%v, resp=%v", err, resp)
+	}
+	counting.roleReads = 0
+	readCreds()
+	if counting.roleReads != 1 {
+		t.Errorf("role read from storage %d times after a write, want 1", counting.roleReads)
+	}
+
+	// So does Vault's invalidation of a key another node changed.
+	b.(*solaceBackend).invalidate(ctx, roleStoragePrefix+"test-role")
+	counting.roleReads = 0
+	readCreds()
+	if counting.roleReads != 1 {
+		t.Errorf("role read from storage %d times after invalidation, want 1", counting.roleReads)
+	}
+}
+
+func TestEntryCache_PeriodicRotationDropsRole(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	role, _ := getRole(ctx, storage, "test-role")
+	role.RotationPeriod = time.Hour
+	role.LastRotated = time.Now().Add(-2 * time.Hour)
+	if err := putRole(ctx, storage, "test-role", role); err != nil {
+		t.Fatalf("putRole: %v", err)
+	}
+	sb.invalidate(ctx, roleStoragePrefix+"test-role")
+
+	readLastRotated := func() time.Time {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "roles/test-role",
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("read role: err=%v, resp=%v", err, resp)
+		}
+		lastRotated, err := time.Parse(time.RFC3339, resp.Data["last_rotated"].(string))
+		if err != nil {
+			t.Fatalf("last_rotated: %v", err)
+		}
+		return lastRotated
+	}
+	before := readLastRotated()
+
+	// Vault starts the pass without going through HandleRequest.
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if after := readLastRotated(); !after.After(before) {
+		t.Errorf("roles/ served last_rotated %s after a periodic rotation, want later than %s", after, before)
+	}
+}
@@ -0,0 +1,37 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Event types published on Vault's event bus. Subscribers can react to
+// credential changes without polling creds/.
+const (
+	eventRotateSuccess     = "solace/rotate-success"
+	eventRotateFail        = "solace/rotate-fail"
+	eventSync              = "solace/sync"
+	eventBrokerWrite       = "solace/broker-write"
+	eventBrokerDelete      = "solace/broker-delete"
+	eventBrokerGroupWrite  = "solace/broker-group-write"
+	eventBrokerGroupDelete = "solace/broker-group-delete"
+	eventRoleWrite         = "solace/role-write"
+	eventRoleDelete        = "solace/role-delete"
+	eventRoleDecommission  = "solace/role-decommission"
+	eventDriftDetected     = "solace/drift-detected"
+
+	eventRoleOwnershipTransferred = "solace/role-ownership-transferred"
+)
+
+// sendEvent publishes an event with the given metadata key/value pairs.
+// Events are best effort: a Vault without the event system enabled, or a
+// failed send, never fails the operation that triggered it.
+func (b *solaceBackend) sendEvent(ctx context.Context, eventType string, metadataPairs ...string) {
+	err := logical.SendEvent(ctx, b.Backend, eventType, metadataPairs...)
+	if err != nil && !errors.Is(err, framework.ErrNoEvents) {
+		b.Logger().Debug("failed to send event", "event_type", eventType, "error", err)
+	}
+}
@@ -0,0 +1,99 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+type recordingEventSender struct {
+	mu     sync.Mutex
+	events []*logical.EventData
+	types  []logical.EventType
+}
+
+func (r *recordingEventSender) SendEvent(_ context.Context, eventType logical.EventType, event *logical.EventData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types = append(r.types, eventType)
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestEvents_RotationAndConfigChanges(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		if fail.Load() {
+			w.Write([]byte(`<rpc-reply><execute-result code="fail" reason="denied"/></rpc-reply>`))
+			return
+		}
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	sender := &recordingEventSender{}
+	config := logical.TestBackendConfig()
+	config.EventsSender = sender
+	b, storage := newTestBackend(t, config)
+	allowInsecureTransport(t, storage)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "config/brokers/test-broker",
+			Data: map[string]interface{}{
+				"semp_url":       server.URL,
+				"admin_username": "admin",
+				"admin_password": "secret",
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "roles/test-role",
+			Data: map[string]interface{}{
+				"broker":       "test-broker",
+				"cli_username": "monitor",
+			},
+		},
+		{Operation: logical.UpdateOperation, Path: "rotate-role/test-role"},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		if resp, err := b.HandleRequest(ctx, req); err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: err=%v, resp=%v", req.Path, err, resp)
+		}
+	}
+
+	fail.Store(true)
+	if _, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil {
+		t.Fatalf("rotateRole: %v", err)
+	}
+
+	want := []logical.EventType{eventBrokerWrite, eventRoleWrite, eventRotateSuccess, eventRotateFail}
+	if len(sender.types) != len(want) {
+		t.Fatalf("events = %v, want %v", sender.types, want)
+	}
+	for i := range want {
+		if sender.types[i] != want[i] {
+			t.Errorf("event %d = %s, want %s", i, sender.types[i], want[i])
+		}
+	}
+
+	success := sender.events[2].Metadata.AsMap()
+	if success["role"] != "test-role" || success["broker"] != "test-broker" || success["cli_username"] != "monitor" {
+		t.Errorf("rotate-success metadata = %v", success)
+	}
+	if _, leaked := success["password"]; leaked {
+		t.Error("rotate-success metadata must not include the password")
+	}
+	if reason := sender.events[3].Metadata.AsMap()["reason"]; reason != sempErrCommand {
+		t.Errorf("rotate-fail reason = %v, want %s", reason, sempErrCommand)
+	}
+}
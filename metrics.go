@@ -0,0 +1,81 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"time"
+
+	metrics "github.com/hashicorp/go-metrics/compat"
+)
+
+// recordSEMPCall emits request count, latency and error telemetry for a
+// single SEMP operation against a broker. Metrics go to the global sink,
+// which Vault wires to its configured telemetry pipeline.
+func recordSEMPCall(broker, operation string, start time.Time, err error) {
+	labels := []metrics.Label{
+		{Name: "broker", Value: broker},
+		{Name: "operation", Value: operation},
+	}
+
+	metrics.IncrCounterWithLabels([]string{"solace", "semp", "request"}, 1, labels)
+	metrics.MeasureSinceWithLabels([]string{"solace", "semp", "latency"}, start, labels)
+
+	if err != nil {
+		class := sempErrorClass(err)
+		if class == "" {
+			class = "unknown"
+		}
+		metrics.IncrCounterWithLabels([]string{"solace", "semp", "error"}, 1,
+			append(labels, metrics.Label{Name: "class", Value: class}))
+	}
+}
+
+type metricsMountKey struct{}
+
+// withMetricsMount returns a context whose metrics are labeled with mount,
+// the path the plugin was reached at.
+func withMetricsMount(ctx context.Context, mount string) context.Context {
+	return context.WithValue(ctx, metricsMountKey{}, mount)
+}
+
+func metricsMount(ctx context.Context) string {
+	mount, _ := ctx.Value(metricsMountKey{}).(string)
+	return mount
+}
+
+// recordRotation counts the outcome of a password rotation on a broker. The
+// counters are labeled by mount, like the periodic gauges, but not by role:
+// a mount can hold more roles than a metrics backend should keep series for.
+func recordRotation(ctx context.Context, broker string, success bool) {
+	name := "rotations_fail_total"
+	if success {
+		name = "rotations_success_total"
+	}
+	metrics.IncrCounterWithLabels([]string{"solace", name}, 1, []metrics.Label{
+		{Name: "mount", Value: metricsMount(ctx)},
+		{Name: "broker", Value: broker},
+	})
+}
+
+// recordPeriodicRun publishes mount-wide gauges after a periodic pass. They
+// are labeled by mount so that several Solace mounts do not overwrite each
+// other's values.
+func recordPeriodicRun(mount string, roles, overdue, nonCompliant int, duration time.Duration) {
+	labels := []metrics.Label{{Name: "mount", Value: mount}}
+	metrics.SetGaugeWithLabels([]string{"solace", "roles_total"}, float32(roles), labels)
+	metrics.SetGaugeWithLabels([]string{"solace", "roles_overdue"}, float32(overdue), labels)
+	metrics.SetGaugeWithLabels([]string{"solace", "roles_non_compliant"}, float32(nonCompliant), labels)
+	metrics.SetGaugeWithLabels([]string{"solace", "periodic_duration_seconds"}, float32(duration.Seconds()), labels)
+}
+
+// recordNextRotations publishes, for each role with automatic rotation, the
+// seconds until it is next due at now, so upcoming rotation load can be
+// seen before it reaches the brokers. Roles already due report 0.
+func recordNextRotations(mount string, nextDue map[string]time.Time, now time.Time) {
+	for role, due := range nextDue {
+		seconds := max(due.Sub(now).Seconds(), 0)
+		metrics.SetGaugeWithLabels([]string{"solace", "role_seconds_until_rotation"}, float32(seconds), []metrics.Label{
+			{Name: "mount", Value: mount},
+			{Name: "role", Value: role},
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	metrics "github.com/hashicorp/go-metrics/compat"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func setupTestMetrics(t *testing.T) *metrics.InmemSink {
+	t.Helper()
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	conf := metrics.DefaultConfig("vault")
+	conf.EnableHostname = false
+	conf.EnableRuntimeMetrics = false
+	if _, err := metrics.NewGlobal(conf, sink); err != nil {
+		t.Fatalf("NewGlobal: %v", err)
+	}
+	t.Cleanup(func() {
+		metrics.NewGlobal(metrics.DefaultConfig("vault"), &metrics.BlackholeSink{})
+	})
+	return sink
+}
+
+func findCounter(sink *metrics.InmemSink, prefix string) (metrics.SampledValue, bool) {
+	for _, interval := range sink.Data() {
+		for key, v := range interval.Counters {
+			if strings.HasPrefix(key, prefix) {
+				return v, true
+			}
+		}
+	}
+	return metrics.SampledValue{}, false
+}
+
+func TestRecordSEMPCall_Success(t *testing.T) {
+	sink := setupTestMetrics(t)
+
+	recordSEMPCall("prod", "change_password", time.Now(), nil)
+
+	v, ok := findCounter(sink, "vault.solace.semp.request")
+	if !ok {
+		t.Fatal("expected request counter to be emitted")
+	}
+	if v.Count != 1 {
+		t.Errorf("request count = %d, want 1", v.Count)
+	}
+	if _, ok := findCounter(sink, "vault.solace.semp.error"); ok {
+		t.Error("error counter should not be emitted on success")
+	}
+}
+
+func TestRecordSEMPCall_ErrorClass(t *testing.T) {
+	sink := setupTestMetrics(t)
+
+	err := &SEMPError{Class: sempErrCommand, Err: errors.New("boom")}
+	recordSEMPCall("prod", "change_password", time.Now(), err)
+
+	v, ok := findCounter(sink, "vault.solace.semp.error")
+	if !ok {
+		t.Fatal("expected error counter to be emitted")
+	}
+	found := false
+	for _, l := range v.Labels {
+		if l.Name == "class" && l.Value == sempErrCommand {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("error counter labels = %v, want class=%s", v.Labels, sempErrCommand)
+	}
+}
+
+func TestRecordRotationAndPeriodicRun(t *testing.T) {
+	sink := setupTestMetrics(t)
+
+	ctx := withMetricsMount(context.Background(), "solace/")
+	recordRotation(ctx, "prod", true)
+	recordRotation(ctx, "prod", false)
+	recordRotation(ctx, "prod", false)
+	recordPeriodicRun("solace/", 7, 2, 1, 1500*time.Millisecond)
+
+	if v, ok := findCounter(sink, "vault.solace.rotations_success_total"); !ok || v.Count != 1 {
+		t.Errorf("rotations_success_total = %+v (found=%v), want 1", v, ok)
+	}
+	if v, ok := findCounter(sink, "vault.solace.rotations_fail_total"); !ok || v.Count != 2 {
+		t.Errorf("rotations_fail_total = %+v (found=%v), want 2", v, ok)
+	}
+	if v, _ := findCounter(sink, "vault.solace.rotations_fail_total"); !slices.Contains(v.Labels, metrics.Label{Name: "mount", Value: "solace/"}) ||
+		slices.ContainsFunc(v.Labels, func(l metrics.Label) bool { return l.Name == "role" }) {
+		t.Errorf("rotations_fail_total labels = %v, want mount and no role", v.Labels)
+	}
+
+	gauges := map[string]float32{}
+	for _, interval := range sink.Data() {
+		for _, g := range interval.Gauges {
+			gauges[g.Name] = g.Value
+		}
+	}
+	want := map[string]float32{
+		"vault.solace.roles_total":               7,
+		"vault.solace.roles_overdue":             2,
+		"vault.solace.roles_non_compliant":       1,
+		"vault.solace.periodic_duration_seconds": 1.5,
+	}
+	for name, value := range want {
+		if gauges[name] != value {
+			t.Errorf("%s = %v, want %v", name, gauges[name], value)
+		}
+	}
+}
+
+func TestPeriodicFunc_RecordsNextRotations(t *testing.T) {
+	sink := setupTestMetrics(t)
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	sb := b.(*solaceBackend)
+	ctx := context.Background()
+
+	role, _ := getRole(ctx, storage, "test-role")
+	role.RotationPeriod = time.Hour
+	role.LastRotated = time.Now().Add(-2 * time.Hour)
+	putRole(ctx, storage, "test-role", role)
+	role.RotationPeriod = 3 * time.Hour
+	role.LastRotated = time.Now().Add(-time.Hour)
+	putRole(ctx, storage, "later-role", role)
+	role.RotationPeriod = 0
+	putRole(ctx, storage, "manual-role", role)
+
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage, MountPoint: "solace/"}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+
+	// test-role was rotated by the pass, so it is due an hour from now.
+	got := map[string]float32{}
+	for _, interval := range sink.Data() {
+		for _, g := range interval.Gauges {
+			if g.Name != "vault.solace.role_seconds_until_rotation" {
+				continue
+			}
+			for _, l := range g.Labels {
+				if l.Name == "role" {
+					got[l.Value] = g.Value
+				}
+			}
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("role_seconds_until_rotation = %v, want test-role and later-role", got)
+	}
+	if v := got["test-role"]; v < 3500 || v > 3600 {
+		t.Errorf("test-role = %v seconds, want about 3600", v)
+	}
+	if v := got["later-role"]; v < 7100 || v > 7200 {
+		t.Errorf("later-role = %v seconds, want about 7200", v)
+	}
+}
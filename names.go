@@ -0,0 +1,120 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Role, broker and broker group names end up in storage keys, under a
+// prefix of their own and in the broker-to-role index, so they are held to
+// the form the paths naming them accept: no slash that would put the entry
+// under another prefix or let a list of one name take in another's entries.
+// Paths only check names they capture; these checks also cover names given
+// in request bodies, such as a role's broker, and names in role specs.
+
+// namePattern matches the names role, broker and broker group paths accept.
+var namePattern = regexp.MustCompile("^" + framework.GenericNameRegex("name") + "$")
+
+// reservedRoleNames are taken by fixed paths under roles/, so a role of
+// that name could never be read, written or deleted through roles/:name.
+var reservedRoleNames = map[string]bool{
+	"bulk-delete": true,
+	"export":      true,
+	"import":      true,
+}
+
+// validateNameReference checks a name a request refers to an existing role,
+// broker or broker group, kind, by.
+func validateNameReference(kind, name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%s name %q must be letters, digits, _, - and ., starting and ending with a letter or digit", kind, name)
+	}
+	return nil
+}
+
+// validateNewName checks the name of a role, broker or broker group, kind,
+// about to be created. Beyond the form of a reference, it may not contain
+// "..", which reads as a path traversal wherever the name is joined into a
+// path or URL, and a role may not take a reserved name.
+func validateNewName(kind, name string) error {
+	if err := validateNameReference(kind, name); err != nil {
+		return err
+	}
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("%s name %q cannot contain ..", kind, name)
+	}
+	if kind == "role" && reservedRoleNames[name] {
+		return fmt.Errorf("role name %q is reserved for roles/%s", name, name)
+	}
+	return nil
+}
+
+// problemNames returns a description of each stored role, broker and broker
+// group whose name, or whose reference to a broker or group, would be
+// refused today: entries written by earlier versions, or directly to
+// storage.
+func problemNames(ctx context.Context, s logical.Storage) ([]string, error) {
+	var problems []string
+	for _, kind := range []struct {
+		name string
+		list func(context.Context, logical.Storage) ([]string, error)
+	}{
+		{"broker", listBrokers},
+		{"broker group", listBrokerGroups},
+		{"role", listRoles},
+	} {
+		names, err := kind.list(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			// A name with a slash is listed as its first segment and a
+			// trailing slash.
+			if err := validateNewName(kind.name, strings.TrimSuffix(name, "/")); err != nil || strings.HasSuffix(name, "/") {
+				problems = append(problems, fmt.Sprintf("%s %q: stored under a name that is not allowed", kind.name, name))
+			}
+		}
+	}
+
+	roles, err := listRoles(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range roles {
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		role, err := getRole(ctx, s, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+		if role.Broker != "" && validateNameReference("broker", role.Broker) != nil {
+			problems = append(problems, fmt.Sprintf("role %q: refers to broker %q, which is not a valid name", name, role.Broker))
+		}
+		if role.BrokerGroup != "" && validateNameReference("broker group", role.BrokerGroup) != nil {
+			problems = append(problems, fmt.Sprintf("role %q: refers to broker group %q, which is not a valid name", name, role.BrokerGroup))
+		}
+	}
+	return problems, nil
+}
+
+// flagProblemNames logs the stored names problemNames finds, so operators
+// can rename the entries before they cause trouble. Nothing is changed.
+func (b *solaceBackend) flagProblemNames(ctx context.Context, s logical.Storage) error {
+	problems, err := problemNames(ctx, s)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		b.Logger().Warn("stored name would be refused by this version; recreate the entry under a valid name", "entry", problem)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestValidateNewName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"app":           true,
+		"app-1.prod_2":  true,
+		"a":             true,
+		"a..b":          false,
+		"a/b":           false,
+		"-app":          false,
+		"app.":          false,
+		"":              false,
+		"export":        false,
+		"bulk-delete":   false,
+		"import-orders": true,
+	} {
+		if err := validateNewName("role", name); (err == nil) != valid {
+			t.Errorf("validateNewName(%q) = %v, want valid=%v", name, err, valid)
+		}
+	}
+	// Only role names are reserved.
+	if err := validateNewName("broker", "export"); err != nil {
+		t.Errorf("broker named export: %v", err)
+	}
+}
+
+func TestNames_RefusedOnWrite(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	writeBroker(t, b, storage, "east")
+
+	for path, data := range map[string]map[string]interface{}{
+		"roles/a..b":                {"broker": "east", "cli_username": "a"},
+		"roles/app":                 {"broker": "../secrets/app", "cli_username": "a"},
+		"roles/grouped":             {"broker_group": "dr/x", "cli_username": "a"},
+		"config/brokers/a..b":       {"semp_url": "https://broker:943", "admin_username": "admin", "admin_password": "secret"},
+		"config/broker-groups/a..b": {"brokers": "east"},
+		"config/broker-groups/dr":   {"brokers": "east,west/x"},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Errorf("%s %v: err=%v, resp=%v, want an error response", path, data, err, resp)
+		}
+	}
+
+	// A reserved name cannot be reached through roles/:name, nor created by
+	// an import.
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/import",
+		Storage:   storage,
+		Data: map[string]interface{}{"spec": map[string]interface{}{
+			"spec_version": roleSpecVersion,
+			"name":         "export",
+			"role":         map[string]interface{}{"broker": "east", "cli_username": "x"},
+		}},
+	})
+	if err != nil || resp.Data["errors"].(map[string]interface{})["export"] == nil {
+		t.Errorf("import of a role named export: err=%v, resp=%v", err, resp)
+	}
+	if role, _ := getRole(ctx, storage, "export"); role != nil {
+		t.Error("role named export was created")
+	}
+}
+
+func TestProblemNames(t *testing.T) {
+	_, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	putBroker(ctx, storage, "east", &BrokerConfig{SEMPURL: "https://east:943"})
+	putBroker(ctx, storage, "west..1", &BrokerConfig{SEMPURL: "https://west:943"})
+	putRole(ctx, storage, "app", &RoleEntry{Broker: "east", CLIUsername: "app"})
+	putRole(ctx, storage, "export", &RoleEntry{Broker: "east", CLIUsername: "legacy"})
+	putRole(ctx, storage, "nested/app", &RoleEntry{Broker: "east", CLIUsername: "nested"})
+	putRole(ctx, storage, "stray", &RoleEntry{Broker: "../east", CLIUsername: "stray"})
+
+	problems, err := problemNames(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(problems, "\n")
+	for _, want := range []string{`broker "west..1"`, `role "export"`, `role "nested/"`, `broker "../east"`} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("problems = %q, want one about %s", problems, want)
+		}
+	}
+	if len(problems) != 4 {
+		t.Errorf("problems = %q, want 4", problems)
+	}
+}
@@ -1,28 +1,192 @@
 package solacevaultplugin
 
 import (
+	"bytes"
 	"crypto/rand"
+	"crypto/subtle"
 	"fmt"
 	"math/big"
+	"strings"
 )
 
 // Solace password constraints: max 128 chars, excludes :()";'<>,`\*&|
-const passwordCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^-_=+.~"
+const (
+	minPasswordLength = 16
+	maxPasswordLength = 128
 
-func generatePassword(length int) (string, error) {
-	if length < 16 {
-		return "", fmt.Errorf("password length must be at least 16, got %d", length)
+	passwordLower   = "abcdefghijklmnopqrstuvwxyz"
+	passwordUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits  = "0123456789"
+	passwordSymbols = "!@#$%^-_=+.~"
+
+	passwordCharset = passwordLower + passwordUpper + passwordDigits + passwordSymbols
+
+	// passwordForbidden are the characters Solace does not accept in CLI
+	// passwords.
+	passwordForbidden = ":()\";'<>,`\\*&|"
+)
+
+// passwordClasses are the character classes a password must contain when
+// class coverage is required.
+var passwordClasses = []string{passwordLower, passwordUpper, passwordDigits, passwordSymbols}
+
+// maxPasswordAttempts bounds regeneration when class coverage is required.
+// At the minimum length a draw misses a class only a few percent of the
+// time, so this is never reached in practice.
+const maxPasswordAttempts = 100
+
+// passwordPolicy controls how passwords are generated.
+type passwordPolicy struct {
+	// Charset is the set of characters to draw from; empty means
+	// passwordCharset.
+	Charset string
+
+	// RequireClasses makes every password contain at least one character
+	// from each class in passwordClasses that Charset includes.
+	RequireClasses bool
+
+	// Exclude are characters the broker rejects, left out of Charset.
+	Exclude string
+}
+
+// generatePassword returns a random password of the given length. With
+// RequireClasses set, passwords are redrawn rather than patched so that
+// every compliant password stays equally likely. The caller owns the
+// returned buffer and should wipe it once the password has been used.
+func generatePassword(length int, policy passwordPolicy) ([]byte, error) {
+	if length < minPasswordLength || length > maxPasswordLength {
+		return nil, fmt.Errorf("password length must be between %d and %d, got %d", minPasswordLength, maxPasswordLength, length)
+	}
+	charset := policy.Charset
+	if charset == "" {
+		charset = passwordCharset
+	}
+	if err := validateCharset(charset); err != nil {
+		return nil, err
+	}
+	if policy.Exclude != "" {
+		charset = strings.Map(func(c rune) rune {
+			if strings.ContainsRune(policy.Exclude, c) {
+				return -1
+			}
+			return c
+		}, charset)
+		if len(charset) < 2 {
+			return nil, fmt.Errorf("the broker's excluded password characters leave fewer than 2 characters to draw from")
+		}
+	}
+
+	var required []string
+	if policy.RequireClasses {
+		for _, class := range passwordClasses {
+			if strings.ContainsAny(charset, class) {
+				required = append(required, class)
+			}
+		}
+	}
+
+	for attempt := 0; attempt < maxPasswordAttempts; attempt++ {
+		pw, err := randomBytes(charset, length)
+		if err != nil {
+			return nil, err
+		}
+		if coversClasses(pw, required) {
+			return pw, nil
+		}
+		wipe(pw)
+	}
+	return nil, fmt.Errorf("could not generate a password containing every character class in %d attempts", maxPasswordAttempts)
+}
+
+// generateReplacementPassword returns a password that differs from current.
+// A collision is astronomically unlikely, but a rotation must never hand
+// back the password it is meant to replace.
+func generateReplacementPassword(length int, policy passwordPolicy, current []byte) ([]byte, error) {
+	for attempt := 0; attempt < maxPasswordAttempts; attempt++ {
+		pw, err := generatePassword(length, policy)
+		if err != nil {
+			return nil, err
+		}
+		if subtle.ConstantTimeCompare(pw, current) == 0 {
+			return pw, nil
+		}
+		wipe(pw)
+	}
+	return nil, fmt.Errorf("could not generate a password different from the current one in %d attempts", maxPasswordAttempts)
+}
+
+// validatePassword checks that a password not generated by this plugin meets
+// Solace's length limits and holds none of the excluded characters, which
+// default to those Solace does not accept.
+func validatePassword(password []byte, excluded string) error {
+	if excluded == "" {
+		excluded = passwordForbidden
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("password length must be between %d and %d, got %d", minPasswordLength, maxPasswordLength, len(password))
+	}
+	for _, c := range password {
+		if c <= ' ' || c > '~' {
+			return fmt.Errorf("passwords may only contain printable ASCII characters other than space")
+		}
+		if strings.IndexByte(excluded, c) >= 0 {
+			return fmt.Errorf("password contains a character the broker does not accept in passwords: %s", excluded)
+		}
+	}
+	return nil
+}
+
+// validateCharset checks that a charset only holds printable ASCII that
+// Solace accepts in passwords, without repeats that would skew the
+// distribution.
+func validateCharset(charset string) error {
+	if len(charset) < 2 {
+		return fmt.Errorf("password charset must contain at least 2 characters")
 	}
+	seen := make(map[rune]bool, len(charset))
+	for _, c := range charset {
+		if c <= ' ' || c > '~' {
+			return fmt.Errorf("password charset may only contain printable ASCII characters, got %q", c)
+		}
+		if strings.ContainsRune(passwordForbidden, c) {
+			return fmt.Errorf("password charset contains %q, which Solace does not accept in passwords", c)
+		}
+		if seen[c] {
+			return fmt.Errorf("password charset contains %q more than once", c)
+		}
+		seen[c] = true
+	}
+	return nil
+}
+
+func randomBytes(charset string, length int) ([]byte, error) {
 	result := make([]byte, length)
-	charsetLen := big.NewInt(int64(len(passwordCharset)))
+	charsetLen := big.NewInt(int64(len(charset)))
 
 	for i := 0; i < length; i++ {
 		idx, err := rand.Int(rand.Reader, charsetLen)
 		if err != nil {
-			return "", err
+			wipe(result)
+			return nil, err
 		}
-		result[i] = passwordCharset[idx.Int64()]
+		result[i] = charset[idx.Int64()]
 	}
 
-	return string(result), nil
+	return result, nil
+}
+
+func coversClasses(pw []byte, classes []string) bool {
+	for _, class := range classes {
+		if !bytes.ContainsAny(pw, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// wipe overwrites b with zeros. Passwords are kept in byte slices rather
+// than strings while they pass through the plugin so that they can be
+// cleared once used instead of lingering in the heap until collected.
+func wipe(b []byte) {
+	clear(b)
 }
@@ -0,0 +1,57 @@
+package solacevaultplugin
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// Password encodings: how creds/ presents a role's password, token or
+// client secret to consumers that cannot take it as it is. The stored
+// credential, and the one set on the broker, are never encoded.
+const (
+	passwordEncodingPlain  = "plain"
+	passwordEncodingBase64 = "base64"
+	passwordEncodingURL    = "url"
+)
+
+// validPasswordEncodings are the accepted password_encoding values.
+var validPasswordEncodings = map[string]bool{
+	passwordEncodingPlain:  true,
+	passwordEncodingBase64: true,
+	passwordEncodingURL:    true,
+}
+
+// encodePassword returns password in the given encoding. Roles stored
+// before encodings existed have none and get the password unchanged.
+func encodePassword(password, encoding string) string {
+	switch encoding {
+	case passwordEncodingBase64:
+		return base64.StdEncoding.EncodeToString([]byte(password))
+	case passwordEncodingURL:
+		return percentEncode(password)
+	default:
+		return password
+	}
+}
+
+// percentEncode escapes every byte of s but RFC 3986's unreserved
+// characters, so the result can go into any part of a URI, including the
+// userinfo, where url.QueryEscape's + for space and url.PathEscape's bare
+// @ and : would be misread.
+func percentEncode(s string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&0x0f])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,74 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestEncodePassword(t *testing.T) {
+	const password = "a#b%c@d e+f/g?h~i"
+	for encoding, want := range map[string]string{
+		"":                     password,
+		passwordEncodingPlain:  password,
+		passwordEncodingBase64: "YSNiJWNAZCBlK2YvZz9ofmk=",
+		passwordEncodingURL:    "a%23b%25c%40d%20e%2Bf%2Fg%3Fh~i",
+	} {
+		if got := encodePassword(password, encoding); got != want {
+			t.Errorf("encodePassword(%q) = %q, want %q", encoding, got, want)
+		}
+	}
+
+	// A percent-encoded password survives a round trip through a URI's
+	// userinfo.
+	u, err := url.Parse("amqps://app:" + encodePassword(password, passwordEncodingURL) + "@broker:5671")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := u.User.Password(); got != password {
+		t.Errorf("password parsed from URI = %q, want %q", got, password)
+	}
+}
+
+func TestPathCreds_PasswordEncoding(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"password_encoding": passwordEncodingURL},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("patch role: err=%v, resp=%v", err, resp)
+	}
+	if err := putRoleSecret(ctx, storage, "test-role", &RoleSecret{Password: "pass#word%0123456789", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	data := readCreds(t, b, storage, "test-role")
+	if data["password"] != "pass%23word%250123456789" {
+		t.Errorf("password = %q, want it percent-encoded", data["password"])
+	}
+	if data["password_encoding"] != passwordEncodingURL {
+		t.Errorf("password_encoding = %v, want %s", data["password_encoding"], passwordEncodingURL)
+	}
+	// The fingerprint is of the credential, not its encoding.
+	if secret, _ := getRoleSecret(ctx, storage, "test-role"); data["credential_fingerprint"] != credentialFingerprint(secret) {
+		t.Errorf("credential_fingerprint = %v, want that of the stored password", data["credential_fingerprint"])
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"password_encoding": "hex"},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Errorf("unknown password_encoding: err=%v, resp=%v, want an error response", err, resp)
+	}
+}
@@ -0,0 +1,111 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Brokers whose logins are checked against a password policy, their own
+// or that of the RADIUS or LDAP server behind them, can refuse a new
+// password the plugin has no way to know of in advance: one the history
+// says was used recently, or any password at all while the current one is
+// younger than the policy's minimum age. SEMP has no call to read such a
+// policy, so it is recognized by the broker's refusals.
+var (
+	errPasswordReused = errors.New("broker refused the password as recently used")
+	errPasswordMinAge = errors.New("broker refused to change a password younger than its minimum age")
+)
+
+// passwordReusedPhrases and passwordMinAgePhrases are what brokers and the
+// servers behind them say, in lower case, when they refuse a password
+// under a password history or a minimum age.
+var (
+	passwordReusedPhrases = []string{
+		"recently used",
+		"password history",
+		"previously used",
+		"used before",
+		"reuse",
+	}
+	passwordMinAgePhrases = []string{
+		"minimum age",
+		"minimum password age",
+		"changed too recently",
+		"too soon",
+	}
+)
+
+// maxPasswordReuseAttempts bounds how many passwords one rotation offers a
+// broker that keeps refusing them as recently used.
+const maxPasswordReuseAttempts = 3
+
+// passwordMinAgeRetryDelay is how long automatic rotation leaves a role
+// alone after the broker refused to change its password before the
+// minimum age, rather than being refused again every pass.
+const passwordMinAgeRetryDelay = time.Hour
+
+// classifyPasswordRefusal wraps a failed SEMP command in errPasswordReused
+// or errPasswordMinAge when the broker's reason reads like one. Other
+// errors are returned as they are.
+func classifyPasswordRefusal(err error) error {
+	if err == nil || sempErrorClass(err) != sempErrCommand {
+		return err
+	}
+	reason := strings.ToLower(err.Error())
+	for _, phrase := range passwordMinAgePhrases {
+		if strings.Contains(reason, phrase) {
+			return fmt.Errorf("%w: %w", errPasswordMinAge, err)
+		}
+	}
+	for _, phrase := range passwordReusedPhrases {
+		if strings.Contains(reason, phrase) {
+			return fmt.Errorf("%w: %w", errPasswordReused, err)
+		}
+	}
+	return err
+}
+
+// applyGeneratedCredential applies cred on the broker client reaches. If
+// the broker refuses it as recently used and the role's password was
+// generated, a new one is generated and offered in its place, up to
+// maxPasswordReuseAttempts passwords in all. It returns the credential the
+// broker was last offered; any it replaced are wiped.
+func (b *solaceBackend) applyGeneratedCredential(ctx context.Context, client *SEMPClient, name string, role *RoleEntry, settings *Settings, excluded string, current *credential, opts rotationOptions, cred *credential) (*credential, error) {
+	err := classifyPasswordRefusal(b.applyCredential(ctx, client, role, cred))
+	// A caller-supplied password is the caller's to change.
+	if !role.generatesPassword() || len(opts.password) > 0 {
+		return cred, err
+	}
+	for attempt := 1; attempt < maxPasswordReuseAttempts && errors.Is(err, errPasswordReused); attempt++ {
+		b.Logger().Warn("broker refused the new password under its password history; generating another",
+			"role", name,
+			"broker", role.Broker,
+			"attempt", attempt,
+			"error", err,
+		)
+		next, genErr := b.generateCredential(ctx, name, role, settings, excluded, current, opts)
+		if genErr != nil {
+			return cred, fmt.Errorf("generating credential: %w", genErr)
+		}
+		cred.wipe()
+		cred = next
+		err = classifyPasswordRefusal(b.applyCredential(ctx, client, role, cred))
+	}
+	return cred, err
+}
+
+// postponeRole keeps automatic rotation from trying a role again before
+// until. A role write, or the next pass to read the role after until,
+// schedules it as usual again.
+func (b *solaceBackend) postponeRole(name string, until time.Time) {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	if b.schedule == nil {
+		b.schedule = make(map[string]time.Time)
+	}
+	b.schedule[name] = until
+}
@@ -0,0 +1,162 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClassifyPasswordRefusal(t *testing.T) {
+	command := func(reason string) error {
+		return &SEMPError{Class: sempErrCommand, Err: errors.New("SEMP command failed: " + reason)}
+	}
+	for name, tc := range map[string]struct {
+		err  error
+		want error
+	}{
+		"reused":    {command("Password was recently used"), errPasswordReused},
+		"history":   {command("new password violates the Password History policy"), errPasswordReused},
+		"min age":   {command("Password has not reached its minimum age"), errPasswordMinAge},
+		"too soon":  {command("password changed too recently"), errPasswordMinAge},
+		"other":     {command("Invalid username"), nil},
+		"transport": {&SEMPError{Class: sempErrTransport, Err: errors.New("recently used connection reset")}, nil},
+	} {
+		got := classifyPasswordRefusal(tc.err)
+		for _, sentinel := range []error{errPasswordReused, errPasswordMinAge} {
+			if errors.Is(got, sentinel) != (sentinel == tc.want) {
+				t.Errorf("%s: errors.Is(%v, %v) = %v", name, got, sentinel, !(sentinel == tc.want))
+			}
+		}
+		if sempErrorClass(got) != sempErrorClass(tc.err) {
+			t.Errorf("%s: class %q lost, got %q", name, sempErrorClass(tc.err), sempErrorClass(got))
+		}
+	}
+	if classifyPasswordRefusal(nil) != nil {
+		t.Error("expected nil to stay nil")
+	}
+}
+
+// historyBroker is a SEMP server that refuses its first refusals password
+// changes with reason, and keeps the last password it took.
+type historyBroker struct {
+	mu       sync.Mutex
+	reason   string
+	refusals int
+	changes  int
+	password string
+}
+
+func (hb *historyBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if strings.Contains(string(body), "<change-password>") {
+		hb.changes++
+		if hb.changes <= hb.refusals {
+			w.Write([]byte(`<rpc-reply><parse-error>` + hb.reason + `</parse-error><execute-result code="fail"/></rpc-reply>`))
+			return
+		}
+		if m := sempPasswordPattern.FindSubmatch(body); m != nil {
+			hb.password = string(m[1])
+		}
+	}
+	w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+}
+
+func (hb *historyBroker) reset(reason string, refusals int) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	hb.reason, hb.refusals, hb.changes = reason, refusals, 0
+}
+
+func TestPathRotate_PasswordHistory(t *testing.T) {
+	hb := &historyBroker{}
+	server := httptest.NewServer(hb)
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	b, storage, _ = setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	t.Run("regenerated", func(t *testing.T) {
+		hb.reset("Password was recently used", maxPasswordReuseAttempts-1)
+		resp, err := sb.rotateRole(ctx, storage, "test-role")
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+		}
+		secret, _ := getRoleSecret(ctx, storage, "test-role")
+		hb.mu.Lock()
+		defer hb.mu.Unlock()
+		if hb.changes != maxPasswordReuseAttempts {
+			t.Errorf("expected %d password changes, got %d", maxPasswordReuseAttempts, hb.changes)
+		}
+		if secret == nil || secret.Password != hb.password {
+			t.Error("stored password is not the one the broker took")
+		}
+	})
+
+	t.Run("refused every time", func(t *testing.T) {
+		hb.reset("Password was recently used", maxPasswordReuseAttempts)
+		before, _ := getRoleSecret(ctx, storage, "test-role")
+		resp, err := sb.rotateRole(ctx, storage, "test-role")
+		if err != nil {
+			t.Fatalf("rotateRole: %v", err)
+		}
+		if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "recently used") {
+			t.Fatalf("expected a password history error, got %v", resp)
+		}
+		after, _ := getRoleSecret(ctx, storage, "test-role")
+		if after.Password != before.Password {
+			t.Error("stored password changed though the broker refused every new one")
+		}
+		if hb.changes != maxPasswordReuseAttempts {
+			t.Errorf("expected %d password changes, got %d", maxPasswordReuseAttempts, hb.changes)
+		}
+	})
+
+	t.Run("supplied password", func(t *testing.T) {
+		hb.reset("Password was recently used", 1)
+		settings := defaultSettings()
+		settings.AllowSuppliedPasswords = true
+		if err := putSettings(ctx, storage, settings); err != nil {
+			t.Fatalf("putSettings: %v", err)
+		}
+		defer putSettings(ctx, storage, defaultSettings())
+		resp, err := sb.rotateRoleWith(ctx, storage, "test-role", rotationOptions{
+			password: []byte("Supplied-Password-1234"),
+			actor:    rotationActor{trigger: rotationTriggerManual},
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected the refused supplied password to fail the rotation, got err=%v, resp=%v", err, resp)
+		}
+		if hb.changes != 1 {
+			t.Errorf("expected a supplied password to be offered once, got %d changes", hb.changes)
+		}
+	})
+
+	t.Run("minimum age", func(t *testing.T) {
+		hb.reset("Password has not reached its minimum age", 1)
+		resp, err := sb.rotateRole(ctx, storage, "test-role")
+		if err != nil {
+			t.Fatalf("rotateRole: %v", err)
+		}
+		if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "minimum password age") {
+			t.Fatalf("expected a minimum age error, got %v", resp)
+		}
+		if hb.changes != 1 {
+			t.Errorf("expected no new password after a minimum age refusal, got %d changes", hb.changes)
+		}
+		next := sb.scheduledRoles([]string{"test-role"})["test-role"]
+		if until := time.Until(next); until < passwordMinAgeRetryDelay-time.Minute || until > passwordMinAgeRetryDelay {
+			t.Errorf("expected the role postponed by %s, next due in %s", passwordMinAgeRetryDelay, until)
+		}
+	})
+}
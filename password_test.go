@@ -1,12 +1,13 @@
 package solacevaultplugin
 
 import (
+	"bytes"
 	"strings"
 	"testing"
 )
 
 func TestGeneratePassword(t *testing.T) {
-	pw, err := generatePassword(32)
+	pw, err := generatePassword(32, passwordPolicy{})
 	if err != nil {
 		t.Fatalf("generatePassword: %v", err)
 	}
@@ -15,29 +16,28 @@ func TestGeneratePassword(t *testing.T) {
 	}
 
 	// Verify no excluded characters
-	excluded := `:()";'<>,` + "`\\*&|"
 	for _, c := range pw {
-		if strings.ContainsRune(excluded, c) {
+		if strings.ContainsRune(passwordForbidden, rune(c)) {
 			t.Errorf("password contains excluded character: %c", c)
 		}
 	}
 }
 
 func TestGeneratePassword_Uniqueness(t *testing.T) {
-	pw1, _ := generatePassword(32)
-	pw2, _ := generatePassword(32)
-	if pw1 == pw2 {
+	pw1, _ := generatePassword(32, passwordPolicy{})
+	pw2, _ := generatePassword(32, passwordPolicy{})
+	if bytes.Equal(pw1, pw2) {
 		t.Error("two generated passwords should not be identical")
 	}
 }
 
 func TestGeneratePassword_MinLength(t *testing.T) {
-	_, err := generatePassword(15)
+	_, err := generatePassword(15, passwordPolicy{})
 	if err == nil {
 		t.Error("expected error for length < 16")
 	}
 
-	pw, err := generatePassword(16)
+	pw, err := generatePassword(16, passwordPolicy{})
 	if err != nil {
 		t.Fatalf("generatePassword(16): %v", err)
 	}
@@ -47,7 +47,11 @@ func TestGeneratePassword_MinLength(t *testing.T) {
 }
 
 func TestGeneratePassword_MaxLength(t *testing.T) {
-	pw, err := generatePassword(128)
+	if _, err := generatePassword(129, passwordPolicy{}); err == nil {
+		t.Error("expected error for length > 128")
+	}
+
+	pw, err := generatePassword(128, passwordPolicy{})
 	if err != nil {
 		t.Fatalf("generatePassword(128): %v", err)
 	}
@@ -55,3 +59,103 @@ func TestGeneratePassword_MaxLength(t *testing.T) {
 		t.Errorf("len = %d, want 128", len(pw))
 	}
 }
+
+func TestGeneratePassword_CharacterClasses(t *testing.T) {
+	// At the minimum length roughly one draw in twenty misses a class, so
+	// enough iterations exercise the regeneration path.
+	for i := 0; i < 200; i++ {
+		pw, err := generatePassword(16, passwordPolicy{RequireClasses: true})
+		if err != nil {
+			t.Fatalf("generatePassword: %v", err)
+		}
+		for _, class := range passwordClasses {
+			if !bytes.ContainsAny(pw, class) {
+				t.Fatalf("password is missing a character from %q", class)
+			}
+		}
+	}
+}
+
+func TestGeneratePassword_CustomCharset(t *testing.T) {
+	pw, err := generatePassword(32, passwordPolicy{Charset: "abcdef0123", RequireClasses: true})
+	if err != nil {
+		t.Fatalf("generatePassword: %v", err)
+	}
+	if len(bytes.Trim(pw, "abcdef0123")) != 0 {
+		t.Errorf("password %q uses characters outside the charset", pw)
+	}
+	// Only the classes the charset includes are required.
+	if !bytes.ContainsAny(pw, passwordLower) || !bytes.ContainsAny(pw, passwordDigits) {
+		t.Errorf("password %q is missing a class present in the charset", pw)
+	}
+
+	for _, charset := range []string{"abc:def", "aab", "abc def", "a"} {
+		if _, err := generatePassword(32, passwordPolicy{Charset: charset}); err == nil {
+			t.Errorf("expected error for charset %q", charset)
+		}
+	}
+}
+
+func TestWipe(t *testing.T) {
+	pw, err := generatePassword(32, passwordPolicy{})
+	if err != nil {
+		t.Fatalf("generatePassword: %v", err)
+	}
+	wipe(pw)
+	if !bytes.Equal(pw, make([]byte, 32)) {
+		t.Errorf("password not wiped: %q", pw)
+	}
+}
+
+func TestGenerateReplacementPassword(t *testing.T) {
+	current, err := generatePassword(16, passwordPolicy{Charset: "ab"})
+	if err != nil {
+		t.Fatalf("generatePassword: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		pw, err := generateReplacementPassword(16, passwordPolicy{Charset: "ab"}, current)
+		if err != nil {
+			t.Fatalf("generateReplacementPassword: %v", err)
+		}
+		if bytes.Equal(pw, current) {
+			t.Fatal("replacement password equals the current one")
+		}
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	for password, valid := range map[string]bool{
+		"abcdefghijklmnop":       true,
+		"short":                  false,
+		strings.Repeat("a", 129): false,
+		"abcdefgh:ijklmnop":      false,
+		"abcdefgh ijklmnop":      false,
+		"abcdefghéijklmnop":      false,
+	} {
+		if err := validatePassword([]byte(password), ""); (err == nil) != valid {
+			t.Errorf("validatePassword(%q) = %v, want valid=%v", password, err, valid)
+		}
+	}
+	if err := validatePassword([]byte("abcdefgh#ijklmnop"), "#$"); err == nil {
+		t.Error("validatePassword accepted a character the broker excludes")
+	}
+	if err := validatePassword([]byte("abcdefgh:ijklmnop"), "#$"); err != nil {
+		t.Errorf("validatePassword with the broker's own exclusions: %v", err)
+	}
+}
+
+func TestGeneratePassword_Exclude(t *testing.T) {
+	excluded := passwordSymbols + "0Oo1lI"
+	for i := 0; i < 20; i++ {
+		pw, err := generatePassword(32, passwordPolicy{RequireClasses: true, Exclude: excluded})
+		if err != nil {
+			t.Fatalf("generatePassword: %v", err)
+		}
+		if bytes.ContainsAny(pw, excluded) {
+			t.Fatalf("password %q contains an excluded character", pw)
+		}
+	}
+	if _, err := generatePassword(32, passwordPolicy{Charset: "abc", Exclude: "bc"}); err == nil {
+		t.Error("expected an error when exclusions leave fewer than 2 characters")
+	}
+}
@@ -0,0 +1,25 @@
+package solacevaultplugin
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/vault/sdk/framework"
+)
+
+// patchFieldData applies the request's fields to resource with JSON merge
+// patch semantics and returns the result as field data, so a PATCH goes
+// through the same write handler, and the same validation, as a full
+// update. The path's name capture is part of the request fields and so ends
+// up in the result.
+func patchFieldData(d *framework.FieldData, resource map[string]interface{}) (*framework.FieldData, error) {
+	patched, err := framework.HandlePatchOperation(d, resource, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+	if err := json.Unmarshal(patched, &raw); err != nil {
+		return nil, err
+	}
+	return &framework.FieldData{Raw: raw, Schema: d.Schema}, nil
+}
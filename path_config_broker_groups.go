@@ -0,0 +1,225 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathConfigBrokerGroups(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "config/broker-groups/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "broker-group",
+				ItemType:        "Broker Group",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the broker group.",
+					Required:    true,
+					DisplayAttrs: &framework.DisplayAttributes{
+						Identifier: true,
+					},
+				},
+				"brokers": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Names of the broker configurations in the group, in the order rotation applies passwords to them.",
+					Required:    true,
+					DisplayAttrs: &framework.DisplayAttributes{
+						Description: "Names of the broker configurations in the group, in the order rotation applies passwords to them.",
+					},
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback:                    b.pathConfigBrokerGroupsWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "configure",
+					},
+					Responses: noContentResponses,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathConfigBrokerGroupsWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "configure",
+					},
+					Responses: noContentResponses,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathConfigBrokerGroupsRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"brokers": {
+									Type:        framework.TypeStringSlice,
+									Description: "Names of the broker configurations in the group.",
+								},
+							},
+						}},
+					},
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback:                    b.pathConfigBrokerGroupsDelete,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   noContentResponses,
+				},
+			},
+			ExistenceCheck:  b.pathConfigBrokerGroupsExistenceCheck,
+			HelpSynopsis:    "Configure a group of brokers that share CLI users.",
+			HelpDescription: "Configure a group of brokers, such as a DR pair or the nodes of a DMR cluster. Roles on a group give their CLI user the same password on every member.",
+		},
+		{
+			Pattern: "config/broker-groups/?$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "broker-groups",
+				Navigation:      true,
+				ItemType:        "Broker Group",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathConfigBrokerGroupsList,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      listResponseFields,
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "List configured broker groups.",
+			HelpDescription: "List the names of all configured broker groups.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathConfigBrokerGroupsExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	name := d.Get("name").(string)
+	group, err := getBrokerGroup(ctx, req.Storage, name)
+	if err != nil {
+		return false, err
+	}
+	return group != nil, nil
+}
+
+func (b *solaceBackend) pathConfigBrokerGroupsWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	brokers := d.Get("brokers").([]string)
+
+	if len(brokers) == 0 {
+		return logical.ErrorResponse("brokers is required"), nil
+	}
+	existing, err := getBrokerGroup(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		if err := validateNewName("broker group", name); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+	seen := make(map[string]bool, len(brokers))
+	for _, broker := range brokers {
+		if err := validateNameReference("broker", broker); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if seen[broker] {
+			return logical.ErrorResponse("broker %q is listed more than once", broker), nil
+		}
+		seen[broker] = true
+
+		config, err := getBroker(ctx, req.Storage, broker)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return logical.ErrorResponse("broker %q not found", broker), nil
+		}
+	}
+
+	if err := putBrokerGroup(ctx, req.Storage, name, &BrokerGroup{Brokers: brokers}); err != nil {
+		return nil, err
+	}
+	b.sendEvent(ctx, eventBrokerGroupWrite, "broker_group", name)
+
+	return nil, nil
+}
+
+func (b *solaceBackend) pathConfigBrokerGroupsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	group, err := getBrokerGroup(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"brokers": group.Brokers,
+		},
+	}, nil
+}
+
+func (b *solaceBackend) pathConfigBrokerGroupsDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	dependents, err := listGroupRoles(ctx, req.Storage, name)
+	if err != nil {
+		return nil, fmt.Errorf("checking dependent roles: %w", err)
+	}
+	if len(dependents) > 0 {
+		return logical.ErrorResponse("cannot delete broker group %q: referenced by roles: %s", name, strings.Join(dependents, ", ")), nil
+	}
+
+	if err := deleteBrokerGroup(ctx, req.Storage, name); err != nil {
+		return nil, err
+	}
+	b.sendEvent(ctx, eventBrokerGroupDelete, "broker_group", name)
+
+	return nil, nil
+}
+
+func (b *solaceBackend) pathConfigBrokerGroupsList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	groups, err := listBrokerGroups(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(groups), nil
+}
+
+// brokerGroups returns the groups a broker is a member of.
+func brokerGroups(ctx context.Context, s logical.Storage, broker string) ([]string, error) {
+	names, err := listBrokerGroups(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	var groups []string
+	for _, name := range names {
+		group, err := getBrokerGroup(ctx, s, name)
+		if err != nil {
+			return nil, err
+		}
+		if group != nil && slices.Contains(group.Brokers, broker) {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,216 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// setupBrokerGroup configures a broker per passwordBroker, a group "dr" of
+// them in order, and a role "shared" whose CLI user is monitor.
+func setupBrokerGroup(t *testing.T, members ...*passwordBroker) (logical.Backend, logical.Storage) {
+	t.Helper()
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	var names []string
+	for i, pb := range members {
+		server := httptest.NewServer(pb)
+		t.Cleanup(server.Close)
+		name := []string{"east", "west", "south"}[i]
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/brokers/" + name,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"semp_url":       server.URL,
+				"admin_username": "admin",
+				"admin_password": "secret",
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("create broker: err=%v, resp=%v", err, resp)
+		}
+		names = append(names, name)
+	}
+
+	for _, write := range []struct {
+		path string
+		data map[string]interface{}
+	}{
+		{"config/broker-groups/dr", map[string]interface{}{"brokers": strings.Join(names, ",")}},
+		{"roles/shared", map[string]interface{}{"broker_group": "dr", "cli_username": "monitor"}},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      write.path,
+			Storage:   storage,
+			Data:      write.data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("write %s: err=%v, resp=%v", write.path, err, resp)
+		}
+	}
+	return b, storage
+}
+
+func TestPathConfigBrokerGroups(t *testing.T) {
+	b, storage := setupBrokerGroup(t, &passwordBroker{}, &passwordBroker{})
+	ctx := context.Background()
+
+	for name, data := range map[string]map[string]interface{}{
+		"empty":     {"brokers": ""},
+		"unknown":   {"brokers": "east,north"},
+		"duplicate": {"brokers": "east,east"},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/broker-groups/bad",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Errorf("%s: expected error, got err=%v, resp=%v", name, err, resp)
+		}
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/broker-groups/dr",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("read group: err=%v, resp=%v", err, resp)
+	}
+	if brokers := resp.Data["brokers"].([]string); strings.Join(brokers, ",") != "east,west" {
+		t.Errorf("brokers = %v, want [east west]", brokers)
+	}
+
+	// A JSON array is accepted as well as a comma-separated string.
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/broker-groups/dr",
+		Storage:   storage,
+		Data:      map[string]interface{}{"brokers": []interface{}{"west", "east"}},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("write group as array: err=%v, resp=%v", err, resp)
+	}
+	if group, _ := getBrokerGroup(ctx, storage, "dr"); strings.Join(group.Brokers, ",") != "west,east" {
+		t.Errorf("brokers = %v, want [west east]", group.Brokers)
+	}
+
+	for _, path := range []string{"config/broker-groups/dr", "config/brokers/west"} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      path,
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Errorf("delete %s: expected error while in use, got err=%v, resp=%v", path, err, resp)
+		}
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/both",
+		Storage:   storage,
+		Data:      map[string]interface{}{"broker": "east", "broker_group": "dr", "cli_username": "monitor"},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Errorf("expected error for a role with both broker and broker_group, got err=%v, resp=%v", err, resp)
+	}
+}
+
+func TestPathRotate_BrokerGroup(t *testing.T) {
+	east, west, south := &passwordBroker{}, &passwordBroker{}, &passwordBroker{}
+	b, storage := setupBrokerGroup(t, east, west, south)
+	ctx := context.Background()
+
+	resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "shared")
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	statuses := resp.Data["brokers"].(map[string]interface{})
+	for _, name := range []string{"east", "west", "south"} {
+		if statuses[name] != groupMemberChanged {
+			t.Errorf("%s status = %v, want %s", name, statuses[name], groupMemberChanged)
+		}
+	}
+	creds := readCreds(t, b, storage, "shared")
+	password := creds["password"].(string)
+	for name, pb := range map[string]*passwordBroker{"east": east, "west": west, "south": south} {
+		if pb.current() != password {
+			t.Errorf("%s does not have the stored password", name)
+		}
+	}
+	if creds["broker_group"] != "dr" {
+		t.Errorf("broker_group = %v, want dr", creds["broker_group"])
+	}
+
+	// A failure on the last member rolls the others back.
+	south.mu.Lock()
+	south.failChanges = true
+	south.mu.Unlock()
+	resp, err = b.(*solaceBackend).rotateRole(ctx, storage, "shared")
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got err=%v, resp=%v", err, resp)
+	}
+	if msg := resp.Error().Error(); !strings.Contains(msg, "east rolled back, west rolled back, south failed") {
+		t.Errorf("unexpected error: %s", msg)
+	}
+	if east.current() != password || west.current() != password {
+		t.Error("members were not rolled back to the stored password")
+	}
+	if got := readCreds(t, b, storage, "shared")["password"]; got != password {
+		t.Error("stored password changed after a failed group rotation")
+	}
+}
+
+func TestPathRotate_BrokerGroupFirstRotationFailure(t *testing.T) {
+	east, west := &passwordBroker{}, &passwordBroker{failChanges: true}
+	b, storage := setupBrokerGroup(t, east, west)
+	ctx := context.Background()
+
+	resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "shared")
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got err=%v, resp=%v", err, resp)
+	}
+	if msg := resp.Error().Error(); !strings.Contains(msg, "east left with new password, west failed") {
+		t.Errorf("unexpected error: %s", msg)
+	}
+
+	// With no stored password to go back to, east keeps the new one, which
+	// is saved for recovery.
+	recovery, err := getRecovery(ctx, storage, "shared")
+	if err != nil || recovery == nil {
+		t.Fatalf("getRecovery: err=%v, entry=%v", err, recovery)
+	}
+	if recovery.Password != east.current() || recovery.Broker != "dr" {
+		t.Errorf("unexpected recovery entry: broker=%q", recovery.Broker)
+	}
+}
+
+func TestPathRotate_BrokerGroupNotReady(t *testing.T) {
+	east, west := &passwordBroker{}, &passwordBroker{failShows: true}
+	b, storage := setupBrokerGroup(t, east, west)
+	ctx := context.Background()
+
+	resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "shared")
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got err=%v, resp=%v", err, resp)
+	}
+	if msg := resp.Error().Error(); !strings.Contains(msg, "not attempted") || !strings.Contains(msg, "east ready, west failed") {
+		t.Errorf("unexpected error: %s", msg)
+	}
+	// Phase one failed, so no member was changed.
+	if east.current() != "" || west.current() != "" {
+		t.Error("a member was changed although the group was not ready")
+	}
+	if secret, _ := getRoleSecret(ctx, storage, "shared"); !secret.empty() {
+		t.Error("a password was stored although the group was not ready")
+	}
+}
@@ -3,8 +3,10 @@ package solacevaultplugin
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -14,15 +16,23 @@ func pathConfigBrokers(b *solaceBackend) []*framework.Path {
 	return []*framework.Path{
 		{
 			Pattern: "config/brokers/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "broker",
+				ItemType:        "Broker",
+			},
 			Fields: map[string]*framework.FieldSchema{
 				"name": {
 					Type:        framework.TypeString,
 					Description: "Name of the broker configuration.",
 					Required:    true,
+					DisplayAttrs: &framework.DisplayAttributes{
+						Identifier: true,
+					},
 				},
 				"semp_url": {
 					Type:        framework.TypeString,
-					Description: "SEMP v1 endpoint URL, e.g., https://broker:8080",
+					Description: "SEMP v1 endpoint URL, e.g., https://broker:8080, or unix:///path/to/socket to reach the broker through a local Unix domain socket.",
 					Required:    true,
 				},
 				"admin_username": {
@@ -38,6 +48,18 @@ func pathConfigBrokers(b *solaceBackend) []*framework.Path {
 						Sensitive: true,
 					},
 				},
+				"mate_semp_url": {
+					Type:        framework.TypeString,
+					Description: "SEMP URL of the other node of an HA pair. When set, password changes are sent to whichever node reports itself active.",
+				},
+				"semp_dial_address": {
+					Type:        framework.TypeString,
+					Description: "Host and port to connect to instead of semp_url's, such as the local end of an SSH port forward. Requests still name semp_url's host, and its TLS certificate is verified against it. Optional.",
+				},
+				"mate_semp_dial_address": {
+					Type:        framework.TypeString,
+					Description: "Host and port to connect to instead of mate_semp_url's. Optional.",
+				},
 				"semp_version": {
 					Type:        framework.TypeString,
 					Description: "SEMP schema version string, e.g., soltr/10_4. Optional.",
@@ -47,19 +69,115 @@ func pathConfigBrokers(b *solaceBackend) []*framework.Path {
 					Description: "Skip TLS certificate verification. Do not use in production.",
 					Default:     false,
 				},
+				"connect_timeout": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Timeout for establishing the TCP connection to the broker. Default: 10s.",
+				},
+				"request_timeout": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Overall timeout for a SEMP request, including connecting. Default: 30s.",
+				},
+				"force_http1": {
+					Type:        framework.TypeBool,
+					Description: "Disable HTTP/2 and always use HTTP/1.1 when talking to the broker.",
+				},
+				"max_idle_conns_per_host": {
+					Type:        framework.TypeInt,
+					Description: "Maximum idle keep-alive connections kept open to the broker. Default: 2.",
+				},
+				"tls_handshake_timeout": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Timeout for the TLS handshake with the broker. Default: 10s.",
+				},
+				"cloud_api_url": {
+					Type:        framework.TypeString,
+					Description: "Solace Cloud REST API base URL, for brokers hosted in Solace Cloud. Default: " + defaultCloudAPIURL + " when cloud_api_token is set.",
+				},
+				"cloud_api_token": {
+					Type:        framework.TypeString,
+					Description: "Solace Cloud API token allowed to manage the organization's API tokens, for cloud_token roles.",
+					DisplayAttrs: &framework.DisplayAttributes{
+						Sensitive: true,
+					},
+				},
+				"notify_url": {
+					Type:        framework.TypeString,
+					Description: "REST messaging endpoint of the broker's message VPN, e.g., https://broker:9443. Required with notify_topic.",
+				},
+				"notify_topic": {
+					Type:        framework.TypeString,
+					Description: "Topic a notice of each rotation on this broker is published to. The notice carries the role, broker and time, never the credential. Optional.",
+				},
+				"notify_username": {
+					Type:        framework.TypeString,
+					Description: "Client username to publish rotation notices as. Optional.",
+				},
+				"notify_password": {
+					Type:        framework.TypeString,
+					Description: "Password of notify_username.",
+					DisplayAttrs: &framework.DisplayAttributes{
+						Sensitive: true,
+					},
+				},
+				"notify_signing_key": {
+					Type:        framework.TypeString,
+					Description: "Shared secret, at least 32 characters, to sign rotation notices with HMAC-SHA256 so receivers can tell they came from Vault. Optional.",
+					DisplayAttrs: &framework.DisplayAttributes{
+						Sensitive: true,
+					},
+				},
+				"allowed_semp_networks": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "CIDR ranges or addresses the SEMP hosts of semp_url and mate_semp_url must resolve to. Connections to any other address are refused, so a hijacked DNS record cannot redirect a rotation. Optional.",
+				},
+				"password_excluded_characters": {
+					Type:        framework.TypeString,
+					Description: "Characters the broker rejects in passwords, such as when logins pass through to RADIUS. Generated passwords leave them out and supplied passwords may not contain them. Default: Solace's documented exclusions, " + passwordForbidden + ".",
+				},
+				"rotation_lock_ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "When set, each rotation on this broker first takes a lock on the broker for the credential it changes, held for at most this long, so that plugins in other Vault clusters managing the broker do not rotate it at the same time. Must be at least 60s and longer than any rotation takes. Optional.",
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.CreateOperation: &framework.PathOperation{
-					Callback: b.pathConfigBrokersWrite,
+					Callback:                    b.pathConfigBrokersWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "configure",
+					},
+					Responses: noContentResponses,
 				},
 				logical.UpdateOperation: &framework.PathOperation{
-					Callback: b.pathConfigBrokersWrite,
+					Callback:                    b.pathConfigBrokersWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "configure",
+					},
+					Responses: noContentResponses,
+				},
+				logical.PatchOperation: &framework.PathOperation{
+					Callback:                    b.pathConfigBrokersPatch,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   noContentResponses,
 				},
 				logical.ReadOperation: &framework.PathOperation{
 					Callback: b.pathConfigBrokersRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      brokerResponseFields,
+						}},
+					},
 				},
 				logical.DeleteOperation: &framework.PathOperation{
-					Callback: b.pathConfigBrokersDelete,
+					Callback:                    b.pathConfigBrokersDelete,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   noContentResponses,
 				},
 			},
 			ExistenceCheck:  b.pathConfigBrokersExistenceCheck,
@@ -68,9 +186,21 @@ func pathConfigBrokers(b *solaceBackend) []*framework.Path {
 		},
 		{
 			Pattern: "config/brokers/?$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "brokers",
+				Navigation:      true,
+				ItemType:        "Broker",
+			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.ListOperation: &framework.PathOperation{
 					Callback: b.pathConfigBrokersList,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      listResponseFields,
+						}},
+					},
 				},
 			},
 			HelpSynopsis:    "List configured Solace brokers.",
@@ -79,6 +209,34 @@ func pathConfigBrokers(b *solaceBackend) []*framework.Path {
 	}
 }
 
+// brokerResponseFields describes a broker read.
+var brokerResponseFields = map[string]*framework.FieldSchema{
+	"semp_url":                     {Type: framework.TypeString, Description: "SEMP v1 endpoint URL."},
+	"mate_semp_url":                {Type: framework.TypeString, Description: "SEMP URL of the other node of an HA pair."},
+	"semp_dial_address":            {Type: framework.TypeString, Description: "Host and port connections to semp_url are made to instead of its own."},
+	"mate_semp_dial_address":       {Type: framework.TypeString, Description: "Host and port connections to mate_semp_url are made to instead of its own."},
+	"admin_username":               {Type: framework.TypeString, Description: "Admin username for SEMP authentication."},
+	"semp_version":                 {Type: framework.TypeString, Description: "SEMP schema version string."},
+	"tls_skip_verify":              {Type: framework.TypeBool, Description: "Whether TLS certificate verification is skipped."},
+	"connect_timeout":              {Type: framework.TypeDurationSecond, Description: "Timeout for establishing the TCP connection, in seconds."},
+	"request_timeout":              {Type: framework.TypeDurationSecond, Description: "Overall timeout for a SEMP request, in seconds."},
+	"force_http1":                  {Type: framework.TypeBool, Description: "Whether HTTP/2 is disabled."},
+	"max_idle_conns_per_host":      {Type: framework.TypeInt, Description: "Maximum idle keep-alive connections kept open to the broker."},
+	"tls_handshake_timeout":        {Type: framework.TypeDurationSecond, Description: "Timeout for the TLS handshake, in seconds."},
+	"cloud_api_url":                {Type: framework.TypeString, Description: "Solace Cloud REST API base URL."},
+	"notify_url":                   {Type: framework.TypeString, Description: "REST messaging endpoint rotation notices are published through."},
+	"notify_topic":                 {Type: framework.TypeString, Description: "Topic rotation notices are published to."},
+	"notify_username":              {Type: framework.TypeString, Description: "Client username rotation notices are published as."},
+	"password_excluded_characters": {Type: framework.TypeString, Description: "Characters the broker rejects in passwords; empty for Solace's documented exclusions."},
+	"allowed_semp_networks":        {Type: framework.TypeCommaStringSlice, Description: "CIDR ranges the broker's SEMP hosts must resolve to."},
+	"rotation_lock_ttl":            {Type: framework.TypeDurationSecond, Description: "Longest a rotation lock on the broker is held, in seconds; 0 if rotations take no lock."},
+	"circuit_state":                {Type: framework.TypeString, Description: "State of the broker's circuit breaker on this node: closed, open or half-open."},
+	"circuit_open_until":           {Type: framework.TypeTime, Description: "When an open circuit next lets a request through."},
+	"admin_last_used":              {Type: framework.TypeTime, Description: "When this node last used the admin credential."},
+	"admin_last_outcome":           {Type: framework.TypeString, Description: "Outcome of the last use of the admin credential: success, rejected or failed."},
+	"admin_last_success":           {Type: framework.TypeTime, Description: "When the broker last accepted the admin credential."},
+}
+
 func (b *solaceBackend) pathConfigBrokersExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
 	name := d.Get("name").(string)
 	broker, err := getBroker(ctx, req.Storage, name)
@@ -96,7 +254,11 @@ func (b *solaceBackend) pathConfigBrokersWrite(ctx context.Context, req *logical
 	if err != nil {
 		return nil, err
 	}
+	created := config == nil
 	if config == nil {
+		if err := validateNewName("broker", name); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
 		config = &BrokerConfig{}
 	}
 
@@ -109,25 +271,130 @@ func (b *solaceBackend) pathConfigBrokersWrite(ctx context.Context, req *logical
 	if v, ok := d.GetOk("admin_password"); ok {
 		config.AdminPassword = v.(string)
 	}
+	if v, ok := d.GetOk("mate_semp_url"); ok {
+		config.MateSEMPURL = v.(string)
+	}
+	if v, ok := d.GetOk("semp_dial_address"); ok {
+		config.SEMPDialAddress = v.(string)
+	}
+	if v, ok := d.GetOk("mate_semp_dial_address"); ok {
+		config.MateSEMPDialAddress = v.(string)
+	}
 	if v, ok := d.GetOk("semp_version"); ok {
 		config.SEMPVersion = v.(string)
 	}
 	if v, ok := d.GetOk("tls_skip_verify"); ok {
 		config.TLSSkipVerify = v.(bool)
 	}
+	if v, ok := d.GetOk("connect_timeout"); ok {
+		config.ConnectTimeout = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("request_timeout"); ok {
+		config.RequestTimeout = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("force_http1"); ok {
+		config.ForceHTTP1 = v.(bool)
+	}
+	if v, ok := d.GetOk("max_idle_conns_per_host"); ok {
+		config.MaxIdleConnsPerHost = v.(int)
+	}
+	if v, ok := d.GetOk("tls_handshake_timeout"); ok {
+		config.TLSHandshakeTimeout = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("cloud_api_url"); ok {
+		config.CloudAPIURL = v.(string)
+	}
+	if v, ok := d.GetOk("cloud_api_token"); ok {
+		config.CloudAPIToken = v.(string)
+	}
+	if v, ok := d.GetOk("notify_url"); ok {
+		config.NotifyURL = v.(string)
+	}
+	if v, ok := d.GetOk("notify_topic"); ok {
+		config.NotifyTopic = v.(string)
+	}
+	if v, ok := d.GetOk("notify_username"); ok {
+		config.NotifyUsername = v.(string)
+	}
+	if v, ok := d.GetOk("notify_password"); ok {
+		config.NotifyPassword = v.(string)
+	}
+	if v, ok := d.GetOk("notify_signing_key"); ok {
+		config.NotifySigningKey = v.(string)
+	}
+	if v, ok := d.GetOk("password_excluded_characters"); ok {
+		config.PasswordExcludedChars = v.(string)
+	}
+	if v, ok := d.GetOk("allowed_semp_networks"); ok {
+		networks, err := normalizeNetworks(v.([]string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		config.AllowedSEMPNetworks = networks
+	}
+	if v, ok := d.GetOk("rotation_lock_ttl"); ok {
+		config.RotationLockTTL = time.Duration(v.(int)) * time.Second
+	}
+	if config.CloudAPIToken != "" && config.CloudAPIURL == "" {
+		config.CloudAPIURL = defaultCloudAPIURL
+	}
 
 	if config.SEMPURL == "" {
 		return logical.ErrorResponse("semp_url is required"), nil
 	}
-	parsedURL, err := url.Parse(config.SEMPURL)
+	settings, err := getSettings(ctx, req.Storage)
 	if err != nil {
-		return logical.ErrorResponse("semp_url is not a valid URL"), nil
+		return nil, err
+	}
+	// A Unix socket never leaves the host, so it may carry plain HTTP.
+	if err := validateSEMPEndpoint("semp_url", config.SEMPURL, config.SEMPDialAddress, settings.AllowInsecureTransport); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if config.TLSSkipVerify && !settings.AllowInsecureTransport {
+		return logical.ErrorResponse("tls_skip_verify is not permitted; set allow_insecure_transport on config/settings to permit it"), nil
+	}
+	if config.MateSEMPURL == "" && config.MateSEMPDialAddress != "" {
+		return logical.ErrorResponse("mate_semp_dial_address requires mate_semp_url"), nil
 	}
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return logical.ErrorResponse("semp_url must use http or https scheme"), nil
+	if config.MateSEMPURL != "" {
+		if err := validateSEMPEndpoint("mate_semp_url", config.MateSEMPURL, config.MateSEMPDialAddress, settings.AllowInsecureTransport); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if config.MateSEMPURL == config.SEMPURL {
+			return logical.ErrorResponse("mate_semp_url must differ from semp_url"), nil
+		}
+	}
+	if config.CloudAPIURL != "" {
+		cloudURL, err := url.Parse(config.CloudAPIURL)
+		if err != nil || cloudURL.Host == "" || (cloudURL.Scheme != "https" && cloudURL.Scheme != "http") {
+			return logical.ErrorResponse("cloud_api_url must be an http or https URL with a host"), nil
+		}
+		if cloudURL.Scheme != "https" && !settings.AllowInsecureTransport {
+			return logical.ErrorResponse("cloud_api_url must use https; set allow_insecure_transport on config/settings to permit http"), nil
+		}
+	}
+	if config.NotifyTopic != "" && config.NotifyURL == "" {
+		return logical.ErrorResponse("notify_url is required with notify_topic"), nil
+	}
+	if config.NotifyURL != "" {
+		notifyURL, err := url.Parse(config.NotifyURL)
+		if err != nil || notifyURL.Host == "" || (notifyURL.Scheme != "https" && notifyURL.Scheme != "http") {
+			return logical.ErrorResponse("notify_url must be an http or https URL with a host"), nil
+		}
+		if notifyURL.Scheme != "https" && !settings.AllowInsecureTransport {
+			return logical.ErrorResponse("notify_url must use https; set allow_insecure_transport on config/settings to permit http"), nil
+		}
 	}
-	if parsedURL.Host == "" {
-		return logical.ErrorResponse("semp_url must include a host"), nil
+	if strings.HasPrefix(config.NotifyTopic, "/") || strings.HasSuffix(config.NotifyTopic, "/") || strings.Contains(config.NotifyTopic, "//") {
+		return logical.ErrorResponse("notify_topic must not have empty levels"), nil
+	}
+	if config.NotifySigningKey != "" && len(config.NotifySigningKey) < minNotifySigningKeyLength {
+		return logical.ErrorResponse("notify_signing_key must be at least %d characters", minNotifySigningKeyLength), nil
+	}
+	for _, c := range config.PasswordExcludedChars {
+		if c <= ' ' || c > '~' {
+			return logical.ErrorResponse("password_excluded_characters may only contain printable ASCII characters, got %q", c), nil
+		}
 	}
 	if config.AdminUsername == "" {
 		return logical.ErrorResponse("admin_username is required"), nil
@@ -135,10 +402,36 @@ func (b *solaceBackend) pathConfigBrokersWrite(ctx context.Context, req *logical
 	if config.AdminPassword == "" {
 		return logical.ErrorResponse("admin_password is required"), nil
 	}
+	if config.ConnectTimeout < 0 || config.RequestTimeout < 0 {
+		return logical.ErrorResponse("connect_timeout and request_timeout must not be negative"), nil
+	}
+	if config.ConnectTimeout > 0 && config.RequestTimeout > 0 && config.ConnectTimeout > config.RequestTimeout {
+		return logical.ErrorResponse("connect_timeout must not exceed request_timeout"), nil
+	}
+	if config.MaxIdleConnsPerHost < 0 {
+		return logical.ErrorResponse("max_idle_conns_per_host must not be negative"), nil
+	}
+	if config.TLSHandshakeTimeout < 0 {
+		return logical.ErrorResponse("tls_handshake_timeout must not be negative"), nil
+	}
+	if config.RotationLockTTL != 0 && config.RotationLockTTL < minRotationLockTTL {
+		return logical.ErrorResponse("rotation_lock_ttl must be 0 or at least %s", minRotationLockTTL), nil
+	}
+	if created {
+		resp, err := quotaReached("brokers", "max_brokers", settings.MaxBrokers, func() ([]string, error) {
+			return listBrokers(ctx, req.Storage)
+		})
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
 
 	if err := putBroker(ctx, req.Storage, name, config); err != nil {
 		return nil, err
 	}
+	b.invalidateClient(name)
+	b.resetBrokerState(name)
+	b.sendEvent(ctx, eventBrokerWrite, "broker", name)
 
 	return nil, nil
 }
@@ -154,40 +447,114 @@ func (b *solaceBackend) pathConfigBrokersRead(ctx context.Context, req *logical.
 		return nil, nil
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"semp_url":        config.SEMPURL,
-			"admin_username":  config.AdminUsername,
-			"semp_version":    config.SEMPVersion,
-			"tls_skip_verify": config.TLSSkipVerify,
-		},
-	}, nil
+	circuitState, openUntil := b.brokerBreaker(name).state()
+	data := brokerConfigFields(config)
+	data["circuit_state"] = circuitState
+	if !openUntil.IsZero() {
+		data["circuit_open_until"] = openUntil.Format(time.RFC3339)
+	}
+
+	resp := &logical.Response{Data: data}
+	if used, outcome, success := b.brokerAdminUsage(name).last(); !used.IsZero() {
+		data["admin_last_used"] = used.Format(time.RFC3339)
+		data["admin_last_outcome"] = outcome
+		if !success.IsZero() {
+			data["admin_last_success"] = success.Format(time.RFC3339)
+		}
+		if outcome == adminOutcomeRejected {
+			resp.AddWarning(fmt.Sprintf("the broker rejected the admin credential at %s; it may have been changed outside Vault", used.Format(time.RFC3339)))
+		}
+	}
+
+	return resp, nil
+}
+
+func (b *solaceBackend) pathConfigBrokersPatch(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	config, err := getBroker(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("broker %q not found", name), nil
+	}
+
+	resource := brokerConfigFields(config)
+	resource["admin_password"] = config.AdminPassword
+	resource["cloud_api_token"] = config.CloudAPIToken
+	resource["notify_password"] = config.NotifyPassword
+	resource["notify_signing_key"] = config.NotifySigningKey
+	patched, err := patchFieldData(d, resource)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return b.pathConfigBrokersWrite(ctx, req, patched)
+}
+
+// brokerConfigFields returns a broker config in the shape of the path's
+// fields, leaving out the admin password, Cloud API token, and notify
+// password and signing key.
+func brokerConfigFields(config *BrokerConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"semp_url":      config.SEMPURL,
+		"mate_semp_url": config.MateSEMPURL,
+
+		"semp_dial_address":      config.SEMPDialAddress,
+		"mate_semp_dial_address": config.MateSEMPDialAddress,
+
+		"admin_username":  config.AdminUsername,
+		"semp_version":    config.SEMPVersion,
+		"tls_skip_verify": config.TLSSkipVerify,
+		"connect_timeout": int(config.ConnectTimeout.Seconds()),
+		"request_timeout": int(config.RequestTimeout.Seconds()),
+
+		"force_http1":             config.ForceHTTP1,
+		"max_idle_conns_per_host": config.MaxIdleConnsPerHost,
+		"tls_handshake_timeout":   int(config.TLSHandshakeTimeout.Seconds()),
+
+		"cloud_api_url": config.CloudAPIURL,
+
+		"notify_url":      config.NotifyURL,
+		"notify_topic":    config.NotifyTopic,
+		"notify_username": config.NotifyUsername,
+
+		"password_excluded_characters": config.PasswordExcludedChars,
+
+		"allowed_semp_networks": append([]string{}, config.AllowedSEMPNetworks...),
+
+		"rotation_lock_ttl": int(config.RotationLockTTL.Seconds()),
+	}
 }
 
 func (b *solaceBackend) pathConfigBrokersDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 
-	roles, err := listRoles(ctx, req.Storage)
+	dependents, err := listBrokerRoles(ctx, req.Storage, name)
 	if err != nil {
 		return nil, fmt.Errorf("checking dependent roles: %w", err)
 	}
-	var dependents []string
-	for _, roleName := range roles {
-		role, err := getRole(ctx, req.Storage, roleName)
-		if err != nil {
-			return nil, fmt.Errorf("reading role %q: %w", roleName, err)
-		}
-		if role != nil && role.Broker == name {
-			dependents = append(dependents, roleName)
-		}
-	}
 	if len(dependents) > 0 {
 		return logical.ErrorResponse("cannot delete broker %q: referenced by roles: %s", name, strings.Join(dependents, ", ")), nil
 	}
+	groups, err := brokerGroups(ctx, req.Storage, name)
+	if err != nil {
+		return nil, fmt.Errorf("checking broker groups: %w", err)
+	}
+	if len(groups) > 0 {
+		return logical.ErrorResponse("cannot delete broker %q: member of broker groups: %s", name, strings.Join(groups, ", ")), nil
+	}
 
 	if err := deleteBroker(ctx, req.Storage, name); err != nil {
 		return nil, err
 	}
+	if err := deleteBrokerActivity(ctx, req.Storage, name); err != nil {
+		return nil, err
+	}
+	b.invalidateClient(name)
+	b.resetBrokerState(name)
+	b.sendEvent(ctx, eventBrokerDelete, "broker", name)
 
 	return nil, nil
 }
@@ -0,0 +1,103 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathConfigBrokerPublic(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "config/brokers/" + framework.GenericNameRegex("name") + "/public$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "broker-public",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the broker configuration.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathConfigBrokerPublicRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      brokerPublicResponseFields,
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Read the non-sensitive attributes of a broker config.",
+			HelpDescription: "Returns where a broker is and how it is wired, without its admin username, credentials or connection tuning, so application teams can be allowed to discover brokers without reading their full config.",
+		},
+	}
+}
+
+// brokerPublicResponseFields describes a public broker read.
+var brokerPublicResponseFields = map[string]*framework.FieldSchema{
+	"semp_host":      {Type: framework.TypeString, Description: "Host and port of the broker's SEMP endpoint."},
+	"mate_semp_host": {Type: framework.TypeString, Description: "Host and port of the SEMP endpoint of the other node of an HA pair."},
+	"semp_version":   {Type: framework.TypeString, Description: "SEMP schema version string."},
+	"notify_url":     {Type: framework.TypeString, Description: "REST messaging endpoint rotation notices are published through."},
+	"notify_topic":   {Type: framework.TypeString, Description: "Topic rotation notices are published to."},
+	"broker_groups":  {Type: framework.TypeCommaStringSlice, Description: "Broker groups the broker belongs to."},
+}
+
+func (b *solaceBackend) pathConfigBrokerPublicRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	config, err := getBroker(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+	groups, err := brokerGroups(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if groups == nil {
+		groups = []string{}
+	}
+
+	data := map[string]interface{}{
+		"semp_host":     urlHost(config.SEMPURL),
+		"semp_version":  config.SEMPVersion,
+		"notify_url":    urlWithoutUserinfo(config.NotifyURL),
+		"notify_topic":  config.NotifyTopic,
+		"broker_groups": groups,
+	}
+	if config.MateSEMPURL != "" {
+		data["mate_semp_host"] = urlHost(config.MateSEMPURL)
+	}
+	return &logical.Response{Data: data}, nil
+}
+
+// urlHost returns the host and port of a URL, leaving out any user
+// information, path or query that could carry something sensitive.
+func urlHost(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// urlWithoutUserinfo returns a URL with any user information left out.
+func urlWithoutUserinfo(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	parsed.User = nil
+	return parsed.String()
+}
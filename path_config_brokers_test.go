@@ -2,14 +2,25 @@ package solacevaultplugin
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// getTestBackend returns a backend that allows insecure transport, since
+// the SEMP test servers speak plain HTTP.
 func getTestBackend(t *testing.T) (logical.Backend, logical.Storage) {
 	t.Helper()
-	config := logical.TestBackendConfig()
+	b, storage := newTestBackend(t, logical.TestBackendConfig())
+	allowInsecureTransport(t, storage)
+	return b, storage
+}
+
+func newTestBackend(t *testing.T, config *logical.BackendConfig) (logical.Backend, logical.Storage) {
+	t.Helper()
 	config.StorageView = &logical.InmemStorage{}
 
 	b, err := Factory(context.Background(), config)
@@ -19,6 +30,15 @@ func getTestBackend(t *testing.T) (logical.Backend, logical.Storage) {
 	return b, config.StorageView
 }
 
+func allowInsecureTransport(t *testing.T, storage logical.Storage) {
+	t.Helper()
+	settings := defaultSettings()
+	settings.AllowInsecureTransport = true
+	if err := putSettings(context.Background(), storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+}
+
 func TestPathConfigBrokers_WriteReadDeleteList(t *testing.T) {
 	b, storage := getTestBackend(t)
 	ctx := context.Background()
@@ -60,6 +80,9 @@ func TestPathConfigBrokers_WriteReadDeleteList(t *testing.T) {
 	if _, exists := resp.Data["admin_password"]; exists {
 		t.Error("admin_password should not be returned on read")
 	}
+	if resp.Data["circuit_state"] != circuitClosed {
+		t.Errorf("circuit_state = %v, want %s", resp.Data["circuit_state"], circuitClosed)
+	}
 
 	// List brokers
 	req = &logical.Request{
@@ -314,3 +337,156 @@ func TestPathConfigBrokers_InvalidURLScheme(t *testing.T) {
 		})
 	}
 }
+
+func TestPathConfigBrokers_Timeouts(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":        "https://broker:8080",
+			"admin_username":  "admin",
+			"admin_password":  "secret",
+			"connect_timeout": 5,
+			"request_timeout": 60,
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("write: err=%v, resp=%v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil {
+		t.Fatalf("read: err=%v, resp=%v", err, resp)
+	}
+	if resp.Data["connect_timeout"] != 5 {
+		t.Errorf("connect_timeout = %v, want 5", resp.Data["connect_timeout"])
+	}
+	if resp.Data["request_timeout"] != 60 {
+		t.Errorf("request_timeout = %v, want 60", resp.Data["request_timeout"])
+	}
+
+	// connect_timeout larger than request_timeout is rejected
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"connect_timeout": 120,
+		},
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Error("expected error when connect_timeout exceeds request_timeout")
+	}
+}
+
+func TestPathConfigBrokers_Patch(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeBroker(t, b, storage, "test-broker")
+
+	req := &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"request_timeout": 90,
+		},
+	}
+	if resp, err := b.HandleRequest(ctx, req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("patch: err=%v, resp=%v", err, resp)
+	}
+
+	config, _ := getBroker(ctx, storage, "test-broker")
+	if config.RequestTimeout.Seconds() != 90 {
+		t.Errorf("request_timeout = %s, want 90s", config.RequestTimeout)
+	}
+	if config.AdminPassword != "secret" || config.AdminUsername != "admin" || config.SEMPURL != "https://broker:8080" {
+		t.Errorf("patch changed unrelated fields: url=%q user=%q", config.SEMPURL, config.AdminUsername)
+	}
+
+	req.Data = map[string]interface{}{"semp_url": "ftp://broker"}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Error("expected error for invalid patched semp_url")
+	}
+}
+
+func TestPathConfigBrokers_AdminLastUsed(t *testing.T) {
+	var reject atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reject.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	b, storage, _ = setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+
+	readBroker := func() *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "config/brokers/test-broker",
+			Storage:   storage,
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("read broker: err=%v, resp=%v", err, resp)
+		}
+		return resp
+	}
+	rotate := func() {
+		t.Helper()
+		if _, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil {
+			t.Fatalf("rotateRole: %v", err)
+		}
+	}
+
+	if _, ok := readBroker().Data["admin_last_used"]; ok {
+		t.Error("admin_last_used reported before the credential was used")
+	}
+
+	rotate()
+	resp := readBroker()
+	if resp.Data["admin_last_outcome"] != adminOutcomeSuccess {
+		t.Errorf("admin_last_outcome = %v, want %s", resp.Data["admin_last_outcome"], adminOutcomeSuccess)
+	}
+	success := resp.Data["admin_last_success"]
+	if success == nil || success != resp.Data["admin_last_used"] {
+		t.Errorf("admin_last_success = %v, want admin_last_used %v", success, resp.Data["admin_last_used"])
+	}
+
+	reject.Store(true)
+	rotate()
+	resp = readBroker()
+	if resp.Data["admin_last_outcome"] != adminOutcomeRejected {
+		t.Errorf("admin_last_outcome = %v, want %s", resp.Data["admin_last_outcome"], adminOutcomeRejected)
+	}
+	if resp.Data["admin_last_success"] != success {
+		t.Errorf("admin_last_success = %v, want unchanged %v", resp.Data["admin_last_success"], success)
+	}
+	if len(resp.Warnings) == 0 {
+		t.Error("expected a warning for a rejected admin credential")
+	}
+}
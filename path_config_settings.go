@@ -0,0 +1,377 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const maxPeriodicConcurrency = 64
+
+// maxClockSkewTolerance bounds clock_skew_tolerance, which delays every
+// rotation another node's clock is trusted for.
+const maxClockSkewTolerance = time.Hour
+
+// maxStartupCooldown bounds startup_cooldown, during which no role is
+// rotated automatically.
+const maxStartupCooldown = 24 * time.Hour
+
+// maxDeletedRoleRetention bounds deleted_role_retention.
+const maxDeletedRoleRetention = 365 * 24 * time.Hour
+
+// maxPeriodicInterval keeps roles from going unchecked for longer than a
+// day.
+const maxPeriodicInterval = 24 * time.Hour
+
+func pathConfigSettings(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "config/settings$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "settings",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"periodic_concurrency": {
+					Type:        framework.TypeInt,
+					Description: "Number of roles the periodic function rotates in parallel. Default: 1.",
+				},
+				"default_password_length": {
+					Type:        framework.TypeInt,
+					Description: "Password length used for roles that do not set password_length. Default: 25.",
+				},
+				"min_rotation_interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Minimum time between manual rotations of the same role. Default: 10s.",
+				},
+				"rotation_jitter": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Maximum delay added to each role's automatic rotation so roles created together do not all rotate at once. Default: 0.",
+				},
+				"require_character_classes": {
+					Type:        framework.TypeBool,
+					Description: "Make generated passwords contain at least one lowercase letter, uppercase letter, digit and symbol. Default: true.",
+				},
+				"password_charset": {
+					Type:        framework.TypeString,
+					Description: "Characters generated passwords are drawn from. Must be printable ASCII without repeats or characters Solace rejects. Empty restores the built-in charset.",
+				},
+				"allow_insecure_transport": {
+					Type:        framework.TypeBool,
+					Description: "Allow broker configs with an http semp_url or tls_skip_verify set. Default: false.",
+				},
+				"periodic_time_budget": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How long a periodic pass may spend starting rotations before leaving the rest for the next pass. 0 disables the limit. Default: 50s.",
+				},
+				"periodic_interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How often the periodic function checks roles for rotation, from Vault's own periodic calls, about once a minute. 0 checks on every call. Default: 0.",
+				},
+				"clock_skew_tolerance": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How long past its due time a role last rotated by another node, or before this node started, must be before it is rotated, to absorb clock differences between nodes. Default: 2m.",
+				},
+				"startup_cooldown": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How long after the mount is set up, for example after an unseal, reload or leader change, or after a DR or performance secondary is promoted, before the periodic function starts rotating. Manual rotations are not held back. Default: 0.",
+				},
+				"deleted_role_retention": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How long the last credential of a role deleted with purge_history=false is kept under retained/. Default: 30 days.",
+				},
+				"verify_rotation": {
+					Type:        framework.TypeBool,
+					Description: "After each rotation, log in to the broker as the CLI user to confirm the new password is accepted and the previous one is not. Default: false.",
+				},
+				"allow_supplied_passwords": {
+					Type:        framework.TypeBool,
+					Description: "Allow rotate-role to set a password passed in its password parameter instead of generating one, for migrations where the value must match another system. Default: false.",
+				},
+				"max_roles": {
+					Type:        framework.TypeInt,
+					Description: "Most roles the mount may hold. Creating more is refused; existing roles are kept if it is lowered. 0 means no limit. Default: 0.",
+				},
+				"drift_check_interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How often the periodic function checks that every CLI user role's user still exists and accepts the stored password, flagging roles that fail under status/drift. 0 turns the check off. Default: 0.",
+				},
+				"max_brokers": {
+					Type:        framework.TypeInt,
+					Description: "Most broker configs the mount may hold. Creating more is refused; existing brokers are kept if it is lowered. 0 means no limit. Default: 0.",
+				},
+				"blackout_dates": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Dates, like 2026-12-24, in blackout_timezone on which automatic rotations are held back. Manual rotations are not. Default: none.",
+				},
+				"blackout_weekdays": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Days of the week, like saturday, in blackout_timezone on which automatic rotations are held back. Default: none.",
+				},
+				"blackout_timezone": {
+					Type:        framework.TypeString,
+					Description: "IANA time zone, like Europe/Berlin, of blackout_dates, blackout_weekdays and calendar times without a zone. Default: UTC.",
+				},
+				"blackout_ical_url": {
+					Type:        framework.TypeString,
+					Description: "URL of an iCalendar feed whose events hold back automatic rotations. It is fetched when set, or when the settings are written with it again; later changes to the feed are not seen until then. Empty clears it.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathConfigSettingsRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      settingsResponseFields,
+						}},
+					},
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathConfigSettingsWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "configure",
+					},
+					Responses: noContentResponses,
+				},
+			},
+			HelpSynopsis:    "Configure mount-wide settings.",
+			HelpDescription: "Tune behavior shared by all brokers and roles on this mount, such as periodic rotation concurrency and the default password length.",
+		},
+	}
+}
+
+// settingsResponseFields describes a settings read.
+var settingsResponseFields = map[string]*framework.FieldSchema{
+	"periodic_concurrency":      {Type: framework.TypeInt, Description: "Number of roles the periodic function rotates in parallel."},
+	"default_password_length":   {Type: framework.TypeInt, Description: "Password length used for roles that do not set password_length."},
+	"min_rotation_interval":     {Type: framework.TypeDurationSecond, Description: "Minimum time between manual rotations of the same role, in seconds."},
+	"rotation_jitter":           {Type: framework.TypeDurationSecond, Description: "Maximum delay added to each role's automatic rotation, in seconds."},
+	"require_character_classes": {Type: framework.TypeBool, Description: "Whether generated passwords contain every character class."},
+	"password_charset":          {Type: framework.TypeString, Description: "Characters generated passwords are drawn from; empty for the built-in charset."},
+	"allow_insecure_transport":  {Type: framework.TypeBool, Description: "Whether broker configs may use http or skip TLS verification."},
+	"periodic_time_budget":      {Type: framework.TypeDurationSecond, Description: "How long a periodic pass may spend starting rotations, in seconds."},
+	"periodic_interval":         {Type: framework.TypeDurationSecond, Description: "How often the periodic function checks roles for rotation, in seconds; 0 for every call."},
+	"clock_skew_tolerance":      {Type: framework.TypeDurationSecond, Description: "How long past due a role rotated elsewhere must be before it is rotated, in seconds."},
+	"startup_cooldown":          {Type: framework.TypeDurationSecond, Description: "How long after startup or promotion periodic rotation waits, in seconds."},
+	"deleted_role_retention":    {Type: framework.TypeDurationSecond, Description: "How long the credential of a role deleted without purging is kept, in seconds."},
+	"verify_rotation":           {Type: framework.TypeBool, Description: "Whether each rotation is verified by logging in as the CLI user."},
+	"allow_supplied_passwords":  {Type: framework.TypeBool, Description: "Whether rotate-role accepts a caller-supplied password."},
+	"max_roles":                 {Type: framework.TypeInt, Description: "Most roles the mount may hold; 0 for no limit."},
+	"max_brokers":               {Type: framework.TypeInt, Description: "Most broker configs the mount may hold; 0 for no limit."},
+	"drift_check_interval":      {Type: framework.TypeDurationSecond, Description: "How often roles are checked for drift from their brokers, in seconds; 0 when off."},
+	"blackout_dates":            {Type: framework.TypeCommaStringSlice, Description: "Dates on which automatic rotations are held back."},
+	"blackout_weekdays":         {Type: framework.TypeCommaStringSlice, Description: "Days of the week on which automatic rotations are held back."},
+	"blackout_timezone":         {Type: framework.TypeString, Description: "Time zone of the blackout dates and weekdays."},
+	"blackout_ical_url":         {Type: framework.TypeString, Description: "iCalendar feed whose events hold back automatic rotations."},
+	"blackout_ical_events":      {Type: framework.TypeInt, Description: "Events from the feed that had not ended when it was fetched."},
+	"blackout_ical_fetched":     {Type: framework.TypeTime, Description: "When the feed was last fetched."},
+	"in_blackout":               {Type: framework.TypeBool, Description: "Whether automatic rotations are held back now."},
+}
+
+func (b *solaceBackend) pathConfigSettingsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	settings, err := getSettings(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"periodic_concurrency":      settings.PeriodicConcurrency,
+		"default_password_length":   settings.DefaultPasswordLength,
+		"min_rotation_interval":     int(settings.MinRotationInterval.Seconds()),
+		"rotation_jitter":           int(settings.RotationJitter.Seconds()),
+		"require_character_classes": settings.RequireCharacterClasses,
+		"password_charset":          settings.PasswordCharset,
+		"allow_insecure_transport":  settings.AllowInsecureTransport,
+		"periodic_time_budget":      int(settings.PeriodicTimeBudget.Seconds()),
+		"periodic_interval":         int(settings.PeriodicInterval.Seconds()),
+		"clock_skew_tolerance":      int(settings.ClockSkewTolerance.Seconds()),
+		"startup_cooldown":          int(settings.StartupCooldown.Seconds()),
+		"deleted_role_retention":    int(settings.DeletedRoleRetention.Seconds()),
+		"verify_rotation":           settings.VerifyRotation,
+		"allow_supplied_passwords":  settings.AllowSuppliedPasswords,
+		"max_roles":                 settings.MaxRoles,
+		"max_brokers":               settings.MaxBrokers,
+		"drift_check_interval":      int(settings.DriftCheckInterval.Seconds()),
+		"blackout_dates":            settings.BlackoutDates,
+		"blackout_weekdays":         settings.BlackoutWeekdays,
+		"blackout_timezone":         settings.BlackoutTimezone,
+		"blackout_ical_url":         settings.BlackoutICalURL,
+		"in_blackout":               settings.inBlackout(time.Now()),
+	}
+	if settings.BlackoutICalURL != "" {
+		data["blackout_ical_events"] = len(settings.BlackoutPeriods)
+		data["blackout_ical_fetched"] = settings.BlackoutICalFetched.Format(time.RFC3339)
+	}
+	return &logical.Response{Data: data}, nil
+}
+
+func (b *solaceBackend) pathConfigSettingsWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	settings, err := getSettings(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := d.GetOk("periodic_concurrency"); ok {
+		settings.PeriodicConcurrency = v.(int)
+	}
+	if v, ok := d.GetOk("default_password_length"); ok {
+		settings.DefaultPasswordLength = v.(int)
+	}
+	if v, ok := d.GetOk("min_rotation_interval"); ok {
+		settings.MinRotationInterval = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("rotation_jitter"); ok {
+		settings.RotationJitter = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("require_character_classes"); ok {
+		settings.RequireCharacterClasses = v.(bool)
+	}
+	if v, ok := d.GetOk("password_charset"); ok {
+		settings.PasswordCharset = v.(string)
+	}
+	if v, ok := d.GetOk("allow_insecure_transport"); ok {
+		settings.AllowInsecureTransport = v.(bool)
+	}
+	if v, ok := d.GetOk("periodic_time_budget"); ok {
+		settings.PeriodicTimeBudget = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("periodic_interval"); ok {
+		settings.PeriodicInterval = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("clock_skew_tolerance"); ok {
+		settings.ClockSkewTolerance = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("startup_cooldown"); ok {
+		settings.StartupCooldown = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("deleted_role_retention"); ok {
+		settings.DeletedRoleRetention = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("verify_rotation"); ok {
+		settings.VerifyRotation = v.(bool)
+	}
+	if v, ok := d.GetOk("allow_supplied_passwords"); ok {
+		settings.AllowSuppliedPasswords = v.(bool)
+	}
+	if v, ok := d.GetOk("max_roles"); ok {
+		settings.MaxRoles = v.(int)
+	}
+	if v, ok := d.GetOk("max_brokers"); ok {
+		settings.MaxBrokers = v.(int)
+	}
+	if v, ok := d.GetOk("drift_check_interval"); ok {
+		settings.DriftCheckInterval = time.Duration(v.(int)) * time.Second
+	}
+	if v, ok := d.GetOk("blackout_dates"); ok {
+		settings.BlackoutDates = v.([]string)
+	}
+	if v, ok := d.GetOk("blackout_weekdays"); ok {
+		settings.BlackoutWeekdays = v.([]string)
+	}
+	_, timezoneSet := d.GetOk("blackout_timezone")
+	if timezoneSet {
+		settings.BlackoutTimezone = d.Get("blackout_timezone").(string)
+	}
+	_, icalSet := d.GetOk("blackout_ical_url")
+	if icalSet {
+		settings.BlackoutICalURL = d.Get("blackout_ical_url").(string)
+	}
+
+	if settings.PeriodicConcurrency < 1 || settings.PeriodicConcurrency > maxPeriodicConcurrency {
+		return logical.ErrorResponse("periodic_concurrency must be between 1 and %d, got %d", maxPeriodicConcurrency, settings.PeriodicConcurrency), nil
+	}
+	if settings.DefaultPasswordLength < minPasswordLength || settings.DefaultPasswordLength > maxPasswordLength {
+		return logical.ErrorResponse("default_password_length must be between %d and %d, got %d", minPasswordLength, maxPasswordLength, settings.DefaultPasswordLength), nil
+	}
+	if settings.PasswordCharset != "" {
+		if err := validateCharset(settings.PasswordCharset); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+	if settings.MinRotationInterval < 0 {
+		return logical.ErrorResponse("min_rotation_interval must not be negative"), nil
+	}
+	if settings.RotationJitter < 0 {
+		return logical.ErrorResponse("rotation_jitter must not be negative"), nil
+	}
+	if settings.PeriodicTimeBudget < 0 {
+		return logical.ErrorResponse("periodic_time_budget must not be negative"), nil
+	}
+	if settings.PeriodicInterval < 0 || settings.PeriodicInterval > maxPeriodicInterval {
+		return logical.ErrorResponse("periodic_interval must be between 0 and %s, got %s", maxPeriodicInterval, settings.PeriodicInterval), nil
+	}
+	if settings.ClockSkewTolerance < 0 || settings.ClockSkewTolerance > maxClockSkewTolerance {
+		return logical.ErrorResponse("clock_skew_tolerance must be between 0 and %s, got %s", maxClockSkewTolerance, settings.ClockSkewTolerance), nil
+	}
+	if settings.StartupCooldown < 0 || settings.StartupCooldown > maxStartupCooldown {
+		return logical.ErrorResponse("startup_cooldown must be between 0 and %s, got %s", maxStartupCooldown, settings.StartupCooldown), nil
+	}
+	if settings.DeletedRoleRetention <= 0 || settings.DeletedRoleRetention > maxDeletedRoleRetention {
+		return logical.ErrorResponse("deleted_role_retention must be more than 0 and at most %s, got %s", maxDeletedRoleRetention, settings.DeletedRoleRetention), nil
+	}
+
+	if settings.DriftCheckInterval < 0 {
+		return logical.ErrorResponse("drift_check_interval must not be negative"), nil
+	}
+	if settings.MaxRoles < 0 || settings.MaxBrokers < 0 {
+		return logical.ErrorResponse("max_roles and max_brokers must not be negative"), nil
+	}
+	if settings.BlackoutDates, err = normalizeBlackoutDates(settings.BlackoutDates); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if settings.BlackoutWeekdays, err = normalizeBlackoutWeekdays(settings.BlackoutWeekdays); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if settings.BlackoutTimezone != "" {
+		if _, err := time.LoadLocation(settings.BlackoutTimezone); err != nil {
+			return logical.ErrorResponse("blackout_timezone must be an IANA time zone like Europe/Berlin, got %q", settings.BlackoutTimezone), nil
+		}
+	}
+	// The feed is fetched only when the settings name it, so a later
+	// outage of the calendar server cannot stop or start a freeze.
+	// Times without a zone depend on blackout_timezone, so a change to
+	// it fetches the feed again too.
+	switch {
+	case settings.BlackoutICalURL == "":
+		settings.BlackoutPeriods = nil
+		settings.BlackoutICalFetched = time.Time{}
+	case icalSet || timezoneSet:
+		periods, err := fetchICalBlackouts(ctx, settings.BlackoutICalURL, settings.AllowInsecureTransport, settings.blackoutLocation())
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		settings.BlackoutPeriods = periods
+		settings.BlackoutICalFetched = time.Now().UTC()
+	}
+
+	if err := putSettings(ctx, req.Storage, settings); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// quotaReached returns an error response if the mount already holds limit
+// entries of a kind, so another cannot be created. A limit of 0 means no
+// limit. Creations racing each other are not serialized and can overshoot
+// the limit by the number in flight.
+func quotaReached(kind, setting string, limit int, list func() ([]string, error)) (*logical.Response, error) {
+	if limit == 0 {
+		return nil, nil
+	}
+	names, err := list()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) >= limit {
+		return logical.ErrorResponse("this mount already has %d %s, the most %s in config/settings allows; delete one or ask an operator to raise the limit",
+			len(names), kind, setting), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,252 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathConfigSettings_Defaults(t *testing.T) {
+	b, storage := newTestBackend(t, logical.TestBackendConfig())
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/settings",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("read: err=%v, resp=%v", err, resp)
+	}
+	if resp.Data["periodic_concurrency"] != 1 {
+		t.Errorf("periodic_concurrency = %v, want 1", resp.Data["periodic_concurrency"])
+	}
+	if resp.Data["default_password_length"] != defaultPasswordLength {
+		t.Errorf("default_password_length = %v, want %d", resp.Data["default_password_length"], defaultPasswordLength)
+	}
+	if resp.Data["min_rotation_interval"] != int(minRotationInterval.Seconds()) {
+		t.Errorf("min_rotation_interval = %v, want %d", resp.Data["min_rotation_interval"], int(minRotationInterval.Seconds()))
+	}
+	if resp.Data["allow_insecure_transport"] != false {
+		t.Errorf("allow_insecure_transport = %v, want false", resp.Data["allow_insecure_transport"])
+	}
+}
+
+func TestPathConfigSettings_WriteAndValidate(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/settings",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"periodic_concurrency":    4,
+			"default_password_length": 40,
+			"rotation_jitter":         300,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("write: err=%v, resp=%v", err, resp)
+	}
+
+	settings, err := getSettings(ctx, storage)
+	if err != nil {
+		t.Fatalf("getSettings: %v", err)
+	}
+	if settings.PeriodicConcurrency != 4 || settings.DefaultPasswordLength != 40 || settings.RotationJitter != 5*time.Minute {
+		t.Errorf("settings = %+v", settings)
+	}
+	if settings.MinRotationInterval != minRotationInterval {
+		t.Errorf("min_rotation_interval = %s, want unchanged %s", settings.MinRotationInterval, minRotationInterval)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"periodic_concurrency": 0},
+		{"periodic_concurrency": maxPeriodicConcurrency + 1},
+		{"default_password_length": 8},
+		{"rotation_jitter": -1},
+		{"periodic_interval": -1},
+		{"periodic_interval": int((maxPeriodicInterval + time.Second).Seconds())},
+		{"clock_skew_tolerance": -1},
+		{"clock_skew_tolerance": int((maxClockSkewTolerance + time.Second).Seconds())},
+		{"startup_cooldown": -1},
+		{"startup_cooldown": int((maxStartupCooldown + time.Second).Seconds())},
+		{"deleted_role_retention": 0},
+		{"deleted_role_retention": int((maxDeletedRoleRetention + time.Second).Seconds())},
+		{"password_charset": "abc:def"},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/settings",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp == nil || !resp.IsError() {
+			t.Errorf("expected error for %v", data)
+		}
+	}
+}
+
+func TestPathConfigSettings_DefaultPasswordLengthApplied(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeBroker(t, b, storage, "test-broker")
+	settings := defaultSettings()
+	settings.DefaultPasswordLength = 48
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":       "test-broker",
+			"cli_username": "monitor",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("write role: err=%v, resp=%v", err, resp)
+	}
+
+	role, err := getRole(ctx, storage, "test-role")
+	if err != nil || role == nil {
+		t.Fatalf("getRole: err=%v, role=%v", err, role)
+	}
+	if role.PasswordLength != 48 {
+		t.Errorf("password_length = %d, want 48", role.PasswordLength)
+	}
+}
+
+func TestPathConfigSettings_InsecureTransportRejectedByDefault(t *testing.T) {
+	b, storage := newTestBackend(t, logical.TestBackendConfig())
+	ctx := context.Background()
+
+	for name, data := range map[string]map[string]interface{}{
+		"http": {
+			"semp_url": "http://broker:8080",
+		},
+		"tls_skip_verify": {
+			"semp_url":        "https://broker:8080",
+			"tls_skip_verify": true,
+		},
+	} {
+		data["admin_username"] = "admin"
+		data["admin_password"] = "secret"
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/brokers/insecure",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if resp == nil || !resp.IsError() {
+			t.Errorf("%s: expected error without allow_insecure_transport", name)
+		}
+	}
+
+	writeBroker(t, b, storage, "secure")
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/settings",
+		Storage:   storage,
+		Data:      map[string]interface{}{"allow_insecure_transport": true},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("write settings: err=%v, resp=%v", err, resp)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/insecure",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":       "http://broker:8080",
+			"admin_username": "admin",
+			"admin_password": "secret",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Errorf("http broker with allow_insecure_transport: err=%v, resp=%v", err, resp)
+	}
+}
+
+func TestPathConfigSettings_Quotas(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	write := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		return resp
+	}
+
+	if resp := write("config/settings", map[string]interface{}{"max_roles": -1}); resp == nil || !resp.IsError() {
+		t.Errorf("expected error for a negative max_roles, got %v", resp)
+	}
+	if resp := write("config/settings", map[string]interface{}{"max_roles": 1, "max_brokers": 1}); resp != nil && resp.IsError() {
+		t.Fatalf("set quotas: %v", resp.Error())
+	}
+
+	writeBroker(t, b, storage, "east")
+	if resp := write("config/brokers/west", map[string]interface{}{
+		"semp_url":       "https://west:8080",
+		"admin_username": "admin",
+		"admin_password": "secret",
+	}); resp == nil || !resp.IsError() {
+		t.Errorf("expected the second broker to be refused, got %v", resp)
+	}
+	// Updating an existing broker is not a creation.
+	if resp := write("config/brokers/east", map[string]interface{}{"connect_timeout": 5}); resp != nil && resp.IsError() {
+		t.Errorf("update east: %v", resp.Error())
+	}
+
+	if resp := write("roles/first", map[string]interface{}{"broker": "east", "cli_username": "a"}); resp != nil && resp.IsError() {
+		t.Fatalf("first role: %v", resp.Error())
+	}
+	resp := write("roles/second", map[string]interface{}{"broker": "east", "cli_username": "b"})
+	if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "max_roles") {
+		t.Errorf("expected the second role to be refused naming max_roles, got %v", resp)
+	}
+	if resp := write("roles/first", map[string]interface{}{"broker": "east", "cli_username": "c"}); resp != nil && resp.IsError() {
+		t.Errorf("update first: %v", resp.Error())
+	}
+
+	// Raising the limit lets creation continue.
+	write("config/settings", map[string]interface{}{"max_roles": 0})
+	if resp := write("roles/second", map[string]interface{}{"broker": "east", "cli_username": "b"}); resp != nil && resp.IsError() {
+		t.Errorf("second role without a limit: %v", resp.Error())
+	}
+}
+
+func TestRotationJitter(t *testing.T) {
+	if got := rotationJitter("role", 0); got != 0 {
+		t.Errorf("rotationJitter with no max = %s, want 0", got)
+	}
+	max := time.Hour
+	a := rotationJitter("role-a", max)
+	if a < 0 || a >= max {
+		t.Errorf("rotationJitter = %s, want within [0, %s)", a, max)
+	}
+	if a != rotationJitter("role-a", max) {
+		t.Error("rotationJitter is not stable for the same role")
+	}
+}
@@ -2,6 +2,10 @@ package solacevaultplugin
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
@@ -12,16 +16,30 @@ func pathCreds(b *solaceBackend) []*framework.Path {
 	return []*framework.Path{
 		{
 			Pattern: "creds/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "credentials",
+			},
 			Fields: map[string]*framework.FieldSchema{
 				"name": {
 					Type:        framework.TypeString,
 					Description: "Name of the role.",
 					Required:    true,
 				},
+				"min_version": {
+					Type:        framework.TypeInt,
+					Description: "Lowest credential version to return, such as the version a rotate-role response reported. A node that has not yet seen it forwards the read to the active node or fails it with 412, rather than returning an older credential.",
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.ReadOperation: &framework.PathOperation{
 					Callback: b.pathCredsRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      credsResponseFields,
+						}},
+					},
 				},
 			},
 			HelpSynopsis:    "Read current credentials for a Solace CLI user.",
@@ -30,11 +48,80 @@ func pathCreds(b *solaceBackend) []*framework.Path {
 	}
 }
 
+// credsResponseFields describes a credential read. Only the fields of the
+// role's target are returned.
+var credsResponseFields = map[string]*framework.FieldSchema{
+	"broker":                   {Type: framework.TypeString, Description: "Name of the broker the credential is for."},
+	"broker_group":             {Type: framework.TypeString, Description: "Name of the broker group the credential is for, for roles on a group."},
+	"cli_username":             {Type: framework.TypeString, Description: "CLI username on the broker."},
+	"password":                 {Type: framework.TypeString, Description: "Current password.", DisplayAttrs: &framework.DisplayAttributes{Sensitive: true}},
+	"rest_consumer":            {Type: framework.TypeString, Description: "REST consumer the credential is for."},
+	"rest_consumer_username":   {Type: framework.TypeString, Description: "HTTP basic username of the REST consumer."},
+	"certificate":              {Type: framework.TypeString, Description: "PEM certificate the REST consumer presents."},
+	"certificate_expiry":       {Type: framework.TypeTime, Description: "When the certificate expires."},
+	"cloud_token_id":           {Type: framework.TypeString, Description: "ID of the Solace Cloud API token."},
+	"token":                    {Type: framework.TypeString, Description: "Current Solace Cloud API token.", DisplayAttrs: &framework.DisplayAttributes{Sensitive: true}},
+	"oauth_profile":            {Type: framework.TypeString, Description: "OAuth profile the client secret is for."},
+	"semp_rpc_username":        {Type: framework.TypeString, Description: "Username the role's SEMP request template sets the password for."},
+	"client_secret":            {Type: framework.TypeString, Description: "Current OAuth client secret.", DisplayAttrs: &framework.DisplayAttributes{Sensitive: true}},
+	"password_encoding":        {Type: framework.TypeString, Description: "Encoding of the password, token or client secret, for roles that set one other than plain."},
+	"last_rotated":             {Type: framework.TypeTime, Description: "When the credential was last rotated."},
+	"credential_fingerprint":   {Type: framework.TypeString, Description: "First 8 hex characters of the SHA-256 of the password, token or client secret."},
+	"version":                  {Type: framework.TypeInt, Description: "Version of the credential, which changes only when the role is rotated."},
+	"new_credential_served_at": {Type: framework.TypeTime, Description: "While a role's propagation_delay runs after a rotation, when the new credential replaces the one returned."},
+	"audit_tags":               {Type: framework.TypeMap, Description: "The role's audit tags, for roles that set any."},
+}
+
+// fingerprintLength is how many hex characters of a credential's SHA-256
+// reads report: enough to tell credentials apart, too few to help guess one.
+const fingerprintLength = 8
+
+// credentialFingerprint returns the fingerprint of a secret's password,
+// token or client secret, or "" for a secret without one, such as a client
+// certificate.
+func credentialFingerprint(secret *RoleSecret) string {
+	if secret == nil || secret.Password == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(secret.Password))
+	return hex.EncodeToString(sum[:])[:fingerprintLength]
+}
+
+// staleCredentialError is returned for a credential read that asks for a
+// newer version than this node serves: one a performance standby or
+// secondary has not caught up with yet, or one stored but held back until
+// servedFrom by the role's propagation delay. Vault answers it with 412 so
+// the client retries. It is a coded error because only those keep their
+// status across the plugin's gRPC boundary; it also matches
+// logical.ErrMissingRequiredState for callers in process.
+type staleCredentialError struct {
+	name       string
+	minVersion int
+	servedFrom time.Time
+}
+
+func (e *staleCredentialError) Error() string {
+	if !e.servedFrom.IsZero() {
+		return fmt.Sprintf("credential version %d of role %q is not served until %s, when its propagation_delay ends: %s",
+			e.minVersion, e.name, e.servedFrom.Format(time.RFC3339), logical.ErrMissingRequiredState)
+	}
+	return fmt.Sprintf("credential version %d of role %q is not yet stored on this node: %s", e.minVersion, e.name, logical.ErrMissingRequiredState)
+}
+
+func (e *staleCredentialError) Code() int {
+	return http.StatusPreconditionFailed
+}
+
+func (e *staleCredentialError) Unwrap() error {
+	return logical.ErrMissingRequiredState
+}
+
 func (b *solaceBackend) pathCredsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 
-	b.roleMutex.RLock()
-	defer b.roleMutex.RUnlock()
+	lock := b.roleLock(name)
+	lock.RLock()
+	defer lock.RUnlock()
 
 	role, err := getRole(ctx, req.Storage, name)
 	if err != nil {
@@ -44,18 +131,100 @@ func (b *solaceBackend) pathCredsRead(ctx context.Context, req *logical.Request,
 		return logical.ErrorResponse("role %q not found", name), nil
 	}
 
-	if role.Password == "" {
+	secret, err := getRoleSecret(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	// While a role's propagation delay runs, the credential the rotation
+	// replaced is served, so consumers do not pick up a password some of
+	// the broker's nodes have not taken yet. min_version is held to the
+	// credential served, so a read that must see a rotation waits for the
+	// delay as well.
+	stored := secret
+	secret = stored.served(time.Now())
+	if minVersion := d.Get("min_version").(int); minVersion > 0 && secret.version() < minVersion {
+		stale := &staleCredentialError{name: name, minVersion: minVersion}
+		if stored.version() >= minVersion {
+			stale.servedFrom = stored.ServedFrom
+		}
+		return nil, stale
+	}
+	if secret.empty() {
 		return logical.ErrorResponse("password for role %q has not been rotated yet; run rotate-role/%s first", name, name), nil
 	}
 
 	data := map[string]interface{}{
-		"cli_username": role.CLIUsername,
-		"password":     role.Password,
-		"broker":       role.Broker,
+		"broker": role.Broker,
+	}
+	if role.BrokerGroup != "" {
+		data = map[string]interface{}{
+			"broker_group": role.BrokerGroup,
+		}
+	}
+	switch {
+	case role.usesClientCertificate():
+		// The private key stays on the broker; the REST consumer's server
+		// only needs the certificate to trust it.
+		data["rest_consumer"] = role.RESTConsumer
+		data["certificate"] = secret.Certificate
+		if expiry, err := certificateExpiry(secret.Certificate); err == nil {
+			data["certificate_expiry"] = expiry.Format(time.RFC3339)
+		}
+	case role.isCloudToken():
+		data["cloud_token_id"] = role.CloudTokenID
+		data["token"] = encodePassword(secret.Password, role.PasswordEncoding)
+	case role.isOAuthProfile():
+		data["oauth_profile"] = role.OAuthProfile
+		data["client_secret"] = encodePassword(secret.Password, role.PasswordEncoding)
+	case role.isSEMPRPC():
+		if role.SEMPRPCUsername != "" {
+			data["semp_rpc_username"] = role.SEMPRPCUsername
+		}
+		data["password"] = encodePassword(secret.Password, role.PasswordEncoding)
+	case role.isRESTConsumer():
+		data["rest_consumer"] = role.RESTConsumer
+		data["rest_consumer_username"] = role.RESTUsername
+		data["password"] = encodePassword(secret.Password, role.PasswordEncoding)
+	default:
+		data["cli_username"] = role.CLIUsername
+		data["password"] = encodePassword(secret.Password, role.PasswordEncoding)
+	}
+	if role.PasswordEncoding != "" {
+		data["password_encoding"] = role.PasswordEncoding
 	}
 	if !role.LastRotated.IsZero() {
 		data["last_rotated"] = role.LastRotated.Format(time.RFC3339)
 	}
+	if fingerprint := credentialFingerprint(secret); fingerprint != "" {
+		data["credential_fingerprint"] = fingerprint
+	}
+	// Unlike the warnings, the version only changes with the credential, so
+	// templates keyed on it re-render once per rotation.
+	data["version"] = secret.Version
+	if secret != stored {
+		data["new_credential_served_at"] = stored.ServedFrom.Format(time.RFC3339)
+	}
+
+	resp := &logical.Response{Data: data}
+	addAuditTags(resp, role)
+	if overdueBy := roleOverdue(role, time.Now()); overdueBy > 0 {
+		resp.AddWarning(fmt.Sprintf("this credential was last rotated at %s, longer ago than the role's rotation_period of %s; rotation is overdue by %s, so check status/overdue and the server log",
+			role.LastRotated.Format(time.RFC3339), role.RotationPeriod, overdueBy.Truncate(time.Second)))
+	}
+	suspect, err := getRestoreSuspect(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if suspect != nil {
+		resp.AddWarning(fmt.Sprintf("Vault was restored from a snapshot taken before this role's last rotation, so this credential may not match the broker; rotate-role/%s to reconcile", name))
+	}
+	recovery, err := getRecovery(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if recovery != nil {
+		resp.AddWarning(fmt.Sprintf("the last rotation of role %q left a credential under %s%s that may be the one the broker holds; sync/%s or rotate-role/%s to reconcile", name, recoveryPrefix, name, name, name))
+	}
 
-	return &logical.Response{Data: data}, nil
+	return resp, nil
 }
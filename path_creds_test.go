@@ -2,11 +2,18 @@ package solacevaultplugin
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/plugin/pb"
 )
 
 func TestPathCreds_ReadAfterRotation(t *testing.T) {
@@ -73,6 +80,251 @@ func TestPathCreds_ReadAfterRotation(t *testing.T) {
 	if resp.Data["last_rotated"] == nil {
 		t.Error("last_rotated should be set")
 	}
+	password := resp.Data["password"]
+
+	// Updating the role's configuration must leave the live credential alone
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":          "test-broker",
+			"cli_username":    "monitor",
+			"rotation_period": 3600,
+		},
+	}
+	if resp, err := b.HandleRequest(ctx, req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("update role: err=%v, resp=%v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test-role",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil {
+		t.Fatalf("read creds after update: err=%v, resp=%v", err, resp)
+	}
+	if resp.Data["password"] != password {
+		t.Error("password changed after a role configuration update")
+	}
+}
+
+func TestPathCreds_Fingerprint(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	read := func(path string) map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+			Storage:   storage,
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("read %s: err=%v, resp=%v", path, err, resp)
+		}
+		return resp.Data
+	}
+
+	if _, ok := read("roles/test-role")["credential_fingerprint"]; ok {
+		t.Error("a role without a credential has a fingerprint")
+	}
+	if _, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil {
+		t.Fatal(err)
+	}
+
+	creds := read("creds/test-role")
+	sum := sha256.Sum256([]byte(creds["password"].(string)))
+	want := hex.EncodeToString(sum[:4])
+	if creds["credential_fingerprint"] != want {
+		t.Errorf("creds fingerprint = %v, want %s", creds["credential_fingerprint"], want)
+	}
+	role := read("roles/test-role")
+	if role["credential_fingerprint"] != want {
+		t.Errorf("role fingerprint = %v, want %s", role["credential_fingerprint"], want)
+	}
+	if _, ok := role["password"]; ok {
+		t.Error("role read exposes the password")
+	}
+}
+
+func TestPathCreds_Version(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	if _, err := sb.rotateRole(ctx, storage, "test-role"); err != nil {
+		t.Fatal(err)
+	}
+	// Reads between rotations see the same version.
+	for i := 0; i < 2; i++ {
+		if v := readCreds(t, b, storage, "test-role")["version"]; v != 1 {
+			t.Errorf("version = %v after the first rotation, want 1", v)
+		}
+	}
+	if _, err := sb.rotateRole(ctx, storage, "test-role"); err != nil {
+		t.Fatal(err)
+	}
+	if v := readCreds(t, b, storage, "test-role")["version"]; v != 2 {
+		t.Errorf("version = %v after the second rotation, want 2", v)
+	}
+
+	// A secret stored before versions were kept reads as version 0.
+	if err := putRoleSecret(ctx, storage, "test-role", &RoleSecret{Password: "legacy-Password-0123456"}); err != nil {
+		t.Fatal(err)
+	}
+	if v := readCreds(t, b, storage, "test-role")["version"]; v != 0 {
+		t.Errorf("version = %v for a secret without one, want 0", v)
+	}
+}
+
+func TestPathCreds_MinVersion(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-role/test-role",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("rotate: err=%v, resp=%v", err, resp)
+	}
+	if v := resp.Data["version"]; v != 1 {
+		t.Fatalf("rotate-role version = %v, want 1", v)
+	}
+
+	read := func(b logical.Backend, minVersion int) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/test-role",
+			Storage:   storage,
+			Data:      map[string]interface{}{"min_version": minVersion},
+		})
+	}
+	if resp, err := read(b, 1); err != nil || resp == nil || resp.IsError() {
+		t.Errorf("read at the stored version: err=%v, resp=%v", err, resp)
+	}
+	_, err = read(b, 2)
+	if !errors.Is(err, logical.ErrMissingRequiredState) {
+		t.Errorf("read ahead of storage: err = %v, want ErrMissingRequiredState", err)
+	}
+
+	// The error reaches Vault as a 412 even after crossing the plugin's
+	// gRPC boundary, which keeps only coded errors' status.
+	crossed := pb.ProtoErrToErr(pb.ErrToProtoErr(err))
+	status, crossed := logical.RespondErrorCommon(&logical.Request{Operation: logical.ReadOperation}, nil, crossed)
+	w := httptest.NewRecorder()
+	logical.RespondError(w, status, crossed)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("status after the plugin boundary = %d, want %d", w.Code, http.StatusPreconditionFailed)
+	}
+	if !strings.Contains(w.Body.String(), "credential version 2") {
+		t.Errorf("error after the plugin boundary lost its message: %s", w.Body.String())
+	}
+
+	// A performance standby that has not caught up answers the same way.
+	config := logical.TestBackendConfig()
+	config.System.(*logical.StaticSystemView).ReplicationStateVal = consts.ReplicationPerformanceStandby
+	standby, _ := newTestBackend(t, config)
+	if _, err := read(standby, 2); !errors.Is(err, logical.ErrMissingRequiredState) {
+		t.Errorf("read ahead of storage on a standby: err = %v, want ErrMissingRequiredState", err)
+	}
+	if resp, err := read(standby, 1); err != nil || resp == nil || resp.IsError() {
+		t.Errorf("read at the stored version on a standby: err=%v, resp=%v", err, resp)
+	}
+}
+
+func TestPathCreds_PropagationDelay(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"propagation_delay": 300},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("patch role: err=%v, resp=%v", err, resp)
+	}
+
+	// With nothing to fall back to, the first credential is served at once.
+	if _, err := sb.rotateRole(ctx, storage, "test-role"); err != nil {
+		t.Fatal(err)
+	}
+	first := readCreds(t, b, storage, "test-role")
+	if first["version"] != 1 || first["new_credential_served_at"] != nil {
+		t.Fatalf("creds after the first rotation = %v", first)
+	}
+
+	resp, err = sb.rotateRole(ctx, storage, "test-role")
+	if err != nil || resp.IsError() || resp.Data["new_credential_served_at"] == nil {
+		t.Fatalf("second rotation: err=%v, resp=%v", err, resp)
+	}
+	during := readCreds(t, b, storage, "test-role")
+	if during["version"] != 1 || during["password"] != first["password"] || during["new_credential_served_at"] == nil {
+		t.Errorf("creds during the delay = %v, want the first credential", during)
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+	if secret.Version != 2 || secret.Password == first["password"] {
+		t.Errorf("stored secret = version %d, want the new credential", secret.Version)
+	}
+
+	// A read that must see the rotation waits for the delay, and role reads
+	// fingerprint the credential creds/ serves meanwhile.
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"min_version": 2},
+	})
+	if !errors.Is(err, logical.ErrMissingRequiredState) || !strings.Contains(err.Error(), "propagation_delay") {
+		t.Errorf("min_version read during the delay: err = %v, want a propagation_delay 412", err)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.Data["credential_fingerprint"] != during["credential_fingerprint"] {
+		t.Errorf("role fingerprint during the delay = %v, want the served %v", resp.Data["credential_fingerprint"], during["credential_fingerprint"])
+	}
+
+	// Once the delay has passed, the new credential is served.
+	secret.ServedFrom = time.Now().Add(-time.Second)
+	if err := putRoleSecret(ctx, storage, "test-role", secret); err != nil {
+		t.Fatal(err)
+	}
+	after := readCreds(t, b, storage, "test-role")
+	if after["version"] != 2 || after["password"] != secret.Password || after["new_credential_served_at"] != nil {
+		t.Errorf("creds after the delay = %v, want the new credential", after)
+	}
+	if _, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"min_version": 2},
+	}); err != nil {
+		t.Errorf("min_version read after the delay: %v", err)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"rotation_period": 300},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Errorf("propagation_delay equal to rotation_period: err=%v, resp=%v, want an error response", err, resp)
+	}
 }
 
 func TestPathCreds_NoPasswordYet(t *testing.T) {
@@ -124,3 +376,44 @@ func TestPathCreds_RoleNotFound(t *testing.T) {
 		t.Error("expected error for nonexistent role")
 	}
 }
+
+func TestPathCreds_Warnings(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	if _, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil {
+		t.Fatalf("rotateRole: %v", err)
+	}
+	readCreds := func() *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/test-role",
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("read creds: err=%v, resp=%v", err, resp)
+		}
+		return resp
+	}
+	if resp := readCreds(); len(resp.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", resp.Warnings)
+	}
+
+	role, _ := getRole(ctx, storage, "test-role")
+	role.RotationPeriod = time.Hour
+	role.LastRotated = time.Now().Add(-2 * time.Hour)
+	if err := putRole(ctx, storage, "test-role", role); err != nil {
+		t.Fatalf("putRole: %v", err)
+	}
+	// The role was written behind the backend's back, as by another node.
+	b.(*solaceBackend).invalidate(ctx, roleStoragePrefix+"test-role")
+	if err := putRecovery(ctx, storage, "test-role", &RecoveryEntry{Broker: "test-broker", Password: "unstored"}); err != nil {
+		t.Fatalf("putRecovery: %v", err)
+	}
+	resp := readCreds()
+	if len(resp.Warnings) != 2 || !strings.Contains(resp.Warnings[0], "overdue") || !strings.Contains(resp.Warnings[1], recoveryPrefix) {
+		t.Errorf("unexpected warnings: %v", resp.Warnings)
+	}
+}
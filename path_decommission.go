@@ -0,0 +1,222 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathDecommission(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "decommission/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "decommission",
+				OperationSuffix: "role",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role to decommission.",
+					Required:    true,
+				},
+				"scramble": {
+					Type:        framework.TypeBool,
+					Description: "Set a random credential on the broker that is never stored, so the role's last credential stops working. Cloud tokens are regenerated and the new value discarded.",
+					Default:     false,
+				},
+				"delete_user": {
+					Type:        framework.TypeBool,
+					Description: "Delete the role's CLI user from the broker. Only for cli_user roles.",
+					Default:     false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathDecommissionWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"scrambled":    {Type: framework.TypeBool, Description: "Whether the credential was scrambled on the broker."},
+								"user_deleted": {Type: framework.TypeBool, Description: "Whether the CLI user was deleted from the broker."},
+								"brokers":      {Type: framework.TypeStringSlice, Description: "Brokers the credential was scrambled or deleted on."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Tear down a role and, optionally, its credential on the broker.",
+			HelpDescription: "Optionally scrambles the role's credential on the broker or deletes its CLI user, then removes the role with its stored secret, recovery entry and restore flag. If the broker step fails, nothing is removed.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathDecommissionWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	scramble := d.Get("scramble").(bool)
+	deleteUser := d.Get("delete_user").(bool)
+	ctx = withSEMPActivity(withSEMPRequestID(ctx, req.ID), req.Storage)
+
+	if !b.canWriteBrokers() {
+		return nil, logical.ErrReadOnly
+	}
+
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role %q not found", name), nil
+	}
+	if deleteUser && !role.isCLIUser() {
+		return logical.ErrorResponse("delete_user applies only to cli_user roles"), nil
+	}
+
+	// The broker is dealt with first, so that a failure there leaves the
+	// role and its secret in place to retry with.
+	changed := []string{}
+	if scramble || deleteUser {
+		var resp *logical.Response
+		changed, resp, err = b.decommissionOnBrokers(ctx, req.Storage, name, role, scramble, deleteUser)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	if err := deleteRole(ctx, req.Storage, name); err != nil {
+		return nil, fmt.Errorf("deleting role %q: %w", name, err)
+	}
+	b.unscheduleRole(name)
+	if err := deleteRecovery(ctx, req.Storage, name); err != nil {
+		return nil, fmt.Errorf("deleting recovery entry of role %q: %w", name, err)
+	}
+
+	b.Logger().Info("role decommissioned",
+		"role", name,
+		"broker", role.location(),
+		"scrambled", scramble,
+		"user_deleted", deleteUser,
+	)
+	b.sendEvent(ctx, eventRoleDecommission, "role", name, "broker", role.location(),
+		"scrambled", fmt.Sprint(scramble), "user_deleted", fmt.Sprint(deleteUser))
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"scrambled":    scramble,
+			"user_deleted": deleteUser,
+			"brokers":      changed,
+		},
+	}, nil
+}
+
+// decommissionOnBrokers scrambles the role's credential, or deletes its CLI
+// user, on its broker or on every member of its broker group. It returns
+// the brokers changed. A failure stops at the member that failed.
+func (b *solaceBackend) decommissionOnBrokers(ctx context.Context, s logical.Storage, name string, role *RoleEntry, scramble, deleteUser bool) ([]string, *logical.Response, error) {
+	var members []groupMember
+	if role.BrokerGroup != "" {
+		var resp *logical.Response
+		var err error
+		members, resp, err = groupMembers(ctx, s, name, role)
+		if resp != nil || err != nil {
+			return nil, resp, err
+		}
+	} else {
+		config, err := getBroker(ctx, s, role.Broker)
+		if err != nil {
+			return nil, nil, err
+		}
+		if config == nil {
+			return nil, logical.ErrorResponse("broker %q not found for role %q; decommission without scramble or delete_user to remove the role alone", role.Broker, name), nil
+		}
+		members = []groupMember{{name: role.Broker, config: config}}
+	}
+
+	settings, err := getSettings(ctx, s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed := []string{}
+	for _, member := range members {
+		memberRole := *role
+		memberRole.Broker = member.name
+		if err := b.decommissionOnBroker(ctx, name, &memberRole, member.config, settings, scramble, deleteUser); err != nil {
+			b.Logger().Error("decommissioning role on broker failed",
+				"role", name,
+				"cli_username", role.CLIUsername,
+				"broker", member.name,
+				"error", err,
+			)
+			if len(changed) > 0 {
+				return nil, logical.ErrorResponse("failed to decommission role %q on broker %q after changing it on %s; the role was kept", name, member.name, strings.Join(changed, ", ")), nil
+			}
+			if errors.Is(err, errRotationLocked) {
+				return nil, logical.ErrorResponse("decommission of role %q was not attempted: on broker %q its %s; the role was kept", name, member.name, err), nil
+			}
+			return nil, logical.ErrorResponse("failed to decommission role %q on broker %q; the role was kept", name, member.name), nil
+		}
+		changed = append(changed, member.name)
+	}
+	return changed, nil, nil
+}
+
+// decommissionOnBroker scrambles the role's credential, or deletes its CLI
+// user, on one broker. A CLI user that is already gone needs neither. As
+// with rotation, a monitor role never touches a user with more than
+// read-only access.
+func (b *solaceBackend) decommissionOnBroker(ctx context.Context, name string, role *RoleEntry, config *BrokerConfig, settings *Settings, scramble, deleteUser bool) error {
+	if role.isCloudToken() {
+		token, err := NewCloudClient(config).RegenerateToken(ctx, role.CloudTokenID)
+		wipe(token)
+		return err
+	}
+
+	client, err := b.activeSEMPClient(ctx, role.Broker, config)
+	if err != nil {
+		return err
+	}
+	unlock, err := b.acquireRotationLock(ctx, client, role, config.RotationLockTTL)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if role.isCLIUser() {
+		user, err := client.ShowUsername(ctx, role.CLIUsername)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return nil
+		}
+		if role.Monitor && !monitorAccessLevels[user.GlobalAccessLevel] {
+			return fmt.Errorf("%w: %s", errMonitorAccessLevel, user.GlobalAccessLevel)
+		}
+		if deleteUser {
+			return client.DeleteUser(ctx, role.CLIUsername)
+		}
+	}
+
+	cred, err := b.generateCredential(ctx, name, role, settings, config.excludedPasswordChars(), nil, rotationOptions{})
+	if err != nil {
+		return fmt.Errorf("generating credential: %w", err)
+	}
+	defer cred.wipe()
+	if role.isCLIUser() {
+		return client.ChangePassword(ctx, role.CLIUsername, cred.password)
+	}
+	return b.applyCredential(ctx, client, role, cred)
+}
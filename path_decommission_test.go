@@ -0,0 +1,119 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathDecommission(t *testing.T) {
+	pb := &passwordBroker{}
+	server := httptest.NewServer(pb)
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	if err := putRecovery(ctx, storage, "test-role", &RecoveryEntry{Broker: "test-broker", CLIUsername: "monitor", Password: "lost", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("putRecovery: %v", err)
+	}
+	rotated := pb.current()
+
+	decommission := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "decommission/test-role",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("decommission: %v", err)
+		}
+		return resp
+	}
+
+	// A broker failure keeps the role.
+	pb.mu.Lock()
+	pb.failChanges = true
+	pb.mu.Unlock()
+	if resp := decommission(map[string]interface{}{"scramble": true}); resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error when the broker refuses the change, got %v", resp)
+	}
+	if role, _ := getRole(ctx, storage, "test-role"); role == nil {
+		t.Fatal("role removed after a failed decommission")
+	}
+	pb.mu.Lock()
+	pb.failChanges = false
+	pb.mu.Unlock()
+
+	resp := decommission(map[string]interface{}{"scramble": true})
+	if resp == nil || resp.IsError() {
+		t.Fatalf("decommission: resp=%v", resp)
+	}
+	if resp.Data["scrambled"] != true {
+		t.Errorf("scrambled = %v, want true", resp.Data["scrambled"])
+	}
+	if pb.current() == rotated {
+		t.Error("password on the broker was not scrambled")
+	}
+	if role, _ := getRole(ctx, storage, "test-role"); role != nil {
+		t.Error("role still stored")
+	}
+	if secret, _ := getRoleSecret(ctx, storage, "test-role"); !secret.empty() {
+		t.Error("secret still stored")
+	}
+	if entry, _ := getRecovery(ctx, storage, "test-role"); entry != nil {
+		t.Error("recovery entry still stored")
+	}
+
+	if resp := decommission(nil); resp == nil || !resp.IsError() {
+		t.Errorf("expected an error for a missing role, got %v", resp)
+	}
+}
+
+func TestPathDecommission_DeleteUserNeedsCLIUser(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/rc-role",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":              "test-broker",
+			"target":              roleTargetRESTConsumer,
+			"msg_vpn":             "default",
+			"rest_delivery_point": "rdp1",
+			"rest_consumer":       "rc1",
+			"rest_consumer_auth":  "client-certificate",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create role: err=%v, resp=%v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "decommission/rc-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"delete_user": true},
+	})
+	if err != nil {
+		t.Fatalf("decommission: %v", err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for delete_user on a REST consumer role, got %v", resp)
+	}
+	if role, _ := getRole(ctx, storage, "rc-role"); role == nil {
+		t.Error("role removed after a rejected decommission")
+	}
+}
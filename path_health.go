@@ -0,0 +1,53 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathHealth(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "health$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "health",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathHealthRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"healthy":            {Type: framework.TypeBool, Description: "Always true; an unhealthy mount returns an error instead."},
+								"storage_latency_ms": {Type: framework.TypeInt64, Description: "How long reading the mount's settings from storage took, in milliseconds."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Check that the mount itself is working.",
+			HelpDescription: "Reads the mount's settings from storage and returns without contacting any broker. Fails if storage cannot be read. Needs no token, so load balancers and monitors can poll it; use status for the health of rotations and brokers.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathHealthRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	start := time.Now()
+	if _, err := getSettings(ctx, req.Storage); err != nil {
+		return nil, fmt.Errorf("reading settings: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"healthy":            true,
+			"storage_latency_ms": time.Since(start).Milliseconds(),
+		},
+	}, nil
+}
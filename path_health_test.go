@@ -0,0 +1,39 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathHealth(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	if !slices.Contains(b.SpecialPaths().Unauthenticated, "health") {
+		t.Error("health should not need a token")
+	}
+
+	// A standby cannot write storage, but is still healthy.
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "health",
+		Storage:   &readOnlyStorage{storage},
+	})
+	if err != nil || resp == nil || resp.IsError() || resp.Data["healthy"] != true {
+		t.Fatalf("health: err=%v, resp=%v", err, resp)
+	}
+
+	if err := storage.Put(ctx, &logical.StorageEntry{Key: settingsStorageKey, Value: []byte("{")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "health",
+		Storage:   storage,
+	}); err == nil {
+		t.Error("expected an error when storage cannot be read")
+	}
+}
@@ -0,0 +1,110 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// sempAPIVersions are the SEMP APIs the plugin talks to brokers with: the
+// v1 XML RPC interface for CLI users and custom requests, and the v2 config
+// API for REST consumers and OAuth profiles.
+var sempAPIVersions = []string{"v1", "v2"}
+
+// pluginCapabilities name what this build of the plugin can do, so that
+// orchestration can check for a capability rather than parse the version.
+// A capability is never renamed or removed once added.
+var pluginCapabilities = []string{
+	"allowed_semp_networks",
+	"audit_tags",
+	"broker_groups",
+	"creds_min_version",
+	"drift_check",
+	"ha_pairs",
+	"owner_approval",
+	"password_encoding",
+	"password_history",
+	"password_policies",
+	"role_specs",
+	"rotation_blackouts",
+	"rotation_locks",
+	"rotation_notices",
+	"semp_dial_address",
+	"semp_unix_socket",
+	"supplied_passwords",
+	"terminate_sessions",
+}
+
+func pathInfo(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "info$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "info",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathInfoRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"version":            {Type: framework.TypeString, Description: "Version of the plugin."},
+								"credential_types":   {Type: framework.TypeStringSlice, Description: "Role targets the plugin can rotate."},
+								"rest_consumer_auth": {Type: framework.TypeStringSlice, Description: "REST consumer authentication schemes rest_consumer roles can rotate."},
+								"password_encodings": {Type: framework.TypeStringSlice, Description: "Values a role's password_encoding accepts."},
+								"semp_api_versions":  {Type: framework.TypeStringSlice, Description: "SEMP APIs the plugin uses."},
+								"role_spec_version":  {Type: framework.TypeInt, Description: "Version of the role specs roles/export writes and roles/import reads."},
+								"capabilities":       {Type: framework.TypeStringSlice, Description: "Capabilities of this build of the plugin."},
+								"features":           {Type: framework.TypeMap, Description: "Whether each optional behavior is turned on in the mount's settings."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Report the plugin's version and capabilities.",
+			HelpDescription: "Returns the plugin's version, the credential types and SEMP APIs it supports, a list of capabilities that only grows between versions, and which optional behaviors are turned on in config/settings. Never contacts a broker.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathInfoRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	settings, err := getSettings(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("reading settings: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"version": pluginVersion,
+			"credential_types": []string{
+				roleTargetCLIUser, roleTargetRESTConsumer, roleTargetOAuthProfile, roleTargetCloudToken, roleTargetSEMPRPC,
+			},
+			"rest_consumer_auth": []string{restAuthHTTPBasic, restAuthClientCertificate},
+			"password_encodings": []string{passwordEncodingPlain, passwordEncodingBase64, passwordEncodingURL},
+			"semp_api_versions":  sempAPIVersions,
+			"role_spec_version":  roleSpecVersion,
+			"capabilities":       pluginCapabilities,
+			"features":           settingsFeatures(settings),
+		},
+	}, nil
+}
+
+// settingsFeatures reports which optional behaviors the mount's settings
+// turn on.
+func settingsFeatures(s *Settings) map[string]interface{} {
+	return map[string]interface{}{
+		"verify_rotation":           s.VerifyRotation,
+		"allow_supplied_passwords":  s.AllowSuppliedPasswords,
+		"allow_insecure_transport":  s.AllowInsecureTransport,
+		"require_character_classes": s.RequireCharacterClasses,
+		"custom_password_charset":   s.PasswordCharset != "",
+		"drift_check":               s.DriftCheckInterval > 0,
+		"rotation_blackouts":        len(s.BlackoutDates) > 0 || len(s.BlackoutWeekdays) > 0 || s.BlackoutICalURL != "",
+		"rotation_jitter":           s.RotationJitter > 0,
+	}
+}
@@ -0,0 +1,54 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathInfo(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	read := func() map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "info",
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("info: err=%v, resp=%v", err, resp)
+		}
+		return resp.Data
+	}
+
+	data := read()
+	if data["version"] != pluginVersion {
+		t.Errorf("version = %v, want %s", data["version"], pluginVersion)
+	}
+	if types := data["credential_types"].([]string); !slices.Contains(types, roleTargetCLIUser) || !slices.Contains(types, roleTargetSEMPRPC) {
+		t.Errorf("credential_types = %v", types)
+	}
+	if !slices.Contains(data["capabilities"].([]string), "role_specs") {
+		t.Errorf("capabilities = %v, want role_specs", data["capabilities"])
+	}
+	features := data["features"].(map[string]interface{})
+	if features["allow_insecure_transport"] != true || features["verify_rotation"] != false {
+		t.Errorf("features = %v", features)
+	}
+
+	settings, err := getSettings(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.VerifyRotation = true
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatal(err)
+	}
+	if features := read()["features"].(map[string]interface{}); features["verify_rotation"] != true {
+		t.Errorf("verify_rotation = %v after turning it on", features["verify_rotation"])
+	}
+}
@@ -0,0 +1,120 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRecovery(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "recovery/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "recovery-entry",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathRecoveryRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      recoveryResponseFields,
+						}},
+					},
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback:                    b.pathRecoveryDelete,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   noContentResponses,
+				},
+			},
+			HelpSynopsis:    "Recover a password that was set on the broker but not stored.",
+			HelpDescription: "When a rotation changes the password on the broker but Vault fails to store it, the new password is kept here instead of in the server log. Read it to recover access, then delete the entry.",
+		},
+		{
+			Pattern: "recovery/?$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "recovery-entries",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathRecoveryList,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      listResponseFields,
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "List roles with a recovery entry.",
+			HelpDescription: "List the roles whose last rotation changed the broker password but failed to store it.",
+		},
+	}
+}
+
+// recoveryResponseFields describes a recovery entry read.
+var recoveryResponseFields = map[string]*framework.FieldSchema{
+	"broker":       {Type: framework.TypeString, Description: "Broker, or broker group, whose credential was changed."},
+	"cli_username": {Type: framework.TypeString, Description: "CLI username whose password was changed."},
+	"password":     {Type: framework.TypeString, Description: "Password set on the broker but not stored.", DisplayAttrs: &framework.DisplayAttributes{Sensitive: true}},
+	"created_at":   {Type: framework.TypeTime, Description: "When the entry was saved."},
+	"certificate":  {Type: framework.TypeString, Description: "PEM certificate set on the broker but not stored."},
+	"private_key":  {Type: framework.TypeString, Description: "PEM private key of the certificate.", DisplayAttrs: &framework.DisplayAttributes{Sensitive: true}},
+}
+
+func (b *solaceBackend) pathRecoveryRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	entry, err := getRecovery(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"broker":       entry.Broker,
+		"cli_username": entry.CLIUsername,
+		"password":     entry.Password,
+		"created_at":   entry.CreatedAt.Format(time.RFC3339),
+	}
+	if entry.Certificate != "" {
+		data["certificate"] = entry.Certificate
+		data["private_key"] = entry.PrivateKey
+	}
+
+	return &logical.Response{Data: data}, nil
+}
+
+func (b *solaceBackend) pathRecoveryDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	if err := deleteRecovery(ctx, req.Storage, name); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *solaceBackend) pathRecoveryList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	names, err := listRecoveries(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
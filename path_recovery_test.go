@@ -0,0 +1,66 @@
+package solacevaultplugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// failingSecretStorage rejects writes of role secrets, as a storage backend
+// that fails halfway through a rotation would.
+type failingSecretStorage struct {
+	logical.Storage
+}
+
+func (s *failingSecretStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if strings.HasPrefix(entry.Key, secretStoragePrefix) {
+		return errors.New("storage unavailable")
+	}
+	return s.Storage.Put(ctx, entry)
+}
+
+func TestRotate_StorageFailureSavesRecoveryEntry(t *testing.T) {
+	var logs bytes.Buffer
+	config := logical.TestBackendConfig()
+	config.Logger = hclog.New(&hclog.LoggerOptions{Output: &logs, Level: hclog.Trace})
+	b, storage := newTestBackend(t, config)
+	allowInsecureTransport(t, storage)
+	ctx := context.Background()
+
+	_, _, server := setupRotationTestWith(t, b, storage)
+	defer server.Close()
+
+	_, err := b.(*solaceBackend).rotateRole(ctx, &failingSecretStorage{storage}, "test-role")
+	if err == nil || !strings.Contains(err.Error(), "recovery/test-role") {
+		t.Fatalf("rotateRole err = %v, want it to point at recovery/test-role", err)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "recovery/test-role",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("read recovery: err=%v, resp=%v", err, resp)
+	}
+	password, _ := resp.Data["password"].(string)
+	if len(password) != defaultPasswordLength {
+		t.Fatalf("recovered password length = %d, want %d", len(password), defaultPasswordLength)
+	}
+	if strings.Contains(logs.String(), password) {
+		t.Error("new password must not appear in the server log")
+	}
+
+	// A later successful rotation supersedes the recovery entry.
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	if entry, _ := getRecovery(ctx, storage, "test-role"); entry != nil {
+		t.Error("recovery entry should be removed after a successful rotation")
+	}
+}
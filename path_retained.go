@@ -0,0 +1,174 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// defaultDeletedRoleRetention is how long a role deleted without
+// purge_history stays under retained/.
+const defaultDeletedRoleRetention = 30 * 24 * time.Hour
+
+func pathRetained(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "retained/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "retained-role",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the deleted role.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathRetainedRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      retainedResponseFields,
+						}},
+					},
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback:                    b.pathRetainedDelete,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   noContentResponses,
+				},
+			},
+			HelpSynopsis:    "Read the last credential of a deleted role.",
+			HelpDescription: "A role deleted with purge_history=false keeps its configuration and last credential here, seal-wrapped, until the mount's deleted_role_retention has passed. Delete the entry to purge it sooner.",
+		},
+		{
+			Pattern: "retained/?$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "retained-roles",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathRetainedList,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      listResponseFields,
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "List deleted roles whose last credential is retained.",
+			HelpDescription: "List the roles deleted with purge_history=false whose retention has not yet run out.",
+		},
+	}
+}
+
+// retainedResponseFields describes a retained entry read: the role as role
+// reads report it, and its last credential.
+var retainedResponseFields = func() map[string]*framework.FieldSchema {
+	fields := map[string]*framework.FieldSchema{
+		"password":    {Type: framework.TypeString, Description: "Last password of the role.", DisplayAttrs: &framework.DisplayAttributes{Sensitive: true}},
+		"certificate": {Type: framework.TypeString, Description: "Last PEM client certificate of the role."},
+		"private_key": {Type: framework.TypeString, Description: "PEM private key of the certificate.", DisplayAttrs: &framework.DisplayAttributes{Sensitive: true}},
+		"deleted_at":  {Type: framework.TypeTime, Description: "When the role was deleted."},
+		"expires_at":  {Type: framework.TypeTime, Description: "When the entry is purged."},
+	}
+	for name, field := range roleResponseFields {
+		fields[name] = field
+	}
+	return fields
+}()
+
+func (b *solaceBackend) pathRetainedRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	entry, err := getRetained(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || !time.Now().Before(entry.ExpiresAt) {
+		return nil, nil
+	}
+
+	data := roleResponseData(&entry.Role)
+	data["deleted_at"] = entry.DeletedAt.Format(time.RFC3339)
+	data["expires_at"] = entry.ExpiresAt.Format(time.RFC3339)
+	if entry.Secret.Certificate != "" {
+		data["certificate"] = entry.Secret.Certificate
+		data["private_key"] = entry.Secret.PrivateKey
+	} else {
+		data["password"] = entry.Secret.Password
+	}
+	return &logical.Response{Data: data}, nil
+}
+
+func (b *solaceBackend) pathRetainedDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	if err := deleteRetained(ctx, req.Storage, name); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *solaceBackend) pathRetainedList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	names, err := listRetained(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+// retainDeletedRole keeps a role about to be deleted, with its last
+// credential, under retained/ for the mount's deleted_role_retention. A
+// role never rotated has no credential to keep. An earlier entry for a
+// role of the same name is replaced.
+func retainDeletedRole(ctx context.Context, s logical.Storage, name string, role *RoleEntry) error {
+	secret, err := getRoleSecret(ctx, s, name)
+	if err != nil || secret.empty() {
+		return err
+	}
+	settings, err := getSettings(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	return putRetained(ctx, s, name, &RetainedEntry{
+		Role:      *role,
+		Secret:    *secret,
+		DeletedAt: now,
+		ExpiresAt: now.Add(settings.DeletedRoleRetention),
+	})
+}
+
+// purgeExpiredRetained deletes the retained entries whose retention has run
+// out.
+func (b *solaceBackend) purgeExpiredRetained(ctx context.Context, s logical.Storage, now time.Time) error {
+	names, err := listRetained(ctx, s)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		entry, err := getRetained(ctx, s, name)
+		if err != nil {
+			return err
+		}
+		if entry == nil || now.Before(entry.ExpiresAt) {
+			continue
+		}
+		if err := deleteRetained(ctx, s, name); err != nil {
+			return err
+		}
+		b.Logger().Info("purged retained credential of deleted role", "role", name, "deleted_at", entry.DeletedAt)
+	}
+	return nil
+}
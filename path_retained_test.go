@@ -0,0 +1,84 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathRetained(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	if resp, err := sb.rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+
+	deleteRole := func(data map[string]interface{}) {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "roles/test-role",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("delete role: err=%v, resp=%v", err, resp)
+		}
+	}
+	readRetained := func() *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "retained/test-role",
+			Storage:   storage,
+		})
+		if err != nil {
+			t.Fatalf("read retained: %v", err)
+		}
+		return resp
+	}
+
+	deleteRole(map[string]interface{}{"purge_history": false})
+	if role, _ := getRole(ctx, storage, "test-role"); role != nil {
+		t.Fatal("role still stored after delete")
+	}
+	resp := readRetained()
+	if resp == nil || resp.Data["password"] != secret.Password || resp.Data["cli_username"] != "monitor" {
+		t.Fatalf("retained entry = %v", resp)
+	}
+	if names, _ := listRetained(ctx, storage); len(names) != 1 {
+		t.Errorf("retained entries = %v, want test-role", names)
+	}
+
+	// Entries past their retention are purged by the periodic function.
+	entry, _ := getRetained(ctx, storage, "test-role")
+	entry.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := putRetained(ctx, storage, "test-role", entry); err != nil {
+		t.Fatalf("putRetained: %v", err)
+	}
+	if resp := readRetained(); resp != nil {
+		t.Errorf("expired entry returned: %v", resp)
+	}
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if entry, _ := getRetained(ctx, storage, "test-role"); entry != nil {
+		t.Error("expired entry not purged")
+	}
+
+	// By default a delete keeps nothing.
+	setupRotationTestWithServer(t, b, storage, server)
+	if resp, err := sb.rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	deleteRole(nil)
+	if entry, _ := getRetained(ctx, storage, "test-role"); entry != nil {
+		t.Error("delete without purge_history=false retained the credential")
+	}
+}
@@ -3,55 +3,74 @@ package solacevaultplugin
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sort"
 	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// validAccessLevels are the CLI user access levels accepted by the broker.
+var validAccessLevels = map[string]bool{
+	"none":       true,
+	"read-only":  true,
+	"read-write": true,
+	"admin":      true,
+}
+
+// monitorAccessLevel is the access level monitor roles create CLI users
+// with; monitorAccessLevels are the levels they accept on existing users.
+const monitorAccessLevel = "read-only"
+
+var monitorAccessLevels = map[string]bool{
+	"none":      true,
+	"read-only": true,
+}
+
 func pathRoles(b *solaceBackend) []*framework.Path {
 	return []*framework.Path{
 		{
 			Pattern: "roles/" + framework.GenericNameRegex("name"),
-			Fields: map[string]*framework.FieldSchema{
-				"name": {
-					Type:        framework.TypeString,
-					Description: "Name of the role.",
-					Required:    true,
-				},
-				"broker": {
-					Type:        framework.TypeString,
-					Description: "Name of the broker configuration to use.",
-					Required:    true,
-				},
-				"cli_username": {
-					Type:        framework.TypeString,
-					Description: "CLI username on the Solace broker.",
-					Required:    true,
-				},
-				"rotation_period": {
-					Type:        framework.TypeDurationSecond,
-					Description: "How often to rotate the password, in seconds. 0 disables automatic rotation.",
-					Default:     0,
-				},
-				"password_length": {
-					Type:        framework.TypeInt,
-					Description: "Length of generated passwords. Must be between 16 and 128. Default: 25.",
-					Default:     25,
-				},
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "role",
+				ItemType:        "Role",
 			},
+			Fields: roleFieldSchema(),
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.CreateOperation: &framework.PathOperation{
-					Callback: b.pathRolesWrite,
+					Callback:                    b.pathRolesWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   roleWriteResponses,
 				},
 				logical.UpdateOperation: &framework.PathOperation{
-					Callback: b.pathRolesWrite,
+					Callback:                    b.pathRolesWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   roleWriteResponses,
+				},
+				logical.PatchOperation: &framework.PathOperation{
+					Callback:                    b.pathRolesPatch,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   roleWriteResponses,
 				},
 				logical.ReadOperation: &framework.PathOperation{
 					Callback: b.pathRolesRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      roleResponseFields,
+						}},
+					},
 				},
 				logical.DeleteOperation: &framework.PathOperation{
-					Callback: b.pathRolesDelete,
+					Callback:                    b.pathRolesDelete,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   noContentResponses,
 				},
 			},
 			ExistenceCheck:  b.pathRolesExistenceCheck,
@@ -60,17 +79,270 @@ func pathRoles(b *solaceBackend) []*framework.Path {
 		},
 		{
 			Pattern: "roles/?$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "roles",
+				Navigation:      true,
+				ItemType:        "Role",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"broker": {
+					Type:        framework.TypeString,
+					Description: "Only list roles whose credential lives on this broker, directly or through a broker group.",
+					Query:       true,
+				},
+				"detailed": {
+					Type:        framework.TypeBool,
+					Description: "Read every listed role to report its non_compliant flag in key_info.",
+					Query:       true,
+				},
+			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.ListOperation: &framework.PathOperation{
 					Callback: b.pathRolesList,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"keys":     {Type: framework.TypeStringSlice, Description: "Names of the roles."},
+								"key_info": {Type: framework.TypeMap, Description: "With detailed, each role's non_compliant flag: whether its credential is older than its max_password_age."},
+							},
+						}},
+					},
 				},
 			},
 			HelpSynopsis:    "List configured roles.",
-			HelpDescription: "List the names of all configured roles.",
+			HelpDescription: "List the names of all configured roles, and whether each one is non-compliant with its max_password_age. Set broker to list only the roles on one broker, including roles on a broker group it belongs to.",
 		},
 	}
 }
 
+// roleFieldSchema returns the fields of a role write, which role imports
+// are checked against too.
+func roleFieldSchema() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"name": {
+			Type:        framework.TypeString,
+			Description: "Name of the role.",
+			Required:    true,
+			DisplayAttrs: &framework.DisplayAttributes{
+				Identifier: true,
+			},
+		},
+		"broker": {
+			Type:        framework.TypeString,
+			Description: "Name of the broker configuration to use. Required unless broker_group is set.",
+		},
+		"broker_group": {
+			Type:        framework.TypeString,
+			Description: "Name of a broker group whose members all get the CLI user's new password, instead of a single broker. Only for cli_user roles.",
+		},
+		"cli_username": {
+			Type:        framework.TypeString,
+			Description: "CLI username on the Solace broker. Required for cli_user roles unless username_template is set.",
+		},
+		"username_template": {
+			Type:        framework.TypeString,
+			Description: "Template the CLI username is derived from when cli_username is not set, for example {{ .RoleName | lowercase }}. It can use .RoleName, .Broker, .BrokerGroup, and the .DisplayName and .EntityID of the token writing the role. The username is kept on later writes until the template changes.",
+		},
+		"rotation_period": {
+			Type:        framework.TypeDurationSecond,
+			Description: "How often to rotate the password, in seconds. 0 disables automatic rotation.",
+			Default:     0,
+		},
+		"max_password_age": {
+			Type:        framework.TypeDurationSecond,
+			Description: "How old the credential may get, in seconds, before the role is reported non_compliant, for example when rotations keep failing. At least rotation_period. 0 disables the check.",
+			Default:     0,
+		},
+		"password_length": {
+			Type:        framework.TypeInt,
+			Description: "Length of generated passwords. Must be between 16 and 128. Default: the mount's default_password_length (25).",
+		},
+		"rotate_on_policy_change": {
+			Type:        framework.TypeBool,
+			Description: "Rotate the role as soon as a write changes its password_length, so its current password meets the new length without waiting for the next rotation.",
+			Default:     false,
+		},
+		"propagation_delay": {
+			Type:        framework.TypeDurationSecond,
+			Description: "How long after a rotation, in seconds, creds/ keeps returning the previous credential, for brokers that take time to pass a new password on to all their nodes. Less than rotation_period. 0 serves the new credential at once.",
+			Default:     0,
+		},
+		"password_encoding": {
+			Type:        framework.TypeString,
+			Description: "How creds/ returns the password, token or client secret: plain; base64; or url, percent-encoded for use in a URI. The credential on the broker is not affected.",
+			Default:     passwordEncodingPlain,
+		},
+		"audit_tags": {
+			Type:        framework.TypeKVPairs,
+			Description: "Tags, as name=value pairs, returned under audit_tags with the role's credentials and rotations so that audit entries can be routed by them, for example by application. Tune the mount with audit_non_hmac_response_keys=audit_tags to keep them readable in the audit log. At most 16. Optional.",
+		},
+		"owner_entity_id": {
+			Type:        framework.TypeString,
+			Description: "Vault entity ID of the role's owner. Once set, it can only be changed with transfer-ownership.",
+		},
+		"approver_entity_ids": {
+			Type:        framework.TypeCommaStringSlice,
+			Description: "Vault entity IDs that may approve the role's manual rotations besides its owner.",
+		},
+		"require_owner_approval": {
+			Type:        framework.TypeBool,
+			Description: "Refuse manual rotations not requested or approved by the owner or an approver. Requires owner_entity_id.",
+			Default:     false,
+		},
+		"create_if_missing": {
+			Type:        framework.TypeBool,
+			Description: "Create the CLI user on the broker during rotation if it does not exist.",
+			Default:     false,
+		},
+		"global_access_level": {
+			Type:        framework.TypeString,
+			Description: "Global access level for CLI users created by create_if_missing: none, read-only, read-write, or admin. Optional.",
+		},
+		"vpn_access_level": {
+			Type:        framework.TypeString,
+			Description: "Message VPN access level for CLI users created by create_if_missing: none, read-only, read-write, or admin. Optional.",
+		},
+		"vpn_access_level_exceptions": {
+			Type:        framework.TypeKVPairs,
+			Description: "Message VPN access levels that differ from vpn_access_level for CLI users created by create_if_missing, as vpn=level pairs. Optional.",
+		},
+		"monitor": {
+			Type:        framework.TypeBool,
+			Description: "Issue a read-only monitoring credential. The CLI user is created with read-only access if it does not exist, and is not rotated if it exists with more access.",
+			Default:     false,
+		},
+		"disable_during_rotation": {
+			Type:        framework.TypeBool,
+			Description: "Shut the CLI user down while its password is changed and enable it again afterwards, ending every session logged in with the old password. A user already shut down is left that way.",
+			Default:     false,
+		},
+		"terminate_sessions": {
+			Type:        framework.TypeBool,
+			Description: "After a rotation has stored the new password, briefly shut the CLI user down and enable it again, ending every session logged in with the old password. Unlike disable_during_rotation, the user stays enabled while its password changes, and a failure to end sessions does not fail the rotation. A user already shut down is left that way.",
+			Default:     false,
+		},
+		"target": {
+			Type:        framework.TypeString,
+			Description: "What the role rotates: cli_user, a CLI user's password; rest_consumer, a REST delivery point's REST consumer credential; oauth_profile, an OAuth profile's client secret; cloud_token, a Solace Cloud API token; or semp_rpc, a password set by a custom SEMP v1 request.",
+			Default:     roleTargetCLIUser,
+		},
+		"msg_vpn": {
+			Type:        framework.TypeString,
+			Description: "Message VPN of the REST delivery point or OAuth profile. Required for rest_consumer roles; for oauth_profile roles, omit it to target a broker-level OAuth profile.",
+		},
+		"rest_delivery_point": {
+			Type:        framework.TypeString,
+			Description: "REST delivery point of the REST consumer. Required for rest_consumer roles.",
+		},
+		"rest_consumer": {
+			Type:        framework.TypeString,
+			Description: "REST consumer whose credential is rotated. Required for rest_consumer roles.",
+		},
+		"rest_consumer_auth": {
+			Type:        framework.TypeString,
+			Description: "How the REST consumer authenticates: http-basic, with a rotated password, or client-certificate, with a rotated self-signed certificate.",
+			Default:     restAuthHTTPBasic,
+		},
+		"rest_consumer_username": {
+			Type:        framework.TypeString,
+			Description: "HTTP basic username of the REST consumer. Required for http-basic.",
+		},
+		"oauth_profile": {
+			Type:        framework.TypeString,
+			Description: "OAuth profile whose client secret is kept in sync with the identity provider. Required for oauth_profile roles.",
+		},
+		"cloud_token_id": {
+			Type:        framework.TypeString,
+			Description: "ID of the Solace Cloud API token to regenerate, using the broker's cloud_api_token. Required for cloud_token roles.",
+		},
+		"semp_rpc_template": {
+			Type:        framework.TypeString,
+			Description: "SEMP v1 request that sets the password, without its enclosing <rpc> element. {{password}} is replaced with the new password and {{username}} with semp_rpc_username, both XML-escaped. Required for semp_rpc roles.",
+		},
+		"semp_rpc_username": {
+			Type:        framework.TypeString,
+			Description: "Username the semp_rpc_template's {{username}} placeholder stands for, returned with the password by creds/.",
+		},
+		"current_password": {
+			Type:        framework.TypeString,
+			Description: "Password the credential already has, imported when the role is created so creds/ serves it without an initial rotation. Requires the mount's allow_supplied_passwords.",
+			DisplayAttrs: &framework.DisplayAttributes{
+				Sensitive: true,
+			},
+		},
+		"last_rotated": {
+			Type:        framework.TypeTime,
+			Description: "When the imported current_password was set, as an RFC 3339 time or Unix seconds. Automatic rotation is timed from it. Default: now.",
+		},
+		"dry_run": {
+			Type:        framework.TypeBool,
+			Description: "Validate the role and return it as it would be stored, without storing it. With the mount's verify_rotation setting on, also checks that the CLI user exists on the broker.",
+			Default:     false,
+		},
+		"purge_history": {
+			Type:        framework.TypeBool,
+			Description: "On delete, destroy the role's stored credential at once. When false, the role and its last credential are kept, seal-wrapped, under retained/ for the mount's deleted_role_retention.",
+			Default:     true,
+		},
+	}
+}
+
+// roleResponseFields describes a role read. Only the fields of the role's
+// target are returned.
+var roleResponseFields = map[string]*framework.FieldSchema{
+	"broker":                      {Type: framework.TypeString, Description: "Name of the broker configuration."},
+	"broker_group":                {Type: framework.TypeString, Description: "Name of the broker group, for roles on a group."},
+	"target":                      {Type: framework.TypeString, Description: "What the role rotates: cli_user, rest_consumer, oauth_profile, cloud_token or semp_rpc."},
+	"rotation_period":             {Type: framework.TypeDurationSecond, Description: "How often the credential is rotated, in seconds; 0 when automatic rotation is off."},
+	"max_password_age":            {Type: framework.TypeDurationSecond, Description: "How old the credential may get before the role is non-compliant, in seconds; 0 when unchecked."},
+	"password_length":             {Type: framework.TypeInt, Description: "Length of generated passwords."},
+	"cli_username":                {Type: framework.TypeString, Description: "CLI username on the broker."},
+	"username_template":           {Type: framework.TypeString, Description: "Template the CLI username was derived from."},
+	"create_if_missing":           {Type: framework.TypeBool, Description: "Whether rotation creates the CLI user if it does not exist."},
+	"global_access_level":         {Type: framework.TypeString, Description: "Global access level for CLI users created by create_if_missing."},
+	"vpn_access_level":            {Type: framework.TypeString, Description: "Message VPN access level for CLI users created by create_if_missing."},
+	"vpn_access_level_exceptions": {Type: framework.TypeKVPairs, Description: "Per-VPN access levels for CLI users created by create_if_missing."},
+	"monitor":                     {Type: framework.TypeBool, Description: "Whether the role issues a read-only monitoring credential."},
+	"disable_during_rotation":     {Type: framework.TypeBool, Description: "Whether the CLI user is shut down while its password is changed."},
+	"terminate_sessions":          {Type: framework.TypeBool, Description: "Whether the CLI user's sessions are ended after each rotation."},
+	"rotate_on_policy_change":     {Type: framework.TypeBool, Description: "Whether a change to password_length rotates the role at once."},
+	"propagation_delay":           {Type: framework.TypeDurationSecond, Description: "How long after a rotation creds/ keeps returning the previous credential, in seconds."},
+	"password_encoding":           {Type: framework.TypeString, Description: "How creds/ returns the credential: plain, base64 or url."},
+	"audit_tags":                  {Type: framework.TypeKVPairs, Description: "Tags returned with the role's credentials and rotations, for routing audit entries."},
+	"owner_entity_id":             {Type: framework.TypeString, Description: "Vault entity ID of the role's owner."},
+	"approver_entity_ids":         {Type: framework.TypeCommaStringSlice, Description: "Vault entity IDs that may approve manual rotations besides the owner."},
+	"require_owner_approval":      {Type: framework.TypeBool, Description: "Whether manual rotations must be requested or approved by the owner or an approver."},
+	"last_rotation_approved_by":   {Type: framework.TypeString, Description: "Entity ID that approved the last manual rotation."},
+	"ownership_transferred_at":    {Type: framework.TypeTime, Description: "When the role was last transferred to a new owner."},
+	"ownership_transferred_by":    {Type: framework.TypeString, Description: "Entity ID that last transferred the role to a new owner."},
+	"msg_vpn":                     {Type: framework.TypeString, Description: "Message VPN of the REST delivery point or OAuth profile."},
+	"rest_delivery_point":         {Type: framework.TypeString, Description: "REST delivery point of the REST consumer."},
+	"rest_consumer":               {Type: framework.TypeString, Description: "REST consumer whose credential is rotated."},
+	"rest_consumer_auth":          {Type: framework.TypeString, Description: "How the REST consumer authenticates: http-basic or client-certificate."},
+	"rest_consumer_username":      {Type: framework.TypeString, Description: "HTTP basic username of the REST consumer."},
+	"oauth_profile":               {Type: framework.TypeString, Description: "OAuth profile whose client secret is kept in sync."},
+	"cloud_token_id":              {Type: framework.TypeString, Description: "ID of the Solace Cloud API token."},
+	"semp_rpc_template":           {Type: framework.TypeString, Description: "SEMP v1 request template that sets the password."},
+	"semp_rpc_username":           {Type: framework.TypeString, Description: "Value of the template's {{username}} placeholder."},
+	"last_rotated":                {Type: framework.TypeTime, Description: "When the credential was last rotated."},
+	"credential_fingerprint":      {Type: framework.TypeString, Description: "First 8 hex characters of the SHA-256 of the current password, token or client secret."},
+	"last_rotation_trigger":       {Type: framework.TypeString, Description: "What triggered the last rotation: manual or periodic, or import for a password imported with the role."},
+	"last_rotated_by":             {Type: framework.TypeString, Description: "Display name of the token that last rotated the credential manually, or imported it."},
+	"last_rotated_by_entity_id":   {Type: framework.TypeString, Description: "Entity ID of the token that last rotated the credential manually, or imported it."},
+}
+
+// roleWriteResponses describes a role write, which returns the role only
+// for a dry run.
+var roleWriteResponses = map[int][]framework.Response{
+	http.StatusOK: {{
+		Description: "OK",
+		Fields:      roleResponseFields,
+	}},
+	http.StatusNoContent: {{Description: "No Content"}},
+}
+
 func (b *solaceBackend) pathRolesExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
 	name := d.Get("name").(string)
 	role, err := getRole(ctx, req.Storage, name)
@@ -82,53 +354,396 @@ func (b *solaceBackend) pathRolesExistenceCheck(ctx context.Context, req *logica
 
 func (b *solaceBackend) pathRolesWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
+
+	// Hold the role lock so a rotation in progress does not write back the
+	// role it read before this write.
+	lock := b.roleLock(name)
+	lock.Lock()
+	resp, rotate, err := b.writeRole(ctx, req, d)
+	lock.Unlock()
+	if err != nil || !rotate {
+		return resp, err
+	}
+	return b.rotateForPolicyChange(ctx, req, name), nil
+}
+
+// writeRole validates and stores a role write, with the role lock held. It
+// reports whether the role should then be rotated for a changed
+// password_length, which has to wait until the lock is released.
+func (b *solaceBackend) writeRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, bool, error) {
+	name := d.Get("name").(string)
 	broker := d.Get("broker").(string)
+	brokerGroup := d.Get("broker_group").(string)
 	cliUsername := d.Get("cli_username").(string)
+	usernameTemplate := d.Get("username_template").(string)
 	rotationPeriodSec := d.Get("rotation_period").(int)
+	maxPasswordAgeSec := d.Get("max_password_age").(int)
 	passwordLength := d.Get("password_length").(int)
+	createIfMissing := d.Get("create_if_missing").(bool)
+	globalAccessLevel := d.Get("global_access_level").(string)
+	vpnAccessLevel := d.Get("vpn_access_level").(string)
+	vpnExceptions := d.Get("vpn_access_level_exceptions").(map[string]string)
+	monitor := d.Get("monitor").(bool)
+	disableDuringRotation := d.Get("disable_during_rotation").(bool)
+	terminateSessions := d.Get("terminate_sessions").(bool)
+	rotateOnPolicyChange := d.Get("rotate_on_policy_change").(bool)
+	passwordEncoding := d.Get("password_encoding").(string)
+	propagationDelaySec := d.Get("propagation_delay").(int)
+	ownerEntityID, ownerSet := d.GetOk("owner_entity_id")
+	approvers := d.Get("approver_entity_ids").([]string)
+	auditTags := d.Get("audit_tags").(map[string]string)
+	requireOwnerApproval := d.Get("require_owner_approval").(bool)
+	target := d.Get("target").(string)
+	msgVPN := d.Get("msg_vpn").(string)
+	rdp := d.Get("rest_delivery_point").(string)
+	restConsumer := d.Get("rest_consumer").(string)
+	restAuth := d.Get("rest_consumer_auth").(string)
+	restUsername := d.Get("rest_consumer_username").(string)
+	oauthProfile := d.Get("oauth_profile").(string)
+	cloudTokenID := d.Get("cloud_token_id").(string)
+	rpcTemplate := d.Get("semp_rpc_template").(string)
+	rpcUsername := d.Get("semp_rpc_username").(string)
+	dryRun := d.Get("dry_run").(bool)
+	currentPassword := d.Get("current_password").(string)
+	lastRotated, lastRotatedSet := d.GetOk("last_rotated")
 
-	if broker == "" {
-		return logical.ErrorResponse("broker is required"), nil
+	if broker == "" && brokerGroup == "" {
+		return logical.ErrorResponse("broker is required"), false, nil
 	}
-	if cliUsername == "" {
-		return logical.ErrorResponse("cli_username is required"), nil
+	if broker != "" {
+		if err := validateNameReference("broker", broker); err != nil {
+			return logical.ErrorResponse(err.Error()), false, nil
+		}
 	}
-	if passwordLength < 16 || passwordLength > 128 {
-		return logical.ErrorResponse(fmt.Sprintf("password_length must be between 16 and 128, got %d", passwordLength)), nil
+	if brokerGroup != "" {
+		if err := validateNameReference("broker group", brokerGroup); err != nil {
+			return logical.ErrorResponse(err.Error()), false, nil
+		}
 	}
-
-	// Verify the referenced broker exists
-	brokerConfig, err := getBroker(ctx, req.Storage, broker)
-	if err != nil {
-		return nil, err
+	if broker != "" && brokerGroup != "" {
+		return logical.ErrorResponse("only one of broker and broker_group can be set"), false, nil
+	}
+	if brokerGroup != "" && target != roleTargetCLIUser {
+		return logical.ErrorResponse("broker_group applies only to cli_user roles"), false, nil
+	}
+	switch target {
+	case roleTargetCLIUser:
+		if cliUsername != "" && usernameTemplate != "" {
+			return logical.ErrorResponse("only one of cli_username and username_template can be set"), false, nil
+		}
+		if cliUsername == "" && usernameTemplate == "" {
+			return logical.ErrorResponse("cli_username is required"), false, nil
+		}
+	case roleTargetRESTConsumer:
+		if msgVPN == "" || rdp == "" || restConsumer == "" {
+			return logical.ErrorResponse("msg_vpn, rest_delivery_point and rest_consumer are required for rest_consumer roles"), false, nil
+		}
+		if restAuth != restAuthHTTPBasic && restAuth != restAuthClientCertificate {
+			return logical.ErrorResponse("rest_consumer_auth must be %s or %s, got %q", restAuthHTTPBasic, restAuthClientCertificate, restAuth), false, nil
+		}
+		if restAuth == restAuthHTTPBasic && restUsername == "" {
+			return logical.ErrorResponse("rest_consumer_username is required for %s", restAuthHTTPBasic), false, nil
+		}
+	case roleTargetOAuthProfile:
+		if oauthProfile == "" {
+			return logical.ErrorResponse("oauth_profile is required for oauth_profile roles"), false, nil
+		}
+		if rotationPeriodSec != 0 {
+			return logical.ErrorResponse("oauth_profile roles cannot rotate automatically; their client secret comes from the identity provider"), false, nil
+		}
+	case roleTargetCloudToken:
+		if cloudTokenID == "" {
+			return logical.ErrorResponse("cloud_token_id is required for cloud_token roles"), false, nil
+		}
+	case roleTargetSEMPRPC:
+		if err := validateRPCTemplate(rpcTemplate, rpcUsername); err != nil {
+			return logical.ErrorResponse(err.Error()), false, nil
+		}
+	default:
+		return logical.ErrorResponse("target must be one of %s, %s, %s, %s, %s, got %q",
+			roleTargetCLIUser, roleTargetRESTConsumer, roleTargetOAuthProfile, roleTargetCloudToken, roleTargetSEMPRPC, target), false, nil
+	}
+	if target != roleTargetSEMPRPC && (rpcTemplate != "" || rpcUsername != "") {
+		return logical.ErrorResponse("semp_rpc_template and semp_rpc_username apply only to semp_rpc roles"), false, nil
+	}
+	if target != roleTargetCLIUser && (cliUsername != "" || usernameTemplate != "" || createIfMissing || globalAccessLevel != "" ||
+		vpnAccessLevel != "" || len(vpnExceptions) > 0 || monitor || disableDuringRotation || terminateSessions) {
+		return logical.ErrorResponse("cli_username, username_template, create_if_missing, global_access_level, vpn_access_level, vpn_access_level_exceptions, monitor, disable_during_rotation and terminate_sessions apply only to cli_user roles"), false, nil
+	}
+	if disableDuringRotation && terminateSessions {
+		return logical.ErrorResponse("disable_during_rotation already ends the CLI user's sessions; set only one of it and terminate_sessions"), false, nil
+	}
+	if propagationDelaySec < 0 {
+		return logical.ErrorResponse("propagation_delay cannot be negative"), false, nil
+	}
+	if propagationDelaySec > 0 && rotationPeriodSec > 0 && propagationDelaySec >= rotationPeriodSec {
+		return logical.ErrorResponse("propagation_delay must be less than rotation_period (%ds), got %ds", rotationPeriodSec, propagationDelaySec), false, nil
+	}
+	if err := validateAuditTags(auditTags); err != nil {
+		return logical.ErrorResponse(err.Error()), false, nil
+	}
+	if !validPasswordEncodings[passwordEncoding] {
+		return logical.ErrorResponse("password_encoding must be one of %s, %s, %s, got %q",
+			passwordEncodingPlain, passwordEncodingBase64, passwordEncodingURL, passwordEncoding), false, nil
+	}
+	if monitor && globalAccessLevel != "" && globalAccessLevel != monitorAccessLevel {
+		return logical.ErrorResponse("monitor roles are read-only; global_access_level cannot be %q", globalAccessLevel), false, nil
+	}
+	if monitor && vpnAccessLevel != "" && !monitorAccessLevels[vpnAccessLevel] {
+		return logical.ErrorResponse("monitor roles are read-only; vpn_access_level cannot be %q", vpnAccessLevel), false, nil
+	}
+	if maxPasswordAgeSec < 0 {
+		return logical.ErrorResponse("max_password_age cannot be negative"), false, nil
 	}
-	if brokerConfig == nil {
-		return logical.ErrorResponse("broker %q not found", broker), nil
+	if maxPasswordAgeSec > 0 && maxPasswordAgeSec < rotationPeriodSec {
+		return logical.ErrorResponse("max_password_age must be at least rotation_period (%ds), got %ds", rotationPeriodSec, maxPasswordAgeSec), false, nil
+	}
+	if _, ok := d.GetOk("password_length"); !ok {
+		settings, err := getSettings(ctx, req.Storage)
+		if err != nil {
+			return nil, false, err
+		}
+		passwordLength = settings.DefaultPasswordLength
+	}
+	if passwordLength < minPasswordLength || passwordLength > maxPasswordLength {
+		return logical.ErrorResponse(fmt.Sprintf("password_length must be between %d and %d, got %d", minPasswordLength, maxPasswordLength, passwordLength)), false, nil
+	}
+	if globalAccessLevel != "" && !validAccessLevels[globalAccessLevel] {
+		return logical.ErrorResponse("global_access_level must be one of none, read-only, read-write, admin, got %q", globalAccessLevel), false, nil
+	}
+	if vpnAccessLevel != "" && !validAccessLevels[vpnAccessLevel] {
+		return logical.ErrorResponse("vpn_access_level must be one of none, read-only, read-write, admin, got %q", vpnAccessLevel), false, nil
+	}
+	for vpn, level := range vpnExceptions {
+		if vpn == "" || !validAccessLevels[level] {
+			return logical.ErrorResponse("vpn_access_level_exceptions must map message VPNs to none, read-only, read-write or admin, got %q=%q", vpn, level), false, nil
+		}
+		if monitor && !monitorAccessLevels[level] {
+			return logical.ErrorResponse("monitor roles are read-only; the access level to message VPN %q cannot be %q", vpn, level), false, nil
+		}
+	}
+	if len(vpnExceptions) == 0 {
+		vpnExceptions = nil
 	}
 
-	// Preserve existing password and last_rotated if updating
+	// Verify the referenced broker or group exists
+	if brokerGroup != "" {
+		group, err := getBrokerGroup(ctx, req.Storage, brokerGroup)
+		if err != nil {
+			return nil, false, err
+		}
+		if group == nil {
+			return logical.ErrorResponse("broker group %q not found", brokerGroup), false, nil
+		}
+	} else {
+		brokerConfig, err := getBroker(ctx, req.Storage, broker)
+		if err != nil {
+			return nil, false, err
+		}
+		if brokerConfig == nil {
+			return logical.ErrorResponse("broker %q not found", broker), false, nil
+		}
+		if target == roleTargetCloudToken && brokerConfig.CloudAPIToken == "" {
+			return logical.ErrorResponse("broker %q has no cloud_api_token configured", broker), false, nil
+		}
+	}
+
+	// Preserve last_rotated if updating
 	existing, err := getRole(ctx, req.Storage, name)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if existing == nil {
+		if err := validateNewName("role", name); err != nil {
+			return logical.ErrorResponse(err.Error()), false, nil
+		}
+		settings, err := getSettings(ctx, req.Storage)
+		if err != nil {
+			return nil, false, err
+		}
+		resp, err := quotaReached("roles", "max_roles", settings.MaxRoles, func() ([]string, error) {
+			return listRoles(ctx, req.Storage)
+		})
+		if resp != nil || err != nil {
+			return resp, false, err
+		}
+	}
+
+	// A templated username is rendered once and then kept, so that
+	// rewriting the role does not move it to a new CLI user when the
+	// template includes a timestamp or the writer's identity.
+	if usernameTemplate != "" {
+		if existing != nil && existing.UsernameTemplate == usernameTemplate && existing.CLIUsername != "" {
+			cliUsername = existing.CLIUsername
+		} else {
+			cliUsername, err = renderUsername(usernameTemplate, usernameTemplateData{
+				RoleName:    name,
+				Broker:      broker,
+				BrokerGroup: brokerGroup,
+				DisplayName: req.DisplayName,
+				EntityID:    req.EntityID,
+			})
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), false, nil
+			}
+		}
 	}
 
 	role := &RoleEntry{
-		Broker:         broker,
-		CLIUsername:    cliUsername,
-		RotationPeriod: time.Duration(rotationPeriodSec) * time.Second,
-		PasswordLength: passwordLength,
+		Broker:           broker,
+		BrokerGroup:      brokerGroup,
+		CLIUsername:      cliUsername,
+		UsernameTemplate: usernameTemplate,
+		RotationPeriod:   time.Duration(rotationPeriodSec) * time.Second,
+		MaxPasswordAge:   time.Duration(maxPasswordAgeSec) * time.Second,
+		PasswordLength:   passwordLength,
+		PropagationDelay: time.Duration(propagationDelaySec) * time.Second,
+
+		CreateIfMissing:          createIfMissing,
+		GlobalAccessLevel:        globalAccessLevel,
+		VPNAccessLevel:           vpnAccessLevel,
+		VPNAccessLevelExceptions: vpnExceptions,
+		Monitor:                  monitor,
+		DisableDuringRotation:    disableDuringRotation,
+		TerminateSessions:        terminateSessions,
+		RotateOnPolicyChange:     rotateOnPolicyChange,
+	}
+	if len(auditTags) > 0 {
+		role.AuditTags = auditTags
+	}
+	if target == roleTargetRESTConsumer {
+		role.Target = target
+		role.MsgVPN = msgVPN
+		role.RESTDeliveryPoint = rdp
+		role.RESTConsumer = restConsumer
+		role.RESTAuthScheme = restAuth
+		if restAuth == restAuthHTTPBasic {
+			role.RESTUsername = restUsername
+		}
+	}
+	if target == roleTargetOAuthProfile {
+		role.Target = target
+		role.MsgVPN = msgVPN
+		role.OAuthProfile = oauthProfile
+	}
+	if target == roleTargetCloudToken {
+		role.Target = target
+		role.CloudTokenID = cloudTokenID
+	}
+	if target == roleTargetSEMPRPC {
+		role.Target = target
+		role.SEMPRPCTemplate = rpcTemplate
+		role.SEMPRPCUsername = rpcUsername
+	}
+
+	if rotateOnPolicyChange && !role.generatesPassword() {
+		return logical.ErrorResponse("rotate_on_policy_change applies only to roles whose password is generated"), false, nil
+	}
+	if passwordEncoding != passwordEncodingPlain {
+		if role.usesClientCertificate() {
+			return logical.ErrorResponse("password_encoding does not apply to roles whose credential is a client certificate"), false, nil
+		}
+		role.PasswordEncoding = passwordEncoding
 	}
 
 	if existing != nil {
-		role.Password = existing.Password
 		role.LastRotated = existing.LastRotated
+		role.LastRotationTrigger = existing.LastRotationTrigger
+		role.LastRotatedBy = existing.LastRotatedBy
+		role.LastRotatedByEntity = existing.LastRotatedByEntity
+		role.LastRotationApprovedBy = existing.LastRotationApprovedBy
+		role.OwnerEntityID = existing.OwnerEntityID
+		role.OwnershipTransferredAt = existing.OwnershipTransferredAt
+		role.OwnershipTransferredBy = existing.OwnershipTransferredBy
+	}
+
+	// Once a role has an owner, ownership changes go through
+	// transfer-ownership, which records them.
+	if ownerSet {
+		if existing != nil && existing.OwnerEntityID != "" && ownerEntityID.(string) != existing.OwnerEntityID {
+			return logical.ErrorResponse("owner_entity_id of an existing role can only be changed with transfer-ownership/%s", name), false, nil
+		}
+		role.OwnerEntityID = ownerEntityID.(string)
+	}
+	if len(approvers) > 0 {
+		role.ApproverEntityIDs = approvers
+	}
+	role.RequireOwnerApproval = requireOwnerApproval
+	if role.RequireOwnerApproval && role.OwnerEntityID == "" {
+		return logical.ErrorResponse("require_owner_approval requires owner_entity_id"), false, nil
 	}
 
+	var imported *RoleSecret
+	if currentPassword != "" || lastRotatedSet {
+		if existing != nil {
+			return logical.ErrorResponse("current_password and last_rotated can only be set when a role is created; use rotate-role with password to replace an existing role's password"), false, nil
+		}
+		if currentPassword == "" {
+			return logical.ErrorResponse("last_rotated requires current_password"), false, nil
+		}
+		when := time.Now().UTC()
+		if lastRotatedSet {
+			when = lastRotated.(time.Time).UTC()
+		}
+		var resp *logical.Response
+		imported, resp, err = importRoleCredential(ctx, req.Storage, name, role, currentPassword, when)
+		if err != nil || resp != nil {
+			return resp, false, err
+		}
+		role.LastRotated = when
+		role.LastRotationTrigger = rotationTriggerImport
+		role.LastRotatedBy = req.DisplayName
+		role.LastRotatedByEntity = req.EntityID
+	}
+
+	if dryRun {
+		resp, err := b.dryRunRole(ctx, req, name, role)
+		return resp, false, err
+	}
+
+	// The credential is stored first, so a role never exists without the
+	// password it was created with.
+	if imported != nil {
+		if err := putRoleSecret(ctx, req.Storage, name, imported); err != nil {
+			return nil, false, err
+		}
+	}
 	if err := putRole(ctx, req.Storage, name, role); err != nil {
-		return nil, err
+		return nil, false, err
 	}
+	b.unscheduleRole(name)
+	b.sendEvent(ctx, eventRoleWrite, "role", name, "broker", role.location())
 
-	return nil, nil
+	// A role that was never rotated has no password to bring up to date.
+	if role.RotateOnPolicyChange && existing != nil && !existing.LastRotated.IsZero() &&
+		existing.PasswordLength != role.PasswordLength {
+		return nil, true, nil
+	}
+	return nil, false, nil
+}
+
+// rotateForPolicyChange rotates a role whose password_length a write just
+// changed. The write has been saved either way, so a failed rotation is
+// reported as a warning; the role is rotated again on its schedule or by
+// hand.
+func (b *solaceBackend) rotateForPolicyChange(ctx context.Context, req *logical.Request, name string) *logical.Response {
+	resp, err := b.rotateRoleWith(withMetricsMount(withSEMPRequestID(ctx, req.ID), req.MountPoint), req.Storage, name, rotationOptions{
+		actor: rotationActor{
+			trigger:     rotationTriggerPolicyChange,
+			displayName: req.DisplayName,
+			entityID:    req.EntityID,
+		},
+	})
+	warning := &logical.Response{}
+	switch {
+	case err != nil:
+		warning.AddWarning(fmt.Sprintf("role %q was saved, but rotating it for its new password_length failed: %v", name, err))
+	case resp != nil && resp.IsError():
+		warning.AddWarning(fmt.Sprintf("role %q was saved, but rotating it for its new password_length failed: %v", name, resp.Error()))
+	default:
+		warning.AddWarning(fmt.Sprintf("role %q was rotated for its new password_length", name))
+	}
+	return warning
 }
 
 func (b *solaceBackend) pathRolesRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
@@ -141,35 +756,313 @@ func (b *solaceBackend) pathRolesRead(ctx context.Context, req *logical.Request,
 	if role == nil {
 		return nil, nil
 	}
+	// The fingerprint lets holders of the credential check it is current
+	// without role reads exposing the credential itself. It is that of the
+	// credential creds/ serves, which lags the stored one while a
+	// propagation delay runs.
+	secret, err := getRoleSecret(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	secret = secret.served(time.Now())
 
-	data := map[string]interface{}{
-		"broker":          role.Broker,
-		"cli_username":    role.CLIUsername,
-		"rotation_period": int(role.RotationPeriod.Seconds()),
-		"password_length": role.PasswordLength,
+	data := roleResponseData(role)
+	if fingerprint := credentialFingerprint(secret); fingerprint != "" {
+		data["credential_fingerprint"] = fingerprint
+	}
+	return &logical.Response{Data: data}, nil
+}
+
+// roleResponseData returns a role as role reads report it.
+func roleResponseData(role *RoleEntry) map[string]interface{} {
+	data := roleFields(role)
+	if role.UsernameTemplate != "" {
+		data["cli_username"] = role.CLIUsername
 	}
 	if !role.LastRotated.IsZero() {
 		data["last_rotated"] = role.LastRotated.Format(time.RFC3339)
 	}
+	if role.LastRotationTrigger != "" {
+		data["last_rotation_trigger"] = role.LastRotationTrigger
+	}
+	if role.LastRotatedBy != "" {
+		data["last_rotated_by"] = role.LastRotatedBy
+	}
+	if role.LastRotatedByEntity != "" {
+		data["last_rotated_by_entity_id"] = role.LastRotatedByEntity
+	}
+	if role.LastRotationApprovedBy != "" {
+		data["last_rotation_approved_by"] = role.LastRotationApprovedBy
+	}
+	if !role.OwnershipTransferredAt.IsZero() {
+		data["ownership_transferred_at"] = role.OwnershipTransferredAt.Format(time.RFC3339)
+		data["ownership_transferred_by"] = role.OwnershipTransferredBy
+	}
+	return data
+}
 
-	return &logical.Response{Data: data}, nil
+// importRoleCredential checks a password imported with current_password for
+// a new role: supplied passwords must be allowed on the mount, the role must
+// be one whose password is generated, and the password must be one its
+// broker, or every member of its group, accepts.
+func importRoleCredential(ctx context.Context, s logical.Storage, name string, role *RoleEntry, password string, lastRotated time.Time) (*RoleSecret, *logical.Response, error) {
+	settings, err := getSettings(ctx, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !settings.AllowSuppliedPasswords {
+		return nil, logical.ErrorResponse("importing current_password needs supplied passwords; set allow_supplied_passwords in config/settings to allow them"), nil
+	}
+	if !role.generatesPassword() {
+		return nil, logical.ErrorResponse("current_password applies only to roles whose password is otherwise generated"), nil
+	}
+	if lastRotated.After(time.Now()) {
+		return nil, logical.ErrorResponse("last_rotated cannot be in the future"), nil
+	}
+
+	var excluded string
+	if role.BrokerGroup != "" {
+		members, resp, err := groupMembers(ctx, s, name, role)
+		if err != nil || resp != nil {
+			return nil, resp, err
+		}
+		excluded = groupExcludedPasswordChars(members)
+	} else {
+		config, err := getBroker(ctx, s, role.Broker)
+		if err != nil {
+			return nil, nil, err
+		}
+		excluded = config.excludedPasswordChars()
+	}
+	if err := validatePassword([]byte(password), excluded); err != nil {
+		return nil, logical.ErrorResponse("current_password: %s", err), nil
+	}
+	return &RoleSecret{Password: password, Version: 1}, nil, nil
 }
 
-func (b *solaceBackend) pathRolesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+// dryRunRole answers a role write made with dry_run: it returns the role
+// that passed validation as it would be stored, without storing it. With
+// verify_rotation on, a CLI user role's user must also exist on the broker,
+// or on every member of its group, unless rotation would create it.
+func (b *solaceBackend) dryRunRole(ctx context.Context, req *logical.Request, name string, role *RoleEntry) (*logical.Response, error) {
+	settings, err := getSettings(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{Data: roleResponseData(role)}
+	if settings.VerifyRotation && role.isCLIUser() && !role.CreateIfMissing && !role.Monitor {
+		ctx = withSEMPRequestID(ctx, req.ID)
+		var verified *logical.Response
+		if role.BrokerGroup != "" {
+			verified, err = b.verifyGroupRole(ctx, req.Storage, name, role)
+		} else {
+			verified, err = b.verifyRoleOnBroker(ctx, req.Storage, name, role)
+		}
+		if err != nil || verified.IsError() {
+			return verified, err
+		}
+		resp.Warnings = verified.Warnings
+	}
+	return resp, nil
+}
+
+func (b *solaceBackend) pathRolesPatch(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 
-	if err := deleteRole(ctx, req.Storage, name); err != nil {
-		return nil, err
+	// The patch is applied to the role as read under the lock, so a
+	// concurrent rotation's changes to it are not lost.
+	lock := b.roleLock(name)
+	lock.Lock()
+	resp, rotate, err := b.patchRole(ctx, req, d, name)
+	lock.Unlock()
+	if err != nil || !rotate {
+		return resp, err
+	}
+	return b.rotateForPolicyChange(ctx, req, name), nil
+}
+
+// patchRole applies a patch to a stored role, with the role lock held. It
+// reports whether the role should then be rotated, as writeRole does.
+func (b *solaceBackend) patchRole(ctx context.Context, req *logical.Request, d *framework.FieldData, name string) (*logical.Response, bool, error) {
+	role, err := getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, false, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role %q not found", name), false, nil
+	}
+
+	patched, err := patchFieldData(d, roleFields(role))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), false, nil
+	}
+
+	return b.writeRole(ctx, req, patched)
+}
+
+// roleFields returns a role's configuration in the shape of the path's
+// fields.
+func roleFields(role *RoleEntry) map[string]interface{} {
+	fields := map[string]interface{}{
+		"broker":                  role.Broker,
+		"target":                  roleTargetCLIUser,
+		"rotation_period":         int(role.RotationPeriod.Seconds()),
+		"max_password_age":        int(role.MaxPasswordAge.Seconds()),
+		"password_length":         role.PasswordLength,
+		"rotate_on_policy_change": role.RotateOnPolicyChange,
+		"password_encoding":       role.passwordEncoding(),
+		"propagation_delay":       int(role.PropagationDelay.Seconds()),
+		"owner_entity_id":         role.OwnerEntityID,
+		"approver_entity_ids":     append([]string{}, role.ApproverEntityIDs...),
+		"require_owner_approval":  role.RequireOwnerApproval,
+	}
+	tags := make(map[string]string, len(role.AuditTags))
+	for name, value := range role.AuditTags {
+		tags[name] = value
+	}
+	fields["audit_tags"] = tags
+	if role.isRESTConsumer() {
+		fields["target"] = roleTargetRESTConsumer
+		fields["msg_vpn"] = role.MsgVPN
+		fields["rest_delivery_point"] = role.RESTDeliveryPoint
+		fields["rest_consumer"] = role.RESTConsumer
+		fields["rest_consumer_auth"] = role.RESTAuthScheme
+		fields["rest_consumer_username"] = role.RESTUsername
+		return fields
+	}
+	if role.isCloudToken() {
+		fields["target"] = roleTargetCloudToken
+		fields["cloud_token_id"] = role.CloudTokenID
+		return fields
+	}
+	if role.isSEMPRPC() {
+		fields["target"] = roleTargetSEMPRPC
+		fields["semp_rpc_template"] = role.SEMPRPCTemplate
+		fields["semp_rpc_username"] = role.SEMPRPCUsername
+		return fields
+	}
+	if role.isOAuthProfile() {
+		fields["target"] = roleTargetOAuthProfile
+		fields["msg_vpn"] = role.MsgVPN
+		fields["oauth_profile"] = role.OAuthProfile
+		return fields
+	}
+	fields["broker_group"] = role.BrokerGroup
+	// A templated role's username comes from its template, so a patch
+	// that leaves both alone keeps the rendered username.
+	if role.UsernameTemplate != "" {
+		fields["username_template"] = role.UsernameTemplate
+	} else {
+		fields["cli_username"] = role.CLIUsername
+	}
+	fields["create_if_missing"] = role.CreateIfMissing
+	fields["global_access_level"] = role.GlobalAccessLevel
+	fields["vpn_access_level"] = role.VPNAccessLevel
+	exceptions := make(map[string]string, len(role.VPNAccessLevelExceptions))
+	for vpn, level := range role.VPNAccessLevelExceptions {
+		exceptions[vpn] = level
 	}
+	fields["vpn_access_level_exceptions"] = exceptions
+	fields["monitor"] = role.Monitor
+	fields["disable_during_rotation"] = role.DisableDuringRotation
+	fields["terminate_sessions"] = role.TerminateSessions
+	return fields
+}
+
+func (b *solaceBackend) pathRolesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	purge := d.Get("purge_history").(bool)
 
-	return nil, nil
+	// Hold the role lock so a rotation in progress does not store a secret
+	// for the role after it is deleted.
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return nil, b.removeRole(ctx, req.Storage, name, purge)
+}
+
+// removeRole deletes a role and its stored secret. Unless purge is set, the
+// role and its last credential are retained first.
+func (b *solaceBackend) removeRole(ctx context.Context, s logical.Storage, name string, purge bool) error {
+	if !purge {
+		role, err := getRole(ctx, s, name)
+		if err != nil {
+			return err
+		}
+		if role != nil {
+			if err := retainDeletedRole(ctx, s, name, role); err != nil {
+				return fmt.Errorf("retaining credential of role %q: %w", name, err)
+			}
+		}
+	}
+	if err := deleteRole(ctx, s, name); err != nil {
+		return err
+	}
+	b.unscheduleRole(name)
+	b.sendEvent(ctx, eventRoleDelete, "role", name, "purge_history", fmt.Sprint(purge))
+	return nil
 }
 
 func (b *solaceBackend) pathRolesList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	roles, err := listRoles(ctx, req.Storage)
+	broker := d.Get("broker").(string)
+
+	var roles []string
+	var err error
+	if broker == "" {
+		roles, err = listRoles(ctx, req.Storage)
+	} else {
+		roles, err = listRolesOnBroker(ctx, req.Storage, broker)
+	}
 	if err != nil {
 		return nil, err
 	}
+	// Only a detailed list reads the roles themselves, so a plain one costs
+	// a single storage list however many roles the mount has.
+	if !d.Get("detailed").(bool) {
+		return logical.ListResponse(roles), nil
+	}
+
+	now := time.Now()
+	keys := make([]string, 0, len(roles))
+	keyInfo := make(map[string]interface{}, len(roles))
+	for _, name := range roles {
+		role, err := getRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+		keys = append(keys, name)
+		keyInfo[name] = map[string]interface{}{
+			"non_compliant": roleNonCompliant(role, now),
+		}
+	}
 
-	return logical.ListResponse(roles), nil
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+// listRolesOnBroker returns, sorted, the names of the roles whose credential
+// lives on broker: those that reference it, and those on the groups it
+// belongs to. It reads the role indexes rather than the roles.
+func listRolesOnBroker(ctx context.Context, s logical.Storage, broker string) ([]string, error) {
+	roles, err := listBrokerRoles(ctx, s, broker)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := brokerGroups(ctx, s, broker)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		groupRoles, err := listGroupRoles(ctx, s, group)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, groupRoles...)
+	}
+	sort.Strings(roles)
+	return roles, nil
 }
@@ -0,0 +1,135 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRolesBulkDelete(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "roles/bulk-delete$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "bulk-delete",
+				OperationSuffix: "roles",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"broker": {
+					Type:        framework.TypeString,
+					Description: "Delete the roles on this broker. Roles on a broker group are not matched; use broker_group for those.",
+				},
+				"broker_group": {
+					Type:        framework.TypeString,
+					Description: "Delete the roles on this broker group.",
+				},
+				"confirm": {
+					Type:        framework.TypeString,
+					Description: "Token returned by a request without confirm. Roles are deleted only if it still matches the roles the filter selects.",
+				},
+				"purge_history": {
+					Type:        framework.TypeBool,
+					Description: "Destroy the deleted roles' stored credentials at once. By default each role and its last credential are retained, as on a single delete with purge_history=false.",
+					Default:     false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathRolesBulkDeleteWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"roles":   {Type: framework.TypeStringSlice, Description: "Roles the filter selects."},
+								"confirm": {Type: framework.TypeString, Description: "Token to send back to delete the roles; only returned when nothing was deleted."},
+								"deleted": {Type: framework.TypeBool, Description: "Whether the roles were deleted."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Delete every role on a broker or broker group.",
+			HelpDescription: "Without confirm, lists the roles the filter selects and returns a confirm token, deleting nothing. With that token, deletes them all in one request. If the selected roles changed in between, the token no longer matches and nothing is deleted. Credentials on the broker are left as they are; use decommission to change them first.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathRolesBulkDeleteWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	broker := d.Get("broker").(string)
+	group := d.Get("broker_group").(string)
+	confirm := d.Get("confirm").(string)
+	purge := d.Get("purge_history").(bool)
+
+	if (broker == "") == (group == "") {
+		return logical.ErrorResponse("exactly one of broker and broker_group is required"), nil
+	}
+
+	var matched []string
+	var err error
+	if broker != "" {
+		matched, err = listBrokerRoles(ctx, req.Storage, broker)
+	} else {
+		matched, err = listGroupRoles(ctx, req.Storage, group)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if matched == nil {
+		matched = []string{}
+	}
+	sort.Strings(matched)
+	token := bulkDeleteToken(broker, group, matched)
+
+	if confirm == "" {
+		data := map[string]interface{}{
+			"roles":   matched,
+			"deleted": false,
+		}
+		if len(matched) > 0 {
+			data["confirm"] = token
+		}
+		return &logical.Response{Data: data}, nil
+	}
+	if confirm != token {
+		return logical.ErrorResponse("confirm does not match the roles selected now; request a new token and review its roles"), nil
+	}
+
+	for _, name := range matched {
+		lock := b.roleLock(name)
+		lock.Lock()
+		err := b.removeRole(ctx, req.Storage, name, purge)
+		lock.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b.Logger().Info("roles deleted in bulk",
+		"broker", broker,
+		"broker_group", group,
+		"roles", len(matched),
+		"purge_history", purge,
+	)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"roles":   matched,
+			"deleted": true,
+		},
+	}, nil
+}
+
+// bulkDeleteToken identifies a bulk delete's filter and the roles it selects,
+// so a confirmation only applies to the set the caller reviewed.
+func bulkDeleteToken(broker, group string, roles []string) string {
+	sum := sha256.Sum256([]byte(broker + "\x00" + group + "\x00" + strings.Join(roles, "\n")))
+	return hex.EncodeToString(sum[:16])
+}
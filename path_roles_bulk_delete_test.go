@@ -0,0 +1,82 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathRolesBulkDelete(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	writeBroker(t, b, storage, "east")
+	writeBroker(t, b, storage, "west")
+
+	write := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		return resp
+	}
+	write("config/broker-groups/dr", map[string]interface{}{"brokers": "east,west"})
+	write("roles/east-a", map[string]interface{}{"broker": "east", "cli_username": "a"})
+	write("roles/east-b", map[string]interface{}{"broker": "east", "cli_username": "b"})
+	write("roles/west", map[string]interface{}{"broker": "west", "cli_username": "c"})
+	write("roles/shared", map[string]interface{}{"broker_group": "dr", "cli_username": "d"})
+	for _, name := range []string{"east-a", "shared"} {
+		putRoleSecret(ctx, storage, name, &RoleSecret{Password: "Password-" + name})
+	}
+
+	if resp := write("roles/bulk-delete", nil); resp == nil || !resp.IsError() {
+		t.Errorf("expected error without a filter, got %v", resp)
+	}
+
+	preview := write("roles/bulk-delete", map[string]interface{}{"broker": "east"})
+	if preview.IsError() || preview.Data["deleted"] != false {
+		t.Fatalf("preview = %v", preview)
+	}
+	if roles := preview.Data["roles"]; !reflect.DeepEqual(roles, []string{"east-a", "east-b"}) {
+		t.Fatalf("roles = %v, want east-a and east-b", roles)
+	}
+	token := preview.Data["confirm"].(string)
+
+	// A role added after the preview invalidates its token.
+	write("roles/east-c", map[string]interface{}{"broker": "east", "cli_username": "e"})
+	if resp := write("roles/bulk-delete", map[string]interface{}{"broker": "east", "confirm": token}); resp == nil || !resp.IsError() {
+		t.Fatalf("expected a stale token to be rejected, got %v", resp)
+	}
+	if role, _ := getRole(ctx, storage, "east-a"); role == nil {
+		t.Fatal("stale token deleted a role")
+	}
+
+	token = write("roles/bulk-delete", map[string]interface{}{"broker": "east"}).Data["confirm"].(string)
+	resp := write("roles/bulk-delete", map[string]interface{}{"broker": "east", "confirm": token})
+	if resp.IsError() || resp.Data["deleted"] != true {
+		t.Fatalf("bulk delete = %v", resp)
+	}
+	roles, _ := listRoles(ctx, storage)
+	if !reflect.DeepEqual(roles, []string{"shared", "west"}) {
+		t.Errorf("roles left = %v, want shared and west", roles)
+	}
+	if retained, _ := getRetained(ctx, storage, "east-a"); retained == nil {
+		t.Error("expected a bulk delete to retain the roles by default")
+	}
+
+	token = write("roles/bulk-delete", map[string]interface{}{"broker_group": "dr"}).Data["confirm"].(string)
+	resp = write("roles/bulk-delete", map[string]interface{}{"broker_group": "dr", "confirm": token, "purge_history": true})
+	if resp.IsError() || !reflect.DeepEqual(resp.Data["roles"], []string{"shared"}) {
+		t.Fatalf("bulk delete by group = %v", resp)
+	}
+	if retained, _ := getRetained(ctx, storage, "shared"); retained != nil {
+		t.Error("expected purge_history to destroy the role's credential")
+	}
+}
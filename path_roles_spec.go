@@ -0,0 +1,297 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// roleSpecVersion is the version of the role spec schema exports write and
+// imports accept. It changes only if a spec's meaning would.
+const roleSpecVersion = 1
+
+// roleSpec is a role's definition as exported and imported: the fields a
+// role write takes, without its credential or rotation history, so specs
+// can be kept in version control and diffed.
+type roleSpec struct {
+	SpecVersion int                    `json:"spec_version"`
+	Name        string                 `json:"name"`
+	Role        map[string]interface{} `json:"role"`
+}
+
+// nonSpecRoleFields are role write fields a spec may not set: the role's
+// name, which the spec carries itself, options of a single write, and the
+// imported credential, which never leaves Vault in a spec.
+var nonSpecRoleFields = map[string]bool{
+	"name":             true,
+	"current_password": true,
+	"last_rotated":     true,
+	"dry_run":          true,
+	"purge_history":    true,
+}
+
+// Outcomes of importing a role spec.
+const (
+	roleImportCreated   = "created"
+	roleImportUpdated   = "updated"
+	roleImportUnchanged = "unchanged"
+)
+
+func pathRolesSpec(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "roles/" + framework.GenericNameRegex("name") + "/export$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "export",
+				OperationSuffix: "role",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role to export.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathRoleExportRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"spec": {Type: framework.TypeMap, Description: "The role's spec: spec_version, name, and role, its configuration as a role write takes it."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Export a role's definition as a spec.",
+			HelpDescription: "Returns the role's configuration as a spec that roles/import accepts. The spec holds no credential and none of the role's rotation history, so it only changes when the role's configuration does.",
+		},
+		{
+			Pattern: "roles/export$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "export",
+				OperationSuffix: "roles",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathRolesExportRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"specs": {Type: framework.TypeSlice, Description: "Spec of every role, in name order."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Export every role's definition as a spec.",
+			HelpDescription: "Returns the spec of every role, in name order, as roles/import accepts them.",
+		},
+		{
+			Pattern: "roles/import$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "import",
+				OperationSuffix: "roles",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"spec": {
+					Type:        framework.TypeMap,
+					Description: "Spec of one role to create or update, as roles/:name/export returns it.",
+				},
+				"specs": {
+					Type:        framework.TypeSlice,
+					Description: "Specs of several roles to create or update, as roles/export returns them.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathRolesImportWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"roles":  {Type: framework.TypeMap, Description: "Outcome for each role imported: created, updated or unchanged."},
+								"errors": {Type: framework.TypeMap, Description: "Why each role that could not be imported was refused."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Create or update roles from specs.",
+			HelpDescription: "Writes each spec as a role write would, with the same validation, and reports whether the role was created, updated or left unchanged. Specs are checked before any is written, so a malformed spec imports nothing; a role that fails validation is reported under errors without holding back the others. Roles not named in the specs are left alone.",
+		},
+	}
+}
+
+// exportRoleSpec returns the spec of a role.
+func exportRoleSpec(name string, role *RoleEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"spec_version": roleSpecVersion,
+		"name":         name,
+		"role":         roleFields(role),
+	}
+}
+
+func (b *solaceBackend) pathRoleExportRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role %q not found", name), nil
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"spec": exportRoleSpec(name, role),
+		},
+	}, nil
+}
+
+func (b *solaceBackend) pathRolesExportRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	names, err := listRoles(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	specs := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		role, err := getRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+		specs = append(specs, exportRoleSpec(name, role))
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"specs": specs,
+		},
+	}, nil
+}
+
+func (b *solaceBackend) pathRolesImportWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	raw := d.Get("specs").([]interface{})
+	if spec, ok := d.GetOk("spec"); ok {
+		if len(raw) > 0 {
+			return logical.ErrorResponse("only one of spec and specs can be set"), nil
+		}
+		raw = []interface{}{spec}
+	}
+	if len(raw) == 0 {
+		return logical.ErrorResponse("spec or specs is required"), nil
+	}
+
+	schema := roleFieldSchema()
+	specs := make([]*roleSpec, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for i, r := range raw {
+		spec, err := parseRoleSpec(r, schema)
+		if err != nil {
+			return logical.ErrorResponse("spec %d: %s", i, err), nil
+		}
+		if seen[spec.Name] {
+			return logical.ErrorResponse("spec %d: role %q is specified more than once", i, spec.Name), nil
+		}
+		seen[spec.Name] = true
+		specs = append(specs, spec)
+	}
+
+	outcomes := make(map[string]interface{}, len(specs))
+	failures := make(map[string]interface{})
+	resp := &logical.Response{}
+	for _, spec := range specs {
+		existing, err := getRole(ctx, req.Storage, spec.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		fields := make(map[string]interface{}, len(spec.Role)+1)
+		for k, v := range spec.Role {
+			fields[k] = v
+		}
+		fields["name"] = spec.Name
+		written, err := b.pathRolesWrite(ctx, req, &framework.FieldData{Raw: fields, Schema: schema})
+		if err != nil {
+			return nil, fmt.Errorf("importing role %q: %w", spec.Name, err)
+		}
+		if written != nil && written.IsError() {
+			failures[spec.Name] = written.Error().Error()
+			continue
+		}
+		if written != nil {
+			for _, warning := range written.Warnings {
+				resp.AddWarning(fmt.Sprintf("role %q: %s", spec.Name, warning))
+			}
+		}
+
+		outcome := roleImportCreated
+		if existing != nil {
+			imported, err := getRole(ctx, req.Storage, spec.Name)
+			if err != nil {
+				return nil, err
+			}
+			outcome = roleImportUpdated
+			if imported != nil && reflect.DeepEqual(roleFields(existing), roleFields(imported)) {
+				outcome = roleImportUnchanged
+			}
+		}
+		outcomes[spec.Name] = outcome
+	}
+
+	b.Logger().Info("roles imported from specs",
+		"roles", len(outcomes),
+		"refused", len(failures),
+	)
+	resp.Data = map[string]interface{}{
+		"roles":  outcomes,
+		"errors": failures,
+	}
+	return resp, nil
+}
+
+// parseRoleSpec checks that raw is a role spec of a version this plugin
+// reads, naming a valid role and setting only fields a role write takes.
+func parseRoleSpec(raw interface{}, schema map[string]*framework.FieldSchema) (*roleSpec, error) {
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var spec roleSpec
+	if err := json.Unmarshal(encoded, &spec); err != nil {
+		return nil, fmt.Errorf("not a role spec: %w", err)
+	}
+	if spec.SpecVersion != roleSpecVersion {
+		return nil, fmt.Errorf("spec_version must be %d, got %d", roleSpecVersion, spec.SpecVersion)
+	}
+	if err := validateNameReference("role", spec.Name); err != nil {
+		return nil, err
+	}
+	if len(spec.Role) == 0 {
+		return nil, fmt.Errorf("role %q has no role configuration", spec.Name)
+	}
+	for field := range spec.Role {
+		if _, ok := schema[field]; !ok || nonSpecRoleFields[field] {
+			return nil, fmt.Errorf("role %q: %q cannot be set in a spec", spec.Name, field)
+		}
+	}
+	return &spec, nil
+}
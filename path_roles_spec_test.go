@@ -0,0 +1,107 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathRolesSpec_ExportImport(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	writeBroker(t, b, storage, "east")
+
+	do := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("%s %s: %v", op, path, err)
+		}
+		return resp
+	}
+	do(logical.UpdateOperation, "roles/app", map[string]interface{}{"broker": "east", "cli_username": "app", "rotation_period": 3600})
+	do(logical.UpdateOperation, "roles/monitor", map[string]interface{}{"broker": "east", "cli_username": "mon", "monitor": true})
+	if err := putRoleSecret(ctx, storage, "app", &RoleSecret{Password: "app-Password-0123456789", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Specs travel as JSON, so round-trip them as a pipeline would.
+	var specs []interface{}
+	encoded, _ := json.Marshal(do(logical.ReadOperation, "roles/export", nil).Data["specs"])
+	if err := json.Unmarshal(encoded, &specs); err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 || specs[0].(map[string]interface{})["name"] != "app" {
+		t.Fatalf("specs = %v, want app and monitor in order", specs)
+	}
+	if strings.Contains(string(encoded), "app-Password") {
+		t.Error("export contains the role's password")
+	}
+	single := do(logical.ReadOperation, "roles/app/export", nil).Data["spec"]
+	if !reflect.DeepEqual(single, exportRoleSpec("app", mustGetRole(t, storage, "app"))) {
+		t.Errorf("roles/app/export = %v, want the same spec as roles/export", single)
+	}
+
+	// Reimporting the export changes nothing; a spec for a deleted role
+	// brings it back; an edited spec updates its role.
+	do(logical.DeleteOperation, "roles/monitor", nil)
+	specs[0].(map[string]interface{})["role"].(map[string]interface{})["rotation_period"] = 7200
+	resp := do(logical.UpdateOperation, "roles/import", map[string]interface{}{"specs": specs})
+	if resp.IsError() {
+		t.Fatalf("import: %v", resp.Error())
+	}
+	want := map[string]interface{}{"app": roleImportUpdated, "monitor": roleImportCreated}
+	if !reflect.DeepEqual(resp.Data["roles"], want) {
+		t.Errorf("roles = %v, want %v", resp.Data["roles"], want)
+	}
+	if role := mustGetRole(t, storage, "monitor"); !role.Monitor || role.CLIUsername != "mon" {
+		t.Errorf("reimported role = %+v", role)
+	}
+	if secret, _ := getRoleSecret(ctx, storage, "app"); secret == nil || secret.Password != "app-Password-0123456789" {
+		t.Error("importing a role changed its stored password")
+	}
+	resp = do(logical.UpdateOperation, "roles/import", map[string]interface{}{"spec": specs[1]})
+	if resp.IsError() || resp.Data["roles"].(map[string]interface{})["monitor"] != roleImportUnchanged {
+		t.Errorf("reimport = %v, want monitor unchanged", resp)
+	}
+
+	// A spec the role write refuses is reported without holding back the
+	// others; a malformed spec imports nothing.
+	bad := map[string]interface{}{"spec_version": 1, "name": "lost", "role": map[string]interface{}{"broker": "missing", "cli_username": "x"}}
+	resp = do(logical.UpdateOperation, "roles/import", map[string]interface{}{"specs": []interface{}{bad, specs[1]}})
+	if failures := resp.Data["errors"].(map[string]interface{}); failures["lost"] == nil || resp.Data["roles"].(map[string]interface{})["monitor"] == nil {
+		t.Errorf("import with a refused role = %v", resp.Data)
+	}
+	for name, spec := range map[string]map[string]interface{}{
+		"unknown version": {"spec_version": 2, "name": "x", "role": map[string]interface{}{"broker": "east", "cli_username": "x"}},
+		"password":        {"spec_version": 1, "name": "x", "role": map[string]interface{}{"broker": "east", "cli_username": "x", "current_password": "p"}},
+		"unknown field":   {"spec_version": 1, "name": "x", "role": map[string]interface{}{"broker": "east", "cli_username": "x", "colour": "red"}},
+		"bad name":        {"spec_version": 1, "name": "../x", "role": map[string]interface{}{"broker": "east", "cli_username": "x"}},
+	} {
+		resp := do(logical.UpdateOperation, "roles/import", map[string]interface{}{"specs": []interface{}{specs[1], spec}})
+		if !resp.IsError() {
+			t.Errorf("%s: import = %v, want an error", name, resp.Data)
+		}
+	}
+	if resp := do(logical.UpdateOperation, "roles/import", map[string]interface{}{"specs": []interface{}{specs[1], specs[1]}}); !resp.IsError() {
+		t.Error("import of the same role twice succeeded")
+	}
+}
+
+func mustGetRole(t *testing.T, storage logical.Storage, name string) *RoleEntry {
+	t.Helper()
+	role, err := getRole(context.Background(), storage, name)
+	if err != nil || role == nil {
+		t.Fatalf("role %q: %v", name, err)
+	}
+	return role
+}
@@ -2,7 +2,9 @@ package solacevaultplugin
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -113,6 +115,54 @@ func TestPathRoles_WriteReadDeleteList(t *testing.T) {
 	}
 }
 
+func TestPathRoles_ListByBroker(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	writeBroker(t, b, storage, "east")
+	writeBroker(t, b, storage, "west")
+
+	for _, w := range []struct {
+		path string
+		data map[string]interface{}
+	}{
+		{"config/broker-groups/dr", map[string]interface{}{"brokers": "east,west"}},
+		{"roles/east-only", map[string]interface{}{"broker": "east", "cli_username": "a"}},
+		{"roles/west-only", map[string]interface{}{"broker": "west", "cli_username": "b"}},
+		{"roles/shared", map[string]interface{}{"broker_group": "dr", "cli_username": "c"}},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      w.path,
+			Storage:   storage,
+			Data:      w.data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("write %s: err=%v, resp=%v", w.path, err, resp)
+		}
+	}
+
+	for broker, want := range map[string]string{
+		"":      "east-only,shared,west-only",
+		"east":  "east-only,shared",
+		"west":  "shared,west-only",
+		"north": "",
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "roles/",
+			Storage:   storage,
+			Data:      map[string]interface{}{"broker": broker},
+		})
+		if err != nil || resp.IsError() {
+			t.Fatalf("list broker=%q: err=%v, resp=%v", broker, err, resp)
+		}
+		keys, _ := resp.Data["keys"].([]string)
+		if got := strings.Join(keys, ","); got != want {
+			t.Errorf("list broker=%q = %q, want %q", broker, got, want)
+		}
+	}
+}
+
 func TestPathRoles_BrokerNotFound(t *testing.T) {
 	b, storage := getTestBackend(t)
 	ctx := context.Background()
@@ -210,3 +260,470 @@ func TestPathRoles_PasswordLengthValidation(t *testing.T) {
 		t.Errorf("password_length = %v, want %d (default)", resp.Data["password_length"], defaultPasswordLength)
 	}
 }
+
+func TestPathRoles_MaxPasswordAgeValidation(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeBroker(t, b, storage, "test-broker")
+
+	tests := []struct {
+		name           string
+		maxPasswordAge int
+		wantError      bool
+	}{
+		{"negative rejected", -1, true},
+		{"below rotation_period rejected", 3599, true},
+		{"equal to rotation_period accepted", 3600, false},
+		{"disabled accepted", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "roles/age-test",
+				Storage:   storage,
+				Data: map[string]interface{}{
+					"broker":           "test-broker",
+					"cli_username":     "test",
+					"rotation_period":  3600,
+					"max_password_age": tt.maxPasswordAge,
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotError := resp != nil && resp.IsError(); gotError != tt.wantError {
+				t.Errorf("max_password_age=%d: error = %v, want %v (resp=%v)", tt.maxPasswordAge, gotError, tt.wantError, resp)
+			}
+		})
+	}
+}
+
+func TestPathRoles_GlobalAccessLevelValidation(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeBroker(t, b, storage, "test-broker")
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/bad-level",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":              "test-broker",
+			"cli_username":        "test",
+			"create_if_missing":   true,
+			"global_access_level": "superuser",
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Error("expected error for invalid global_access_level")
+	}
+}
+
+func TestPathRoles_VPNAccessLevels(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeBroker(t, b, storage, "test-broker")
+
+	for name, data := range map[string]map[string]interface{}{
+		"bad level":           {"vpn_access_level": "superuser"},
+		"bad exception":       {"vpn_access_level_exceptions": "prod=superuser"},
+		"monitor level":       {"monitor": true, "vpn_access_level": "read-write"},
+		"monitor exception":   {"monitor": true, "vpn_access_level_exceptions": "prod=admin"},
+		"not a cli_user role": {"target": roleTargetOAuthProfile, "oauth_profile": "idp", "cli_username": "", "vpn_access_level": "read-only"},
+	} {
+		data["broker"] = "test-broker"
+		if _, ok := data["cli_username"]; !ok {
+			data["cli_username"] = "test"
+		}
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/bad-vpn-level",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Errorf("%s: expected error, got err=%v, resp=%v", name, err, resp)
+		}
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/scoped",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":                      "test-broker",
+			"cli_username":                "ops",
+			"create_if_missing":           true,
+			"global_access_level":         "read-only",
+			"vpn_access_level":            "none",
+			"vpn_access_level_exceptions": []interface{}{"prod=read-write", "stage=read-only"},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create role: err=%v, resp=%v", err, resp)
+	}
+	role, _ := getRole(ctx, storage, "scoped")
+	access := role.access()
+	if access.VPNAccessLevel != "none" || access.VPNAccessLevelExceptions["prod"] != "read-write" || access.VPNAccessLevelExceptions["stage"] != "read-only" {
+		t.Errorf("access = %+v", access)
+	}
+}
+
+func TestPathRoles_Patch(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeBroker(t, b, storage, "test-broker")
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":          "test-broker",
+			"cli_username":    "monitor",
+			"rotation_period": 3600,
+			"password_length": 40,
+		},
+	}
+	if resp, err := b.HandleRequest(ctx, req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create: err=%v, resp=%v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"rotation_period": 7200,
+		},
+	}
+	if resp, err := b.HandleRequest(ctx, req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("patch: err=%v, resp=%v", err, resp)
+	}
+
+	role, _ := getRole(ctx, storage, "test-role")
+	if role.RotationPeriod != 2*time.Hour {
+		t.Errorf("rotation_period = %s, want 2h", role.RotationPeriod)
+	}
+	if role.PasswordLength != 40 || role.CLIUsername != "monitor" || role.Broker != "test-broker" {
+		t.Errorf("patch changed unrelated fields: %+v", role)
+	}
+
+	// Patched values are validated like a full write
+	req.Data = map[string]interface{}{"password_length": 8}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Error("expected error for invalid patched password_length")
+	}
+
+	req.Path = "roles/missing"
+	req.Data = map[string]interface{}{"rotation_period": 60}
+	resp, err = b.HandleRequest(ctx, req)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Error("expected patching a missing role to fail")
+	}
+}
+
+func TestPathRoles_WaitForRoleLock(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	writeBroker(t, b, storage, "test-broker")
+	for _, req := range []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "roles/test-role",
+			Data:      map[string]interface{}{"broker": "test-broker", "cli_username": "monitor"},
+		},
+		{
+			Operation: logical.PatchOperation,
+			Path:      "roles/test-role",
+			Data:      map[string]interface{}{"rotation_period": 7200},
+		},
+		{
+			Operation: logical.DeleteOperation,
+			Path:      "roles/test-role",
+		},
+	} {
+		req.Storage = storage
+		lock := sb.roleLock("test-role")
+		lock.Lock()
+		done := make(chan error, 1)
+		go func() {
+			resp, err := b.HandleRequest(ctx, req)
+			if err == nil && resp != nil && resp.IsError() {
+				err = resp.Error()
+			}
+			done <- err
+		}()
+		select {
+		case <-done:
+			t.Errorf("%s %s did not wait for the role lock", req.Operation, req.Path)
+		case <-time.After(50 * time.Millisecond):
+		}
+		lock.Unlock()
+		if err := <-done; err != nil {
+			t.Fatalf("%s %s: %v", req.Operation, req.Path, err)
+		}
+	}
+}
+
+func TestPathRoles_RotateOnPolicyChange(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	patch := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.PatchOperation,
+			Path:      "roles/test-role",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("patch %v: err=%v, resp=%v", data, err, resp)
+		}
+		return resp
+	}
+
+	// A role that was never rotated has nothing to bring up to date.
+	if resp := patch(map[string]interface{}{"rotate_on_policy_change": true, "password_length": 30}); resp != nil {
+		t.Errorf("patch of a never-rotated role = %v, want no rotation", resp)
+	}
+	if _, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A write that leaves password_length alone does not rotate.
+	if resp := patch(map[string]interface{}{"rotation_period": 3600}); resp != nil {
+		t.Errorf("patch without a length change = %v, want no rotation", resp)
+	}
+
+	resp := patch(map[string]interface{}{"password_length": 40})
+	if resp == nil || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "was rotated") {
+		t.Fatalf("patch with a length change = %v, want a rotation", resp)
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+	role, _ := getRole(ctx, storage, "test-role")
+	if len(secret.Password) != 40 || role.LastRotationTrigger != rotationTriggerPolicyChange {
+		t.Errorf("password length = %d, trigger = %q", len(secret.Password), role.LastRotationTrigger)
+	}
+
+	// The flag is refused where no password is generated.
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/oauth",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":                  "test-broker",
+			"target":                  "oauth_profile",
+			"oauth_profile":           "idp",
+			"rotate_on_policy_change": true,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Errorf("expected an error for an oauth_profile role, got err=%v, resp=%v", err, resp)
+	}
+}
+
+func TestPathRoles_DryRun(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	dryRun := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		data["dry_run"] = true
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/new-role",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("dry run: %v", err)
+		}
+		return resp
+	}
+
+	resp := dryRun(map[string]interface{}{"broker": "test-broker", "cli_username": "ci", "rotation_period": 3600})
+	if resp == nil || resp.IsError() {
+		t.Fatalf("dry run: resp=%v", resp)
+	}
+	if resp.Data["cli_username"] != "ci" || resp.Data["rotation_period"] != 3600 || resp.Data["password_length"] != defaultPasswordLength {
+		t.Errorf("dry run returned %v", resp.Data)
+	}
+	if role, _ := getRole(ctx, storage, "new-role"); role != nil {
+		t.Error("dry run must not store the role")
+	}
+
+	if resp := dryRun(map[string]interface{}{"broker": "missing", "cli_username": "ci"}); !resp.IsError() {
+		t.Errorf("expected dry run against a missing broker to fail, got %v", resp)
+	}
+
+	// With verify_rotation on, the CLI user must exist on the broker, which
+	// here reports no users.
+	if resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/settings",
+		Storage:   storage,
+		Data:      map[string]interface{}{"verify_rotation": true},
+	}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("write settings: err=%v, resp=%v", err, resp)
+	}
+	if resp := dryRun(map[string]interface{}{"broker": "test-broker", "cli_username": "ci"}); !resp.IsError() {
+		t.Errorf("expected dry run for a missing CLI user to fail, got %v", resp)
+	}
+	if resp := dryRun(map[string]interface{}{"broker": "test-broker", "cli_username": "ci", "create_if_missing": true}); resp.IsError() {
+		t.Errorf("dry run for a CLI user rotation creates: %v", resp)
+	}
+}
+
+func TestPathRoles_UsernameTemplate(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	writeBroker(t, b, storage, "test-broker")
+
+	displayName := "token-ci"
+	write := func(op logical.Operation, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation:   op,
+			Path:        "roles/App-Monitor",
+			Storage:     storage,
+			Data:        data,
+			DisplayName: displayName,
+		})
+		if err != nil {
+			t.Fatalf("write role: %v", err)
+		}
+		return resp
+	}
+
+	tmpl := `{{ .RoleName | lowercase }}-{{ .DisplayName }}-{{ unix_time }}`
+	if resp := write(logical.CreateOperation, map[string]interface{}{"broker": "test-broker", "username_template": tmpl}); resp != nil && resp.IsError() {
+		t.Fatalf("create: %v", resp)
+	}
+	role, _ := getRole(ctx, storage, "App-Monitor")
+	if !strings.HasPrefix(role.CLIUsername, "app-monitor-token-ci-") || role.UsernameTemplate != tmpl {
+		t.Fatalf("cli_username = %q, username_template = %q", role.CLIUsername, role.UsernameTemplate)
+	}
+	rendered := role.CLIUsername
+	resp, err := b.HandleRequest(ctx, &logical.Request{Operation: logical.ReadOperation, Path: "roles/App-Monitor", Storage: storage})
+	if err != nil || resp == nil || resp.Data["cli_username"] != rendered || resp.Data["username_template"] != tmpl {
+		t.Errorf("read: err=%v, resp=%v", err, resp)
+	}
+
+	// Later writes and patches keep the rendered username, even by another
+	// token.
+	displayName = "token-ops"
+	if resp := write(logical.UpdateOperation, map[string]interface{}{"broker": "test-broker", "username_template": tmpl, "rotation_period": 3600}); resp != nil && resp.IsError() {
+		t.Fatalf("update: %v", resp)
+	}
+	if resp := write(logical.PatchOperation, map[string]interface{}{"rotation_period": 7200}); resp != nil && resp.IsError() {
+		t.Fatalf("patch: %v", resp)
+	}
+	if role, _ := getRole(ctx, storage, "App-Monitor"); role.CLIUsername != rendered {
+		t.Errorf("cli_username = %q after rewrites, want %q", role.CLIUsername, rendered)
+	}
+
+	// Changing the template renders it again.
+	if resp := write(logical.PatchOperation, map[string]interface{}{"username_template": "{{ .RoleName | uppercase }}"}); resp != nil && resp.IsError() {
+		t.Fatalf("patch template: %v", resp)
+	}
+	if role, _ := getRole(ctx, storage, "App-Monitor"); role.CLIUsername != "APP-MONITOR" {
+		t.Errorf("cli_username = %q after a template change, want APP-MONITOR", role.CLIUsername)
+	}
+
+	for name, data := range map[string]map[string]interface{}{
+		"both set":       {"broker": "test-broker", "cli_username": "app", "username_template": tmpl},
+		"bad template":   {"broker": "test-broker", "username_template": "{{ .RoleName"},
+		"empty username": {"broker": "test-broker", "username_template": "{{ .BrokerGroup }}"},
+	} {
+		if resp := write(logical.UpdateOperation, data); resp == nil || !resp.IsError() {
+			t.Errorf("%s: expected an error, got %v", name, resp)
+		}
+	}
+}
+
+func TestPathRoles_ImportCurrentPassword(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeBroker(t, b, storage, "test-broker")
+	const imported = "Legacy-Store-Passw0rd"
+	lastRotated := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+
+	write := func(op logical.Operation, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		data["broker"] = "test-broker"
+		data["cli_username"] = "legacy"
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: op,
+			Path:      "roles/imported",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("write role: %v", err)
+		}
+		return resp
+	}
+
+	data := map[string]interface{}{"current_password": imported, "last_rotated": lastRotated.Format(time.RFC3339)}
+	if resp := write(logical.CreateOperation, data); resp == nil || !resp.IsError() {
+		t.Fatal("expected import to be refused while supplied passwords are disabled")
+	}
+
+	settings, _ := getSettings(ctx, storage)
+	settings.AllowSuppliedPasswords = true
+	putSettings(ctx, storage, settings)
+
+	for name, data := range map[string]map[string]interface{}{
+		"forbidden chars":        {"current_password": "Legacy:Store;Password"},
+		"future last_rotated":    {"current_password": imported, "last_rotated": time.Now().Add(time.Hour).Format(time.RFC3339)},
+		"last_rotated by itself": {"last_rotated": lastRotated.Format(time.RFC3339)},
+	} {
+		if resp := write(logical.CreateOperation, data); resp == nil || !resp.IsError() {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if resp := write(logical.CreateOperation, data); resp != nil && resp.IsError() {
+		t.Fatalf("import: %v", resp.Error())
+	}
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/imported",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read creds: err=%v, resp=%v", err, resp)
+	}
+	if resp.Data["password"] != imported {
+		t.Errorf("password = %v, want the imported one", resp.Data["password"])
+	}
+	role, _ := getRole(ctx, storage, "imported")
+	if !role.LastRotated.Equal(lastRotated) || role.LastRotationTrigger != rotationTriggerImport {
+		t.Errorf("last_rotated = %s (%s), want %s (import)", role.LastRotated, role.LastRotationTrigger, lastRotated)
+	}
+
+	// Only a new role takes an imported password.
+	if resp := write(logical.UpdateOperation, map[string]interface{}{"current_password": imported + "2"}); resp == nil || !resp.IsError() {
+		t.Error("expected current_password to be refused on an existing role")
+	}
+}
@@ -0,0 +1,97 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRoleTransferOwnership(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "transfer-ownership/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "transfer-ownership",
+				OperationSuffix: "role",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role to transfer.",
+					Required:    true,
+				},
+				"new_owner_entity_id": {
+					Type:        framework.TypeString,
+					Description: "Vault entity ID of the role's new owner.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathRoleTransferOwnershipWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"previous_owner_entity_id": {Type: framework.TypeString, Description: "Entity ID of the role's previous owner."},
+								"owner_entity_id":          {Type: framework.TypeString, Description: "Entity ID of the role's new owner."},
+								"ownership_transferred_at": {Type: framework.TypeTime, Description: "When the role was transferred."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Transfer a role to a new owner.",
+			HelpDescription: "Sets the role's owner_entity_id and records when the transfer was made and by which entity. A role's owner can only be changed here once it is set, so every change of ownership leaves a record.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathRoleTransferOwnershipWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	newOwner := d.Get("new_owner_entity_id").(string)
+	if newOwner == "" {
+		return logical.ErrorResponse("new_owner_entity_id is required"), nil
+	}
+
+	// Hold the role lock so a rotation in progress does not write back the
+	// role it read before the transfer.
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role %q not found", name), nil
+	}
+	if role.OwnerEntityID == newOwner {
+		return logical.ErrorResponse("role %q is already owned by %q", name, newOwner), nil
+	}
+
+	previous := role.OwnerEntityID
+	role.OwnerEntityID = newOwner
+	role.OwnershipTransferredAt = time.Now().UTC()
+	role.OwnershipTransferredBy = req.EntityID
+	if err := putRole(ctx, req.Storage, name, role); err != nil {
+		return nil, err
+	}
+	b.sendEvent(ctx, eventRoleOwnershipTransferred, "role", name, "previous_owner_entity_id", previous,
+		"owner_entity_id", newOwner, "transferred_by_entity_id", req.EntityID)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"previous_owner_entity_id": previous,
+			"owner_entity_id":          newOwner,
+			"ownership_transferred_at": role.OwnershipTransferredAt.Format(time.RFC3339),
+		},
+	}, nil
+}
@@ -0,0 +1,125 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathRoleTransferOwnership(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	settings, _ := getSettings(ctx, storage)
+	settings.MinRotationInterval = 0
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(op logical.Operation, path, entityID string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			EntityID:  entityID,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("%s %s: %v", op, path, err)
+		}
+		return resp
+	}
+	roleData := map[string]interface{}{
+		"broker":                 "test-broker",
+		"cli_username":           "monitor",
+		"owner_entity_id":        "entity-alice",
+		"approver_entity_ids":    "entity-carol",
+		"require_owner_approval": true,
+	}
+	if resp := request(logical.UpdateOperation, "roles/test-role", "", roleData); resp != nil && resp.IsError() {
+		t.Fatalf("write role: %v", resp.Error())
+	}
+
+	// Writing the role again may not quietly hand it to someone else.
+	roleData["owner_entity_id"] = "entity-mallory"
+	if resp := request(logical.UpdateOperation, "roles/test-role", "", roleData); resp == nil || !resp.IsError() {
+		t.Errorf("changing owner_entity_id on a role write: resp=%v, want an error", resp)
+	}
+
+	for name, tc := range map[string]struct {
+		requester, approvedBy string
+	}{
+		"no approval":          {"entity-bob", ""},
+		"approval by stranger": {"entity-bob", "entity-mallory"},
+	} {
+		data := map[string]interface{}{}
+		if tc.approvedBy != "" {
+			data["approved_by"] = tc.approvedBy
+		}
+		if resp := request(logical.UpdateOperation, "rotate-role/test-role", tc.requester, data); resp == nil || !resp.IsError() {
+			t.Errorf("%s: resp=%v, want an error", name, resp)
+		}
+	}
+
+	for _, tc := range []struct {
+		requester, approvedBy, want string
+	}{
+		{"entity-alice", "", "entity-alice"},
+		{"entity-bob", "entity-carol", "entity-carol"},
+	} {
+		data := map[string]interface{}{}
+		if tc.approvedBy != "" {
+			data["approved_by"] = tc.approvedBy
+		}
+		if resp := request(logical.UpdateOperation, "rotate-role/test-role", tc.requester, data); resp == nil || resp.IsError() {
+			t.Fatalf("rotation by %s: resp=%v", tc.requester, resp)
+		}
+		role, _ := getRole(ctx, storage, "test-role")
+		if role.LastRotationApprovedBy != tc.want {
+			t.Errorf("last_rotation_approved_by = %q, want %q", role.LastRotationApprovedBy, tc.want)
+		}
+	}
+
+	if resp := request(logical.UpdateOperation, "transfer-ownership/test-role", "entity-admin",
+		map[string]interface{}{"new_owner_entity_id": "entity-bob"}); resp == nil || resp.IsError() {
+		t.Fatalf("transfer: resp=%v", resp)
+	} else if resp.Data["previous_owner_entity_id"] != "entity-alice" || resp.Data["owner_entity_id"] != "entity-bob" {
+		t.Errorf("transfer response = %v", resp.Data)
+	}
+	resp := request(logical.ReadOperation, "roles/test-role", "", nil)
+	if resp.Data["owner_entity_id"] != "entity-bob" || resp.Data["ownership_transferred_by"] != "entity-admin" ||
+		resp.Data["ownership_transferred_at"] == nil {
+		t.Errorf("role after transfer = %v", resp.Data)
+	}
+
+	// The new owner can rotate the role and the previous one no longer can.
+	if resp := request(logical.UpdateOperation, "rotate-role/test-role", "entity-alice", nil); resp == nil || !resp.IsError() {
+		t.Errorf("rotation by the previous owner: resp=%v, want an error", resp)
+	}
+	if resp := request(logical.UpdateOperation, "rotate-role/test-role", "entity-bob", nil); resp == nil || resp.IsError() {
+		t.Errorf("rotation by the new owner: resp=%v", resp)
+	}
+
+	// Periodic rotations need no approval and clear the last one.
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	if role, _ := getRole(ctx, storage, "test-role"); role.LastRotationApprovedBy != "" {
+		t.Errorf("last_rotation_approved_by = %q after a periodic rotation", role.LastRotationApprovedBy)
+	}
+
+	if resp := request(logical.UpdateOperation, "transfer-ownership/missing", "",
+		map[string]interface{}{"new_owner_entity_id": "entity-bob"}); resp == nil || !resp.IsError() {
+		t.Errorf("transfer of a missing role: resp=%v, want an error", resp)
+	}
+	if resp := request(logical.UpdateOperation, "roles/other", "", map[string]interface{}{
+		"broker":                 "test-broker",
+		"cli_username":           "other",
+		"require_owner_approval": true,
+	}); resp == nil || !resp.IsError() {
+		t.Errorf("require_owner_approval without an owner: resp=%v, want an error", resp)
+	}
+}
@@ -1,8 +1,12 @@
 package solacevaultplugin
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
@@ -16,19 +20,53 @@ func pathRotateRole(b *solaceBackend) []*framework.Path {
 	return []*framework.Path{
 		{
 			Pattern: "rotate-role/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "rotate",
+				OperationSuffix: "role",
+			},
 			Fields: map[string]*framework.FieldSchema{
 				"name": {
 					Type:        framework.TypeString,
 					Description: "Name of the role to rotate.",
 					Required:    true,
 				},
+				"client_secret": {
+					Type:        framework.TypeString,
+					Description: "For oauth_profile roles, the client secret the identity provider issued, to set on the broker in place of the current one.",
+				},
+				"password_length": {
+					Type:        framework.TypeInt,
+					Description: "Length of the password generated by this rotation only, between 16 and 128. Defaults to the role's password_length.",
+				},
+				"password_policy": {
+					Type:        framework.TypeString,
+					Description: "Name of a Vault password policy to generate this rotation's password from, instead of the mount's charset. The password must still meet Solace's length and character rules.",
+				},
+				"approved_by": {
+					Type:        framework.TypeString,
+					Description: "Entity ID of the role's owner or an approver who approved this rotation, for roles with require_owner_approval that are rotated by someone else. Not needed when the owner or an approver rotates the role themselves.",
+				},
+				"password": {
+					Type:        framework.TypeString,
+					Description: "Password to set instead of a generated one, for migrations where it must match another system. Only accepted when the mount's allow_supplied_passwords setting is on.",
+					DisplayAttrs: &framework.DisplayAttributes{
+						Sensitive: true,
+					},
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.CreateOperation: &framework.PathOperation{
-					Callback: b.pathRotateRoleWrite,
+					Callback:                    b.pathRotateRoleWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   rotateResponses,
 				},
 				logical.UpdateOperation: &framework.PathOperation{
-					Callback: b.pathRotateRoleWrite,
+					Callback:                    b.pathRotateRoleWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   rotateResponses,
 				},
 			},
 			ExistenceCheck:  b.pathRotateRoleExistenceCheck,
@@ -38,32 +76,200 @@ func pathRotateRole(b *solaceBackend) []*framework.Path {
 	}
 }
 
+// rotateResponses describes a rotation. A role on a broker group also
+// reports each member's outcome.
+var rotateResponses = map[int][]framework.Response{
+	http.StatusOK: {{
+		Description: "OK",
+		Fields: map[string]*framework.FieldSchema{
+			"last_rotated":               {Type: framework.TypeTime, Description: "When the credential was rotated."},
+			"version":                    {Type: framework.TypeInt, Description: "Version of the new credential, to pass as min_version to creds reads that must see it. Such reads answer 412 until new_credential_served_at."},
+			"new_credential_served_at":   {Type: framework.TypeTime, Description: "For roles with a propagation_delay, when creds/ starts returning the new credential."},
+			"next_manual_rotation_after": {Type: framework.TypeTime, Description: "With a min_rotation_interval, when the role may next be rotated manually."},
+			"sessions_terminated":        {Type: framework.TypeBool, Description: "For roles with terminate_sessions, whether the CLI user's sessions were ended."},
+			"audit_tags":                 {Type: framework.TypeMap, Description: "The role's audit tags, for roles that set any."},
+			"broker_group":               {Type: framework.TypeString, Description: "Name of the role's broker group."},
+			"brokers":                    {Type: framework.TypeMap, Description: "Outcome of the rotation on each member of the group."},
+		},
+	}},
+}
+
+// pathRotateRoleExistenceCheck reports that rotate-role always exists, so
+// that Vault treats every rotation as an update. Rotation acts on a role
+// rather than creating anything, and tying the operation to whether the role
+// exists would make callers such as the Terraform provider need different
+// capabilities for the same request.
 func (b *solaceBackend) pathRotateRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
-	name := d.Get("name").(string)
-	role, err := getRole(ctx, req.Storage, name)
-	if err != nil {
-		return false, err
-	}
-	return role != nil, nil
+	return true, nil
 }
 
 func (b *solaceBackend) pathRotateRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
+	ctx = withMetricsMount(withSEMPRequestID(ctx, req.ID), req.MountPoint)
+
+	// Let Vault forward the request to the node that owns role storage
+	// instead of changing the password somewhere it cannot be saved.
+	if !b.canWriteBrokers() {
+		return nil, logical.ErrReadOnly
+	}
+
+	settings, err := getSettings(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
 
 	role, err := getRole(ctx, req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
-	if role != nil && !role.LastRotated.IsZero() && time.Since(role.LastRotated) < minRotationInterval {
-		return logical.ErrorResponse("role %q was rotated less than %s ago; try again later", name, minRotationInterval), nil
+	// An imported password was not set by a rotation, so it does not hold
+	// the first one back.
+	if role != nil && !role.LastRotated.IsZero() && role.LastRotationTrigger != rotationTriggerImport &&
+		time.Since(role.LastRotated) < settings.MinRotationInterval {
+		return logical.ErrorResponse("role %q was rotated less than %s ago; try again after %s", name, settings.MinRotationInterval,
+			role.LastRotated.Add(settings.MinRotationInterval).Format(time.RFC3339)), nil
+	}
+
+	// An OAuth profile's client secret is issued by the identity provider,
+	// so it is supplied rather than generated.
+	opts := rotationOptions{
+		secret:         []byte(d.Get("client_secret").(string)),
+		password:       []byte(d.Get("password").(string)),
+		passwordLength: d.Get("password_length").(int),
+		passwordPolicy: d.Get("password_policy").(string),
+		actor: rotationActor{
+			trigger:     rotationTriggerManual,
+			displayName: req.DisplayName,
+			entityID:    req.EntityID,
+		},
+	}
+	defer wipe(opts.secret)
+	defer wipe(opts.password)
+	if role != nil {
+		approver, resp := rotationApprover(name, role, req.EntityID, d.Get("approved_by").(string))
+		if resp != nil {
+			return resp, nil
+		}
+		opts.actor.approvedBy = approver
+		switch {
+		case role.isOAuthProfile() && len(opts.secret) == 0:
+			return logical.ErrorResponse("client_secret is required to rotate oauth_profile role %q", name), nil
+		case !role.isOAuthProfile() && len(opts.secret) > 0:
+			return logical.ErrorResponse("client_secret applies only to oauth_profile roles"), nil
+		}
+	}
+	if opts.passwordLength != 0 || opts.passwordPolicy != "" {
+		switch {
+		case role != nil && !role.generatesPassword():
+			return logical.ErrorResponse("password_length and password_policy apply only to roles whose password is generated"), nil
+		case opts.passwordLength != 0 && opts.passwordPolicy != "":
+			return logical.ErrorResponse("password_length cannot be combined with password_policy, which sets its own length"), nil
+		case opts.passwordLength != 0 && (opts.passwordLength < minPasswordLength || opts.passwordLength > maxPasswordLength):
+			return logical.ErrorResponse("password_length must be between %d and %d, got %d", minPasswordLength, maxPasswordLength, opts.passwordLength), nil
+		}
+	}
+	if len(opts.password) > 0 {
+		switch {
+		case !settings.AllowSuppliedPasswords:
+			return logical.ErrorResponse("supplied passwords are disabled; set allow_supplied_passwords in config/settings to allow them"), nil
+		case role != nil && !role.generatesPassword():
+			return logical.ErrorResponse("password applies only to roles whose password is otherwise generated"), nil
+		case opts.passwordLength != 0 || opts.passwordPolicy != "":
+			return logical.ErrorResponse("password cannot be combined with password_length or password_policy"), nil
+		}
+	}
+
+	resp, err := b.rotateRoleWith(ctx, req.Storage, name, opts)
+	if err != nil || resp == nil || resp.IsError() {
+		return resp, err
+	}
+	addAuditTags(resp, role)
+	// Tell the caller up front when the next manual rotation is allowed,
+	// rather than only rejecting it.
+	if settings.MinRotationInterval > 0 {
+		resp.Data["next_manual_rotation_after"] = time.Now().Add(settings.MinRotationInterval).UTC().Format(time.RFC3339)
 	}
+	return resp, nil
+}
+
+// rotationOptions are the parameters a single manual rotation may set.
+type rotationOptions struct {
+	// secret is the credential to set instead of a generated one; required
+	// for oauth_profile roles.
+	secret []byte
+
+	// passwordLength, if set, overrides the role's password_length.
+	passwordLength int
+
+	// passwordPolicy, if set, names the Vault password policy to generate
+	// the password from.
+	passwordPolicy string
+
+	// password, if set, is used as the new password instead of generating
+	// one.
+	password []byte
+
+	// actor is recorded on the role as what triggered the rotation.
+	actor rotationActor
+}
+
+// Rotation triggers, as recorded on roles.
+const (
+	rotationTriggerManual   = "manual"
+	rotationTriggerPeriodic = "periodic"
 
-	return b.rotateRole(ctx, req.Storage, name)
+	// rotationTriggerPolicyChange marks a rotation made because a role
+	// write changed its password_length.
+	rotationTriggerPolicyChange = "policy-change"
+
+	// rotationTriggerImport marks a password imported when its role was
+	// created rather than set by a rotation.
+	rotationTriggerImport = "import"
+)
+
+// rotationActor is who or what triggered a rotation: the periodic function,
+// or the token, by display name and entity ID, of a manual request.
+type rotationActor struct {
+	trigger     string
+	displayName string
+	entityID    string
+
+	// approvedBy is the owner or approver entity that approved a manual
+	// rotation, if any.
+	approvedBy string
+}
+
+// rotationApprover returns the entity approving a manual rotation of a
+// role: the requester if they are its owner or an approver, or else the
+// approved_by they name. Roles with require_owner_approval cannot be
+// rotated without one.
+func rotationApprover(name string, role *RoleEntry, requester, approvedBy string) (string, *logical.Response) {
+	switch {
+	case approvedBy != "" && !role.canApprove(approvedBy):
+		return "", logical.ErrorResponse("approved_by %q is neither the owner nor an approver of role %q", approvedBy, name)
+	case role.canApprove(requester):
+		return requester, nil
+	case approvedBy != "":
+		return approvedBy, nil
+	case role.RequireOwnerApproval:
+		return "", logical.ErrorResponse("role %q requires owner approval; rotate it as its owner or an approver, or set approved_by to one of them", name)
+	}
+	return "", nil
 }
 
+// rotateRole gives a role a newly generated credential on behalf of the
+// periodic function.
 func (b *solaceBackend) rotateRole(ctx context.Context, s logical.Storage, name string) (*logical.Response, error) {
-	b.roleMutex.Lock()
-	defer b.roleMutex.Unlock()
+	return b.rotateRoleWith(ctx, s, name, rotationOptions{actor: rotationActor{trigger: rotationTriggerPeriodic}})
+}
+
+// rotateRoleWith gives a role a new credential: opts.secret, which must be
+// set for oauth_profile roles, or else a generated one, shaped by opts.
+func (b *solaceBackend) rotateRoleWith(ctx context.Context, s logical.Storage, name string, opts rotationOptions) (*logical.Response, error) {
+	ctx = withSEMPActivity(ctx, s)
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
 
 	role, err := getRole(ctx, s, name)
 	if err != nil {
@@ -72,8 +278,11 @@ func (b *solaceBackend) rotateRole(ctx context.Context, s logical.Storage, name
 	if role == nil {
 		return logical.ErrorResponse("role %q not found", name), nil
 	}
+	if role.BrokerGroup != "" {
+		return b.rotateGroupRole(ctx, s, name, role, opts)
+	}
 
-	brokerConfig, err := getBroker(ctx, s, role.Broker)
+	brokerConfig, err := getSessionBroker(ctx, s, role.Broker)
 	if err != nil {
 		return nil, err
 	}
@@ -81,13 +290,111 @@ func (b *solaceBackend) rotateRole(ctx context.Context, s logical.Storage, name
 		return logical.ErrorResponse("broker %q not found for role %q", role.Broker, name), nil
 	}
 
-	newPassword, err := generatePassword(role.PasswordLength)
+	settings, err := getSettings(ctx, s)
 	if err != nil {
-		return nil, fmt.Errorf("generating password: %w", err)
+		return nil, err
+	}
+	stored, err := getRoleSecret(ctx, s, name)
+	if err != nil {
+		return nil, err
+	}
+	var current *credential
+	if !stored.empty() {
+		current = credentialFromSecret(stored)
+		defer current.wipe()
 	}
+	var cred *credential
+	switch {
+	case role.isOAuthProfile():
+		if len(opts.secret) == 0 {
+			return logical.ErrorResponse("oauth_profile role %q can only be rotated with a client_secret from its identity provider", name), nil
+		}
+		if current != nil && subtle.ConstantTimeCompare(opts.secret, current.password) == 1 {
+			return logical.ErrorResponse("client_secret for role %q matches the current secret", name), nil
+		}
+		cred = &credential{password: bytes.Clone(opts.secret)}
+	case role.isCloudToken():
+		// Solace Cloud issues the new value when the token is regenerated
+		// below.
+	default:
+		cred, err = b.generateCredential(ctx, name, role, settings, brokerConfig.excludedPasswordChars(), current, opts)
+		if errors.Is(err, errPasswordRejected) {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("generating credential: %w", err)
+		}
+	}
+	defer func() {
+		cred.wipe()
+	}()
 
-	client := NewSEMPClient(brokerConfig)
-	if err := client.ChangePassword(ctx, role.CLIUsername, newPassword); err != nil {
+	// Once a change is sent, the rotation runs to the end even if the
+	// caller's deadline passes, so a credential the broker took is not left
+	// unstored.
+	var client *SEMPClient
+	if role.isCloudToken() {
+		var token []byte
+		ctx = context.WithoutCancel(ctx)
+		token, err = NewCloudClient(brokerConfig).RegenerateToken(ctx, role.CloudTokenID)
+		cred = &credential{password: token}
+	} else if client, err = b.activeSEMPClient(ctx, role.Broker, brokerConfig); err == nil {
+		var unlock func()
+		if unlock, err = b.acquireRotationLock(ctx, client, role, brokerConfig.RotationLockTTL); err == nil {
+			// Held until the new credential is verified and stored.
+			defer unlock()
+			ctx = context.WithoutCancel(ctx)
+			cred, err = b.applyGeneratedCredential(ctx, client, name, role, settings, brokerConfig.excludedPasswordChars(), current, opts, cred)
+		}
+	}
+	if err != nil {
+		forgetSEMPSessionBroker(ctx, role.Broker)
+		if retryAfter := sempRetryAfter(err); retryAfter > 0 {
+			b.deferBroker(role.Broker, time.Now().Add(retryAfter))
+		}
+		recordRotation(ctx, role.Broker, false)
+		b.sendEvent(ctx, eventRotateFail, "role", name, "broker", role.Broker, "cli_username", role.CLIUsername,
+			"reason", rotationFailureReason(err))
+		if sempErrorClass(err) == sempErrCircuitOpen {
+			return logical.ErrorResponse("broker %q is unavailable after repeated failures; rotation for role %q was not attempted", role.Broker, name), nil
+		}
+		if errors.Is(err, errRotationLocked) {
+			b.Logger().Warn("rotation locked by another Vault cluster; not attempted",
+				"role", name,
+				"broker", role.Broker,
+				"error", err,
+			)
+			return logical.ErrorResponse("rotation of role %q was not attempted: on broker %q its %s", name, role.Broker, err), nil
+		}
+		if errors.Is(err, errPasswordMinAge) {
+			b.postponeRole(name, time.Now().Add(passwordMinAgeRetryDelay))
+			b.Logger().Warn("broker refused to change a password younger than its minimum age; rotation postponed",
+				"role", name,
+				"broker", role.Broker,
+				"retry_after", passwordMinAgeRetryDelay,
+				"error", err,
+			)
+			return logical.ErrorResponse("broker %q refuses to change the password of role %q until it reaches the broker's minimum password age; automatic rotation tries again in %s", role.Broker, name, passwordMinAgeRetryDelay), nil
+		}
+		if errors.Is(err, errPasswordReused) {
+			b.Logger().Error("broker refused every new password as recently used",
+				"role", name,
+				"broker", role.Broker,
+				"error", err,
+			)
+			return logical.ErrorResponse("broker %q refused the new password of role %q as recently used under its password history", role.Broker, name), nil
+		}
+		if errors.Is(err, errMonitorAccessLevel) {
+			return logical.ErrorResponse("CLI user %q of monitor role %q has more than read-only access on broker %q; refusing to rotate it", role.CLIUsername, name, role.Broker), nil
+		}
+		if errors.Is(err, errSEMPAddressNotAllowed) {
+			b.Logger().Error("broker SEMP host resolved outside allowed_semp_networks; refusing to rotate",
+				"role", name,
+				"broker", role.Broker,
+				"error", err,
+			)
+			return logical.ErrorResponse("the SEMP host of broker %q resolved to an address outside its allowed_semp_networks; rotation for role %q was refused", role.Broker, name), nil
+		}
 		b.Logger().Error("SEMP password change failed",
 			"role", name,
 			"cli_username", role.CLIUsername,
@@ -97,18 +404,443 @@ func (b *solaceBackend) rotateRole(ctx context.Context, s logical.Storage, name
 		return logical.ErrorResponse("failed to rotate password for role %q on broker %q", name, role.Broker), nil
 	}
 
-	role.Password = newPassword
-	role.LastRotated = time.Now().UTC()
+	// Storage takes the credential as strings and keeps the marshaled entry
+	// it is given, so neither copy can be wiped; they are the only ones that
+	// outlive the rotation.
+	secret := cred.secret()
+	secret.Version = stored.nextVersion()
 
-	if err := putRole(ctx, s, name, role); err != nil {
+	// Only CLI user rotations can be checked by logging in as the account.
+	var verifyErr error
+	if settings.VerifyRotation && role.isCLIUser() {
+		var oldPassword []byte
+		if current != nil {
+			oldPassword = current.password
+		}
+		verifyErr = verifyRotation(ctx, client, role.CLIUsername, oldPassword, cred.password)
+		if errors.Is(verifyErr, errRotationNotApplied) {
+			b.sendEvent(ctx, eventRotateFail, "role", name, "broker", role.Broker, "cli_username", role.CLIUsername,
+				"reason", "verification")
+			recordRotation(ctx, role.Broker, false)
+
+			// The broker acknowledged the change but kept the previous
+			// password, so that one stays stored; the new one goes to
+			// recovery in case the broker takes it later.
+			recoveryErr := b.saveRecovery(ctx, s, name, role, secret)
+			b.Logger().Error("broker kept the previous password after acknowledging the change; stored password left unchanged",
+				"role", name,
+				"cli_username", role.CLIUsername,
+				"broker", role.Broker,
+				"error", verifyErr,
+				"recovery_error", recoveryErr,
+			)
+			if recoveryErr != nil {
+				return logical.ErrorResponse("rotation of role %q was not applied on broker %q and the new password could not be saved for recovery; manual recovery may be required", name, role.Broker), nil
+			}
+			return logical.ErrorResponse("rotation of role %q was not applied on broker %q; the stored password was left unchanged and the new password saved to %s%s", name, role.Broker, recoveryPrefix, name), nil
+		}
+	}
+
+	resp, err := b.storeRotatedSecret(ctx, s, name, role, secret, opts.actor)
+	if err != nil {
+		return resp, err
+	}
+	if verifyErr != nil {
+		b.flagUnverifiedRotation(ctx, s, name, role, verifyErr, resp)
+	}
+	if role.TerminateSessions {
+		resp.Data["sessions_terminated"] = b.terminateSessions(ctx, client, name, role, resp)
+	}
+	return resp, nil
+}
+
+// flagUnverifiedRotation records that a rotation the broker acknowledged
+// could not be verified. The new password is stored regardless, since the
+// broker said it took it; the role is flagged as drifted so that the drift
+// check, or a rotation or sync, settles which password the broker holds.
+func (b *solaceBackend) flagUnverifiedRotation(ctx context.Context, s logical.Storage, name string, role *RoleEntry, verifyErr error, resp *logical.Response) {
+	b.Logger().Warn("password change acknowledged by broker but could not be verified; new password stored",
+		"role", name,
+		"cli_username", role.CLIUsername,
+		"broker", role.Broker,
+		"error", verifyErr,
+	)
+	resp.AddWarning(fmt.Sprintf("the new password of role %q was stored but could not be verified on broker %q; the role is flagged as drifted until a drift check, rotation or sync confirms it", name, role.Broker))
+	drift := &driftEntry{DetectedAt: time.Now().UTC(), Reason: driftRotationUnverified, Broker: role.Broker}
+	if err := putDrift(ctx, s, name, drift); err != nil {
+		b.Logger().Error("failed to flag unverified rotation as drift", "role", name, "error", err)
+		return
+	}
+	b.sendEvent(ctx, eventDriftDetected, "role", name, "broker", role.Broker, "reason", driftRotationUnverified)
+}
+
+// terminateSessions ends the sessions of a role's CLI user on the broker
+// client reaches, once a rotation has stored the user's new password. A
+// user already shut down is left that way. The password is safe by then,
+// so a failure only adds a warning to resp; it reports whether the
+// sessions were ended.
+func (b *solaceBackend) terminateSessions(ctx context.Context, client *SEMPClient, name string, role *RoleEntry, resp *logical.Response) bool {
+	user, err := client.ShowUsername(ctx, role.CLIUsername)
+	if err == nil && (user == nil || !user.Enabled) {
+		return true
+	}
+	if err == nil {
+		err = client.EndSessions(ctx, role.CLIUsername)
+	}
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, errUserLeftShutdown) {
+		b.Logger().Error("CLI user left shut down while ending its sessions; enable it on the broker",
+			"role", name, "cli_username", role.CLIUsername, "broker", client.Broker, "error", err)
+		resp.AddWarning(fmt.Sprintf("CLI user %q was left shut down on broker %q while ending its sessions; enable it on the broker", role.CLIUsername, client.Broker))
+		return false
+	}
+	b.Logger().Warn("password rotated but the CLI user's sessions could not be ended",
+		"role", name, "cli_username", role.CLIUsername, "broker", client.Broker, "error", err)
+	resp.AddWarning(fmt.Sprintf("sessions of CLI user %q on broker %q could not be ended; they keep the access they had", role.CLIUsername, client.Broker))
+	return false
+}
+
+// storeRotatedSecret stores a credential the broker has accepted as the
+// role's secret and records the rotation, and who triggered it. If storage
+// fails, the credential is kept under recovery/ instead.
+func (b *solaceBackend) storeRotatedSecret(ctx context.Context, s logical.Storage, name string, role *RoleEntry, secret *RoleSecret, actor rotationActor) (*logical.Response, error) {
+	if role.PropagationDelay > 0 {
+		if err := b.keepPreviousSecret(ctx, s, name, role, secret); err != nil {
+			return nil, err
+		}
+	}
+	if err := putRoleSecret(ctx, s, name, secret); err != nil {
+		b.sendEvent(ctx, eventRotateFail, "role", name, "broker", role.location(), "cli_username", role.CLIUsername,
+			"reason", "storage")
+		recordRotation(ctx, role.location(), false)
+
+		// Keep the password somewhere an operator can get it back from,
+		// rather than in the log.
+		recoveryErr := b.saveRecovery(ctx, s, name, role, secret)
+		if recoveryErr == nil {
+			b.Logger().Error("password changed on broker but failed to store in Vault; new password saved for recovery",
+				"role", name,
+				"cli_username", role.CLIUsername,
+				"broker", role.location(),
+				"recovery_path", recoveryPrefix+name,
+				"error", err,
+			)
+			return nil, fmt.Errorf("storing rotated password for %q: broker password was changed but Vault storage failed; read it from %s%s: %w", name, recoveryPrefix, name, err)
+		}
 		b.Logger().Error("password changed on broker but failed to store in Vault; manual recovery required",
 			"role", name,
 			"cli_username", role.CLIUsername,
-			"broker", role.Broker,
+			"broker", role.location(),
 			"error", err,
+			"recovery_error", recoveryErr,
 		)
 		return nil, fmt.Errorf("storing rotated password for %q: broker password was changed but Vault storage failed, manual recovery required: %w", name, err)
 	}
 
-	return nil, nil
+	// A stored secret supersedes any password kept from an earlier failure.
+	if err := deleteRecovery(ctx, s, name); err != nil {
+		b.Logger().Warn("failed to remove superseded recovery entry", "role", name, "error", err)
+	}
+
+	if err := b.recordRotationGeneration(ctx, s, name); err != nil {
+		b.Logger().Warn("password rotated but failed to update storage generation",
+			"role", name,
+			"error", err,
+		)
+	}
+
+	// The credential is safe at this point; a failure here only leaves the
+	// schedule behind, so the role is rotated again sooner than needed.
+	rotatedAt := time.Now()
+	role.LastRotated = rotatedAt.UTC()
+	role.LastRotationTrigger = actor.trigger
+	role.LastRotatedBy = actor.displayName
+	role.LastRotatedByEntity = actor.entityID
+	role.LastRotationApprovedBy = actor.approvedBy
+	if err := putRole(ctx, s, name, role); err != nil {
+		b.Logger().Warn("password rotated but failed to record rotation time",
+			"role", name,
+			"error", err,
+		)
+	}
+	b.unscheduleRole(name)
+	b.recordLocalRotation(name, role, rotatedAt)
+	recordRotation(ctx, role.location(), true)
+	b.sendEvent(ctx, eventRotateSuccess, "role", name, "broker", role.location(), "cli_username", role.CLIUsername,
+		"trigger", actor.trigger, "rotated_by", actor.displayName, "rotated_by_entity_id", actor.entityID,
+		"approved_by", actor.approvedBy)
+	b.notifyRotation(ctx, s, name, role)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"last_rotated": role.LastRotated.Format(time.RFC3339),
+			"version":      secret.Version,
+		},
+	}
+	if secret.Previous != nil {
+		resp.Data["new_credential_served_at"] = secret.ServedFrom.Format(time.RFC3339)
+	}
+	return resp, nil
+}
+
+// keepPreviousSecret makes secret hold the credential it replaces, for
+// creds/ to go on serving until the role's propagation delay has passed.
+// The replaced credential is the one the broker last held, even if it
+// was itself still waiting out a delay.
+func (b *solaceBackend) keepPreviousSecret(ctx context.Context, s logical.Storage, name string, role *RoleEntry, secret *RoleSecret) error {
+	stored, err := getRoleSecret(ctx, s, name)
+	if err != nil {
+		return err
+	}
+	if stored.empty() {
+		return nil
+	}
+	secret.Previous = &RoleSecret{
+		Password:    stored.Password,
+		Certificate: stored.Certificate,
+		PrivateKey:  stored.PrivateKey,
+		Version:     stored.Version,
+	}
+	secret.ServedFrom = time.Now().Add(role.PropagationDelay).UTC()
+	return nil
+}
+
+// rotationFailureReason classifies a failed rotation for rotate-fail events.
+func rotationFailureReason(err error) string {
+	if errors.Is(err, errMonitorAccessLevel) {
+		return "access_level"
+	}
+	if errors.Is(err, errSEMPAddressNotAllowed) {
+		return "address_not_allowed"
+	}
+	if errors.Is(err, errRotationLocked) {
+		return "locked"
+	}
+	if errors.Is(err, errPasswordReused) {
+		return "password_reused"
+	}
+	if errors.Is(err, errPasswordMinAge) {
+		return "password_min_age"
+	}
+	if reason := sempErrorClass(err); reason != "" {
+		return reason
+	}
+	return "unknown"
+}
+
+// saveRecovery keeps a credential the broker may hold but Vault has not
+// stored under recovery/, so an operator can reconcile the role.
+func (b *solaceBackend) saveRecovery(ctx context.Context, s logical.Storage, name string, role *RoleEntry, secret *RoleSecret) error {
+	return putRecovery(ctx, s, name, &RecoveryEntry{
+		Broker:      role.location(),
+		CLIUsername: role.CLIUsername,
+		Password:    secret.Password,
+		Certificate: secret.Certificate,
+		PrivateKey:  secret.PrivateKey,
+		CreatedAt:   time.Now().UTC(),
+	})
+}
+
+// credential is a role's credential held in wipeable buffers while it is
+// generated and applied: a password, or for REST consumers authenticating
+// with a client certificate, a PEM certificate and private key.
+type credential struct {
+	password    []byte
+	certificate []byte
+	privateKey  []byte
+}
+
+func credentialFromSecret(secret *RoleSecret) *credential {
+	cred := &credential{}
+	if secret.Password != "" {
+		cred.password = []byte(secret.Password)
+	}
+	if secret.Certificate != "" {
+		cred.certificate = []byte(secret.Certificate)
+		cred.privateKey = []byte(secret.PrivateKey)
+	}
+	return cred
+}
+
+func (c *credential) wipe() {
+	if c == nil {
+		return
+	}
+	wipe(c.password)
+	wipe(c.privateKey)
+}
+
+func (c *credential) secret() *RoleSecret {
+	return &RoleSecret{
+		Password:    string(c.password),
+		Certificate: string(c.certificate),
+		PrivateKey:  string(c.privateKey),
+	}
+}
+
+// errPasswordRejected is returned when the password a rotation asked for,
+// from a Vault password policy or supplied by the caller, cannot be used.
+var errPasswordRejected = errors.New("password rejected")
+
+// generateCredential returns a new credential for a role, never equal to its
+// current password or holding any of the excluded characters. opts may
+// override the password's length, name a Vault password policy to generate
+// it from, or supply the password itself.
+func (b *solaceBackend) generateCredential(ctx context.Context, name string, role *RoleEntry, settings *Settings, excluded string, current *credential, opts rotationOptions) (*credential, error) {
+	if role.usesClientCertificate() {
+		cert, key, err := generateClientCertificate(name, clientCertValidity(role))
+		if err != nil {
+			return nil, err
+		}
+		return &credential{certificate: cert, privateKey: key}, nil
+	}
+
+	var currentPassword []byte
+	if current != nil {
+		currentPassword = current.password
+	}
+	if len(opts.password) > 0 {
+		if err := validatePassword(opts.password, excluded); err != nil {
+			return nil, fmt.Errorf("%w: %v", errPasswordRejected, err)
+		}
+		if subtle.ConstantTimeCompare(opts.password, currentPassword) == 1 {
+			return nil, fmt.Errorf("%w: the supplied password matches the current one", errPasswordRejected)
+		}
+		b.Logger().Info("rotating with a caller-supplied password", "role", name)
+		return &credential{password: bytes.Clone(opts.password)}, nil
+	}
+	if opts.passwordPolicy != "" {
+		password, err := b.generatePolicyPassword(ctx, opts.passwordPolicy, excluded, currentPassword)
+		if err != nil {
+			return nil, err
+		}
+		return &credential{password: password}, nil
+	}
+	length := role.PasswordLength
+	if opts.passwordLength != 0 {
+		length = opts.passwordLength
+	}
+	policy := settings.passwordPolicy()
+	policy.Exclude = excluded
+	password, err := generateReplacementPassword(length, policy, currentPassword)
+	if err != nil {
+		return nil, err
+	}
+	return &credential{password: password}, nil
+}
+
+// generatePolicyPassword returns a password from the named Vault password
+// policy that differs from current and holds none of the excluded
+// characters.
+func (b *solaceBackend) generatePolicyPassword(ctx context.Context, policy, excluded string, current []byte) ([]byte, error) {
+	for attempt := 0; attempt < maxPasswordAttempts; attempt++ {
+		generated, err := b.System().GeneratePasswordFromPolicy(ctx, policy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: password policy %q: %v", errPasswordRejected, policy, err)
+		}
+		password := []byte(generated)
+		if err := validatePassword(password, excluded); err != nil {
+			wipe(password)
+			return nil, fmt.Errorf("%w: password policy %q generated a password the broker does not accept: %v", errPasswordRejected, policy, err)
+		}
+		if subtle.ConstantTimeCompare(password, current) == 0 {
+			return password, nil
+		}
+		wipe(password)
+	}
+	return nil, fmt.Errorf("%w: password policy %q did not generate a password different from the current one in %d attempts", errPasswordRejected, policy, maxPasswordAttempts)
+}
+
+// applyCredential sets a role's credential on the broker.
+func (b *solaceBackend) applyCredential(ctx context.Context, client *SEMPClient, role *RoleEntry, cred *credential) error {
+	switch {
+	case role.usesClientCertificate():
+		content := clientCertContent(cred.certificate, cred.privateKey)
+		defer wipe(content)
+		return client.SetRESTConsumerClientCert(ctx, role.MsgVPN, role.RESTDeliveryPoint, role.RESTConsumer, content)
+	case role.isRESTConsumer():
+		return client.SetRESTConsumerBasicAuth(ctx, role.MsgVPN, role.RESTDeliveryPoint, role.RESTConsumer, role.RESTUsername, cred.password)
+	case role.isOAuthProfile():
+		return client.SetOAuthProfileClientSecret(ctx, role.MsgVPN, role.OAuthProfile, cred.password)
+	case role.isSEMPRPC():
+		return client.ExecuteRPCTemplate(ctx, role.SEMPRPCTemplate, role.SEMPRPCUsername, cred.password)
+	default:
+		return b.applyPassword(ctx, client, role, cred.password)
+	}
+}
+
+// errRotationNotApplied is returned by verifyRotation when the broker
+// rejects the new password outright and still accepts the previous one.
+var errRotationNotApplied = errors.New("broker rejected the new password and still accepts the previous one")
+
+// verifyRotation confirms the broker accepts the new password for the CLI
+// user and, when there was one, no longer accepts the previous password.
+// Only a broker that rejects the new password and still takes the previous
+// one is known to have kept it; that returns errRotationNotApplied.
+func verifyRotation(ctx context.Context, client *SEMPClient, cliUsername string, oldPassword, newPassword []byte) error {
+	accepted, err := client.CheckLogin(ctx, cliUsername, newPassword)
+	if err != nil {
+		return fmt.Errorf("logging in with the new password: %w", err)
+	}
+	if !accepted {
+		if oldPassword != nil {
+			if oldAccepted, err := client.CheckLogin(ctx, cliUsername, oldPassword); err == nil && oldAccepted {
+				return errRotationNotApplied
+			}
+		}
+		return errors.New("broker rejected the new password")
+	}
+	if oldPassword == nil {
+		return nil
+	}
+	accepted, err = client.CheckLogin(ctx, cliUsername, oldPassword)
+	if err != nil {
+		return fmt.Errorf("logging in with the previous password: %w", err)
+	}
+	if accepted {
+		return errors.New("broker still accepts the previous password")
+	}
+	return nil
+}
+
+// errMonitorAccessLevel is returned when a monitor role's CLI user exists
+// with more than read-only access.
+var errMonitorAccessLevel = errors.New("CLI user has more than read-only access")
+
+// applyPassword sets the CLI user's password on the broker, creating the user
+// first when the role allows it and the user does not yet exist. A monitor
+// role's user is always created if missing, and an existing one must not
+// have more than read-only access. With disable_during_rotation an enabled
+// user is shut down while its password changes.
+func (b *solaceBackend) applyPassword(ctx context.Context, client *SEMPClient, role *RoleEntry, password []byte) error {
+	var user *CLIUser
+	if role.CreateIfMissing || role.Monitor || role.DisableDuringRotation {
+		var err error
+		user, err = client.ShowUsername(ctx, role.CLIUsername)
+		if err != nil {
+			return err
+		}
+		if user == nil && (role.CreateIfMissing || role.Monitor) {
+			b.Logger().Info("creating missing CLI user", "cli_username", role.CLIUsername, "broker", role.Broker)
+			return client.CreateUser(ctx, role.CLIUsername, password, role.access())
+		}
+		if user != nil && role.Monitor && !monitorAccessLevels[user.GlobalAccessLevel] {
+			return fmt.Errorf("%w: %s", errMonitorAccessLevel, user.GlobalAccessLevel)
+		}
+	}
+	if !role.DisableDuringRotation || user == nil || !user.Enabled {
+		return client.ChangePassword(ctx, role.CLIUsername, password)
+	}
+
+	// The new password is in place even if the user could not be enabled
+	// again, so it must still be stored; the user needs an operator.
+	err := client.ChangePasswordWithShutdown(ctx, role.CLIUsername, password)
+	if errors.Is(err, errUserLeftShutdown) {
+		b.Logger().Error("CLI user left shut down after its password was changed; enable it on the broker",
+			"cli_username", role.CLIUsername, "broker", role.Broker, "error", err)
+		return nil
+	}
+	return err
 }
@@ -2,9 +2,13 @@ package solacevaultplugin
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/vault/sdk/logical"
@@ -12,13 +16,28 @@ import (
 
 func setupRotationTest(t *testing.T) (logical.Backend, logical.Storage, *httptest.Server) {
 	t.Helper()
+	b, storage := getTestBackend(t)
+	return setupRotationTestWith(t, b, storage)
+}
+
+// setupRotationTestWith configures test-broker, backed by a SEMP server that
+// accepts every request, and test-role on an existing backend.
+func setupRotationTestWith(t *testing.T, b logical.Backend, storage logical.Storage) (logical.Backend, logical.Storage, *httptest.Server) {
+	t.Helper()
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/xml")
 		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
 	}))
 
-	b, storage := getTestBackend(t)
+	return setupRotationTestWithServer(t, b, storage, server)
+}
+
+// setupRotationTestWithServer configures test-broker against the given SEMP
+// server, and test-role on it.
+func setupRotationTestWithServer(t *testing.T, b logical.Backend, storage logical.Storage, server *httptest.Server) (logical.Backend, logical.Storage, *httptest.Server) {
+	t.Helper()
+
 	ctx := context.Background()
 
 	// Create broker pointing to test server
@@ -76,7 +95,11 @@ func TestPathRotate_Success(t *testing.T) {
 	if err != nil {
 		t.Fatalf("getRole: %v", err)
 	}
-	if role.Password == "" {
+	secret, err := getRoleSecret(ctx, storage, "test-role")
+	if err != nil {
+		t.Fatalf("getRoleSecret: %v", err)
+	}
+	if secret == nil || secret.Password == "" {
 		t.Error("password should be set after rotation")
 	}
 	if role.LastRotated.IsZero() {
@@ -84,6 +107,52 @@ func TestPathRotate_Success(t *testing.T) {
 	}
 }
 
+func TestPathRotate_RecordsActor(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	readRole := func() map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "roles/test-role",
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("read role: err=%v, resp=%v", err, resp)
+		}
+		return resp.Data
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "rotate-role/test-role",
+		Storage:     storage,
+		DisplayName: "approle-deployer",
+		EntityID:    "entity-1234",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("rotate: err=%v, resp=%v", err, resp)
+	}
+	data := readRole()
+	if data["last_rotation_trigger"] != rotationTriggerManual || data["last_rotated_by"] != "approle-deployer" || data["last_rotated_by_entity_id"] != "entity-1234" {
+		t.Errorf("after manual rotation: trigger=%v by=%v entity=%v", data["last_rotation_trigger"], data["last_rotated_by"], data["last_rotated_by_entity_id"])
+	}
+
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	b.(*solaceBackend).invalidate(ctx, roleStoragePrefix+"test-role")
+	data = readRole()
+	if data["last_rotation_trigger"] != rotationTriggerPeriodic {
+		t.Errorf("last_rotation_trigger = %v after periodic rotation, want periodic", data["last_rotation_trigger"])
+	}
+	if _, ok := data["last_rotated_by"]; ok {
+		t.Errorf("last_rotated_by = %v after periodic rotation, want it unset", data["last_rotated_by"])
+	}
+}
+
 func TestPathRotate_RoleNotFound(t *testing.T) {
 	b, storage := getTestBackend(t)
 	ctx := context.Background()
@@ -166,11 +235,11 @@ func TestPathRotate_SEMPFailure_SanitizedError(t *testing.T) {
 	}
 
 	// Verify password was NOT stored (rotation safety)
-	role, err := getRole(ctx, storage, "test-role")
+	secret, err := getRoleSecret(ctx, storage, "test-role")
 	if err != nil {
-		t.Fatalf("getRole: %v", err)
+		t.Fatalf("getRoleSecret: %v", err)
 	}
-	if role.Password != "" {
+	if secret != nil {
 		t.Error("password should not be stored after failed rotation")
 	}
 }
@@ -216,9 +285,20 @@ func TestPathRotate_RateLimited(t *testing.T) {
 		Storage:   storage,
 	}
 	resp, err := b.HandleRequest(ctx, req)
-	if err != nil || (resp != nil && resp.IsError()) {
+	if err != nil || resp == nil || resp.IsError() {
 		t.Fatalf("first rotate: err=%v, resp=%v", err, resp)
 	}
+	if resp.Data["last_rotated"] == nil {
+		t.Error("rotation should report last_rotated")
+	}
+	// The caller is told when it may rotate again before it tries, without
+	// a warning on a rotation that went as asked.
+	if resp.Data["next_manual_rotation_after"] == nil {
+		t.Error("rotation should report next_manual_rotation_after")
+	}
+	if len(resp.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", resp.Warnings)
+	}
 
 	// Immediate second rotation should be rate-limited
 	resp, err = b.HandleRequest(ctx, req)
@@ -226,7 +306,10 @@ func TestPathRotate_RateLimited(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if resp == nil || !resp.IsError() {
-		t.Error("expected error response for rate-limited rotation")
+		t.Fatal("expected error response for rate-limited rotation")
+	}
+	if msg := resp.Error().Error(); !strings.Contains(msg, "try again after") {
+		t.Errorf("rate-limit error does not say when to retry: %s", msg)
 	}
 }
 
@@ -284,11 +367,890 @@ func TestPathRotate_CustomPasswordLength(t *testing.T) {
 	}
 
 	// Verify stored password is 64 chars
-	role, err := getRole(ctx, storage, "custom-len-role")
+	secret, err := getRoleSecret(ctx, storage, "custom-len-role")
+	if err != nil || secret == nil {
+		t.Fatalf("getRoleSecret: err=%v", err)
+	}
+	if len(secret.Password) != 64 {
+		t.Errorf("password length = %d, want 64", len(secret.Password))
+	}
+}
+
+func TestPathRotate_CreateIfMissing(t *testing.T) {
+	var created bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/xml")
+		switch {
+		case strings.Contains(string(body), "<show>"):
+			w.Write([]byte(`<rpc-reply><rpc><show><username><usernames/></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+		case strings.Contains(string(body), "<create>"):
+			created = true
+			w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+		case strings.Contains(string(body), "<change-password>"):
+			w.Write([]byte(`<rpc-reply><execute-result code="fail"/><parse-error>no such user</parse-error></rpc-reply>`))
+		default:
+			w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+		}
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":       server.URL,
+			"admin_username": "admin",
+			"admin_password": "secret",
+		},
+	}
+	b.HandleRequest(ctx, req)
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/new-user",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":              "test-broker",
+			"cli_username":        "newuser",
+			"create_if_missing":   true,
+			"global_access_level": "read-only",
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create role: err=%v, resp=%v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "rotate-role/new-user",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("rotate: err=%v, resp=%v", err, resp)
+	}
+	if !created {
+		t.Error("expected CLI user to be created on the broker")
+	}
+
+	secret, _ := getRoleSecret(ctx, storage, "new-user")
+	if secret == nil || secret.Password == "" {
+		t.Error("password should be stored after the user is created")
+	}
+}
+
+func TestPathRotate_MonitorRole(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		accessLevel string
+		requests    []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, string(body))
+		w.Header().Set("Content-Type", "application/xml")
+		switch {
+		case strings.Contains(string(body), "<show>") && accessLevel == "":
+			w.Write([]byte(`<rpc-reply><rpc><show><username><usernames/></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+		case strings.Contains(string(body), "<show>"):
+			w.Write([]byte(`<rpc-reply><rpc><show><username><usernames><username><name>grafana</name><global-access-level>` + accessLevel + `</global-access-level></username></usernames></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+		default:
+			w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+		}
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":       server.URL,
+			"admin_username": "admin",
+			"admin_password": "secret",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create broker: err=%v, resp=%v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/grafana",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":              "test-broker",
+			"cli_username":        "grafana",
+			"monitor":             true,
+			"global_access_level": "admin",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for a monitor role with admin access, got err=%v, resp=%v", err, resp)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/grafana",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":       "test-broker",
+			"cli_username": "grafana",
+			"monitor":      true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create role: err=%v, resp=%v", err, resp)
+	}
+
+	// A missing user is created read-only.
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "grafana"); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	mu.Lock()
+	if last := requests[len(requests)-1]; !strings.Contains(last, "<access-level>read-only</access-level>") {
+		t.Errorf("expected the user to be made read-only, last request: %s", last)
+	}
+	accessLevel = "read-write"
+	requests = nil
+	mu.Unlock()
+
+	// An existing user with more access is left alone.
+	resp, err = b.(*solaceBackend).rotateRole(ctx, storage, "grafana")
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error rotating a read-write user, got err=%v, resp=%v", err, resp)
+	}
+	mu.Lock()
+	for _, req := range requests {
+		if strings.Contains(req, "<change-password>") {
+			t.Error("password of a read-write user must not be changed")
+		}
+	}
+	mu.Unlock()
+}
+
+func TestPathRotate_DisableDuringRotation(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		enabled  = "true"
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.Contains(string(body), "<show>"):
+			requests = append(requests, "show")
+			w.Write([]byte(`<rpc-reply><rpc><show><username><usernames><username><name>app</name><enabled>` + enabled + `</enabled></username></usernames></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+			return
+		case strings.Contains(string(body), "<no><shutdown/></no>"):
+			requests = append(requests, "enable")
+		case strings.Contains(string(body), "<shutdown/>"):
+			requests = append(requests, "shutdown")
+		case strings.Contains(string(body), "<change-password>"):
+			requests = append(requests, "change")
+		}
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":       server.URL,
+			"admin_username": "admin",
+			"admin_password": "secret",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create broker: err=%v, resp=%v", err, resp)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/app",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":                  "test-broker",
+			"cli_username":            "app",
+			"disable_during_rotation": true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create role: err=%v, resp=%v", err, resp)
+	}
+
+	for _, tc := range []struct {
+		enabled string
+		want    string
+	}{
+		{"true", "show,shutdown,change,enable"},
+		// A user an operator shut down is not enabled by rotation.
+		{"false", "show,change"},
+	} {
+		mu.Lock()
+		enabled, requests = tc.enabled, nil
+		mu.Unlock()
+		if resp, err := sb.rotateRole(ctx, storage, "app"); err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+		}
+		mu.Lock()
+		if got := strings.Join(requests, ","); got != tc.want {
+			t.Errorf("enabled=%s: requests = %s, want %s", tc.enabled, got, tc.want)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestPathRotate_TerminateSessions(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		enabled    = "true"
+		failEnable bool
+		requests   []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.Contains(string(body), "<show>"):
+			requests = append(requests, "show")
+			w.Write([]byte(`<rpc-reply><rpc><show><username><usernames><username><name>app</name><enabled>` + enabled + `</enabled></username></usernames></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+			return
+		case strings.Contains(string(body), "<no><shutdown/></no>"):
+			requests = append(requests, "enable")
+			if failEnable {
+				w.Write([]byte(`<rpc-reply><execute-result code="fail"/></rpc-reply>`))
+				return
+			}
+		case strings.Contains(string(body), "<shutdown/>"):
+			requests = append(requests, "shutdown")
+		case strings.Contains(string(body), "<change-password>"):
+			requests = append(requests, "change")
+		}
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":       server.URL,
+			"admin_username": "admin",
+			"admin_password": "secret",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create broker: err=%v, resp=%v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/app",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":                  "test-broker",
+			"cli_username":            "app",
+			"terminate_sessions":      true,
+			"disable_during_rotation": true,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected terminate_sessions with disable_during_rotation to be refused, got err=%v, resp=%v", err, resp)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/app",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":             "test-broker",
+			"cli_username":       "app",
+			"terminate_sessions": true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create role: err=%v, resp=%v", err, resp)
+	}
+
+	for _, tc := range []struct {
+		enabled    string
+		failEnable bool
+		want       string
+		terminated bool
+	}{
+		{"true", false, "change,show,shutdown,enable", true},
+		// A user an operator shut down is not enabled by rotation.
+		{"false", false, "change,show", true},
+		// The password is stored either way; the failure is a warning.
+		{"true", true, "change,show,shutdown,enable", false},
+	} {
+		mu.Lock()
+		enabled, failEnable, requests = tc.enabled, tc.failEnable, nil
+		mu.Unlock()
+		before, _ := getRoleSecret(ctx, storage, "app")
+		resp, err := sb.rotateRole(ctx, storage, "app")
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+		}
+		if got := resp.Data["sessions_terminated"]; got != tc.terminated {
+			t.Errorf("enabled=%s, failEnable=%v: sessions_terminated = %v, want %v", tc.enabled, tc.failEnable, got, tc.terminated)
+		}
+		if !tc.terminated && len(resp.Warnings) == 0 {
+			t.Errorf("enabled=%s, failEnable=%v: expected a warning", tc.enabled, tc.failEnable)
+		}
+		if after, _ := getRoleSecret(ctx, storage, "app"); after == nil || (before != nil && after.Password == before.Password) {
+			t.Errorf("enabled=%s, failEnable=%v: new password not stored", tc.enabled, tc.failEnable)
+		}
+		mu.Lock()
+		if got := strings.Join(requests, ","); got != tc.want {
+			t.Errorf("enabled=%s, failEnable=%v: requests = %s, want %s", tc.enabled, tc.failEnable, got, tc.want)
+		}
+		mu.Unlock()
+	}
+}
+
+// haNode is one node of an HA pair: it answers show redundancy as the active
+// or standby node and, like a standby, refuses configuration changes unless
+// active.
+type haNode struct {
+	mu      sync.Mutex
+	active  bool
+	changes int
+	shows   int
+}
+
+func (n *haNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case strings.Contains(string(body), "<redundancy/>"):
+		n.shows++
+		activity := "Local Standby"
+		if n.active {
+			activity = "Local Active"
+		}
+		w.Write([]byte(`<rpc-reply><rpc><show><redundancy><config-status>Enabled</config-status><virtual-routers><primary><status><activity>` + activity + `</activity></status></primary></virtual-routers></redundancy></show></rpc><execute-result code="ok"/></rpc-reply>`))
+	case !n.active:
+		w.Write([]byte(`<rpc-reply><execute-result code="fail"/><parse-error>node is standby</parse-error></rpc-reply>`))
+	default:
+		n.changes++
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}
+}
+
+func TestPathRotate_UsesActiveNode(t *testing.T) {
+	primary, backup := &haNode{}, &haNode{active: true}
+	primaryServer, backupServer := httptest.NewServer(primary), httptest.NewServer(backup)
+	defer primaryServer.Close()
+	defer backupServer.Close()
+
+	b, storage := getTestBackend(t)
+	setupRotationTestWithServer(t, b, storage, primaryServer)
+	ctx := context.Background()
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data:      map[string]interface{}{"mate_semp_url": backupServer.URL},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("patch broker: err=%v, resp=%v", err, resp)
+	}
+
+	// The configured node is the standby, so the change goes to its mate.
+	resp, err = b.(*solaceBackend).rotateRole(ctx, storage, "test-role")
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	if primary.changes != 0 || backup.changes != 1 {
+		t.Errorf("changes: primary=%d backup=%d, want 0 and 1", primary.changes, backup.changes)
+	}
+
+	// With neither node active, rotation fails without touching either.
+	backup.mu.Lock()
+	backup.active = false
+	backup.mu.Unlock()
+	resp, err = b.(*solaceBackend).rotateRole(ctx, storage, "test-role")
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error with no active node, got err=%v, resp=%v", err, resp)
+	}
+}
+
+// passwordBroker is a SEMP server that tracks a CLI user's password and
+// answers logins as that user, optionally ignoring or failing password
+// changes, or failing show commands.
+type passwordBroker struct {
+	mu            sync.Mutex
+	password      string
+	ignoreChanges bool
+	failChanges   bool
+	failShows     bool
+	dropLogins    bool
+}
+
+var sempPasswordPattern = regexp.MustCompile(`<password>(.*)</password>`)
+
+func (pb *passwordBroker) current() string {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	return pb.password
+}
+
+func (pb *passwordBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	user, pass, _ := r.BasicAuth()
+	if user != "admin" {
+		if pb.dropLogins {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		if user != "monitor" || pass != pb.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	show := strings.Contains(string(body), "<show>")
+	switch {
+	case show && pb.failShows, !show && pb.failChanges:
+		w.Write([]byte(`<rpc-reply><execute-result code="fail"/></rpc-reply>`))
+		return
+	case show:
+		w.Write([]byte(`<rpc-reply><rpc><show><username><usernames><username><name>monitor</name><global-access-level>read-only</global-access-level></username></usernames></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+		return
+	case !pb.ignoreChanges:
+		if m := sempPasswordPattern.FindSubmatch(body); m != nil {
+			pb.password = string(m[1])
+		}
+	}
+	w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+}
+
+func TestPathRotate_VerifyRotation(t *testing.T) {
+	pb := &passwordBroker{}
+	server := httptest.NewServer(pb)
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	b, storage, _ = setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+
+	settings := defaultSettings()
+	settings.AllowInsecureTransport = true
+	settings.VerifyRotation = true
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+
+	sb := b.(*solaceBackend)
+	for i := 0; i < 2; i++ {
+		resp, err := sb.rotateRole(ctx, storage, "test-role")
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("rotation %d: err=%v, resp=%v", i, err, resp)
+		}
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+	if secret == nil || secret.Password != pb.current() {
+		t.Fatalf("stored password does not match the broker's")
+	}
+
+	// A broker that acknowledges the change but keeps the old password
+	// fails verification, leaving the stored password in place.
+	pb.mu.Lock()
+	pb.ignoreChanges = true
+	pb.mu.Unlock()
+	resp, err := sb.rotateRole(ctx, storage, "test-role")
 	if err != nil {
-		t.Fatalf("getRole: %v", err)
+		t.Fatalf("rotateRole: %v", err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatal("expected verification failure")
+	}
+	after, _ := getRoleSecret(ctx, storage, "test-role")
+	if after.Password != secret.Password {
+		t.Error("stored password changed despite failed verification")
+	}
+	recovery, _ := getRecovery(ctx, storage, "test-role")
+	if recovery == nil || recovery.Password == secret.Password {
+		t.Errorf("expected the unverified password in recovery, got %+v", recovery)
+	}
+
+	// A login check that cannot reach the broker proves nothing: the
+	// acknowledged password is stored, with a warning and a drift flag.
+	pb.mu.Lock()
+	pb.ignoreChanges = false
+	pb.dropLogins = true
+	pb.mu.Unlock()
+	resp, err = sb.rotateRole(ctx, storage, "test-role")
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("expected an unverified rotation to succeed, got err=%v, resp=%v", err, resp)
+	}
+	if len(resp.Warnings) == 0 {
+		t.Error("expected a warning for the unverified rotation")
+	}
+	stored, _ := getRoleSecret(ctx, storage, "test-role")
+	if stored == nil || stored.Password != pb.current() {
+		t.Error("expected the password the broker acknowledged to be stored")
+	}
+	drift, err := getDrift(ctx, storage, "test-role")
+	if err != nil || drift == nil || drift.Reason != driftRotationUnverified {
+		t.Errorf("expected a %s drift flag, got %+v (err=%v)", driftRotationUnverified, drift, err)
+	}
+}
+
+func TestPathRotate_OAuthProfile(t *testing.T) {
+	var mu sync.Mutex
+	var paths, secrets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var update map[string]string
+		json.NewDecoder(r.Body).Decode(&update)
+		paths = append(paths, r.URL.Path)
+		secrets = append(secrets, update["clientSecret"])
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{},"meta":{"responseCode":200}}`))
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	b, storage, _ = setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+
+	writeRole := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		data["broker"] = "test-broker"
+		data["target"] = roleTargetOAuthProfile
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/idp-role",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("write role: %v", err)
+		}
+		return resp
+	}
+	if resp := writeRole(map[string]interface{}{"oauth_profile": "idp", "rotation_period": 3600}); resp == nil || !resp.IsError() {
+		t.Error("expected error for an automatically rotated oauth_profile role")
+	}
+	if resp := writeRole(map[string]interface{}{"oauth_profile": "idp", "msg_vpn": "default"}); resp != nil && resp.IsError() {
+		t.Fatalf("write role: %v", resp)
+	}
+
+	rotate := func(role string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "rotate-role/" + role,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("rotate: %v", err)
+		}
+		return resp
+	}
+	if resp := rotate("idp-role", nil); resp == nil || !resp.IsError() {
+		t.Error("expected error rotating an oauth_profile role without client_secret")
+	}
+	if resp := rotate("test-role", map[string]interface{}{"client_secret": "s3cret"}); resp == nil || !resp.IsError() {
+		t.Error("expected error passing client_secret to a cli_user role")
+	}
+	if resp := rotate("idp-role", map[string]interface{}{"client_secret": "s3cret"}); resp != nil && resp.IsError() {
+		t.Fatalf("rotate: %v", resp)
+	}
+
+	mu.Lock()
+	if len(paths) != 1 || paths[0] != "/SEMP/v2/config/msgVpns/default/authenticationOauthProfiles/idp" || secrets[0] != "s3cret" {
+		t.Errorf("unexpected SEMP calls: paths=%v", paths)
+	}
+	mu.Unlock()
+
+	creds := readCreds(t, b, storage, "idp-role")
+	if creds["client_secret"] != "s3cret" || creds["oauth_profile"] != "idp" {
+		t.Errorf("unexpected creds: %v", creds)
+	}
+
+	// With the rate limit lifted, resupplying the current secret is refused.
+	settings := defaultSettings()
+	settings.AllowInsecureTransport = true
+	settings.MinRotationInterval = 0
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+	if resp := rotate("idp-role", map[string]interface{}{"client_secret": "s3cret"}); resp == nil || !resp.IsError() {
+		t.Error("expected error for a client_secret equal to the current one")
+	}
+}
+
+func TestOAuthProfilePath(t *testing.T) {
+	if got := oauthProfilePath("", "mgmt"); got != "/oauthProfiles/mgmt" {
+		t.Errorf("broker-level path = %q", got)
+	}
+	if got := oauthProfilePath("default", "idp"); got != "/msgVpns/default/authenticationOauthProfiles/idp" {
+		t.Errorf("message VPN path = %q", got)
+	}
+}
+
+func TestPathRotate_ExistenceCheck(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	// Whether or not the role exists, a rotation is always an update, so a
+	// policy needs the same capability for every request.
+	for _, name := range []string{"test-role", "missing"} {
+		checkFound, exists, err := b.HandleExistenceCheck(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "rotate-role/" + name,
+			Storage:   storage,
+		})
+		if err != nil || !checkFound || !exists {
+			t.Errorf("%s: checkFound=%v, exists=%v, err=%v", name, checkFound, exists, err)
+		}
+	}
+}
+
+func TestPathRotate_Overrides(t *testing.T) {
+	config := logical.TestBackendConfig()
+	system := config.System.(*logical.StaticSystemView)
+	system.SetPasswordPolicy("long", func() (string, error) { return strings.Repeat("x", 60) + "A1!", nil })
+	system.SetPasswordPolicy("colons", func() (string, error) { return strings.Repeat("x:", 10), nil })
+	b, storage := newTestBackend(t, config)
+	allowInsecureTransport(t, storage)
+	b, storage, server := setupRotationTestWith(t, b, storage)
+	defer server.Close()
+	ctx := context.Background()
+
+	settings, _ := getSettings(ctx, storage)
+	settings.MinRotationInterval = 0
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+	rotate := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "rotate-role/test-role",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("rotate: %v", err)
+		}
+		return resp
+	}
+
+	if resp := rotate(map[string]interface{}{"password_length": 40}); resp.IsError() {
+		t.Fatalf("rotate with password_length: %v", resp.Error())
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+	if len(secret.Password) != 40 {
+		t.Errorf("password length = %d, want 40", len(secret.Password))
+	}
+	role, _ := getRole(ctx, storage, "test-role")
+	if role.PasswordLength != defaultPasswordLength {
+		t.Errorf("role password_length changed to %d", role.PasswordLength)
+	}
+
+	if resp := rotate(map[string]interface{}{"password_policy": "long"}); resp.IsError() {
+		t.Fatalf("rotate with password_policy: %v", resp.Error())
+	}
+	secret, _ = getRoleSecret(ctx, storage, "test-role")
+	if secret.Password != strings.Repeat("x", 60)+"A1!" {
+		t.Error("password was not generated from the policy")
+	}
+
+	for name, data := range map[string]map[string]interface{}{
+		"too short":       {"password_length": 8},
+		"both":            {"password_length": 40, "password_policy": "long"},
+		"unknown policy":  {"password_policy": "missing"},
+		"invalid policy":  {"password_policy": "colons"},
+		"repeat password": {"password_policy": "long"},
+	} {
+		if resp := rotate(data); !resp.IsError() {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	after, _ := getRoleSecret(ctx, storage, "test-role")
+	if after.Password != secret.Password {
+		t.Error("stored password changed after a rejected override")
+	}
+}
+
+func TestPathRotate_SuppliedPassword(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	const supplied = "Migrated-Passw0rd-From-Elsewhere"
+	rotate := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "rotate-role/test-role",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("rotate: %v", err)
+		}
+		return resp
+	}
+
+	// Disabled by default.
+	if resp := rotate(map[string]interface{}{"password": supplied}); !resp.IsError() {
+		t.Fatal("expected supplied password to be refused while disabled")
+	}
+	if secret, _ := getRoleSecret(ctx, storage, "test-role"); !secret.empty() {
+		t.Fatal("password stored although supplied passwords are disabled")
+	}
+
+	settings, _ := getSettings(ctx, storage)
+	settings.AllowSuppliedPasswords = true
+	settings.MinRotationInterval = 0
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+	if resp := rotate(map[string]interface{}{"password": supplied}); resp.IsError() {
+		t.Fatalf("rotate with supplied password: %v", resp.Error())
+	}
+	if secret, _ := getRoleSecret(ctx, storage, "test-role"); secret.Password != supplied {
+		t.Error("stored password is not the supplied one")
+	}
+
+	for name, data := range map[string]map[string]interface{}{
+		"same as current": {"password": supplied},
+		"too short":       {"password": "short"},
+		"forbidden chars": {"password": "Migrated:Password;From<Elsewhere>"},
+		"with length":     {"password": supplied + "2", "password_length": 40},
+	} {
+		if resp := rotate(data); !resp.IsError() {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPathRotate_BrokerExcludedPasswordChars(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	excluded := passwordLower + passwordDigits + passwordSymbols
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data:      map[string]interface{}{"password_excluded_characters": excluded},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("update broker: err=%v, resp=%v", err, resp)
+	}
+
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+	if strings.ContainsAny(secret.Password, excluded) {
+		t.Errorf("password %q contains a character the broker excludes", secret.Password)
+	}
+
+	settings, _ := getSettings(ctx, storage)
+	settings.AllowSuppliedPasswords = true
+	settings.MinRotationInterval = 0
+	putSettings(ctx, storage, settings)
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-role/test-role",
+		Storage:   storage,
+		Data:      map[string]interface{}{"password": "MIGRATED-PASSWORD-1"},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Errorf("supplied password with excluded characters: err=%v, resp=%v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data:      map[string]interface{}{"password_excluded_characters": "#\t"},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Errorf("non-printable excluded character: err=%v, resp=%v", err, resp)
+	}
+}
+
+func TestPathRotate_AllowedSEMPNetworks(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	setNetworks := func(networks string) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.PatchOperation,
+			Path:      "config/brokers/test-broker",
+			Storage:   storage,
+			Data:      map[string]interface{}{"allowed_semp_networks": networks},
+		})
+		if err != nil {
+			t.Fatalf("patch broker: %v", err)
+		}
+		return resp
+	}
+	if resp := setNetworks("10.0.0.0/33"); resp == nil || !resp.IsError() {
+		t.Errorf("invalid network accepted: %v", resp)
+	}
+
+	// The test server listens on loopback, outside this range.
+	if resp := setNetworks("10.20.0.7/16, 192.0.2.1"); resp != nil && resp.IsError() {
+		t.Fatalf("set networks: %v", resp.Error())
+	}
+	broker, _ := getBroker(ctx, storage, "test-broker")
+	if got := strings.Join(broker.AllowedSEMPNetworks, ","); got != "10.20.0.0/16,192.0.2.1/32" {
+		t.Errorf("allowed_semp_networks = %s", got)
+	}
+	resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role")
+	if err != nil || resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "allowed_semp_networks") {
+		t.Fatalf("rotation outside the allowed networks: err=%v, resp=%v", err, resp)
+	}
+	if secret, _ := getRoleSecret(ctx, storage, "test-role"); !secret.empty() {
+		t.Error("refused rotation stored a password")
+	}
+
+	if resp := setNetworks("127.0.0.0/8,::1"); resp != nil && resp.IsError() {
+		t.Fatalf("set networks: %v", resp.Error())
 	}
-	if len(role.Password) != 64 {
-		t.Errorf("password length = %d, want 64", len(role.Password))
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotation inside the allowed networks: err=%v, resp=%v", err, resp)
 	}
 }
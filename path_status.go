@@ -0,0 +1,274 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathStatus(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "status$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "status",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathStatusRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      statusResponseFields,
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Summarize rotation health for the mount.",
+			HelpDescription: "Reports role counts, overdue and suspended rotations, non-compliant roles, unreachable brokers, and the last periodic run, as a single scrape point for monitoring.",
+		},
+		{
+			Pattern: "status/overdue$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "overdue-roles",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathStatusOverdueRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"keys":     {Type: framework.TypeStringSlice, Description: "Names of the overdue roles."},
+								"key_info": {Type: framework.TypeMap, Description: "Each overdue role's broker, CLI username, rotation period, last rotation and overdue_seconds."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "List roles that are overdue for rotation.",
+			HelpDescription: "Returns every role whose last_rotated is older than its rotation_period, with how long it has been overdue, so compliance tooling can flag rotation SLA breaches.",
+		},
+		{
+			Pattern: "status/drift$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationSuffix: "drifted-roles",
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathStatusDriftRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"keys":     {Type: framework.TypeStringSlice, Description: "Names of the drifted roles."},
+								"key_info": {Type: framework.TypeMap, Description: "Each drifted role's broker, reason (user_missing or password_rejected) and detected_at."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "List roles whose stored password no longer matches the broker.",
+			HelpDescription: "Returns the roles the drift check, enabled with drift_check_interval in config/settings, found with a CLI user missing from a broker or a password the broker rejects. Rotate or sync a role to reconcile it.",
+		},
+	}
+}
+
+// statusResponseFields describes a status read. The last_periodic fields are
+// returned once the periodic function has completed a pass.
+var statusResponseFields = map[string]*framework.FieldSchema{
+	"broker_count":               {Type: framework.TypeInt, Description: "Number of configured brokers."},
+	"role_count":                 {Type: framework.TypeInt, Description: "Number of configured roles."},
+	"overdue_roles":              {Type: framework.TypeInt, Description: "Number of roles overdue for rotation."},
+	"suspended_roles":            {Type: framework.TypeInt, Description: "Number of rotating roles on a broker that is unreachable or backing off."},
+	"unreachable_brokers":        {Type: framework.TypeStringSlice, Description: "Brokers whose circuit is open."},
+	"restore_suspect_roles":      {Type: framework.TypeStringSlice, Description: "Roles whose stored password may predate a snapshot restore."},
+	"non_compliant_roles":        {Type: framework.TypeStringSlice, Description: "Roles whose credential is older than their max_password_age."},
+	"drifted_roles":              {Type: framework.TypeStringSlice, Description: "Roles the drift check found out of step with their broker."},
+	"last_drift_check":           {Type: framework.TypeTime, Description: "When the last complete drift check finished."},
+	"last_periodic_run":          {Type: framework.TypeTime, Description: "When the last periodic run started."},
+	"last_periodic_duration_ms":  {Type: framework.TypeInt64, Description: "How long the last periodic run took, in milliseconds."},
+	"last_periodic_considered":   {Type: framework.TypeInt, Description: "Roles the last periodic run looked at."},
+	"last_periodic_rotated":      {Type: framework.TypeInt, Description: "Roles rotated by the last periodic run."},
+	"last_periodic_skipped":      {Type: framework.TypeInt, Description: "Due roles the last periodic run left alone because their broker was backing off, their configuration could not be read or a rotation blackout was on."},
+	"last_periodic_failed":       {Type: framework.TypeInt, Description: "Roles that failed to rotate in the last periodic run."},
+	"last_periodic_carried_over": {Type: framework.TypeInt, Description: "Roles left for the next periodic run."},
+}
+
+func (b *solaceBackend) pathStatusRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	brokers, err := listBrokers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// A broker is unreachable while its circuit is open, and rotation is
+	// suspended for its roles while it is unreachable or backing off.
+	unreachable := []string{}
+	suspendedBrokers := make(map[string]bool)
+	for _, name := range brokers {
+		if state, _ := b.brokerBreaker(name).state(); state == circuitOpen {
+			unreachable = append(unreachable, name)
+			suspendedBrokers[name] = true
+		}
+		if _, deferred := b.brokerDeferredUntil(name); deferred {
+			suspendedBrokers[name] = true
+		}
+	}
+	sort.Strings(unreachable)
+
+	roles, err := listRoles(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	overdue, suspended := 0, 0
+	nonCompliant := []string{}
+	for _, name := range roles {
+		role, err := getRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+		if roleOverdue(role, now) > 0 {
+			overdue++
+		}
+		if roleNonCompliant(role, now) {
+			nonCompliant = append(nonCompliant, name)
+		}
+		if role.RotationPeriod > 0 {
+			brokers, err := roleBrokers(ctx, req.Storage, role)
+			if err != nil {
+				return nil, err
+			}
+			if slices.ContainsFunc(brokers, func(broker string) bool { return suspendedBrokers[broker] }) {
+				suspended++
+			}
+		}
+	}
+
+	suspects, err := listRestoreSuspects(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if suspects == nil {
+		suspects = []string{}
+	}
+	drifted, err := listDrift(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if drifted == nil {
+		drifted = []string{}
+	}
+
+	data := map[string]interface{}{
+		"broker_count":          len(brokers),
+		"role_count":            len(roles),
+		"overdue_roles":         overdue,
+		"suspended_roles":       suspended,
+		"unreachable_brokers":   unreachable,
+		"restore_suspect_roles": suspects,
+		"non_compliant_roles":   nonCompliant,
+		"drifted_roles":         drifted,
+	}
+	driftCheck, err := getDriftCheckState(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if driftCheck != nil && !driftCheck.LastCompleted.IsZero() {
+		data["last_drift_check"] = driftCheck.LastCompleted.Format(time.RFC3339)
+	}
+	run, err := getLastPeriodicRun(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if run != nil {
+		data["last_periodic_run"] = run.Started.UTC().Format(time.RFC3339)
+		data["last_periodic_duration_ms"] = run.Duration.Milliseconds()
+		data["last_periodic_considered"] = run.Considered
+		data["last_periodic_rotated"] = run.Rotated
+		data["last_periodic_skipped"] = run.Skipped
+		data["last_periodic_failed"] = run.Failed
+		data["last_periodic_carried_over"] = run.CarriedOver
+	}
+
+	return &logical.Response{Data: data}, nil
+}
+
+func (b *solaceBackend) pathStatusOverdueRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := listRoles(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	keys := []string{}
+	keyInfo := make(map[string]interface{})
+	for _, name := range roles {
+		role, err := getRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+		overdueBy := roleOverdue(role, now)
+		if overdueBy <= 0 {
+			continue
+		}
+		keys = append(keys, name)
+		info := map[string]interface{}{
+			"broker":          role.Broker,
+			"cli_username":    role.CLIUsername,
+			"rotation_period": int(role.RotationPeriod.Seconds()),
+			"last_rotated":    role.LastRotated.Format(time.RFC3339),
+			"overdue_seconds": int64(overdueBy.Seconds()),
+		}
+		// Match creds/: a group role names its group instead of a broker.
+		if role.BrokerGroup != "" {
+			delete(info, "broker")
+			info["broker_group"] = role.BrokerGroup
+		}
+		keyInfo[name] = info
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func (b *solaceBackend) pathStatusDriftRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	names, err := listDrift(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	keyInfo := make(map[string]interface{})
+	for _, name := range names {
+		drift, err := getDrift(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if drift == nil {
+			continue
+		}
+		keys = append(keys, name)
+		keyInfo[name] = map[string]interface{}{
+			"broker":      drift.Broker,
+			"reason":      drift.Reason,
+			"detected_at": drift.DetectedAt.Format(time.RFC3339),
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
@@ -0,0 +1,241 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathStatus_Summary(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	writeBroker(t, b, storage, "healthy")
+	writeBroker(t, b, storage, "down")
+
+	putRole(ctx, storage, "on-time", &RoleEntry{
+		Broker:         "healthy",
+		CLIUsername:    "a",
+		RotationPeriod: time.Hour,
+		PasswordLength: defaultPasswordLength,
+		LastRotated:    time.Now(),
+	})
+	putRole(ctx, storage, "late", &RoleEntry{
+		Broker:         "down",
+		CLIUsername:    "b",
+		RotationPeriod: time.Hour,
+		PasswordLength: defaultPasswordLength,
+		LastRotated:    time.Now().Add(-2 * time.Hour),
+	})
+	putRole(ctx, storage, "manual", &RoleEntry{
+		Broker:         "down",
+		CLIUsername:    "c",
+		PasswordLength: defaultPasswordLength,
+	})
+
+	breaker := sb.brokerBreaker("down")
+	for i := 0; i < defaultBreakerThreshold; i++ {
+		breaker.record(&SEMPError{Class: sempErrTransport, Err: errors.New("connection refused")})
+	}
+
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "status",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read status: err=%v, resp=%v", err, resp)
+	}
+
+	if resp.Data["broker_count"] != 2 {
+		t.Errorf("broker_count = %v, want 2", resp.Data["broker_count"])
+	}
+	if resp.Data["role_count"] != 3 {
+		t.Errorf("role_count = %v, want 3", resp.Data["role_count"])
+	}
+	if resp.Data["overdue_roles"] != 1 {
+		t.Errorf("overdue_roles = %v, want 1", resp.Data["overdue_roles"])
+	}
+	if resp.Data["suspended_roles"] != 1 {
+		t.Errorf("suspended_roles = %v, want 1", resp.Data["suspended_roles"])
+	}
+	unreachable := resp.Data["unreachable_brokers"].([]string)
+	if len(unreachable) != 1 || unreachable[0] != "down" {
+		t.Errorf("unreachable_brokers = %v, want [down]", unreachable)
+	}
+	if _, ok := resp.Data["last_periodic_run"]; !ok {
+		t.Error("expected last_periodic_run after a periodic pass")
+	}
+	if resp.Data["last_periodic_failed"] != 1 {
+		t.Errorf("last_periodic_failed = %v, want 1", resp.Data["last_periodic_failed"])
+	}
+}
+
+func TestPathStatus_LastPeriodicRunPersisted(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	role, _ := getRole(ctx, storage, "test-role")
+	role.RotationPeriod = time.Hour
+	role.LastRotated = time.Now().Add(-2 * time.Hour)
+	putRole(ctx, storage, "test-role", role)
+
+	writeBroker(t, b, storage, "backing-off")
+	putRole(ctx, storage, "deferred", &RoleEntry{
+		Broker:         "backing-off",
+		CLIUsername:    "d",
+		RotationPeriod: time.Hour,
+		PasswordLength: defaultPasswordLength,
+		LastRotated:    time.Now().Add(-2 * time.Hour),
+	})
+	sb.deferBroker("backing-off", time.Now().Add(time.Hour))
+
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+
+	// Another node, or this one after a restart, reports the same pass.
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+	other, err := Factory(ctx, config)
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	resp, err := other.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "status",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read status: err=%v, resp=%v", err, resp)
+	}
+	if _, ok := resp.Data["last_periodic_run"]; !ok {
+		t.Error("expected last_periodic_run from storage")
+	}
+	for field, want := range map[string]int{
+		"last_periodic_considered":   2,
+		"last_periodic_rotated":      1,
+		"last_periodic_skipped":      1,
+		"last_periodic_failed":       0,
+		"last_periodic_carried_over": 0,
+	} {
+		if resp.Data[field] != want {
+			t.Errorf("%s = %v, want %d", field, resp.Data[field], want)
+		}
+	}
+}
+
+func TestPathStatus_NonCompliant(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	writeBroker(t, b, storage, "test-broker")
+	for name, role := range map[string]*RoleEntry{
+		"stale":     {MaxPasswordAge: 2 * time.Hour, LastRotated: time.Now().Add(-3 * time.Hour)},
+		"fresh":     {MaxPasswordAge: 2 * time.Hour, LastRotated: time.Now().Add(-time.Hour)},
+		"unchecked": {LastRotated: time.Now().Add(-3 * time.Hour)},
+	} {
+		role.Broker = "test-broker"
+		role.CLIUsername = name
+		role.PasswordLength = defaultPasswordLength
+		putRole(ctx, storage, name, role)
+	}
+
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if got := sb.nonCompliantRoles(time.Now()); got != 1 {
+		t.Errorf("nonCompliantRoles = %d, want 1", got)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "status",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read status: err=%v, resp=%v", err, resp)
+	}
+	if got := resp.Data["non_compliant_roles"].([]string); len(got) != 1 || got[0] != "stale" {
+		t.Errorf("non_compliant_roles = %v, want [stale]", got)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "roles/",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("list roles: err=%v, resp=%v", err, resp)
+	}
+	if _, ok := resp.Data["key_info"]; ok {
+		t.Error("expected no key_info without detailed")
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "roles/",
+		Storage:   storage,
+		Data:      map[string]interface{}{"detailed": true},
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("list roles: err=%v, resp=%v", err, resp)
+	}
+	keyInfo := resp.Data["key_info"].(map[string]interface{})
+	for name, want := range map[string]bool{"stale": true, "fresh": false, "unchecked": false} {
+		if got := keyInfo[name].(map[string]interface{})["non_compliant"]; got != want {
+			t.Errorf("%s non_compliant = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestPathStatus_Overdue(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeBroker(t, b, storage, "test-broker")
+	putRole(ctx, storage, "late", &RoleEntry{
+		Broker:         "test-broker",
+		CLIUsername:    "monitor",
+		RotationPeriod: time.Hour,
+		PasswordLength: defaultPasswordLength,
+		LastRotated:    time.Now().Add(-3 * time.Hour),
+	})
+	putRole(ctx, storage, "on-time", &RoleEntry{
+		Broker:         "test-broker",
+		CLIUsername:    "backup",
+		RotationPeriod: time.Hour,
+		PasswordLength: defaultPasswordLength,
+		LastRotated:    time.Now(),
+	})
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "status/overdue",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read: err=%v, resp=%v", err, resp)
+	}
+
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 1 || keys[0] != "late" {
+		t.Fatalf("keys = %v, want [late]", keys)
+	}
+	info := resp.Data["key_info"].(map[string]interface{})["late"].(map[string]interface{})
+	overdue := info["overdue_seconds"].(int64)
+	if overdue < 2*3600-5 || overdue > 2*3600+5 {
+		t.Errorf("overdue_seconds = %d, want about 7200", overdue)
+	}
+}
@@ -0,0 +1,131 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathSync(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "sync/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "sync",
+				OperationSuffix: "role",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role whose stored password should be pushed to the broker.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathSyncWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses:                   noContentResponses,
+				},
+			},
+			HelpSynopsis:    "Re-apply a role's stored password to the broker.",
+			HelpDescription: "Sets the CLI user's password on the broker to the password currently stored in Vault, without generating a new one. Use it when the broker was restored from its own backup and no longer accepts the stored password.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathSyncWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	ctx = withSEMPActivity(withSEMPRequestID(ctx, req.ID), req.Storage)
+
+	if !b.canWriteBrokers() {
+		return nil, logical.ErrReadOnly
+	}
+
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role %q not found", name), nil
+	}
+	if role.isCloudToken() {
+		return logical.ErrorResponse("role %q holds a Solace Cloud API token, which can only be regenerated, not set; run rotate-role/%s instead", name, name), nil
+	}
+
+	secret, err := getRoleSecret(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if secret.empty() {
+		return logical.ErrorResponse("role %q has no stored password to sync; run rotate-role/%s instead", name, name), nil
+	}
+
+	cred := credentialFromSecret(secret)
+	defer cred.wipe()
+
+	if role.BrokerGroup != "" {
+		if resp, err := b.syncGroupRole(ctx, req.Storage, name, role, cred); resp != nil || err != nil {
+			return resp, err
+		}
+		return b.finishSync(ctx, req.Storage, name, role)
+	}
+
+	brokerConfig, err := getBroker(ctx, req.Storage, role.Broker)
+	if err != nil {
+		return nil, err
+	}
+	if brokerConfig == nil {
+		return logical.ErrorResponse("broker %q not found for role %q", role.Broker, name), nil
+	}
+
+	client, err := b.activeSEMPClient(ctx, role.Broker, brokerConfig)
+	var unlock func()
+	if err == nil {
+		unlock, err = b.acquireRotationLock(ctx, client, role, brokerConfig.RotationLockTTL)
+	}
+	if err == nil {
+		defer unlock()
+		err = b.applyCredential(ctx, client, role, cred)
+	}
+	if err != nil {
+		if sempErrorClass(err) == sempErrCircuitOpen {
+			return logical.ErrorResponse("broker %q is unavailable after repeated failures; sync for role %q was not attempted", role.Broker, name), nil
+		}
+		if errors.Is(err, errRotationLocked) {
+			return logical.ErrorResponse("sync of role %q was not attempted: on broker %q its %s", name, role.Broker, err), nil
+		}
+		b.Logger().Error("SEMP password sync failed",
+			"role", name,
+			"cli_username", role.CLIUsername,
+			"broker", role.Broker,
+			"error", err,
+		)
+		return logical.ErrorResponse("failed to sync password for role %q on broker %q", name, role.Broker), nil
+	}
+
+	return b.finishSync(ctx, req.Storage, name, role)
+}
+
+// finishSync records a sync once every broker has the stored password.
+func (b *solaceBackend) finishSync(ctx context.Context, s logical.Storage, name string, role *RoleEntry) (*logical.Response, error) {
+	// The broker now has the stored password, so a restore or drift flag
+	// no longer applies.
+	if err := deleteRestoreSuspect(ctx, s, name); err != nil {
+		return nil, err
+	}
+	if err := deleteDrift(ctx, s, name); err != nil {
+		return nil, err
+	}
+	b.sendEvent(ctx, eventSync, "role", name, "broker", role.location(), "cli_username", role.CLIUsername)
+
+	return nil, nil
+}
@@ -0,0 +1,79 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathSync_PushesStoredPassword(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	lastRotated := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	putBroker(ctx, storage, "test-broker", &BrokerConfig{SEMPURL: server.URL, AdminUsername: "admin", AdminPassword: "secret"})
+	putRole(ctx, storage, "test-role", &RoleEntry{
+		Broker:         "test-broker",
+		CLIUsername:    "monitor",
+		PasswordLength: defaultPasswordLength,
+		LastRotated:    lastRotated,
+	})
+	putRoleSecret(ctx, storage, "test-role", &RoleSecret{Password: "stored-password-123"})
+	putRestoreSuspect(ctx, storage, "test-role", &restoreSuspect{DetectedAt: time.Now()})
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sync/test-role",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("sync: err=%v, resp=%v", err, resp)
+	}
+
+	if !strings.Contains(body, "stored-password-123") {
+		t.Error("expected the stored password to be sent to the broker")
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+	if secret.Password != "stored-password-123" {
+		t.Error("sync must not change the stored password")
+	}
+	role, _ := getRole(ctx, storage, "test-role")
+	if !role.LastRotated.Equal(lastRotated) {
+		t.Errorf("last_rotated = %v, want unchanged %v", role.LastRotated, lastRotated)
+	}
+	if suspect, _ := getRestoreSuspect(ctx, storage, "test-role"); suspect != nil {
+		t.Error("sync should clear the restore flag")
+	}
+}
+
+func TestPathSync_NoStoredPassword(t *testing.T) {
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sync/test-role",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Error("expected error when the role has never been rotated")
+	}
+}
@@ -0,0 +1,231 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathTidy(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "tidy$",
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "tidy",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"cleanup": {
+					Type:        framework.TypeBool,
+					Description: "Delete the orphaned entries found. When false, tidy only reports them.",
+					Default:     false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.pathTidyWrite,
+					ForwardPerformanceStandby:   true,
+					ForwardPerformanceSecondary: true,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"orphaned_roles":             {Type: framework.TypeStringSlice, Description: "Roles whose broker or broker group no longer exists."},
+								"expired_previous_passwords": {Type: framework.TypeStringSlice, Description: "Roles whose stored secret still holds the credential it replaced after the propagation delay ended."},
+								"expired_rotation_locks":     {Type: framework.TypeMap, Description: "Rotation lock users past their expiry, by broker."},
+								"cleaned_up":                 {Type: framework.TypeBool, Description: "Whether the entries found were deleted."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Find and optionally remove orphaned storage entries.",
+			HelpDescription: "Scans storage for roles that reference deleted brokers or broker groups and for replaced credentials kept past their propagation delay, and the brokers for expired rotation lock users. Reports them and, with cleanup=true, removes them.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathTidyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	cleanup := d.Get("cleanup").(bool)
+	now := time.Now()
+	resp := &logical.Response{}
+
+	orphanedRoles, err := findOrphanedRoles(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	expiredPrevious, err := findExpiredPrevious(ctx, req.Storage, now)
+	if err != nil {
+		return nil, err
+	}
+	expiredLocks := b.findExpiredRotationLocks(ctx, req.Storage, now, resp)
+
+	if cleanup {
+		for _, name := range orphanedRoles {
+			// The role's last credential is retained, as on a delete with
+			// purge_history=false, in case it is still in use somewhere.
+			lock := b.roleLock(name)
+			lock.Lock()
+			err := b.removeRole(ctx, req.Storage, name, false)
+			lock.Unlock()
+			if err != nil {
+				return nil, fmt.Errorf("deleting orphaned role %q: %w", name, err)
+			}
+			b.Logger().Info("tidy: deleted role referencing missing broker", "role", name)
+		}
+		for _, name := range expiredPrevious {
+			if err := b.dropExpiredPrevious(ctx, req.Storage, name, now); err != nil {
+				return nil, fmt.Errorf("removing previous credential of role %q: %w", name, err)
+			}
+		}
+		for broker, locks := range expiredLocks {
+			b.deleteExpiredRotationLocks(ctx, req.Storage, broker, locks, resp)
+		}
+	}
+
+	lockData := make(map[string]interface{}, len(expiredLocks))
+	for broker, locks := range expiredLocks {
+		lockData[broker] = locks
+	}
+	resp.Data = map[string]interface{}{
+		"orphaned_roles":             orphanedRoles,
+		"expired_previous_passwords": expiredPrevious,
+		"expired_rotation_locks":     lockData,
+		"cleaned_up":                 cleanup,
+	}
+	return resp, nil
+}
+
+// findOrphanedRoles returns the roles whose broker, or broker group, no
+// longer exists.
+func findOrphanedRoles(ctx context.Context, s logical.Storage) ([]string, error) {
+	brokers, err := listBrokers(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("listing brokers: %w", err)
+	}
+	known := make(map[string]bool, len(brokers))
+	for _, name := range brokers {
+		known[name] = true
+	}
+
+	roles, err := listRoles(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("listing roles: %w", err)
+	}
+	orphaned := []string{}
+	for _, name := range roles {
+		role, err := getRole(ctx, s, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading role %q: %w", name, err)
+		}
+		if role == nil {
+			continue
+		}
+		if role.BrokerGroup != "" {
+			group, err := getBrokerGroup(ctx, s, role.BrokerGroup)
+			if err != nil {
+				return nil, fmt.Errorf("reading broker group %q: %w", role.BrokerGroup, err)
+			}
+			if group == nil {
+				orphaned = append(orphaned, name)
+			}
+			continue
+		}
+		if !known[role.Broker] {
+			orphaned = append(orphaned, name)
+		}
+	}
+	return orphaned, nil
+}
+
+// findExpiredPrevious returns the roles whose stored secret still holds the
+// credential it replaced, although creds/ stopped serving it at its
+// propagation delay's end.
+func findExpiredPrevious(ctx context.Context, s logical.Storage, now time.Time) ([]string, error) {
+	roles, err := listRoles(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("listing roles: %w", err)
+	}
+	expired := []string{}
+	for _, name := range roles {
+		secret, err := getRoleSecret(ctx, s, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading secret of role %q: %w", name, err)
+		}
+		if secret != nil && secret.Previous != nil && !now.Before(secret.ServedFrom) {
+			expired = append(expired, name)
+		}
+	}
+	return expired, nil
+}
+
+// dropExpiredPrevious removes the replaced credential from a role's stored
+// secret, if its propagation delay is still over when the role is locked.
+func (b *solaceBackend) dropExpiredPrevious(ctx context.Context, s logical.Storage, name string, now time.Time) error {
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	secret, err := getRoleSecret(ctx, s, name)
+	if err != nil || secret == nil || secret.Previous == nil || now.Before(secret.ServedFrom) {
+		return err
+	}
+	secret.Previous = nil
+	secret.ServedFrom = time.Time{}
+	if err := putRoleSecret(ctx, s, name, secret); err != nil {
+		return err
+	}
+	b.Logger().Info("tidy: removed previous credential past its propagation delay", "role", name)
+	return nil
+}
+
+// findExpiredRotationLocks returns, by broker, the rotation lock users past
+// their expiry: locks left behind by rotations that never released them. A
+// broker that cannot be asked adds a warning to resp and is skipped.
+func (b *solaceBackend) findExpiredRotationLocks(ctx context.Context, s logical.Storage, now time.Time, resp *logical.Response) map[string][]string {
+	expired := make(map[string][]string)
+	brokers, err := listBrokers(ctx, s)
+	if err != nil {
+		resp.AddWarning(fmt.Sprintf("rotation locks were not checked: listing brokers: %v", err))
+		return expired
+	}
+	for _, broker := range brokers {
+		config, err := getBroker(ctx, s, broker)
+		if err != nil || config == nil {
+			continue
+		}
+		names, err := b.sempClient(broker, config).ListUsernames(ctx, rotationLockPrefix+"*")
+		if err != nil {
+			resp.AddWarning(fmt.Sprintf("rotation locks on broker %q were not checked: %v", broker, err))
+			continue
+		}
+		for _, name := range names {
+			if expiry, ok := rotationLockExpiry(name); ok && !expiry.After(now) {
+				expired[broker] = append(expired[broker], name)
+			}
+		}
+	}
+	return expired
+}
+
+// deleteExpiredRotationLocks deletes expired rotation lock users from a
+// broker. Failures add a warning to resp; the next rotation of the
+// credential, or the next tidy, tries again.
+func (b *solaceBackend) deleteExpiredRotationLocks(ctx context.Context, s logical.Storage, broker string, locks []string, resp *logical.Response) {
+	config, err := getBroker(ctx, s, broker)
+	if err != nil || config == nil {
+		return
+	}
+	client := b.sempClient(broker, config)
+	for _, name := range locks {
+		if err := client.DeleteUser(ctx, name); err != nil {
+			resp.AddWarning(fmt.Sprintf("expired rotation lock %q on broker %q could not be deleted: %v", name, broker, err))
+			continue
+		}
+		b.Logger().Info("tidy: deleted expired rotation lock", "lock", name, "broker", broker)
+	}
+}
@@ -0,0 +1,102 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathTidy_OrphanedRoles(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeBroker(t, b, storage, "test-broker")
+	putRole(ctx, storage, "healthy", &RoleEntry{Broker: "test-broker", CLIUsername: "a"})
+	putRole(ctx, storage, "orphan", &RoleEntry{Broker: "deleted-broker", CLIUsername: "b"})
+
+	// Report only
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("tidy: err=%v, resp=%v", err, resp)
+	}
+	orphaned := resp.Data["orphaned_roles"].([]string)
+	if len(orphaned) != 1 || orphaned[0] != "orphan" {
+		t.Errorf("orphaned_roles = %v, want [orphan]", orphaned)
+	}
+	if role, _ := getRole(ctx, storage, "orphan"); role == nil {
+		t.Fatal("report-only tidy must not delete roles")
+	}
+
+	// Cleanup
+	req.Data = map[string]interface{}{"cleanup": true}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("tidy cleanup: err=%v, resp=%v", err, resp)
+	}
+	if role, _ := getRole(ctx, storage, "orphan"); role != nil {
+		t.Error("orphaned role should be deleted by cleanup")
+	}
+	if role, _ := getRole(ctx, storage, "healthy"); role == nil {
+		t.Error("healthy role must not be deleted")
+	}
+}
+
+func TestPathTidy_ExpiredPreviousAndLocks(t *testing.T) {
+	ub := &userBroker{users: map[string]bool{"app": true}}
+	server := httptest.NewServer(ub)
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	b, storage, _ = setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+
+	pattern := rotationLockPattern("cli_user/app")
+	expired := pattern + strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10) + "-abcd"
+	live := pattern + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + "-beef"
+	ub.add(expired)
+	ub.add(live)
+
+	putRoleSecret(ctx, storage, "test-role", &RoleSecret{
+		Password:   "Current-Password-1234",
+		Previous:   &RoleSecret{Password: "Previous-Password-1234"},
+		ServedFrom: time.Now().Add(-time.Minute),
+	})
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("tidy: err=%v, resp=%v", err, resp)
+	}
+	if got := resp.Data["expired_previous_passwords"].([]string); len(got) != 1 || got[0] != "test-role" {
+		t.Errorf("expired_previous_passwords = %v, want [test-role]", got)
+	}
+	locks := resp.Data["expired_rotation_locks"].(map[string]interface{})
+	if got, _ := locks["test-broker"].([]string); len(got) != 1 || got[0] != expired {
+		t.Errorf("expired_rotation_locks = %v, want only %s", locks, expired)
+	}
+
+	req.Data = map[string]interface{}{"cleanup": true}
+	if resp, err := b.HandleRequest(ctx, req); err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("tidy cleanup: err=%v, resp=%v", err, resp)
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+	if secret.Previous != nil || secret.Password != "Current-Password-1234" {
+		t.Errorf("expected only the previous credential removed, got %+v", secret)
+	}
+	if got := ub.locks(); len(got) != 1 || got[0] != live {
+		t.Errorf("locks left = %v, want only the live one", got)
+	}
+}
@@ -0,0 +1,220 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathVerify(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "verify/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "verify",
+				OperationSuffix: "role",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role to verify.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathVerifyRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      verifyResponseFields,
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Verify that a role's CLI user, REST consumer or OAuth profile exists on its broker.",
+			HelpDescription: "Queries the broker to confirm the CLI user, REST consumer or OAuth profile associated with the named role exists and is enabled.",
+		},
+	}
+}
+
+// verifyResponseFields describes a verification. Only the fields of the
+// role's target are returned; a role on a broker group reports each member
+// under brokers.
+var verifyResponseFields = map[string]*framework.FieldSchema{
+	"broker":              {Type: framework.TypeString, Description: "Name of the broker checked."},
+	"broker_group":        {Type: framework.TypeString, Description: "Name of the broker group checked."},
+	"brokers":             {Type: framework.TypeMap, Description: "What each member of the broker group reports."},
+	"cli_username":        {Type: framework.TypeString, Description: "CLI username on the broker."},
+	"global_access_level": {Type: framework.TypeString, Description: "Global access level of the CLI user."},
+	"msg_vpn":             {Type: framework.TypeString, Description: "Message VPN of the REST delivery point or OAuth profile."},
+	"rest_delivery_point": {Type: framework.TypeString, Description: "REST delivery point of the REST consumer."},
+	"rest_consumer":       {Type: framework.TypeString, Description: "REST consumer checked."},
+	"rest_consumer_auth":  {Type: framework.TypeString, Description: "How the REST consumer currently authenticates."},
+	"oauth_profile":       {Type: framework.TypeString, Description: "OAuth profile checked."},
+	"exists":              {Type: framework.TypeBool, Description: "Whether the object exists on the broker."},
+	"enabled":             {Type: framework.TypeBool, Description: "Whether the object is enabled."},
+	"restore_suspect":     {Type: framework.TypeBool, Description: "Whether Vault was restored from a snapshot older than the role's last rotation."},
+}
+
+func (b *solaceBackend) pathVerifyRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	ctx = withSEMPRequestID(ctx, req.ID)
+
+	role, err := getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role %q not found", name), nil
+	}
+
+	if role.isCloudToken() {
+		return logical.ErrorResponse("role %q holds a Solace Cloud API token, which has nothing on the broker to verify", name), nil
+	}
+	if role.isSEMPRPC() {
+		return logical.ErrorResponse("role %q sets its password with a custom SEMP request, so there is nothing the plugin knows to verify", name), nil
+	}
+
+	var resp *logical.Response
+	if role.BrokerGroup != "" {
+		resp, err = b.verifyGroupRole(ctx, req.Storage, name, role)
+	} else {
+		resp, err = b.verifyRoleOnBroker(ctx, req.Storage, name, role)
+	}
+	if err != nil || resp.IsError() {
+		return resp, err
+	}
+
+	suspect, err := getRestoreSuspect(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	resp.Data["restore_suspect"] = suspect != nil
+	if suspect != nil {
+		resp.AddWarning("Vault was restored from a snapshot taken before this role's last rotation, so the stored password may not match the broker; rotate-role/" + name + " to reconcile")
+	}
+
+	return resp, nil
+}
+
+// verifyRoleOnBroker checks a single-broker role's CLI user, REST consumer or
+// OAuth profile.
+func (b *solaceBackend) verifyRoleOnBroker(ctx context.Context, s logical.Storage, name string, role *RoleEntry) (*logical.Response, error) {
+	brokerConfig, err := getBroker(ctx, s, role.Broker)
+	if err != nil {
+		return nil, err
+	}
+	if brokerConfig == nil {
+		return logical.ErrorResponse("broker %q not found for role %q", role.Broker, name), nil
+	}
+
+	client := b.sempClient(role.Broker, brokerConfig)
+	switch {
+	case role.isRESTConsumer():
+		return b.verifyRESTConsumer(ctx, client, name, role)
+	case role.isOAuthProfile():
+		return b.verifyOAuthProfile(ctx, client, name, role)
+	default:
+		return b.verifyCLIUser(ctx, client, name, role)
+	}
+}
+
+// verifyCLIUser confirms a role's CLI user exists and is enabled.
+func (b *solaceBackend) verifyCLIUser(ctx context.Context, client *SEMPClient, name string, role *RoleEntry) (*logical.Response, error) {
+	user, err := client.ShowUsername(ctx, role.CLIUsername)
+	if err != nil {
+		b.Logger().Error("SEMP show username failed",
+			"role", name,
+			"cli_username", role.CLIUsername,
+			"broker", role.Broker,
+			"error", err,
+		)
+		return logical.ErrorResponse("failed to query CLI user for role %q on broker %q", name, role.Broker), nil
+	}
+	if user == nil {
+		return logical.ErrorResponse("CLI user %q does not exist on broker %q", role.CLIUsername, role.Broker), nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"broker":              role.Broker,
+			"cli_username":        user.Name,
+			"exists":              true,
+			"enabled":             user.Enabled,
+			"global_access_level": user.GlobalAccessLevel,
+		},
+	}
+	if !user.Enabled {
+		resp.AddWarning("CLI user " + user.Name + " exists but is disabled; rotated credentials will not be usable until it is enabled")
+	}
+
+	return resp, nil
+}
+
+// verifyRESTConsumer confirms a REST consumer role's consumer exists and
+// authenticates the way the role rotates.
+func (b *solaceBackend) verifyRESTConsumer(ctx context.Context, client *SEMPClient, name string, role *RoleEntry) (*logical.Response, error) {
+	consumer, err := client.GetRESTConsumer(ctx, role.MsgVPN, role.RESTDeliveryPoint, role.RESTConsumer)
+	if err != nil {
+		b.Logger().Error("SEMP get REST consumer failed",
+			"role", name,
+			"rest_consumer", role.RESTConsumer,
+			"broker", role.Broker,
+			"error", err,
+		)
+		return logical.ErrorResponse("failed to query REST consumer for role %q on broker %q", name, role.Broker), nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"broker":              role.Broker,
+			"msg_vpn":             role.MsgVPN,
+			"rest_delivery_point": role.RESTDeliveryPoint,
+			"rest_consumer":       consumer.RESTConsumerName,
+			"exists":              true,
+			"enabled":             consumer.Enabled,
+			"rest_consumer_auth":  consumer.AuthenticationScheme,
+		},
+	}
+	if consumer.AuthenticationScheme != role.RESTAuthScheme {
+		resp.AddWarning("REST consumer " + role.RESTConsumer + " authenticates with " + consumer.AuthenticationScheme + " but the role rotates " + role.RESTAuthScheme + "; rotate-role/" + name + " to switch it")
+	}
+	if !consumer.Enabled {
+		resp.AddWarning("REST consumer " + role.RESTConsumer + " exists but is disabled; rotated credentials will not be used until it is enabled")
+	}
+
+	return resp, nil
+}
+
+// verifyOAuthProfile confirms an oauth_profile role's OAuth profile exists.
+func (b *solaceBackend) verifyOAuthProfile(ctx context.Context, client *SEMPClient, name string, role *RoleEntry) (*logical.Response, error) {
+	profile, err := client.GetOAuthProfile(ctx, role.MsgVPN, role.OAuthProfile)
+	if err != nil {
+		b.Logger().Error("SEMP get OAuth profile failed",
+			"role", name,
+			"oauth_profile", role.OAuthProfile,
+			"broker", role.Broker,
+			"error", err,
+		)
+		return logical.ErrorResponse("failed to query OAuth profile for role %q on broker %q", name, role.Broker), nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"broker":        role.Broker,
+			"msg_vpn":       role.MsgVPN,
+			"oauth_profile": profile.OAuthProfileName,
+			"exists":        true,
+			"enabled":       profile.Enabled,
+		},
+	}
+	if !profile.Enabled {
+		resp.AddWarning("OAuth profile " + role.OAuthProfile + " exists but is disabled")
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,161 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathVerifyPassword(b *solaceBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "verify-password/" + framework.GenericNameRegex("name"),
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixSolace,
+				OperationVerb:   "verify",
+				OperationSuffix: "password",
+			},
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role whose password to check.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathVerifyPasswordRead,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"broker":       {Type: framework.TypeString, Description: "Name of the broker checked."},
+								"broker_group": {Type: framework.TypeString, Description: "Name of the broker group checked."},
+								"brokers":      {Type: framework.TypeMap, Description: "Whether each member of the broker group accepts the stored password."},
+								"cli_username": {Type: framework.TypeString, Description: "CLI username on the broker."},
+								"matches":      {Type: framework.TypeBool, Description: "Whether the broker, or every member of the group, accepts the stored password."},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    "Check that the broker accepts a role's stored password.",
+			HelpDescription: "Logs in to the broker as the CLI user of the named role with the password Vault holds, to detect passwords changed on the broker outside Vault.",
+		},
+	}
+}
+
+func (b *solaceBackend) pathVerifyPasswordRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	ctx = withSEMPRequestID(ctx, req.ID)
+
+	// Hold the role still so a rotation cannot change the password between
+	// reading it and trying it.
+	lock := b.roleLock(name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	role, err := getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role %q not found", name), nil
+	}
+	// Only a CLI user can log in with its credential.
+	if !role.isCLIUser() {
+		return logical.ErrorResponse("role %q does not rotate a CLI user's password, so there is no login to check", name), nil
+	}
+
+	secret, err := getRoleSecret(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if secret.empty() {
+		return logical.ErrorResponse("password for role %q has not been rotated yet; run rotate-role/%s first", name, name), nil
+	}
+	password := []byte(secret.Password)
+	defer wipe(password)
+
+	var resp *logical.Response
+	if role.BrokerGroup != "" {
+		resp, err = b.verifyGroupPassword(ctx, req.Storage, name, role, password)
+	} else {
+		resp, err = b.verifyBrokerPassword(ctx, req.Storage, name, role, password)
+	}
+	if err != nil || resp.IsError() {
+		return resp, err
+	}
+	if !resp.Data["matches"].(bool) {
+		resp.AddWarning("the stored password for role " + name + " is not the one the broker holds, so it may have been changed outside Vault; rotate-role/" + name + " or sync/" + name + " to reconcile")
+	}
+	return resp, nil
+}
+
+// verifyBrokerPassword checks a single-broker role's stored password.
+func (b *solaceBackend) verifyBrokerPassword(ctx context.Context, s logical.Storage, name string, role *RoleEntry, password []byte) (*logical.Response, error) {
+	brokerConfig, err := getBroker(ctx, s, role.Broker)
+	if err != nil {
+		return nil, err
+	}
+	if brokerConfig == nil {
+		return logical.ErrorResponse("broker %q not found for role %q", role.Broker, name), nil
+	}
+
+	accepted, err := b.sempClient(role.Broker, brokerConfig).CheckLogin(ctx, role.CLIUsername, password)
+	if err != nil {
+		b.Logger().Error("SEMP login check failed",
+			"role", name,
+			"cli_username", role.CLIUsername,
+			"broker", role.Broker,
+			"error", err,
+		)
+		return logical.ErrorResponse("failed to check password for role %q on broker %q", name, role.Broker), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"broker":       role.Broker,
+			"cli_username": role.CLIUsername,
+			"matches":      accepted,
+		},
+	}, nil
+}
+
+// verifyGroupPassword checks a group role's stored password on every member
+// of its broker group.
+func (b *solaceBackend) verifyGroupPassword(ctx context.Context, s logical.Storage, name string, role *RoleEntry, password []byte) (*logical.Response, error) {
+	members, resp, err := groupMembers(ctx, s, name, role)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	brokers := make(map[string]interface{}, len(members))
+	matches := true
+	for _, member := range members {
+		accepted, err := b.sempClient(member.name, member.config).CheckLogin(ctx, role.CLIUsername, password)
+		if err != nil {
+			b.Logger().Error("SEMP login check failed",
+				"role", name,
+				"cli_username", role.CLIUsername,
+				"broker_group", role.BrokerGroup,
+				"broker", member.name,
+				"error", err,
+			)
+			return logical.ErrorResponse("failed to check password for role %q on broker %q of group %q", name, member.name, role.BrokerGroup), nil
+		}
+		brokers[member.name] = map[string]interface{}{"matches": accepted}
+		matches = matches && accepted
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"broker_group": role.BrokerGroup,
+			"cli_username": role.CLIUsername,
+			"brokers":      brokers,
+			"matches":      matches,
+		},
+	}, nil
+}
@@ -0,0 +1,59 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathVerifyPassword(t *testing.T) {
+	pb := &passwordBroker{}
+	server := httptest.NewServer(pb)
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+
+	verify := func() *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "verify-password/test-role",
+			Storage:   storage,
+		})
+		if err != nil {
+			t.Fatalf("verify-password: %v", err)
+		}
+		return resp
+	}
+
+	if resp := verify(); resp == nil || !resp.IsError() {
+		t.Errorf("expected an error before the first rotation, got %v", resp)
+	}
+
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+	resp := verify()
+	if resp == nil || resp.IsError() {
+		t.Fatalf("verify-password: resp=%v", resp)
+	}
+	if resp.Data["matches"] != true || len(resp.Warnings) != 0 {
+		t.Errorf("after rotation: matches=%v, warnings=%v", resp.Data["matches"], resp.Warnings)
+	}
+
+	// Someone changes the password on the broker.
+	pb.mu.Lock()
+	pb.password = "changed-out-of-band-1234"
+	pb.mu.Unlock()
+	resp = verify()
+	if resp == nil || resp.IsError() {
+		t.Fatalf("verify-password: resp=%v", resp)
+	}
+	if resp.Data["matches"] != false || len(resp.Warnings) != 1 {
+		t.Errorf("after out-of-band change: matches=%v, warnings=%v", resp.Data["matches"], resp.Warnings)
+	}
+}
@@ -0,0 +1,110 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPathVerify_UserExists(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><rpc><show><username><usernames><username><name>monitor</name><global-access-level>read-only</global-access-level></username></usernames></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":       server.URL,
+			"admin_username": "admin",
+			"admin_password": "secret",
+		},
+	}
+	b.HandleRequest(ctx, req)
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":       "test-broker",
+			"cli_username": "monitor",
+		},
+	}
+	b.HandleRequest(ctx, req)
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "verify/test-role",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("verify: err=%v, resp=%v", err, resp)
+	}
+	if resp.Data["exists"] != true {
+		t.Errorf("exists = %v, want true", resp.Data["exists"])
+	}
+	if resp.Data["enabled"] != true {
+		t.Errorf("enabled = %v, want true", resp.Data["enabled"])
+	}
+	if resp.Data["global_access_level"] != "read-only" {
+		t.Errorf("global_access_level = %v, want read-only", resp.Data["global_access_level"])
+	}
+}
+
+func TestPathVerify_UserMissing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><rpc><show><username><usernames/></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":       server.URL,
+			"admin_username": "admin",
+			"admin_password": "secret",
+		},
+	}
+	b.HandleRequest(ctx, req)
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test-role",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":       "test-broker",
+			"cli_username": "typo-user",
+		},
+	}
+	b.HandleRequest(ctx, req)
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "verify/test-role",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Error("expected error response when CLI user does not exist")
+	}
+}
@@ -0,0 +1,500 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// defaultPeriodicTimeBudget keeps a pass shorter than Vault's one-minute
+// periodic interval so passes do not overlap.
+const defaultPeriodicTimeBudget = 50 * time.Second
+
+// defaultClockSkewTolerance absorbs the clock differences NTP usually
+// leaves between the nodes of a cluster.
+const defaultClockSkewTolerance = 2 * time.Minute
+
+// periodicIntervalSlack lets a pass start slightly before periodic_interval
+// has elapsed, so the drift of Vault's calls does not push it back a whole
+// call.
+const periodicIntervalSlack = 5 * time.Second
+
+// periodicRun describes a completed periodic pass. The most recent one is
+// kept in memory on the node that ran it and in storage for status reads.
+type periodicRun struct {
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+
+	// Considered is every role the pass looked at. Of those found due,
+	// Rotated and Failed were attempted, Skipped were left alone because
+	// their broker was backing off, their configuration could not be read
+	// or a rotation blackout was on, and CarriedOver were left for the next
+	// pass when the time budget ran out.
+	Considered  int `json:"considered"`
+	Rotated     int `json:"rotated"`
+	Skipped     int `json:"skipped"`
+	Failed      int `json:"failed"`
+	CarriedOver int `json:"carried_over"`
+}
+
+func (b *solaceBackend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	// A rotation rewrites its role, so the cached entry must be dropped
+	// however the pass was started.
+	req.Storage = b.cachedStorage(req.Storage)
+
+	if !b.canWriteBrokers() {
+		b.setWritable(false)
+		return nil
+	}
+	b.setWritable(true)
+
+	settings, err := getSettings(ctx, req.Storage)
+	if err != nil {
+		b.Logger().Error("periodic: failed to read settings", "error", err)
+		return nil
+	}
+	// Roles that came due while the cluster was down or the node was a
+	// secondary all look due at once, so give operators time to check the
+	// brokers first.
+	if remaining := b.startupCooldownRemaining(settings.StartupCooldown); remaining > 0 {
+		b.Logger().Debug("periodic: in startup cool-down, not rotating", "remaining", remaining)
+		return nil
+	}
+	if last := b.lastPeriodicRun(); settings.PeriodicInterval > 0 && !last.Started.IsZero() &&
+		time.Since(last.Started) < settings.PeriodicInterval-periodicIntervalSlack {
+		return nil
+	}
+
+	runID, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	// Rotations in the pass share a SEMP session, so a broker's HA pair is
+	// asked for its active node once rather than before every change.
+	ctx = withMetricsMount(withSEMPSession(withSEMPRequestID(ctx, "periodic-"+runID)), req.MountPoint)
+	run := periodicRun{Started: time.Now()}
+
+	if _, err := b.detectRestore(ctx, req.Storage); err != nil {
+		b.Logger().Error("periodic: failed to check for snapshot restore", "error", err)
+	}
+	if err := b.purgeExpiredRetained(ctx, req.Storage, time.Now()); err != nil {
+		b.Logger().Error("periodic: failed to purge expired retained credentials", "error", err)
+	}
+
+	roles, err := listRoles(ctx, req.Storage)
+	if err != nil {
+		b.Logger().Error("periodic: failed to list roles", "error", err)
+		return nil
+	}
+	run.Considered = len(roles)
+
+	// Only roles the schedule does not know yet, or that it says are due,
+	// are read from storage.
+	schedule := b.scheduledRoles(roles)
+	var due []string
+	overdue := 0
+	now := time.Now().UTC()
+	blackout := settings.inBlackout(now)
+	if blackout {
+		b.Logger().Debug("periodic: in a rotation blackout, deferring automatic rotations")
+	}
+	for _, name := range roles {
+		jitter := rotationJitter(name, settings.RotationJitter)
+		nextDue, known := schedule[name]
+		if known && !b.rotationDue(name, nextDue, now, jitter, settings.ClockSkewTolerance) {
+			if !nextDue.IsZero() && now.After(nextDue) {
+				overdue++
+			}
+			continue
+		}
+
+		role, err := getRole(ctx, req.Storage, name)
+		if err != nil {
+			b.Logger().Error("periodic: failed to read role", "role", name, "error", err)
+			run.Skipped++
+			continue
+		}
+		if role == nil {
+			b.unscheduleRole(name)
+			continue
+		}
+		b.scheduleRole(name, role)
+		if role.LastRotated.After(now.Add(settings.ClockSkewTolerance)) {
+			b.Logger().Warn("periodic: role was last rotated later than this node's clock reads; node clocks may be skewed",
+				"role", name, "last_rotated", role.LastRotated, "now", now)
+		}
+		if roleOverdue(role, now) > 0 {
+			overdue++
+		}
+		if !b.rotationDue(name, roleNextDue(role), now, jitter, settings.ClockSkewTolerance) {
+			continue
+		}
+		brokers, err := roleBrokers(ctx, req.Storage, role)
+		if err != nil {
+			b.Logger().Error("periodic: failed to read broker group", "role", name, "broker_group", role.BrokerGroup, "error", err)
+			run.Skipped++
+			continue
+		}
+		if broker, until, deferred := b.firstDeferredBroker(brokers); deferred {
+			b.Logger().Debug("periodic: broker asked to back off, deferring rotation",
+				"role", name, "broker", broker, "until", until)
+			run.Skipped++
+			continue
+		}
+		if blackout {
+			run.Skipped++
+			continue
+		}
+		due = append(due, name)
+	}
+	due = b.resumeAfterCursor(due)
+
+	// The time budget bounds the whole pass. Rotations that have not yet
+	// sent their change when it runs out give up, and the roles not started
+	// wait for the next pass, which starts with them.
+	var deadline time.Time
+	budgetCtx := ctx
+	if settings.PeriodicTimeBudget > 0 {
+		deadline = run.Started.Add(settings.PeriodicTimeBudget)
+		var cancel context.CancelFunc
+		budgetCtx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	sem := make(chan struct{}, settings.PeriodicConcurrency)
+	var wg sync.WaitGroup
+	var countMutex sync.Mutex
+	for i, name := range due {
+		acquired := false
+		select {
+		case sem <- struct{}{}:
+			acquired = budgetCtx.Err() == nil
+			if !acquired {
+				<-sem
+			}
+		case <-budgetCtx.Done():
+		}
+		if !acquired {
+			run.CarriedOver = len(due) - i
+			b.Logger().Warn("periodic: time budget exhausted, carrying roles over to the next pass",
+				"budget", settings.PeriodicTimeBudget, "remaining", run.CarriedOver)
+			break
+		}
+		b.setPeriodicCursor(name)
+		wg.Add(1)
+		go func(name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			resp, err := b.rotateRole(budgetCtx, req.Storage, name)
+			if err != nil {
+				b.Logger().Error("periodic: failed to rotate role", "role", name, "error", err)
+			}
+
+			countMutex.Lock()
+			defer countMutex.Unlock()
+			if err != nil || resp.IsError() {
+				run.Failed++
+			} else {
+				run.Rotated++
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	if err := b.checkRestoreOnBrokers(ctx, req.Storage, settings, deadline); err != nil {
+		b.Logger().Error("periodic: failed to check stored passwords for a snapshot restore", "error", err)
+	}
+	if err := b.checkDrift(ctx, req.Storage, settings, deadline); err != nil {
+		b.Logger().Error("periodic: failed to check roles for drift", "error", err)
+	}
+
+	run.Duration = time.Since(run.Started)
+	b.periodicMutex.Lock()
+	b.lastPeriodic = run
+	b.periodicMutex.Unlock()
+	recordPeriodicRun(req.MountPoint, len(roles), overdue, b.nonCompliantRoles(now), run.Duration)
+	recordNextRotations(req.MountPoint, b.nextRotations(), time.Now())
+	if err := putLastPeriodicRun(ctx, req.Storage, &run); err != nil {
+		b.Logger().Error("periodic: failed to store the outcome of the pass", "error", err)
+	}
+
+	return nil
+}
+
+// roleOverdue returns how long past its rotation period a role is at now, or
+// a non-positive value if it is not due. Roles without automatic rotation, or
+// that have never been rotated, are never overdue.
+func roleOverdue(role *RoleEntry, now time.Time) time.Duration {
+	nextDue := roleNextDue(role)
+	if nextDue.IsZero() {
+		return 0
+	}
+	return now.Sub(nextDue)
+}
+
+// roleNonCompliant reports whether a role's credential is older than its
+// max_password_age at now. Roles without a maximum age, or that have never
+// been rotated, are always compliant.
+func roleNonCompliant(role *RoleEntry, now time.Time) bool {
+	expiry := rolePasswordExpiry(role)
+	return !expiry.IsZero() && now.After(expiry)
+}
+
+// rolePasswordExpiry returns when a role's credential exceeds its
+// max_password_age, or the zero time if it never does.
+func rolePasswordExpiry(role *RoleEntry) time.Time {
+	if role.MaxPasswordAge == 0 || role.LastRotated.IsZero() {
+		return time.Time{}
+	}
+	return role.LastRotated.Add(role.MaxPasswordAge)
+}
+
+// roleNextDue returns when a role is next due for automatic rotation, or the
+// zero time if it never is.
+func roleNextDue(role *RoleEntry) time.Time {
+	if role.RotationPeriod == 0 || role.LastRotated.IsZero() {
+		return time.Time{}
+	}
+	return role.LastRotated.Add(role.RotationPeriod)
+}
+
+// localRotation records a rotation made on this node: the role's next due
+// time as stored, and the same moment on this node's monotonic clock.
+type localRotation struct {
+	nextDue time.Time
+	dueAt   time.Time
+}
+
+// rotationDue reports whether a role next due at nextDue should be rotated
+// at now, jitter after its due time.
+//
+// Due times are wall-clock times, which another node's clock, or an NTP
+// step of this one, can put out of line with this node's clock. A role
+// this node rotated is timed by its monotonic clock instead, which neither
+// affects. Any other role, such as one the previous active node rotated
+// before a failover, must be past due by skewTolerance as well, so that a
+// clock running ahead of the one that stamped it does not rotate it again
+// straight away.
+func (b *solaceBackend) rotationDue(name string, nextDue, now time.Time, jitter, skewTolerance time.Duration) bool {
+	if nextDue.IsZero() {
+		return false
+	}
+
+	b.periodicMutex.Lock()
+	local, ok := b.localRotations[name]
+	b.periodicMutex.Unlock()
+	if ok && local.nextDue.Equal(nextDue) {
+		return time.Now().After(local.dueAt.Add(jitter))
+	}
+	return now.Add(-jitter - skewTolerance).After(nextDue)
+}
+
+// recordLocalRotation notes that this node rotated a role at rotatedAt,
+// which must still carry its monotonic clock reading.
+func (b *solaceBackend) recordLocalRotation(name string, role *RoleEntry, rotatedAt time.Time) {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	if role.RotationPeriod == 0 {
+		delete(b.localRotations, name)
+		return
+	}
+	if b.localRotations == nil {
+		b.localRotations = make(map[string]localRotation)
+	}
+	b.localRotations[name] = localRotation{
+		nextDue: roleNextDue(role),
+		dueAt:   rotatedAt.Add(role.RotationPeriod),
+	}
+}
+
+// scheduledRoles returns the schedule entries of the listed roles, and drops
+// those of roles that no longer exist. The schedule lets a periodic pass skip
+// reading roles that are not yet due, which at thousands of roles would
+// otherwise be thousands of storage reads every minute. Role writes and
+// deletes, storage invalidations and snapshot restores remove entries, so
+// the next pass reads those roles again.
+func (b *solaceBackend) scheduledRoles(roles []string) map[string]time.Time {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	listed := make(map[string]time.Time, len(roles))
+	for _, name := range roles {
+		if nextDue, ok := b.schedule[name]; ok {
+			listed[name] = nextDue
+		}
+	}
+	if len(listed) != len(b.schedule) {
+		for name := range b.schedule {
+			if _, ok := listed[name]; !ok {
+				delete(b.schedule, name)
+			}
+		}
+	}
+	for name := range b.passwordExpiry {
+		if _, ok := b.schedule[name]; !ok {
+			delete(b.passwordExpiry, name)
+		}
+	}
+	if len(b.localRotations) > 0 {
+		exists := make(map[string]bool, len(roles))
+		for _, name := range roles {
+			exists[name] = true
+		}
+		for name := range b.localRotations {
+			if !exists[name] {
+				delete(b.localRotations, name)
+			}
+		}
+	}
+	return listed
+}
+
+// scheduleRole records when a role is next due, and when its credential
+// exceeds its max_password_age.
+func (b *solaceBackend) scheduleRole(name string, role *RoleEntry) {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	if b.schedule == nil {
+		b.schedule = make(map[string]time.Time)
+	}
+	b.schedule[name] = roleNextDue(role)
+	if expiry := rolePasswordExpiry(role); !expiry.IsZero() {
+		if b.passwordExpiry == nil {
+			b.passwordExpiry = make(map[string]time.Time)
+		}
+		b.passwordExpiry[name] = expiry
+	} else {
+		delete(b.passwordExpiry, name)
+	}
+}
+
+// nonCompliantRoles counts the scheduled roles whose credential is older
+// than their max_password_age at now.
+func (b *solaceBackend) nonCompliantRoles(now time.Time) int {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	count := 0
+	for _, expiry := range b.passwordExpiry {
+		if now.After(expiry) {
+			count++
+		}
+	}
+	return count
+}
+
+// nextRotations returns when each role with automatic rotation is next due,
+// as far as the schedule knows. Roles this node rotated in the pass have
+// left the schedule until the next pass reads them again, so their due time
+// comes from the rotation instead.
+func (b *solaceBackend) nextRotations() map[string]time.Time {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	next := make(map[string]time.Time, len(b.schedule)+len(b.localRotations))
+	for name, local := range b.localRotations {
+		next[name] = local.nextDue
+	}
+	for name, nextDue := range b.schedule {
+		if !nextDue.IsZero() {
+			next[name] = nextDue
+		}
+	}
+	return next
+}
+
+// unscheduleRole forgets when a role is due, so the next pass reads it.
+func (b *solaceBackend) unscheduleRole(name string) {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	delete(b.schedule, name)
+	delete(b.passwordExpiry, name)
+}
+
+// resetSchedule forgets every role's due time.
+func (b *solaceBackend) resetSchedule() {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	b.schedule = nil
+	b.passwordExpiry = nil
+	b.localRotations = nil
+}
+
+// resumeAfterCursor reorders the due roles to start after the last
+// role the previous pass dispatched, so that when passes run out of time
+// every role still gets its turn.
+func (b *solaceBackend) resumeAfterCursor(due []string) []string {
+	b.periodicMutex.Lock()
+	cursor := b.periodicCursor
+	b.periodicMutex.Unlock()
+
+	sort.Strings(due)
+	i := sort.SearchStrings(due, cursor)
+	if i < len(due) && due[i] == cursor {
+		i++
+	}
+	ordered := make([]string, 0, len(due))
+	ordered = append(ordered, due[i:]...)
+	return append(ordered, due[:i]...)
+}
+
+func (b *solaceBackend) setPeriodicCursor(name string) {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	b.periodicCursor = name
+}
+
+// setWritable records whether this node can rotate, restarting the
+// startup cool-down when it becomes able to.
+func (b *solaceBackend) setWritable(writable bool) {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	switch {
+	case !writable:
+		b.writableSince = time.Time{}
+	case b.writableSince.IsZero():
+		b.writableSince = time.Now()
+	}
+}
+
+// startupCooldownRemaining returns how much of the startup cool-down is
+// left.
+func (b *solaceBackend) startupCooldownRemaining(cooldown time.Duration) time.Duration {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	return cooldown - time.Since(b.writableSince)
+}
+
+// lastPeriodicRun returns the most recent completed periodic pass.
+func (b *solaceBackend) lastPeriodicRun() periodicRun {
+	b.periodicMutex.Lock()
+	defer b.periodicMutex.Unlock()
+
+	return b.lastPeriodic
+}
+
+// rotationJitter returns a stable per-role delay in [0, max), so roles that
+// were created together spread their automatic rotations out instead of all
+// hitting the broker in the same periodic pass.
+func rotationJitter(name string, max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return time.Duration(h.Sum64() % uint64(max))
+}
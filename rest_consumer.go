@@ -0,0 +1,100 @@
+package solacevaultplugin
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// REST consumer authentication schemes, named as SEMP v2 names them.
+const (
+	restAuthHTTPBasic         = "http-basic"
+	restAuthClientCertificate = "client-certificate"
+)
+
+// Client certificates stay valid for twice the rotation period, so the
+// previous certificate still works while the next rotation is pending, but
+// never less than minClientCertValidity. Roles without a rotation period get
+// defaultClientCertValidity.
+const (
+	minClientCertValidity     = 24 * time.Hour
+	defaultClientCertValidity = 365 * 24 * time.Hour
+)
+
+// clientCertValidity returns how long a certificate issued for role is valid.
+func clientCertValidity(role *RoleEntry) time.Duration {
+	if role.RotationPeriod <= 0 {
+		return defaultClientCertValidity
+	}
+	if validity := 2 * role.RotationPeriod; validity > minClientCertValidity {
+		return validity
+	}
+	return minClientCertValidity
+}
+
+// generateClientCertificate issues a self-signed ECDSA P-256 client
+// certificate for commonName, returning the certificate and its private key
+// in PEM form. The REST consumer's server is expected to trust the
+// certificate itself, which can be read from the role's creds.
+func generateClientCertificate(commonName string, validity time.Duration) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding key: %w", err)
+	}
+	defer wipe(keyDER)
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// certificateExpiry returns when a PEM certificate stops being valid.
+func certificateExpiry(certPEM string) (time.Time, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return time.Time{}, fmt.Errorf("no PEM certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter.UTC(), nil
+}
+
+// clientCertContent joins a key and certificate into the single PEM bundle
+// SEMP expects. The caller should wipe the result once it has been sent.
+func clientCertContent(certPEM, keyPEM []byte) []byte {
+	content := bytes.NewBuffer(make([]byte, 0, len(keyPEM)+len(certPEM)))
+	content.Write(keyPEM)
+	content.Write(certPEM)
+	return content.Bytes()
+}
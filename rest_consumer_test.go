@@ -0,0 +1,236 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// restConsumerBroker is a SEMP v2 server holding a single REST consumer's
+// authentication settings.
+type restConsumerBroker struct {
+	mu      sync.Mutex
+	updates []map[string]string
+	scheme  string
+}
+
+func (rb *restConsumerBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if r.URL.Path != "/SEMP/v2/config/msgVpns/default/restDeliveryPoints/rdp1/restConsumers/rc1" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"meta":{"responseCode":400,"error":{"code":6,"description":"Could not find match","status":"NOT_FOUND"}}}`))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodPatch:
+		var update map[string]string
+		json.NewDecoder(r.Body).Decode(&update)
+		rb.updates = append(rb.updates, update)
+		rb.scheme = update["authenticationScheme"]
+		w.Write([]byte(`{"data":{},"meta":{"responseCode":200}}`))
+	case http.MethodGet:
+		data, _ := json.Marshal(map[string]interface{}{
+			"data": map[string]interface{}{"restConsumerName": "rc1", "enabled": true, "authenticationScheme": rb.scheme},
+			"meta": map[string]interface{}{"responseCode": 200},
+		})
+		w.Write(data)
+	}
+}
+
+func (rb *restConsumerBroker) lastUpdate() map[string]string {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if len(rb.updates) == 0 {
+		return nil
+	}
+	return rb.updates[len(rb.updates)-1]
+}
+
+func setupRESTConsumerRole(t *testing.T, auth string) (logical.Backend, logical.Storage, *restConsumerBroker) {
+	t.Helper()
+
+	rb := &restConsumerBroker{scheme: "none"}
+	server := httptest.NewServer(rb)
+	t.Cleanup(server.Close)
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"semp_url":       server.URL,
+			"admin_username": "admin",
+			"admin_password": "secret",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create broker: err=%v, resp=%v", err, resp)
+	}
+
+	data := map[string]interface{}{
+		"broker":              "test-broker",
+		"target":              roleTargetRESTConsumer,
+		"msg_vpn":             "default",
+		"rest_delivery_point": "rdp1",
+		"rest_consumer":       "rc1",
+		"rest_consumer_auth":  auth,
+	}
+	if auth == restAuthHTTPBasic {
+		data["rest_consumer_username"] = "webhook"
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/rdp-role",
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("create role: err=%v, resp=%v", err, resp)
+	}
+
+	return b, storage, rb
+}
+
+func readCreds(t *testing.T, b logical.Backend, storage logical.Storage, name string) map[string]interface{} {
+	t.Helper()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/" + name,
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read creds: err=%v, resp=%v", err, resp)
+	}
+	return resp.Data
+}
+
+func TestRESTConsumer_RoleValidation(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	writeBroker(t, b, storage, "test-broker")
+
+	for name, data := range map[string]map[string]interface{}{
+		"missing consumer": {"target": roleTargetRESTConsumer, "msg_vpn": "default", "rest_delivery_point": "rdp1"},
+		"missing username": {"target": roleTargetRESTConsumer, "msg_vpn": "default", "rest_delivery_point": "rdp1", "rest_consumer": "rc1"},
+		"bad auth":         {"target": roleTargetRESTConsumer, "msg_vpn": "default", "rest_delivery_point": "rdp1", "rest_consumer": "rc1", "rest_consumer_auth": "oauth"},
+		"cli fields":       {"target": roleTargetRESTConsumer, "msg_vpn": "default", "rest_delivery_point": "rdp1", "rest_consumer": "rc1", "rest_consumer_auth": restAuthClientCertificate, "cli_username": "monitor"},
+		"bad target":       {"target": "queue", "cli_username": "monitor"},
+	} {
+		data["broker"] = "test-broker"
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/rdp-role",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if resp == nil || !resp.IsError() {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRESTConsumer_RotateBasicAuth(t *testing.T) {
+	b, storage, rb := setupRESTConsumerRole(t, restAuthHTTPBasic)
+	ctx := context.Background()
+
+	resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "rdp-role")
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+
+	update := rb.lastUpdate()
+	if update["authenticationScheme"] != restAuthHTTPBasic || update["authenticationHttpBasicUsername"] != "webhook" {
+		t.Errorf("unexpected update: %v", update)
+	}
+	creds := readCreds(t, b, storage, "rdp-role")
+	if creds["password"] != update["authenticationHttpBasicPassword"] {
+		t.Error("stored password does not match the one sent to the broker")
+	}
+	if creds["rest_consumer_username"] != "webhook" || creds["rest_consumer"] != "rc1" {
+		t.Errorf("unexpected creds: %v", creds)
+	}
+}
+
+func TestRESTConsumer_RotateClientCertificate(t *testing.T) {
+	b, storage, rb := setupRESTConsumerRole(t, restAuthClientCertificate)
+	ctx := context.Background()
+
+	resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "rdp-role")
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+
+	update := rb.lastUpdate()
+	content := update["authenticationClientCertContent"]
+	if update["authenticationScheme"] != restAuthClientCertificate || !strings.Contains(content, "PRIVATE KEY") {
+		t.Fatalf("unexpected update: %v", update)
+	}
+
+	creds := readCreds(t, b, storage, "rdp-role")
+	cert, _ := creds["certificate"].(string)
+	if cert == "" || !strings.Contains(content, cert) {
+		t.Error("certificate in creds does not match the one sent to the broker")
+	}
+	if _, ok := creds["private_key"]; ok {
+		t.Error("creds must not return the private key")
+	}
+	expiry, err := time.Parse(time.RFC3339, creds["certificate_expiry"].(string))
+	if err != nil || time.Until(expiry) < defaultClientCertValidity-time.Hour {
+		t.Errorf("certificate_expiry = %v, want about %s from now", creds["certificate_expiry"], defaultClientCertValidity)
+	}
+
+	// The stored key is pushed again on sync.
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sync/rdp-role",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("sync: err=%v, resp=%v", err, resp)
+	}
+	if rb.lastUpdate()["authenticationClientCertContent"] != content {
+		t.Error("sync did not push the stored certificate and key")
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "verify/rdp-role",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("verify: err=%v, resp=%v", err, resp)
+	}
+	if resp.Data["rest_consumer_auth"] != restAuthClientCertificate || len(resp.Warnings) != 0 {
+		t.Errorf("unexpected verify response: data=%v warnings=%v", resp.Data, resp.Warnings)
+	}
+}
+
+func TestClientCertValidity(t *testing.T) {
+	for _, tc := range []struct {
+		period time.Duration
+		want   time.Duration
+	}{
+		{0, defaultClientCertValidity},
+		{time.Hour, minClientCertValidity},
+		{30 * 24 * time.Hour, 60 * 24 * time.Hour},
+	} {
+		if got := clientCertValidity(&RoleEntry{RotationPeriod: tc.period}); got != tc.want {
+			t.Errorf("clientCertValidity(%s) = %s, want %s", tc.period, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const eventRestoreDetected = "solace/restore-detected"
+
+// Every successful rotation bumps a mount-wide generation counter in storage
+// and this node remembers the highest value it wrote. If storage later holds
+// a lower generation than that, Vault was restored from a snapshot while this
+// node was running: the roles this node rotated after the snapshot now have
+// a stored password that the broker no longer accepts.
+//
+// A restore that also restarts Vault, or the mount, takes this node's memory
+// of the generation with it. So once after the mount starts, the roles' stored
+// passwords are also tried on their brokers, and roles a broker rejects are
+// flagged the same way.
+
+// loadGeneration primes the in-memory generation from storage and schedules
+// the check of stored passwords against the brokers.
+func (b *solaceBackend) loadGeneration(ctx context.Context, s logical.Storage) error {
+	gen, err := getGeneration(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	b.generationMutex.Lock()
+	defer b.generationMutex.Unlock()
+
+	b.generation = gen
+	b.restoreCheckPending = true
+	b.restoreCheckCursor = ""
+	return nil
+}
+
+// recordRotationGeneration bumps the stored generation after name was
+// rotated and clears any restore suspicion or drift on the role, since its
+// stored password is now known to match the broker.
+func (b *solaceBackend) recordRotationGeneration(ctx context.Context, s logical.Storage, name string) error {
+	b.generationMutex.Lock()
+	defer b.generationMutex.Unlock()
+
+	stored, err := getGeneration(ctx, s)
+	if err != nil {
+		return err
+	}
+	next := stored + 1
+	if b.generation >= next {
+		next = b.generation + 1
+	}
+	if err := putGeneration(ctx, s, next); err != nil {
+		return err
+	}
+	b.generation = next
+	if b.rotatedAt == nil {
+		b.rotatedAt = make(map[string]uint64)
+	}
+	b.rotatedAt[name] = next
+
+	if err := deleteDrift(ctx, s, name); err != nil {
+		return err
+	}
+	return deleteRestoreSuspect(ctx, s, name)
+}
+
+// detectRestore compares the stored generation with the one this node last
+// wrote and flags the roles whose rotations were lost to a restore. It
+// returns the names of newly flagged roles.
+func (b *solaceBackend) detectRestore(ctx context.Context, s logical.Storage) ([]string, error) {
+	b.generationMutex.Lock()
+	defer b.generationMutex.Unlock()
+
+	stored, err := getGeneration(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if stored >= b.generation {
+		return nil, nil
+	}
+
+	var flagged []string
+	now := time.Now().UTC()
+	for name, gen := range b.rotatedAt {
+		if gen <= stored {
+			continue
+		}
+		role, err := getRole(ctx, s, name)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			if err := putRestoreSuspect(ctx, s, name, &restoreSuspect{DetectedAt: now}); err != nil {
+				return nil, err
+			}
+			flagged = append(flagged, name)
+		}
+		delete(b.rotatedAt, name)
+	}
+
+	b.Logger().Warn("storage generation went backwards; Vault appears to have been restored from a snapshot",
+		"stored_generation", stored, "last_written_generation", b.generation, "flagged_roles", flagged)
+	b.generation = stored
+	// Restored roles may be due sooner than this node remembers, and differ
+	// from the entries it has cached.
+	b.resetSchedule()
+	b.entries.reset()
+	for _, name := range flagged {
+		b.sendEvent(ctx, eventRestoreDetected, "role", name)
+	}
+
+	return flagged, nil
+}
+
+// checkRestoreOnBrokers carries out the check of stored passwords that
+// loadGeneration schedules. It logs in as the user of each rotated CLI user
+// role with its stored password, and flags the roles a broker rejects. It
+// stops at deadline, if set, and the next pass continues after the last
+// role it checked.
+//
+// Those logins show in the brokers' logs and count against their lockout
+// policies, so like the drift check they are opt-in: the check only runs
+// while drift_check_interval or verify_rotation is set, and waits for one
+// of them otherwise.
+func (b *solaceBackend) checkRestoreOnBrokers(ctx context.Context, s logical.Storage, settings *Settings, deadline time.Time) error {
+	if settings.DriftCheckInterval <= 0 && !settings.VerifyRotation {
+		return nil
+	}
+	b.generationMutex.Lock()
+	pending, cursor := b.restoreCheckPending, b.restoreCheckCursor
+	b.generationMutex.Unlock()
+	if !pending {
+		return nil
+	}
+
+	roles, err := listRoles(ctx, s)
+	if err != nil {
+		return err
+	}
+	sort.Strings(roles)
+	var flagged []string
+	finished := true
+	for _, name := range roles {
+		if name <= cursor {
+			continue
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			b.Logger().Debug("periodic: time budget exhausted, continuing the restore check on the next pass", "after", cursor)
+			finished = false
+			break
+		}
+		suspect, err := b.checkRoleRestore(ctx, s, name)
+		if err != nil {
+			b.Logger().Warn("periodic: failed to check role's stored password for a restore", "role", name, "error", err)
+		}
+		if suspect {
+			flagged = append(flagged, name)
+		}
+		cursor = name
+	}
+
+	b.generationMutex.Lock()
+	b.restoreCheckCursor = cursor
+	if finished {
+		b.restoreCheckPending = false
+		b.restoreCheckCursor = ""
+	}
+	b.generationMutex.Unlock()
+
+	if len(flagged) > 0 {
+		b.Logger().Warn("brokers reject the stored passwords of roles; Vault may have been restored from a snapshot",
+			"flagged_roles", flagged)
+	}
+	return nil
+}
+
+// checkRoleRestore flags a role whose stored password a broker rejects. It
+// reports whether it flagged the role. Roles that do not rotate a CLI user,
+// were never rotated, or are already flagged are left alone, as are roles on
+// brokers that cannot be asked.
+func (b *solaceBackend) checkRoleRestore(ctx context.Context, s logical.Storage, name string) (bool, error) {
+	lock := b.roleLock(name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	role, err := getRole(ctx, s, name)
+	if err != nil || role == nil || !role.isCLIUser() || role.LastRotated.IsZero() {
+		return false, err
+	}
+	if suspect, err := getRestoreSuspect(ctx, s, name); err != nil || suspect != nil {
+		return false, err
+	}
+	secret, err := getRoleSecret(ctx, s, name)
+	if err != nil || secret.empty() {
+		return false, err
+	}
+	brokers, err := roleBrokers(ctx, s, role)
+	if err != nil {
+		return false, err
+	}
+	if _, _, deferred := b.firstDeferredBroker(brokers); deferred {
+		return false, nil
+	}
+
+	password := []byte(secret.Password)
+	defer wipe(password)
+
+	for _, broker := range brokers {
+		config, err := getSessionBroker(ctx, s, broker)
+		if err != nil || config == nil {
+			return false, err
+		}
+		accepted, err := b.sempClient(broker, config).CheckLogin(ctx, role.CLIUsername, password)
+		if err != nil {
+			return false, fmt.Errorf("checking password on broker %q: %w", broker, err)
+		}
+		if accepted {
+			continue
+		}
+		if err := putRestoreSuspect(ctx, s, name, &restoreSuspect{DetectedAt: time.Now().UTC()}); err != nil {
+			return false, err
+		}
+		b.Logger().Warn("broker rejects the stored password; flagging role as restore suspect", "role", name, "broker", broker)
+		b.sendEvent(ctx, eventRestoreDetected, "role", name)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,172 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestDetectRestore_FlagsRolesRotatedAfterSnapshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	putBroker(ctx, storage, "test-broker", &BrokerConfig{SEMPURL: server.URL, AdminUsername: "admin", AdminPassword: "secret"})
+	for _, name := range []string{"before", "after"} {
+		putRole(ctx, storage, name, &RoleEntry{Broker: "test-broker", CLIUsername: name, PasswordLength: defaultPasswordLength})
+		if resp, err := sb.rotateRole(ctx, storage, name); err != nil || resp.IsError() {
+			t.Fatalf("rotate %s: err=%v, resp=%v", name, err, resp)
+		}
+	}
+
+	// Simulate restoring a snapshot taken between the two rotations.
+	putGeneration(ctx, storage, 1)
+
+	flagged, err := sb.detectRestore(ctx, storage)
+	if err != nil {
+		t.Fatalf("detectRestore: %v", err)
+	}
+	if len(flagged) != 1 || flagged[0] != "after" {
+		t.Fatalf("flagged = %v, want [after]", flagged)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "status",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("status: err=%v, resp=%v", err, resp)
+	}
+	suspects := resp.Data["restore_suspect_roles"].([]string)
+	if len(suspects) != 1 || suspects[0] != "after" {
+		t.Errorf("restore_suspect_roles = %v, want [after]", suspects)
+	}
+
+	// Checking again must not flag anything new.
+	if flagged, _ := sb.detectRestore(ctx, storage); len(flagged) != 0 {
+		t.Errorf("second check flagged %v, want none", flagged)
+	}
+
+	// Re-rotating reconciles the role.
+	if resp, err := sb.rotateRole(ctx, storage, "after"); err != nil || resp.IsError() {
+		t.Fatalf("re-rotate: err=%v, resp=%v", err, resp)
+	}
+	if suspect, _ := getRestoreSuspect(ctx, storage, "after"); suspect != nil {
+		t.Error("restore flag should be cleared by a successful rotation")
+	}
+}
+
+func TestDetectRestore_AfterRestart(t *testing.T) {
+	pb := &passwordBroker{}
+	server := httptest.NewServer(pb)
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	b, storage, _ = setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotate: err=%v, resp=%v", err, resp)
+	}
+	snapshot := snapshotStorage(t, storage)
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotate: err=%v, resp=%v", err, resp)
+	}
+
+	// Restore the snapshot and start the mount again on it, as Vault does:
+	// the new backend primes its generation from the restored storage.
+	restoreStorage(t, storage, snapshot)
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+	restarted, err := Factory(ctx, config)
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	if err := restarted.Initialize(ctx, &logical.InitializationRequest{Storage: storage}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	sb := restarted.(*solaceBackend)
+	if flagged, _ := sb.detectRestore(ctx, storage); len(flagged) != 0 {
+		t.Fatalf("generation check flagged %v after a restart; it cannot see restores", flagged)
+	}
+
+	// Logging in as every role is opt-in, so nothing is checked until drift
+	// checks or verify_rotation are turned on.
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if suspect, _ := getRestoreSuspect(ctx, storage, "test-role"); suspect != nil {
+		t.Fatal("stored passwords were checked on the brokers without drift checks or verify_rotation")
+	}
+	settings := defaultSettings()
+	settings.VerifyRotation = true
+	if err := putSettings(ctx, storage, settings); err != nil {
+		t.Fatalf("putSettings: %v", err)
+	}
+	sb.periodicMutex.Lock()
+	sb.lastPeriodic = periodicRun{}
+	sb.periodicMutex.Unlock()
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if suspect, _ := getRestoreSuspect(ctx, storage, "test-role"); suspect == nil {
+		t.Fatal("expected the role whose stored password the broker rejects to be flagged")
+	}
+
+	// The check runs once per start; a rotation clears the flag for good.
+	if resp, err := sb.rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("re-rotate: err=%v, resp=%v", err, resp)
+	}
+	sb.periodicMutex.Lock()
+	sb.lastPeriodic = periodicRun{}
+	sb.periodicMutex.Unlock()
+	if err := sb.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatalf("periodicFunc: %v", err)
+	}
+	if suspect, _ := getRestoreSuspect(ctx, storage, "test-role"); suspect != nil {
+		t.Error("restore flag should stay cleared after a rotation")
+	}
+}
+
+// snapshotStorage copies every entry in s.
+func snapshotStorage(t *testing.T, s logical.Storage) map[string]*logical.StorageEntry {
+	t.Helper()
+	ctx := context.Background()
+	keys, err := logical.CollectKeys(ctx, s)
+	if err != nil {
+		t.Fatalf("CollectKeys: %v", err)
+	}
+	snapshot := make(map[string]*logical.StorageEntry, len(keys))
+	for _, key := range keys {
+		entry, err := s.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get %s: %v", key, err)
+		}
+		snapshot[key] = &logical.StorageEntry{Key: key, Value: append([]byte(nil), entry.Value...)}
+	}
+	return snapshot
+}
+
+// restoreStorage replaces everything in s with snapshot.
+func restoreStorage(t *testing.T, s logical.Storage, snapshot map[string]*logical.StorageEntry) {
+	t.Helper()
+	ctx := context.Background()
+	if err := logical.ClearView(ctx, s); err != nil {
+		t.Fatalf("ClearView: %v", err)
+	}
+	for _, entry := range snapshot {
+		if err := s.Put(ctx, entry); err != nil {
+			t.Fatalf("Put %s: %v", entry.Key, err)
+		}
+	}
+}
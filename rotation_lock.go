@@ -0,0 +1,174 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rotation locks keep plugins in different Vault clusters that manage the
+// same broker, such as during a migration, from rotating the same
+// credential at once. A lock is a CLI user on the broker, created with no
+// access and a password nobody keeps. Its name carries the credential it
+// guards and when it expires:
+//
+//	vlock-<key hash>-<expiry, unix seconds>-<random>
+//
+// so that a lock left behind by a plugin that stopped mid-rotation stops
+// counting once it expires, and is removed by the next rotation to look.
+// The random part lets a broker hold two locks on one credential side by
+// side, so creating one excludes nobody by itself: a plugin holds the lock
+// only if listing the locks again after creating its own finds no other
+// live one.
+const (
+	rotationLockPrefix = "vlock-"
+
+	// minRotationLockTTL is the shortest rotation_lock_ttl a broker takes,
+	// so that a lock cannot expire while its rotation is still running.
+	minRotationLockTTL = time.Minute
+
+	// rotationLockAccessLevel is the global access level of lock users.
+	rotationLockAccessLevel = "none"
+)
+
+// rotationLockedError is returned when another plugin holds the lock on a
+// role's credential.
+type rotationLockedError struct {
+	until time.Time
+}
+
+func (e *rotationLockedError) Error() string {
+	return fmt.Sprintf("credential is locked for rotation by another Vault cluster until %s", e.until.Format(time.RFC3339))
+}
+
+// errRotationLocked matches every rotationLockedError with errors.Is.
+var errRotationLocked = errors.New("credential is locked for rotation")
+
+func (e *rotationLockedError) Is(target error) bool {
+	return target == errRotationLocked
+}
+
+// lockKey identifies the credential a role rotates on its broker, so that
+// roles in different Vault clusters rotating the same one share its lock.
+func (r *RoleEntry) lockKey() string {
+	switch {
+	case r.isRESTConsumer():
+		return strings.Join([]string{roleTargetRESTConsumer, r.MsgVPN, r.RESTDeliveryPoint, r.RESTConsumer}, "/")
+	case r.isOAuthProfile():
+		return strings.Join([]string{roleTargetOAuthProfile, r.MsgVPN, r.OAuthProfile}, "/")
+	case r.isSEMPRPC():
+		return roleTargetSEMPRPC + "/" + r.SEMPRPCUsername
+	default:
+		return roleTargetCLIUser + "/" + r.CLIUsername
+	}
+}
+
+// rotationLockPattern returns the prefix every lock on key's credential
+// starts with.
+func rotationLockPattern(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return rotationLockPrefix + hex.EncodeToString(sum[:4]) + "-"
+}
+
+// rotationLockExpiry returns when the lock user name expires, and false if
+// it is not a lock user's name.
+func rotationLockExpiry(name string) (time.Time, bool) {
+	fields := strings.Split(name, "-")
+	if len(fields) != 4 || fields[0]+"-" != rotationLockPrefix {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+// acquireRotationLock takes the lock on role's credential on the broker
+// client reaches, for ttl, before a rotation changes it there. It returns
+// a function that releases the lock, or an error wrapping errRotationLocked
+// if another plugin holds it. With no ttl the broker does not use locks and
+// nothing is done.
+//
+// Two plugins taking the lock at once can both see the other's lock user
+// and both back off; neither ever goes ahead while another lock is live.
+func (b *solaceBackend) acquireRotationLock(ctx context.Context, client *SEMPClient, role *RoleEntry, ttl time.Duration) (release func(), err error) {
+	if ttl <= 0 {
+		return func() {}, nil
+	}
+	pattern := rotationLockPattern(role.lockKey())
+
+	names, err := client.ListUsernames(ctx, pattern+"*")
+	if err != nil {
+		return nil, fmt.Errorf("looking up rotation locks: %w", err)
+	}
+	now := time.Now()
+	for _, name := range names {
+		expiry, ok := rotationLockExpiry(name)
+		if !ok {
+			continue
+		}
+		if expiry.After(now) {
+			return nil, &rotationLockedError{until: expiry}
+		}
+		// Another plugin may be removing the same lock; if it cannot be
+		// removed it is ignored below all the same.
+		if err := client.DeleteUser(ctx, name); err != nil {
+			b.Logger().Warn("failed to remove expired rotation lock", "lock", name, "broker", client.Broker, "error", err)
+		}
+	}
+
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	expiry := now.Add(ttl)
+	lock := pattern + strconv.FormatInt(expiry.Unix(), 10) + "-" + hex.EncodeToString(suffix)
+	password, err := generatePassword(maxPasswordLength, passwordPolicy{})
+	if err != nil {
+		return nil, err
+	}
+	err = client.CreateUser(ctx, lock, password, CLIUserAccess{GlobalAccessLevel: rotationLockAccessLevel})
+	wipe(password)
+	if err != nil {
+		// The create fails if another plugin made a lock of the same name
+		// in the same second, so check for it before giving up.
+		if names, listErr := client.ListUsernames(ctx, lock); listErr == nil && len(names) > 0 {
+			return nil, &rotationLockedError{until: expiry}
+		}
+		return nil, fmt.Errorf("creating rotation lock: %w", err)
+	}
+	release = func() {
+		// The lock is released even if ctx has ended since it was taken.
+		if err := client.DeleteUser(context.WithoutCancel(ctx), lock); err != nil {
+			b.Logger().Warn("failed to release rotation lock; it lapses when it expires",
+				"lock", lock,
+				"broker", client.Broker,
+				"expires", expiry.Format(time.RFC3339),
+				"error", err,
+			)
+		}
+	}
+
+	names, err = client.ListUsernames(ctx, pattern+"*")
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("looking up rotation locks: %w", err)
+	}
+	for _, name := range names {
+		if name == lock {
+			continue
+		}
+		if other, ok := rotationLockExpiry(name); ok && other.After(now) {
+			release()
+			return nil, &rotationLockedError{until: other}
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,237 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// userBroker is a SEMP server that keeps a set of CLI users: creates fail
+// for users that exist, and show commands list the users matching their
+// name, wildcards included.
+type userBroker struct {
+	mu    sync.Mutex
+	users map[string]bool
+	// created records every user created, in order.
+	created []string
+}
+
+var sempNamePattern = regexp.MustCompile(`<name>([^<]*)</name>`)
+
+func (ub *userBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ub.mu.Lock()
+	defer ub.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	var name string
+	if m := sempNamePattern.FindSubmatch(body); m != nil {
+		name = string(m[1])
+	}
+	switch {
+	case strings.Contains(string(body), "<show>"):
+		var reply strings.Builder
+		reply.WriteString(`<rpc-reply><rpc><show><username><usernames>`)
+		for user := range ub.users {
+			if ok, _ := path.Match(name, user); ok {
+				reply.WriteString(`<username><name>` + user + `</name></username>`)
+			}
+		}
+		reply.WriteString(`</usernames></username></show></rpc><execute-result code="ok"/></rpc-reply>`)
+		w.Write([]byte(reply.String()))
+		return
+	case strings.Contains(string(body), "<create><username>"):
+		if ub.users[name] {
+			w.Write([]byte(`<rpc-reply><execute-result code="fail"/></rpc-reply>`))
+			return
+		}
+		ub.users[name] = true
+		ub.created = append(ub.created, name)
+	case strings.Contains(string(body), "<no><username>"):
+		delete(ub.users, name)
+	}
+	w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+}
+
+func (ub *userBroker) locks() []string {
+	ub.mu.Lock()
+	defer ub.mu.Unlock()
+	var locks []string
+	for user := range ub.users {
+		if strings.HasPrefix(user, rotationLockPrefix) {
+			locks = append(locks, user)
+		}
+	}
+	return locks
+}
+
+func (ub *userBroker) add(user string) {
+	ub.mu.Lock()
+	defer ub.mu.Unlock()
+	ub.users[user] = true
+}
+
+func TestRotationLock(t *testing.T) {
+	ub := &userBroker{users: map[string]bool{"monitor": true}}
+	server := httptest.NewServer(ub)
+	defer server.Close()
+
+	b, storage := getTestBackend(t)
+	b, storage, _ = setupRotationTestWithServer(t, b, storage, server)
+	ctx := context.Background()
+	sb := b.(*solaceBackend)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data:      map[string]interface{}{"rotation_lock_ttl": 300},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("set rotation_lock_ttl: err=%v, resp=%v", err, resp)
+	}
+	role, _ := getRole(ctx, storage, "test-role")
+	pattern := rotationLockPattern(role.lockKey())
+
+	t.Run("taken and released", func(t *testing.T) {
+		resp, err := sb.rotateRole(ctx, storage, "test-role")
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+		}
+		ub.mu.Lock()
+		created := append([]string(nil), ub.created...)
+		ub.mu.Unlock()
+		if len(created) != 1 || !strings.HasPrefix(created[0], pattern) {
+			t.Fatalf("expected one lock user created, got %v", created)
+		}
+		if len(created[0]) > 32 {
+			t.Errorf("lock user name %q is longer than a broker takes", created[0])
+		}
+		if locks := ub.locks(); len(locks) != 0 {
+			t.Errorf("lock not released: %v", locks)
+		}
+	})
+
+	t.Run("held elsewhere", func(t *testing.T) {
+		before, _ := getRoleSecret(ctx, storage, "test-role")
+		held := pattern + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + "-beef"
+		ub.add(held)
+
+		resp, err := sb.rotateRole(ctx, storage, "test-role")
+		if err != nil {
+			t.Fatalf("rotateRole: %v", err)
+		}
+		if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "locked") {
+			t.Fatalf("expected a locked error, got %v", resp)
+		}
+		after, _ := getRoleSecret(ctx, storage, "test-role")
+		if after.Password != before.Password {
+			t.Error("stored password changed while the lock was held elsewhere")
+		}
+		if locks := ub.locks(); len(locks) != 1 || locks[0] != held {
+			t.Errorf("expected only the other lock to remain, got %v", locks)
+		}
+		ub.mu.Lock()
+		delete(ub.users, held)
+		ub.mu.Unlock()
+	})
+
+	t.Run("expired elsewhere", func(t *testing.T) {
+		ub.add(pattern + strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10) + "-beef")
+
+		resp, err := sb.rotateRole(ctx, storage, "test-role")
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+		}
+		if locks := ub.locks(); len(locks) != 0 {
+			t.Errorf("expired lock not removed: %v", locks)
+		}
+	})
+
+	t.Run("sync and decommission", func(t *testing.T) {
+		held := pattern + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + "-beef"
+		ub.add(held)
+		defer func() {
+			ub.mu.Lock()
+			delete(ub.users, held)
+			ub.mu.Unlock()
+		}()
+
+		for _, req := range []*logical.Request{
+			{Operation: logical.UpdateOperation, Path: "sync/test-role"},
+			{Operation: logical.UpdateOperation, Path: "decommission/test-role", Data: map[string]interface{}{"scramble": true}},
+		} {
+			req.Storage = storage
+			resp, err := b.HandleRequest(ctx, req)
+			if err != nil {
+				t.Fatalf("%s: %v", req.Path, err)
+			}
+			if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "locked") {
+				t.Fatalf("%s: expected a locked error, got %v", req.Path, resp)
+			}
+		}
+		if role, _ := getRole(ctx, storage, "test-role"); role == nil {
+			t.Error("role removed though the decommission was locked out")
+		}
+		if locks := ub.locks(); len(locks) != 1 || locks[0] != held {
+			t.Errorf("expected only the other lock to remain, got %v", locks)
+		}
+	})
+
+	t.Run("other credentials", func(t *testing.T) {
+		other := &RoleEntry{CLIUsername: "someone-else"}
+		ub.add(rotationLockPattern(other.lockKey()) + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + "-beef")
+
+		resp, err := sb.rotateRole(ctx, storage, "test-role")
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+		}
+	})
+}
+
+func TestRotationLock_TTLValidation(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	for ttl, valid := range map[int]bool{0: true, 30: false, 60: true, 3600: true} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/brokers/lock-" + strconv.Itoa(ttl),
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"semp_url":          "https://broker:8080",
+				"admin_username":    "admin",
+				"admin_password":    "secret",
+				"rotation_lock_ttl": ttl,
+			},
+		})
+		if err != nil {
+			t.Fatalf("ttl %d: %v", ttl, err)
+		}
+		if got := resp == nil || !resp.IsError(); got != valid {
+			t.Errorf("ttl %d: expected valid=%v, got resp=%v", ttl, valid, resp)
+		}
+	}
+}
+
+func TestRotationLockExpiry(t *testing.T) {
+	if _, ok := rotationLockExpiry("monitor"); ok {
+		t.Error("expected a plain CLI user not to be a lock")
+	}
+	if _, ok := rotationLockExpiry("vlock-0a1b2c3d-notatime-beef"); ok {
+		t.Error("expected a lock name without an expiry to be refused")
+	}
+	expiry, ok := rotationLockExpiry("vlock-0a1b2c3d-1700000000-beef")
+	if !ok || expiry.Unix() != 1700000000 {
+		t.Errorf("expected expiry 1700000000, got %v, %v", expiry, ok)
+	}
+}
@@ -0,0 +1,151 @@
+package solacevaultplugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// rotationNotice is the message published to a broker's notify_topic after
+// a rotation. It never carries the credential.
+type rotationNotice struct {
+	Role        string `json:"role"`
+	Broker      string `json:"broker"`
+	BrokerGroup string `json:"broker_group,omitempty"`
+	RotatedAt   string `json:"rotated_at"`
+	Trigger     string `json:"trigger,omitempty"`
+}
+
+// minNotifySigningKeyLength is the shortest notify_signing_key accepted:
+// 32 characters, the size of the HMAC-SHA256 output.
+const minNotifySigningKeyLength = 32
+
+// Solace REST messaging turns Solace-User-Property-* headers into user
+// properties of the published message, which is how a signed notice's
+// signature, timestamp and nonce reach its receivers.
+const (
+	noticeSignatureHeader = "Solace-User-Property-vault-signature"
+	noticeTimestampHeader = "Solace-User-Property-vault-timestamp"
+	noticeNonceHeader     = "Solace-User-Property-vault-nonce"
+)
+
+// signNotice returns the signature of a notice body sent at timestamp, in
+// Unix seconds, with nonce: "sha256=" and the hex HMAC-SHA256, keyed with
+// key, of the timestamp, nonce and body joined by dots. Covering the
+// timestamp and nonce lets receivers reject stale or replayed notices.
+func signNotice(key []byte, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyRotation publishes a rotation notice to the notify_topic of the
+// role's broker, or of every member of its group that has one. Like
+// events, notices are best effort: a failed publish is logged and never
+// fails the rotation, whose credential is already stored.
+func (b *solaceBackend) notifyRotation(ctx context.Context, s logical.Storage, name string, role *RoleEntry) {
+	var members []groupMember
+	if role.BrokerGroup != "" {
+		var resp *logical.Response
+		var err error
+		members, resp, err = groupMembers(ctx, s, name, role)
+		if err != nil || resp != nil {
+			b.Logger().Warn("failed to load broker group for rotation notice", "role", name, "broker_group", role.BrokerGroup, "error", err)
+			return
+		}
+	} else {
+		config, err := getBroker(ctx, s, role.Broker)
+		if err != nil || config == nil {
+			b.Logger().Warn("failed to load broker for rotation notice", "role", name, "broker", role.Broker, "error", err)
+			return
+		}
+		members = []groupMember{{name: role.Broker, config: config}}
+	}
+
+	notice := rotationNotice{
+		Role:        name,
+		Broker:      role.Broker,
+		BrokerGroup: role.BrokerGroup,
+		RotatedAt:   role.LastRotated.Format(time.RFC3339),
+		Trigger:     role.LastRotationTrigger,
+	}
+	for _, member := range members {
+		if member.config.NotifyTopic == "" {
+			continue
+		}
+		notice.Broker = member.name
+		body, err := json.Marshal(notice)
+		if err != nil {
+			return
+		}
+		if err := publishToTopic(ctx, member.config, body); err != nil {
+			b.Logger().Warn("failed to publish rotation notice",
+				"role", name,
+				"broker", member.name,
+				"topic", member.config.NotifyTopic,
+				"error", err,
+			)
+		}
+	}
+}
+
+// publishToTopic publishes a direct message to a broker's notify_topic
+// through its REST messaging endpoint, signed when the broker has a
+// notify_signing_key.
+func publishToTopic(ctx context.Context, config *BrokerConfig, body []byte) error {
+	levels := strings.Split(config.NotifyTopic, "/")
+	for i, level := range levels {
+		levels[i] = url.PathEscape(level)
+	}
+	endpoint := strings.TrimSuffix(config.NotifyURL, "/") + "/TOPIC/" + strings.Join(levels, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Solace-Delivery-Mode", "direct")
+	req.Header.Set("User-Agent", sempUserAgent)
+	if config.NotifySigningKey != "" {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("generating nonce: %w", err)
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		nonceHex := hex.EncodeToString(nonce)
+		req.Header.Set(noticeTimestampHeader, timestamp)
+		req.Header.Set(noticeNonceHeader, nonceHex)
+		req.Header.Set(noticeSignatureHeader, signNotice([]byte(config.NotifySigningKey), timestamp, nonceHex, body))
+	}
+	if config.NotifyUsername != "" {
+		req.SetBasicAuth(config.NotifyUsername, config.NotifyPassword)
+	}
+
+	resp, err := newHTTPClient(config).Do(req)
+	if err != nil {
+		return fmt.Errorf("publishing to %s: %w", config.NotifyTopic, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("broker returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
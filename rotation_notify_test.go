@@ -0,0 +1,173 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestNotifyRotation(t *testing.T) {
+	var mu sync.Mutex
+	var paths, users []string
+	var notices []rotationNotice
+	bus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		var notice rotationNotice
+		json.Unmarshal(body, &notice)
+		user, _, _ := r.BasicAuth()
+		paths = append(paths, r.URL.Path)
+		users = append(users, user)
+		notices = append(notices, notice)
+	}))
+	defer bus.Close()
+
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"notify_url":      bus.URL,
+			"notify_topic":    "vault/solace/rotated",
+			"notify_username": "notifier",
+			"notify_password": "bus-secret",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("patch broker: err=%v, resp=%v", err, resp)
+	}
+
+	if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notices) != 1 {
+		t.Fatalf("got %d notices, want 1", len(notices))
+	}
+	if paths[0] != "/TOPIC/vault/solace/rotated" || users[0] != "notifier" {
+		t.Errorf("published to %s as %q", paths[0], users[0])
+	}
+	notice := notices[0]
+	if notice.Role != "test-role" || notice.Broker != "test-broker" || notice.Trigger != rotationTriggerPeriodic || notice.RotatedAt == "" {
+		t.Errorf("notice = %+v", notice)
+	}
+	secret, _ := getRoleSecret(ctx, storage, "test-role")
+	if raw, _ := json.Marshal(notice); strings.Contains(string(raw), secret.Password) {
+		t.Error("notice must not carry the password")
+	}
+}
+
+func TestNotifyRotation_Signed(t *testing.T) {
+	const key = "0123456789abcdef0123456789abcdef"
+	var mu sync.Mutex
+	var headers []http.Header
+	var bodies [][]byte
+	bus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		headers = append(headers, r.Header.Clone())
+		bodies = append(bodies, body)
+	}))
+	defer bus.Close()
+
+	b, storage, server := setupRotationTest(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"notify_url":         bus.URL,
+			"notify_topic":       "vault/solace/rotated",
+			"notify_signing_key": key,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("patch broker: err=%v, resp=%v", err, resp)
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "test-role"); err != nil || resp.IsError() {
+			t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(headers) != 2 {
+		t.Fatalf("got %d notices, want 2", len(headers))
+	}
+	for i, h := range headers {
+		timestamp, nonce := h.Get(noticeTimestampHeader), h.Get(noticeNonceHeader)
+		if timestamp == "" || nonce == "" {
+			t.Fatalf("notice %d has no timestamp or nonce: %v", i, h)
+		}
+		if got, want := h.Get(noticeSignatureHeader), signNotice([]byte(key), timestamp, nonce, bodies[i]); got != want {
+			t.Errorf("notice %d signature = %q, want %q", i, got, want)
+		}
+		if signNotice([]byte("some-other-key-of-at-least-32-chars"), timestamp, nonce, bodies[i]) == h.Get(noticeSignatureHeader) {
+			t.Errorf("notice %d verifies with the wrong key", i)
+		}
+	}
+	if headers[0].Get(noticeNonceHeader) == headers[1].Get(noticeNonceHeader) {
+		t.Error("notices share a nonce")
+	}
+
+	// The key is never returned.
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/brokers/test-broker",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("read broker: err=%v, resp=%v", err, resp)
+	}
+	if _, ok := resp.Data["notify_signing_key"]; ok {
+		t.Error("broker read returned notify_signing_key")
+	}
+}
+
+func TestPathConfigBrokers_NotifyValidation(t *testing.T) {
+	b, storage := newTestBackend(t, logical.TestBackendConfig())
+	ctx := context.Background()
+
+	for name, data := range map[string]map[string]interface{}{
+		"topic without url": {"notify_topic": "vault/rotated"},
+		"http url":          {"notify_url": "http://broker:9000", "notify_topic": "vault/rotated"},
+		"empty level":       {"notify_url": "https://broker:9443", "notify_topic": "vault//rotated"},
+		"short signing key": {"notify_url": "https://broker:9443", "notify_topic": "vault/rotated", "notify_signing_key": "too-short"},
+	} {
+		data["semp_url"] = "https://broker:8080"
+		data["admin_username"] = "admin"
+		data["admin_password"] = "secret"
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/brokers/test-broker",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if resp == nil || !resp.IsError() {
+			t.Errorf("%s: expected an error, got %v", name, resp)
+		}
+	}
+}
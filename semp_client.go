@@ -1,52 +1,204 @@
 package solacevaultplugin
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/hashicorp/go-hclog"
 )
 
-// SEMPClient communicates with a Solace broker via SEMP v1 XML.
+// SEMPClient communicates with a Solace broker via SEMP v1 XML, and via the
+// SEMP v2 config API for message VPN objects.
 type SEMPClient struct {
+	Broker        string
 	SEMPURL       string
 	AdminUsername string
 	AdminPassword string
 	SEMPVersion   string
 	TLSSkipVerify bool
 	HTTPClient    *http.Client
+	Logger        hclog.Logger
+
+	// Breaker, when set, gates every call and is told its outcome.
+	Breaker *circuitBreaker
+
+	// AdminUsage, when set, is told the outcome of every call that
+	// presented the admin credential to the broker.
+	AdminUsage *adminUsage
+
+	// ConfigLock, when set, is held for every configuration change so that
+	// only one at a time reaches the broker.
+	ConfigLock *configLock
+}
+
+// sempUserAgent identifies the plugin in broker-side access and audit logs.
+const sempUserAgent = "solace-vault-plugin/" + pluginVersion
+
+type sempRequestIDKey struct{}
+
+// withSEMPRequestID returns a context whose SEMP calls carry id in the
+// X-Request-ID header, so broker audit logs can be joined with Vault's.
+func withSEMPRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sempRequestIDKey{}, id)
+}
+
+func sempRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(sempRequestIDKey{}).(string)
+	return id
 }
 
 type sempReply struct {
-	XMLName       xml.Name          `xml:"rpc-reply"`
-	ExecuteResult sempExecuteResult `xml:"execute-result"`
-	ParseError    string            `xml:"parse-error"`
+	XMLName       xml.Name           `xml:"rpc-reply"`
+	ExecuteResult *sempExecuteResult `xml:"execute-result"`
+	ParseError    string             `xml:"parse-error"`
+	MoreCookie    *sempMoreCookie    `xml:"more-cookie"`
+}
+
+// sempMoreCookie carries the continuation RPC a broker returns when a show
+// command's output spans more than one page.
+type sempMoreCookie struct {
+	RPC string `xml:",innerxml"`
 }
 
 type sempExecuteResult struct {
 	Code string `xml:"code,attr"`
 }
 
-// NewSEMPClient creates a client from a BrokerConfig.
-func NewSEMPClient(config *BrokerConfig) *SEMPClient {
-	transport := &http.Transport{}
-	if config.TLSSkipVerify {
-		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+// CLIUser describes a CLI user account as reported by the broker.
+type CLIUser struct {
+	Name              string
+	GlobalAccessLevel string
+	Enabled           bool
+}
+
+// CLIUserAccess is the access a CLI user is created with. Levels left empty
+// are left at the broker's default.
+type CLIUserAccess struct {
+	GlobalAccessLevel string
+
+	// VPNAccessLevel is the user's access level to message VPNs, and
+	// VPNAccessLevelExceptions its access level to particular ones.
+	VPNAccessLevel           string
+	VPNAccessLevelExceptions map[string]string
+}
+
+type sempShowUsernameReply struct {
+	Usernames []sempUsername `xml:"rpc>show>username>usernames>username"`
+}
+
+type sempShowRedundancyReply struct {
+	ConfigStatus    string `xml:"rpc>show>redundancy>config-status"`
+	PrimaryActivity string `xml:"rpc>show>redundancy>virtual-routers>primary>status>activity"`
+	BackupActivity  string `xml:"rpc>show>redundancy>virtual-routers>backup>status>activity"`
+}
+
+// sempLocalActive is the activity a redundancy virtual router reports on the
+// node currently serving it.
+const sempLocalActive = "Local Active"
+
+type sempUsername struct {
+	Name              string `xml:"name"`
+	GlobalAccessLevel string `xml:"global-access-level"`
+	Enabled           string `xml:"enabled"`
+}
+
+const (
+	defaultConnectTimeout      = 10 * time.Second
+	defaultRequestTimeout      = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// Retry-After handling: at most maxSEMPRetries retries per call, and never
+// wait longer than maxSEMPRetryDelay for any one of them.
+const (
+	maxSEMPRetries    = 3
+	maxSEMPRetryDelay = 30 * time.Second
+)
+
+// maxSEMPPages bounds how many more-cookie continuations a single show
+// command may follow, guarding against a broker that never stops paging.
+const maxSEMPPages = 1000
+
+// SEMP error classes, used to label telemetry and to let callers tell
+// broker-side command failures apart from transport problems.
+const (
+	sempErrTransport = "transport"
+	sempErrHTTP      = "http"
+	sempErrParse     = "parse"
+	sempErrCommand   = "command"
+)
+
+// SEMPError is returned for any failed SEMP call and records which stage
+// of the exchange failed.
+type SEMPError struct {
+	Class string
+	Err   error
+
+	// RetryAfter is set when the broker asked for a back-off longer than
+	// the client was willing to wait.
+	RetryAfter time.Duration
+
+	// StatusCode is the HTTP status the broker answered with, when it
+	// answered with something other than 200 OK.
+	StatusCode int
+}
+
+func (e *SEMPError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SEMPError) Unwrap() error {
+	return e.Err
+}
+
+// sempErrorClass returns the class of a SEMP error, or an empty string if err
+// did not originate from the SEMP client.
+func sempErrorClass(err error) string {
+	var sempErr *SEMPError
+	if errors.As(err, &sempErr) {
+		return sempErr.Class
 	}
+	return ""
+}
 
-	httpClient := &http.Client{
-		Timeout:   30 * time.Second,
-		Transport: transport,
-		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
+// sempStatusCode returns the HTTP status the broker answered a failed call
+// with, or 0 if err carries none.
+func sempStatusCode(err error) int {
+	var sempErr *SEMPError
+	if errors.As(err, &sempErr) {
+		return sempErr.StatusCode
+	}
+	return 0
+}
+
+// sempRetryAfter returns the back-off the broker requested, if err carries one.
+func sempRetryAfter(err error) time.Duration {
+	var sempErr *SEMPError
+	if errors.As(err, &sempErr) {
+		return sempErr.RetryAfter
 	}
+	return 0
+}
+
+// NewSEMPClient creates a client for the named broker from its BrokerConfig.
+func NewSEMPClient(name string, config *BrokerConfig) *SEMPClient {
+	return newSEMPClientWithHTTP(name, config, newHTTPClient(config))
+}
 
+func newSEMPClientWithHTTP(name string, config *BrokerConfig, httpClient *http.Client) *SEMPClient {
 	return &SEMPClient{
+		Broker:        name,
 		SEMPURL:       config.SEMPURL,
 		AdminUsername: config.AdminUsername,
 		AdminPassword: config.AdminPassword,
@@ -56,46 +208,528 @@ func NewSEMPClient(config *BrokerConfig) *SEMPClient {
 	}
 }
 
+// newHTTPClient builds the HTTP client used to reach a broker. Redirects are
+// never followed so admin credentials cannot be replayed to another host.
+func newHTTPClient(config *BrokerConfig) *http.Client {
+	connectTimeout := config.ConnectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	requestTimeout := config.RequestTimeout
+	if requestTimeout == 0 {
+		requestTimeout = defaultRequestTimeout
+	}
+
+	tlsHandshakeTimeout := config.TLSHandshakeTimeout
+	if tlsHandshakeTimeout == 0 {
+		tlsHandshakeTimeout = defaultTLSHandshakeTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: connectTimeout}
+	if len(config.AllowedSEMPNetworks) > 0 {
+		dialer.Control = allowedNetworksControl(config.AllowedSEMPNetworks)
+	}
+	transport := &http.Transport{
+		DialContext:         redirectingDialer(dialer, sempDialTargets(config)),
+		TLSHandshakeTimeout: tlsHandshakeTimeout,
+		MaxIdleConnsPerHost: config.MaxIdleConnsPerHost,
+		ForceAttemptHTTP2:   !config.ForceHTTP1,
+	}
+	if config.ForceHTTP1 {
+		// A non-nil, empty TLSNextProto disables HTTP/2 negotiation.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if config.TLSSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
 // ChangePassword changes a CLI user's password on the broker via SEMP v1.
-func (c *SEMPClient) ChangePassword(ctx context.Context, cliUsername, newPassword string) error {
+// The request body holding the password is wiped once the call returns;
+// newPassword itself remains the caller's to wipe.
+func (c *SEMPClient) ChangePassword(ctx context.Context, cliUsername string, newPassword []byte) error {
+	release, err := c.ConfigLock.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	body := buildChangePasswordXML(c.SEMPVersion, cliUsername, newPassword)
+	defer wipe(body)
+	return c.executeChange(ctx, "change_password", cliUsername, body)
+}
+
+// errUserLeftShutdown is returned by ChangePasswordWithShutdown when the
+// password was changed but the CLI user could not be enabled again.
+var errUserLeftShutdown = errors.New("CLI user was left shut down")
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.SEMPURL+"/SEMP", strings.NewReader(body))
+// ChangePasswordWithShutdown changes a CLI user's password while the user is
+// shut down, which ends every session logged in with the old password. The
+// user is enabled again whether or not the change succeeded. If the change
+// succeeded but the user stays shut down, the error wraps
+// errUserLeftShutdown. The broker's configuration lock is held across all
+// three RPCs.
+func (c *SEMPClient) ChangePasswordWithShutdown(ctx context.Context, cliUsername string, newPassword []byte) error {
+	release, err := c.ConfigLock.acquire(ctx)
 	if err != nil {
-		return fmt.Errorf("building request: %w", err)
+		return err
 	}
-	req.Header.Set("Content-Type", "application/xml")
-	req.SetBasicAuth(c.AdminUsername, c.AdminPassword)
+	defer release()
+
+	if err := c.executeChange(ctx, "shutdown_username", cliUsername, buildUsernameShutdownXML(c.SEMPVersion, cliUsername, true)); err != nil {
+		return err
+	}
+	body := buildChangePasswordXML(c.SEMPVersion, cliUsername, newPassword)
+	changeErr := c.executeChange(ctx, "change_password", cliUsername, body)
+	wipe(body)
+	if err := c.executeChange(ctx, "enable_username", cliUsername, buildUsernameShutdownXML(c.SEMPVersion, cliUsername, false)); err != nil {
+		if changeErr != nil {
+			return fmt.Errorf("%w; enabling the user again also failed: %v", changeErr, err)
+		}
+		return fmt.Errorf("%w: %v", errUserLeftShutdown, err)
+	}
+	return changeErr
+}
+
+// EndSessions ends every session a CLI user has logged in, by shutting the
+// user down and enabling it again. If the user stays shut down, the error
+// wraps errUserLeftShutdown. The broker's configuration lock is held across
+// both RPCs.
+func (c *SEMPClient) EndSessions(ctx context.Context, cliUsername string) error {
+	release, err := c.ConfigLock.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := c.executeChange(ctx, "shutdown_username", cliUsername, buildUsernameShutdownXML(c.SEMPVersion, cliUsername, true)); err != nil {
+		return err
+	}
+	if err := c.executeChange(ctx, "enable_username", cliUsername, buildUsernameShutdownXML(c.SEMPVersion, cliUsername, false)); err != nil {
+		return fmt.Errorf("%w: %v", errUserLeftShutdown, err)
+	}
+	return nil
+}
+
+// CreateUser creates a CLI user on the broker with the given password, then
+// sets the access levels given in access, one RPC each. If setting them
+// fails, the user is deleted again rather than left on the broker with the
+// defaults it was created with. As with ChangePassword, only the request
+// body is wiped. The broker's configuration lock is held across all the
+// RPCs.
+func (c *SEMPClient) CreateUser(ctx context.Context, cliUsername string, password []byte, access CLIUserAccess) error {
+	release, err := c.ConfigLock.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	body := buildCreateUsernameXML(c.SEMPVersion, cliUsername, password)
+	err = c.executeChange(ctx, "create_username", cliUsername, body)
+	wipe(body)
+	if err != nil {
+		return err
+	}
+	if accessErr := c.setUserAccess(ctx, cliUsername, access); accessErr != nil {
+		// The user is removed even if ctx has ended since it was made.
+		body = buildDeleteUsernameXML(c.SEMPVersion, cliUsername)
+		if err := c.executeChange(context.WithoutCancel(ctx), "delete_username", cliUsername, body); err != nil {
+			return fmt.Errorf("%w; deleting the partly created user also failed: %v", accessErr, err)
+		}
+		return accessErr
+	}
+	return nil
+}
+
+// setUserAccess sets the access levels given in access on a CLI user the
+// caller just created, holding the broker's configuration lock.
+func (c *SEMPClient) setUserAccess(ctx context.Context, cliUsername string, access CLIUserAccess) error {
+	if access.GlobalAccessLevel != "" {
+		body := buildGlobalAccessLevelXML(c.SEMPVersion, cliUsername, access.GlobalAccessLevel)
+		if err := c.executeChange(ctx, "set_global_access_level", cliUsername, body); err != nil {
+			return err
+		}
+	}
+	if access.VPNAccessLevel != "" {
+		body := buildVPNAccessLevelXML(c.SEMPVersion, cliUsername, access.VPNAccessLevel)
+		if err := c.executeChange(ctx, "set_vpn_access_level", cliUsername, body); err != nil {
+			return err
+		}
+	}
+	vpns := make([]string, 0, len(access.VPNAccessLevelExceptions))
+	for vpn := range access.VPNAccessLevelExceptions {
+		vpns = append(vpns, vpn)
+	}
+	sort.Strings(vpns)
+	for _, vpn := range vpns {
+		body := buildVPNAccessLevelExceptionXML(c.SEMPVersion, cliUsername, vpn, access.VPNAccessLevelExceptions[vpn])
+		if err := c.executeChange(ctx, "create_vpn_access_level_exception", cliUsername+"/"+vpn, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckLogin reports whether the broker accepts password for the CLI user,
+// by sending it a harmless show command with the user's credentials instead
+// of the admin's. Only an HTTP 401 counts as a rejected password; any other
+// failure is returned as an error. Basic auth needs the password as a
+// string, so unlike request bodies that copy cannot be wiped.
+func (c *SEMPClient) CheckLogin(ctx context.Context, cliUsername string, password []byte) (accepted bool, err error) {
+	start := time.Now()
+	defer func() {
+		recordSEMPCall(c.Broker, "check_login", start, err)
+	}()
+
+	login := *c
+	login.AdminUsername = cliUsername
+	login.AdminPassword = string(password)
+
+	body := buildShowUsernameXML(c.SEMPVersion, cliUsername)
+	if _, err := login.post(ctx, body); err != nil {
+		if sempStatusCode(err) == http.StatusUnauthorized {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
 
-	resp, err := c.HTTPClient.Do(req)
+// DeleteUser removes a CLI user from the broker.
+func (c *SEMPClient) DeleteUser(ctx context.Context, cliUsername string) error {
+	release, err := c.ConfigLock.acquire(ctx)
 	if err != nil {
-		return fmt.Errorf("SEMP request to %s failed: %w", c.SEMPURL, err)
+		return err
 	}
-	defer resp.Body.Close()
+	defer release()
 
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	body := buildDeleteUsernameXML(c.SEMPVersion, cliUsername)
+	return c.executeChange(ctx, "delete_username", cliUsername, body)
+}
+
+// ShowUsername looks up a CLI user on the broker. It returns nil without an
+// error if the user does not exist.
+func (c *SEMPClient) ShowUsername(ctx context.Context, cliUsername string) (*CLIUser, error) {
+	body := buildShowUsernameXML(c.SEMPVersion, cliUsername)
+	pages, err := c.executeShow(ctx, "show_username", body)
 	if err != nil {
-		return fmt.Errorf("reading SEMP response: %w", err)
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("SEMP returned HTTP %d: %s", resp.StatusCode, string(respBody))
+	for _, page := range pages {
+		var reply sempShowUsernameReply
+		if err := xml.Unmarshal(page, &reply); err != nil {
+			return nil, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("parsing show username response: %w", err)}
+		}
+		for _, u := range reply.Usernames {
+			if u.Name != cliUsername {
+				continue
+			}
+			return &CLIUser{
+				Name:              u.Name,
+				GlobalAccessLevel: u.GlobalAccessLevel,
+				// Brokers that do not report an enabled state have no
+				// notion of a disabled CLI user.
+				Enabled: u.Enabled == "" || u.Enabled == "true",
+			}, nil
+		}
 	}
 
-	var reply sempReply
+	return nil, nil
+}
+
+// ListUsernames returns the names of the CLI users matching pattern, which
+// may use the broker's * wildcard.
+func (c *SEMPClient) ListUsernames(ctx context.Context, pattern string) ([]string, error) {
+	body := buildShowUsernameXML(c.SEMPVersion, pattern)
+	pages, err := c.executeShow(ctx, "show_username", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, page := range pages {
+		var reply sempShowUsernameReply
+		if err := xml.Unmarshal(page, &reply); err != nil {
+			return nil, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("parsing show username response: %w", err)}
+		}
+		for _, u := range reply.Usernames {
+			names = append(names, u.Name)
+		}
+	}
+	return names, nil
+}
+
+// IsActive reports whether the broker node is the active node of its HA
+// pair, i.e. one of its redundancy virtual routers is locally active. A node
+// without redundancy enabled is always active.
+func (c *SEMPClient) IsActive(ctx context.Context) (bool, error) {
+	body := buildShowRedundancyXML(c.SEMPVersion)
+	respBody, _, err := c.execute(ctx, "show_redundancy", body)
+	if err != nil {
+		return false, err
+	}
+	var reply sempShowRedundancyReply
 	if err := xml.Unmarshal(respBody, &reply); err != nil {
-		return fmt.Errorf("parsing SEMP response: %w", err)
+		return false, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("parsing show redundancy response: %w", err)}
+	}
+	if reply.ConfigStatus != "Enabled" {
+		return true, nil
+	}
+	return reply.PrimaryActivity == sempLocalActive || reply.BackupActivity == sempLocalActive, nil
+}
+
+// executeShow runs a show RPC and follows any more-cookie continuations,
+// returning the raw reply body of every page in order.
+func (c *SEMPClient) executeShow(ctx context.Context, operation string, body []byte) ([][]byte, error) {
+	var pages [][]byte
+	for {
+		respBody, reply, err := c.execute(ctx, operation, body)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, respBody)
+
+		if reply.MoreCookie == nil || strings.TrimSpace(reply.MoreCookie.RPC) == "" {
+			return pages, nil
+		}
+		if len(pages) >= maxSEMPPages {
+			return nil, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("SEMP show command exceeded %d pages", maxSEMPPages)}
+		}
+		body = []byte(strings.TrimSpace(reply.MoreCookie.RPC))
+	}
+}
+
+// executeChange runs a configuration RPC on target, a CLI username, and
+// records it in the broker's activity log. The caller holds the broker's
+// configuration lock.
+func (c *SEMPClient) executeChange(ctx context.Context, operation, target string, body []byte) error {
+	_, _, err := c.execute(ctx, operation, body)
+	c.recordActivity(ctx, operation, target, err)
+	return err
+}
+
+// execute posts an RPC to the broker, records telemetry for the call and
+// returns the raw and parsed reply once the broker has reported success.
+func (c *SEMPClient) execute(ctx context.Context, operation string, body []byte) (respBody []byte, reply *sempReply, err error) {
+	start := time.Now()
+	defer func() {
+		c.finishCall(ctx, operation, start, err)
+	}()
+
+	if err := c.Breaker.allow(c.Broker); err != nil {
+		return nil, nil, err
 	}
 
-	if reply.ExecuteResult.Code != "ok" {
+	respBody, err = c.post(ctx, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reply, err = parseSEMPReply(respBody)
+	if err != nil {
+		return nil, nil, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("malformed SEMP response: %w", err)}
+	}
+
+	if reply.ExecuteResult == nil || reply.ExecuteResult.Code != "ok" {
 		errMsg := reply.ParseError
 		if errMsg == "" {
 			errMsg = fmt.Sprintf("execute-result code=%q", reply.ExecuteResult.Code)
 		}
-		return fmt.Errorf("SEMP command failed: %s", errMsg)
+		return nil, nil, &SEMPError{Class: sempErrCommand, Err: fmt.Errorf("SEMP command failed: %s", errMsg)}
 	}
 
-	return nil
+	return respBody, reply, nil
+}
+
+// parseSEMPReply parses a SEMP v1 reply, which must be a single complete
+// rpc-reply document carrying an execute-result. Replies from a proxy or a
+// load balancer standing in for the broker, such as an HTML error page or a
+// body cut short, are reported as malformed rather than as a failed command.
+func parseSEMPReply(body []byte) (*sempReply, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	root, err := nextStartElement(dec)
+	if err == io.EOF {
+		return nil, errors.New("empty reply")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if root.Name.Local != "rpc-reply" {
+		return nil, fmt.Errorf("unexpected root element <%s>, want <rpc-reply>", root.Name.Local)
+	}
+
+	reply := &sempReply{}
+	if err := dec.DecodeElement(reply, &root); err != nil {
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) && strings.Contains(syntaxErr.Msg, "unexpected EOF") {
+			return nil, errors.New("reply truncated before </rpc-reply>")
+		}
+		return nil, err
+	}
+	if next, err := nextStartElement(dec); err != io.EOF {
+		if err != nil {
+			return nil, fmt.Errorf("content after </rpc-reply>: %w", err)
+		}
+		return nil, fmt.Errorf("unexpected element <%s> after </rpc-reply>", next.Name.Local)
+	}
+	// A broker that could not parse the request may answer with only a
+	// parse-error, which execute reports as a failed command.
+	if reply.ExecuteResult == nil && reply.ParseError == "" {
+		return nil, errors.New("reply has no execute-result")
+	}
+	if reply.ExecuteResult != nil && reply.ExecuteResult.Code == "" {
+		return nil, errors.New("execute-result has no code")
+	}
+	return reply, nil
+}
+
+// nextStartElement skips the prolog, comments and whitespace and returns
+// the next element, or io.EOF at the end of the document. Any other text
+// is an error.
+func nextStartElement(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return xml.StartElement{}, errors.New("reply is not XML")
+			}
+		}
+	}
+}
+
+func (c *SEMPClient) post(ctx context.Context, body []byte) ([]byte, error) {
+	status, respBody, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, sempRequestBase(c.SEMPURL)+"/SEMP", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/xml")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, &SEMPError{Class: sempErrHTTP, StatusCode: status, Err: fmt.Errorf("SEMP returned HTTP %d: %s", status, string(respBody))}
+	}
+
+	return respBody, nil
+}
+
+// do sends the request built by newRequest and returns the status code and
+// body of the response. When the broker answers 429 or 503 with a
+// Retry-After header the request is retried after the requested delay, up to
+// maxSEMPRetries times; delays longer than maxSEMPRetryDelay are returned to
+// the caller as a SEMPError carrying RetryAfter instead of being waited out.
+func (c *SEMPClient) do(ctx context.Context, newRequest func() (*http.Request, error)) (int, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return 0, nil, &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("building request: %w", err)}
+		}
+		c.setHeaders(ctx, req)
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			return 0, nil, &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("SEMP request to %s failed: %w", c.SEMPURL, err)}
+		}
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil {
+			return 0, nil, &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("reading SEMP response: %w", err)}
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			return resp.StatusCode, respBody, nil
+		}
+		delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			return resp.StatusCode, respBody, nil
+		}
+		if attempt >= maxSEMPRetries || delay > maxSEMPRetryDelay {
+			return 0, nil, &SEMPError{
+				Class:      sempErrHTTP,
+				RetryAfter: delay,
+				StatusCode: resp.StatusCode,
+				Err:        fmt.Errorf("SEMP returned HTTP %d, broker asked to retry after %s", resp.StatusCode, delay),
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, nil, &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("waiting to retry SEMP request: %w", ctx.Err())}
+		case <-timer.C:
+		}
+	}
+}
+
+// parseRetryAfter interprets a Retry-After header given either as a number of
+// seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		delay := t.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// setHeaders adds the authentication and identification headers common to
+// every SEMP request.
+func (c *SEMPClient) setHeaders(ctx context.Context, req *http.Request) {
+	req.SetBasicAuth(c.AdminUsername, c.AdminPassword)
+	req.Header.Set("User-Agent", sempUserAgent)
+	if id := sempRequestID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+}
+
+// finishCall records telemetry for a completed SEMP call and logs it with
+// its request ID.
+func (c *SEMPClient) finishCall(ctx context.Context, operation string, start time.Time, err error) {
+	c.Breaker.record(err)
+	c.AdminUsage.record(err)
+	recordSEMPCall(c.Broker, operation, start, err)
+	if c.Logger == nil {
+		return
+	}
+	c.Logger.Debug("SEMP call",
+		"broker", c.Broker,
+		"operation", operation,
+		"request_id", sempRequestID(ctx),
+		"duration", time.Since(start),
+		"error", err,
+	)
 }
 
 func escapeXML(s string) string {
@@ -104,14 +738,90 @@ func escapeXML(s string) string {
 	return buf.String()
 }
 
-func buildChangePasswordXML(sempVersion, username, password string) string {
-	var b strings.Builder
+// newRPCBuffer starts a SEMP v1 request. Requests are built into byte slices
+// rather than strings so that bodies carrying a password can be wiped after
+// they are sent; size should cover the rest of the body so the buffer never
+// grows and leaves a stale copy behind.
+func newRPCBuffer(sempVersion string, size int) *bytes.Buffer {
+	b := bytes.NewBuffer(make([]byte, 0, len(sempVersion)*6+size+64))
 	if sempVersion != "" {
-		fmt.Fprintf(&b, `<rpc semp-version="%s">`, escapeXML(sempVersion))
+		fmt.Fprintf(b, `<rpc semp-version="%s">`, escapeXML(sempVersion))
 	} else {
 		b.WriteString(`<rpc>`)
 	}
-	fmt.Fprintf(&b, `<username><name>%s</name><change-password><password>%s</password></change-password></username>`, escapeXML(username), escapeXML(password))
+	return b
+}
+
+// escapedLen bounds the length of s once XML-escaped; no character expands
+// to more than six bytes.
+func escapedLen(s string) int {
+	return len(s) * 6
+}
+
+func buildChangePasswordXML(sempVersion, username string, password []byte) []byte {
+	b := newRPCBuffer(sempVersion, escapedLen(username)+len(password)*6+128)
+	fmt.Fprintf(b, `<username><name>%s</name><change-password><password>`, escapeXML(username))
+	xml.EscapeText(b, password)
+	b.WriteString(`</password></change-password></username></rpc>`)
+	return b.Bytes()
+}
+
+func buildShowUsernameXML(sempVersion, username string) []byte {
+	b := newRPCBuffer(sempVersion, escapedLen(username)+64)
+	fmt.Fprintf(b, `<show><username><name>%s</name></username></show>`, escapeXML(username))
+	b.WriteString(`</rpc>`)
+	return b.Bytes()
+}
+
+func buildCreateUsernameXML(sempVersion, username string, password []byte) []byte {
+	b := newRPCBuffer(sempVersion, escapedLen(username)+len(password)*6+128)
+	fmt.Fprintf(b, `<create><username><name>%s</name><password>`, escapeXML(username))
+	xml.EscapeText(b, password)
+	b.WriteString(`</password></username></create></rpc>`)
+	return b.Bytes()
+}
+
+func buildShowRedundancyXML(sempVersion string) []byte {
+	b := newRPCBuffer(sempVersion, 32)
+	b.WriteString(`<show><redundancy/></show></rpc>`)
+	return b.Bytes()
+}
+
+func buildGlobalAccessLevelXML(sempVersion, username, accessLevel string) []byte {
+	b := newRPCBuffer(sempVersion, escapedLen(username)+escapedLen(accessLevel)+128)
+	fmt.Fprintf(b, `<username><name>%s</name><global-access-level><access-level>%s</access-level></global-access-level></username>`, escapeXML(username), escapeXML(accessLevel))
+	b.WriteString(`</rpc>`)
+	return b.Bytes()
+}
+
+func buildVPNAccessLevelXML(sempVersion, username, accessLevel string) []byte {
+	b := newRPCBuffer(sempVersion, escapedLen(username)+escapedLen(accessLevel)+160)
+	fmt.Fprintf(b, `<username><name>%s</name><message-vpn><default-access-level><access-level>%s</access-level></default-access-level></message-vpn></username>`, escapeXML(username), escapeXML(accessLevel))
+	b.WriteString(`</rpc>`)
+	return b.Bytes()
+}
+
+func buildVPNAccessLevelExceptionXML(sempVersion, username, vpn, accessLevel string) []byte {
+	b := newRPCBuffer(sempVersion, escapedLen(username)+escapedLen(vpn)+escapedLen(accessLevel)+192)
+	fmt.Fprintf(b, `<username><name>%s</name><message-vpn><create><access-level-exception><vpn-name>%s</vpn-name><access-level>%s</access-level></access-level-exception></create></message-vpn></username>`, escapeXML(username), escapeXML(vpn), escapeXML(accessLevel))
+	b.WriteString(`</rpc>`)
+	return b.Bytes()
+}
+
+func buildUsernameShutdownXML(sempVersion, username string, shutdown bool) []byte {
+	b := newRPCBuffer(sempVersion, escapedLen(username)+64)
+	if shutdown {
+		fmt.Fprintf(b, `<username><name>%s</name><shutdown/></username>`, escapeXML(username))
+	} else {
+		fmt.Fprintf(b, `<username><name>%s</name><no><shutdown/></no></username>`, escapeXML(username))
+	}
+	b.WriteString(`</rpc>`)
+	return b.Bytes()
+}
+
+func buildDeleteUsernameXML(sempVersion, username string) []byte {
+	b := newRPCBuffer(sempVersion, escapedLen(username)+64)
+	fmt.Fprintf(b, `<no><username><name>%s</name></username></no>`, escapeXML(username))
 	b.WriteString(`</rpc>`)
-	return b.String()
+	return b.Bytes()
 }
@@ -0,0 +1,212 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// cachedHTTPClient is an HTTP client kept alive across SEMP calls to the same
+// broker, together with the config it was built from.
+type cachedHTTPClient struct {
+	config     BrokerConfig
+	httpClient *http.Client
+}
+
+// sempClient returns a SEMP client for the named broker, reusing the broker's
+// cached HTTP client (and its pooled TLS connections) when the broker config
+// has not changed since it was built.
+func (b *solaceBackend) sempClient(name string, config *BrokerConfig) *SEMPClient {
+	b.clientMutex.Lock()
+	defer b.clientMutex.Unlock()
+
+	if b.clients == nil {
+		b.clients = make(map[string]*cachedHTTPClient)
+	}
+
+	cached, ok := b.clients[name]
+	if !ok || !reflect.DeepEqual(cached.config, *config) {
+		if ok {
+			cached.httpClient.CloseIdleConnections()
+		}
+		cached = &cachedHTTPClient{
+			config:     *config,
+			httpClient: newHTTPClient(config),
+		}
+		b.clients[name] = cached
+	}
+
+	client := newSEMPClientWithHTTP(name, config, cached.httpClient)
+	client.Logger = b.Logger()
+	client.Breaker = b.brokerBreaker(name)
+	client.AdminUsage = b.brokerAdminUsage(name)
+	client.ConfigLock = b.brokerConfigLock(name)
+	return client
+}
+
+// invalidateClient drops the cached HTTP client for a broker, closing any idle
+// connections it holds.
+func (b *solaceBackend) invalidateClient(name string) {
+	b.clientMutex.Lock()
+	defer b.clientMutex.Unlock()
+
+	if cached, ok := b.clients[name]; ok {
+		cached.httpClient.CloseIdleConnections()
+		delete(b.clients, name)
+	}
+}
+
+// resetClients drops every cached HTTP client.
+func (b *solaceBackend) resetClients() {
+	b.clientMutex.Lock()
+	defer b.clientMutex.Unlock()
+
+	for _, cached := range b.clients {
+		cached.httpClient.CloseIdleConnections()
+	}
+	b.clients = nil
+}
+
+// activeSEMPClient returns a SEMP client for the node of a broker that takes
+// configuration changes. For an HA pair, configured with mate_semp_url, that
+// is whichever node reports itself active, so a semp_url that points at the
+// standby after a failover does not fail the change. Both nodes share the
+// broker's circuit breaker and configuration lock.
+//
+// Inside a SEMP session the node found is kept for the rest of the session.
+func (b *solaceBackend) activeSEMPClient(ctx context.Context, name string, config *BrokerConfig) (*SEMPClient, error) {
+	session := sempSessionFrom(ctx)
+	if session == nil {
+		return b.resolveActiveSEMPClient(ctx, name, config)
+	}
+
+	entry := session.entry(name)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.client != nil && reflect.DeepEqual(entry.config, *config) {
+		return entry.client, nil
+	}
+	client, err := b.resolveActiveSEMPClient(ctx, name, config)
+	if err != nil {
+		entry.client = nil
+		return nil, err
+	}
+	entry.config = *config
+	entry.client = client
+	return client, nil
+}
+
+func (b *solaceBackend) resolveActiveSEMPClient(ctx context.Context, name string, config *BrokerConfig) (*SEMPClient, error) {
+	client := b.sempClient(name, config)
+	if config.MateSEMPURL == "" {
+		return client, nil
+	}
+	active, err := client.IsActive(ctx)
+	if err == nil && active {
+		return client, nil
+	}
+
+	mate := *client
+	mate.SEMPURL = config.MateSEMPURL
+	mateActive, mateErr := mate.IsActive(ctx)
+	if mateErr == nil && mateActive {
+		b.Logger().Info("configured broker node is not active, using its mate", "broker", name, "error", err)
+		return &mate, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mateErr != nil {
+		return nil, mateErr
+	}
+	return nil, &SEMPError{Class: sempErrCommand, Err: fmt.Errorf("neither node of broker %q reports itself active", name)}
+}
+
+// sempSession keeps the config and active node of each broker for the
+// length of a bulk operation, such as a periodic pass, that makes many
+// changes on the same brokers. SEMP v1 takes one RPC per request, so changes
+// cannot be sent together; instead every change after the first goes
+// straight to the client built before, over the broker's pooled connection,
+// without reading the broker's config from storage or asking an HA pair
+// which node is active each time. A config written during the session is
+// picked up by the next one.
+type sempSession struct {
+	mu      sync.Mutex
+	brokers map[string]*sempSessionEntry
+}
+
+// sempSessionEntry is what a session found for one broker: the broker's
+// stored config, and the client for its active node. Its mutex is held
+// while either is looked up, so concurrent rotations on the broker wait for
+// one lookup rather than each making their own.
+type sempSessionEntry struct {
+	mu     sync.Mutex
+	stored *BrokerConfig
+	config BrokerConfig
+	client *SEMPClient
+}
+
+type sempSessionKey struct{}
+
+// withSEMPSession returns a context whose SEMP calls share a session.
+func withSEMPSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sempSessionKey{}, &sempSession{brokers: make(map[string]*sempSessionEntry)})
+}
+
+func sempSessionFrom(ctx context.Context) *sempSession {
+	session, _ := ctx.Value(sempSessionKey{}).(*sempSession)
+	return session
+}
+
+func (s *sempSession) entry(name string) *sempSessionEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.brokers[name]
+	if !ok {
+		entry = &sempSessionEntry{}
+		s.brokers[name] = entry
+	}
+	return entry
+}
+
+// getSessionBroker returns the named broker's config like getBroker, reading
+// it from storage only once per SEMP session. The config returned is shared
+// by the session and must not be changed.
+func getSessionBroker(ctx context.Context, s logical.Storage, name string) (*BrokerConfig, error) {
+	session := sempSessionFrom(ctx)
+	if session == nil {
+		return getBroker(ctx, s, name)
+	}
+
+	entry := session.entry(name)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.stored != nil {
+		return entry.stored, nil
+	}
+	config, err := getBroker(ctx, s, name)
+	if err != nil || config == nil {
+		return config, err
+	}
+	entry.stored = config
+	return config, nil
+}
+
+// forgetSEMPSessionBroker drops the node the context's session holds for a
+// broker after a change there failed, so the next change looks it up again
+// in case the pair failed over.
+func forgetSEMPSessionBroker(ctx context.Context, name string) {
+	session := sempSessionFrom(ctx)
+	if session == nil {
+		return
+	}
+	entry := session.entry(name)
+	entry.mu.Lock()
+	entry.client = nil
+	entry.mu.Unlock()
+}
@@ -0,0 +1,215 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSEMPClientCache_ReusesHTTPClient(t *testing.T) {
+	b := backend()
+	config := &BrokerConfig{
+		SEMPURL:       "https://broker:8080",
+		AdminUsername: "admin",
+		AdminPassword: "secret",
+	}
+
+	first := b.sempClient("prod", config)
+	second := b.sempClient("prod", config)
+	if first.HTTPClient != second.HTTPClient {
+		t.Error("expected HTTP client to be reused for unchanged config")
+	}
+
+	changed := *config
+	changed.AdminPassword = "rotated"
+	third := b.sempClient("prod", &changed)
+	if third.HTTPClient == first.HTTPClient {
+		t.Error("expected a new HTTP client after config change")
+	}
+	if third.AdminPassword != "rotated" {
+		t.Errorf("AdminPassword = %q, want rotated", third.AdminPassword)
+	}
+}
+
+func TestSEMPClientCache_InvalidatedOnBrokerWrite(t *testing.T) {
+	b, storage := getTestBackend(t)
+	sb := b.(*solaceBackend)
+
+	writeBroker(t, b, storage, "test-broker")
+	config, err := getBroker(context.Background(), storage, "test-broker")
+	if err != nil {
+		t.Fatalf("getBroker: %v", err)
+	}
+	first := sb.sempClient("test-broker", config)
+
+	writeBroker(t, b, storage, "test-broker")
+	second := sb.sempClient("test-broker", config)
+	if first.HTTPClient == second.HTTPClient {
+		t.Error("expected cached client to be dropped after broker write")
+	}
+}
+
+func TestSEMPClientCache_StorageInvalidation(t *testing.T) {
+	b := backend()
+	config := &BrokerConfig{SEMPURL: "https://broker:8080", AdminUsername: "admin", AdminPassword: "secret"}
+
+	first := b.sempClient("prod", config)
+	b.invalidate(context.Background(), brokerStoragePrefix+"prod")
+	second := b.sempClient("prod", config)
+	if first.HTTPClient == second.HTTPClient {
+		t.Error("expected cached client to be dropped on storage invalidation")
+	}
+}
+
+func TestSEMPClientCache_SerializesConfigChanges(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	b := backend()
+	config := &BrokerConfig{SEMPURL: server.URL, AdminUsername: "admin", AdminPassword: "secret"}
+
+	// The lock outlives a reset of the broker's runtime state, so clients
+	// built on either side of one still take turns.
+	first := b.sempClient("prod", config)
+	b.resetBrokerState("prod")
+	second := b.sempClient("prod", config)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for _, client := range []*SEMPClient{first, second} {
+			wg.Add(1)
+			go func(client *SEMPClient) {
+				defer wg.Done()
+				if err := client.ChangePassword(context.Background(), "user", []byte("password")); err != nil {
+					t.Errorf("ChangePassword: %v", err)
+				}
+			}(client)
+		}
+	}
+	wg.Wait()
+	if maxInFlight != 1 {
+		t.Errorf("%d configuration changes reached the broker at once, want 1", maxInFlight)
+	}
+
+	// Waiting for the lock ends with the caller's context.
+	release, err := first.ConfigLock.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := second.DeleteUser(ctx, "user"); err != context.DeadlineExceeded {
+		t.Errorf("DeleteUser while locked: err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestActiveSEMPClient_SessionKeepsActiveNode(t *testing.T) {
+	primary, backup := &haNode{}, &haNode{active: true}
+	primaryServer, backupServer := httptest.NewServer(primary), httptest.NewServer(backup)
+	defer primaryServer.Close()
+	defer backupServer.Close()
+
+	b := backend()
+	config := &BrokerConfig{
+		SEMPURL:       primaryServer.URL,
+		MateSEMPURL:   backupServer.URL,
+		AdminUsername: "admin",
+		AdminPassword: "secret",
+	}
+	ctx := withSEMPSession(context.Background())
+
+	for i := 0; i < 3; i++ {
+		client, err := b.activeSEMPClient(ctx, "prod", config)
+		if err != nil {
+			t.Fatalf("activeSEMPClient: %v", err)
+		}
+		if client.SEMPURL != backupServer.URL {
+			t.Fatalf("SEMPURL = %q, want the active mate %q", client.SEMPURL, backupServer.URL)
+		}
+	}
+	if primary.shows != 1 || backup.shows != 1 {
+		t.Errorf("redundancy checks: primary=%d backup=%d, want one each for the whole session", primary.shows, backup.shows)
+	}
+
+	// After a failure the node is looked up again, and a failover found.
+	forgetSEMPSessionBroker(ctx, "prod")
+	primary.mu.Lock()
+	primary.active = true
+	primary.mu.Unlock()
+	backup.mu.Lock()
+	backup.active = false
+	backup.mu.Unlock()
+	client, err := b.activeSEMPClient(ctx, "prod", config)
+	if err != nil {
+		t.Fatalf("activeSEMPClient: %v", err)
+	}
+	if client.SEMPURL != primaryServer.URL {
+		t.Errorf("SEMPURL = %q after failover, want %q", client.SEMPURL, primaryServer.URL)
+	}
+
+	// Without a session every call checks again.
+	if _, err := b.activeSEMPClient(context.Background(), "prod", config); err != nil {
+		t.Fatalf("activeSEMPClient: %v", err)
+	}
+	if primary.shows != 3 {
+		t.Errorf("primary redundancy checks = %d, want 3", primary.shows)
+	}
+}
+
+func TestSEMPSession_KeepsBrokerConfigAndClient(t *testing.T) {
+	b, storage := getTestBackend(t)
+	sb := b.(*solaceBackend)
+	writeBroker(t, b, storage, "test-broker")
+	ctx := withSEMPSession(context.Background())
+
+	config, err := getSessionBroker(ctx, storage, "test-broker")
+	if err != nil || config == nil {
+		t.Fatalf("getSessionBroker: config=%v, err=%v", config, err)
+	}
+	first, err := sb.activeSEMPClient(ctx, "test-broker", config)
+	if err != nil {
+		t.Fatalf("activeSEMPClient: %v", err)
+	}
+
+	changed := *config
+	changed.RequestTimeout = time.Minute
+	if err := putBroker(ctx, storage, "test-broker", &changed); err != nil {
+		t.Fatalf("putBroker: %v", err)
+	}
+	again, err := getSessionBroker(ctx, storage, "test-broker")
+	if err != nil || again != config {
+		t.Fatalf("expected the session's config for the rest of the session, got %v, err=%v", again, err)
+	}
+	second, err := sb.activeSEMPClient(ctx, "test-broker", again)
+	if err != nil {
+		t.Fatalf("activeSEMPClient: %v", err)
+	}
+	if second != first {
+		t.Error("expected the session's client for a broker without a mate")
+	}
+
+	fresh, err := getSessionBroker(withSEMPSession(context.Background()), storage, "test-broker")
+	if err != nil || fresh == nil || fresh.RequestTimeout != time.Minute {
+		t.Errorf("expected a new session to read the written config, got %v, err=%v", fresh, err)
+	}
+	if missing, err := getSessionBroker(ctx, storage, "missing"); err != nil || missing != nil {
+		t.Errorf("expected no config for a missing broker, got %v, err=%v", missing, err)
+	}
+}
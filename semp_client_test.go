@@ -2,9 +2,13 @@ package solacevaultplugin
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSEMPClient_ChangePassword_Success(t *testing.T) {
@@ -38,7 +42,7 @@ func TestSEMPClient_ChangePassword_Success(t *testing.T) {
 		HTTPClient:    server.Client(),
 	}
 
-	err := client.ChangePassword(context.Background(), "testuser", "newpassword")
+	err := client.ChangePassword(context.Background(), "testuser", []byte("newpassword"))
 	if err != nil {
 		t.Fatalf("ChangePassword: %v", err)
 	}
@@ -61,10 +65,13 @@ func TestSEMPClient_ChangePassword_SEMPError(t *testing.T) {
 		HTTPClient:    server.Client(),
 	}
 
-	err := client.ChangePassword(context.Background(), "testuser", "newpassword")
+	err := client.ChangePassword(context.Background(), "testuser", []byte("newpassword"))
 	if err == nil {
 		t.Fatal("expected error for SEMP failure")
 	}
+	if class := sempErrorClass(err); class != sempErrCommand {
+		t.Errorf("error class = %q, want %q", class, sempErrCommand)
+	}
 }
 
 func TestSEMPClient_ChangePassword_HTTPError(t *testing.T) {
@@ -75,14 +82,49 @@ func TestSEMPClient_ChangePassword_HTTPError(t *testing.T) {
 		HTTPClient:    http.DefaultClient,
 	}
 
-	err := client.ChangePassword(context.Background(), "testuser", "newpassword")
+	err := client.ChangePassword(context.Background(), "testuser", []byte("newpassword"))
 	if err == nil {
 		t.Fatal("expected error for unreachable broker")
 	}
 }
 
+func TestSEMPClient_MalformedReply(t *testing.T) {
+	for name, body := range map[string]string{
+		"empty":                  ``,
+		"not XML":                `Bad Gateway`,
+		"unexpected root":        `<html><body>502 Bad Gateway</body></html>`,
+		"truncated":              `<rpc-reply><execute-result code="ok"/>`,
+		"missing execute-result": `<rpc-reply><rpc><show/></rpc></rpc-reply>`,
+		"execute-result no code": `<rpc-reply><execute-result/></rpc-reply>`,
+		"trailing element":       `<rpc-reply><execute-result code="ok"/></rpc-reply><rpc-reply/>`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			client := &SEMPClient{SEMPURL: server.URL, HTTPClient: server.Client()}
+			err := client.ChangePassword(context.Background(), "testuser", []byte("newpassword"))
+			if class := sempErrorClass(err); class != sempErrParse {
+				t.Errorf("error class = %q, want %q (err=%v)", class, sempErrParse, err)
+			}
+		})
+	}
+
+	// A reply with a prolog and surrounding whitespace is well formed.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<rpc-reply semp-version=\"soltr/9_0VMR\">\n  <execute-result code=\"ok\"/>\n</rpc-reply>\n"))
+	}))
+	defer server.Close()
+	client := &SEMPClient{SEMPURL: server.URL, HTTPClient: server.Client()}
+	if err := client.ChangePassword(context.Background(), "testuser", []byte("newpassword")); err != nil {
+		t.Errorf("well-formed reply: %v", err)
+	}
+}
+
 func TestBuildChangePasswordXML(t *testing.T) {
-	xml := buildChangePasswordXML("soltr/10_4", "myuser", "mypass")
+	xml := string(buildChangePasswordXML("soltr/10_4", "myuser", []byte("mypass")))
 	expected := `<rpc semp-version="soltr/10_4"><username><name>myuser</name><change-password><password>mypass</password></change-password></username></rpc>`
 	if xml != expected {
 		t.Errorf("got:\n%s\nwant:\n%s", xml, expected)
@@ -90,7 +132,7 @@ func TestBuildChangePasswordXML(t *testing.T) {
 }
 
 func TestBuildChangePasswordXML_NoVersion(t *testing.T) {
-	xml := buildChangePasswordXML("", "myuser", "mypass")
+	xml := string(buildChangePasswordXML("", "myuser", []byte("mypass")))
 	expected := `<rpc><username><name>myuser</name><change-password><password>mypass</password></change-password></username></rpc>`
 	if xml != expected {
 		t.Errorf("got:\n%s\nwant:\n%s", xml, expected)
@@ -108,16 +150,16 @@ func TestSEMPClient_ChangePassword_RedirectBlocked(t *testing.T) {
 		AdminUsername: "admin",
 		AdminPassword: "adminpass",
 	}
-	client := NewSEMPClient(config)
+	client := NewSEMPClient("test-broker", config)
 
-	err := client.ChangePassword(context.Background(), "testuser", "newpassword")
+	err := client.ChangePassword(context.Background(), "testuser", []byte("newpassword"))
 	if err == nil {
 		t.Fatal("expected error when server returns redirect")
 	}
 }
 
 func TestBuildChangePasswordXML_EscapesXMLChars(t *testing.T) {
-	result := buildChangePasswordXML("", "user</name><inject>", "pass&word")
+	result := string(buildChangePasswordXML("", "user</name><inject>", []byte("pass&word")))
 	expected := `<rpc><username><name>user&lt;/name&gt;&lt;inject&gt;</name><change-password><password>pass&amp;word</password></change-password></username></rpc>`
 	if result != expected {
 		t.Errorf("got:\n%s\nwant:\n%s", result, expected)
@@ -125,9 +167,424 @@ func TestBuildChangePasswordXML_EscapesXMLChars(t *testing.T) {
 }
 
 func TestBuildChangePasswordXML_EscapesSEMPVersion(t *testing.T) {
-	result := buildChangePasswordXML(`ver"1.0`, "user", "pass")
+	result := string(buildChangePasswordXML(`ver"1.0`, "user", []byte("pass")))
 	expected := `<rpc semp-version="ver&#34;1.0"><username><name>user</name><change-password><password>pass</password></change-password></username></rpc>`
 	if result != expected {
 		t.Errorf("got:\n%s\nwant:\n%s", result, expected)
 	}
 }
+
+func TestSEMPClient_ExecuteShow_FollowsMoreCookie(t *testing.T) {
+	const cookie = `<rpc semp-version="soltr/10_4"><show><username><name>*</name><num-elements>1</num-elements></username></show></rpc>`
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		w.Header().Set("Content-Type", "application/xml")
+		if len(bodies) == 1 {
+			w.Write([]byte(`<rpc-reply><rpc><show><username><usernames><username><name>a</name></username></usernames></username></show></rpc><execute-result code="ok"/><more-cookie>` + cookie + `</more-cookie></rpc-reply>`))
+			return
+		}
+		w.Write([]byte(`<rpc-reply><rpc><show><username><usernames><username><name>b</name></username></usernames></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "adminpass",
+		HTTPClient:    server.Client(),
+	}
+
+	pages, err := client.executeShow(context.Background(), "show_username", []byte(`<rpc><show><username><name>*</name></username></show></rpc>`))
+	if err != nil {
+		t.Fatalf("executeShow: %v", err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("pages = %d, want 2", len(pages))
+	}
+	if len(bodies) != 2 || bodies[1] != cookie {
+		t.Errorf("second request body = %q, want the more-cookie RPC %q", bodies[1], cookie)
+	}
+}
+
+func TestSEMPClient_ShowUsername(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><rpc><show><username><usernames>
+			<username><name>monitor</name><global-access-level>read-only</global-access-level><enabled>false</enabled></username>
+		</usernames></username></show></rpc><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "adminpass",
+		HTTPClient:    server.Client(),
+	}
+
+	user, err := client.ShowUsername(context.Background(), "monitor")
+	if err != nil {
+		t.Fatalf("ShowUsername: %v", err)
+	}
+	if user == nil {
+		t.Fatal("expected user to be found")
+	}
+	if user.GlobalAccessLevel != "read-only" {
+		t.Errorf("GlobalAccessLevel = %q, want read-only", user.GlobalAccessLevel)
+	}
+	if user.Enabled {
+		t.Error("Enabled = true, want false")
+	}
+
+	user, err = client.ShowUsername(context.Background(), "missing")
+	if err != nil {
+		t.Fatalf("ShowUsername(missing): %v", err)
+	}
+	if user != nil {
+		t.Errorf("expected nil for missing user, got %+v", user)
+	}
+}
+
+func TestBuildShowUsernameXML(t *testing.T) {
+	result := string(buildShowUsernameXML("soltr/10_4", "monitor"))
+	expected := `<rpc semp-version="soltr/10_4"><show><username><name>monitor</name></username></show></rpc>`
+	if result != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestBuildCreateUsernameXML(t *testing.T) {
+	result := string(buildCreateUsernameXML("", "monitor", []byte("p&ss")))
+	expected := `<rpc><create><username><name>monitor</name><password>p&amp;ss</password></username></create></rpc>`
+	if result != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestBuildDeleteUsernameXML(t *testing.T) {
+	result := string(buildDeleteUsernameXML("", "monitor"))
+	expected := `<rpc><no><username><name>monitor</name></username></no></rpc>`
+	if result != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestSEMPClient_CreateUser_SetsAccessLevel(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "adminpass",
+		HTTPClient:    server.Client(),
+	}
+
+	if err := client.CreateUser(context.Background(), "monitor", []byte("newpassword"), CLIUserAccess{GlobalAccessLevel: "read-only"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("requests = %d, want 2", len(bodies))
+	}
+	if !strings.Contains(bodies[0], "<create><username>") {
+		t.Errorf("first request should create the user, got %s", bodies[0])
+	}
+	if !strings.Contains(bodies[1], "<access-level>read-only</access-level>") {
+		t.Errorf("second request should set the access level, got %s", bodies[1])
+	}
+}
+
+func TestSEMPClient_CreateUser_SetsVPNAccessLevels(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{SEMPURL: server.URL, HTTPClient: server.Client()}
+	access := CLIUserAccess{
+		VPNAccessLevel:           "none",
+		VPNAccessLevelExceptions: map[string]string{"stage": "read-only", "prod": "read-write"},
+	}
+	if err := client.CreateUser(context.Background(), "ops", []byte("newpassword"), access); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	want := []string{
+		"<create><username><name>ops</name>",
+		"<message-vpn><default-access-level><access-level>none</access-level></default-access-level></message-vpn>",
+		"<access-level-exception><vpn-name>prod</vpn-name><access-level>read-write</access-level></access-level-exception>",
+		"<access-level-exception><vpn-name>stage</vpn-name><access-level>read-only</access-level></access-level-exception>",
+	}
+	if len(bodies) != len(want) {
+		t.Fatalf("requests = %d, want %d", len(bodies), len(want))
+	}
+	for i, fragment := range want {
+		if !strings.Contains(bodies[i], fragment) {
+			t.Errorf("request %d = %s, want it to contain %s", i, bodies[i], fragment)
+		}
+	}
+}
+
+func TestSEMPClient_CreateUser_DeletesOnAccessFailure(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if strings.Contains(string(body), "<access-level-exception>") {
+			w.Write([]byte(`<rpc-reply><parse-error>Invalid Message VPN</parse-error><execute-result code="fail"/></rpc-reply>`))
+			return
+		}
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{SEMPURL: server.URL, HTTPClient: server.Client()}
+	access := CLIUserAccess{
+		GlobalAccessLevel:        "read-only",
+		VPNAccessLevelExceptions: map[string]string{"missing": "read-write"},
+	}
+	err := client.CreateUser(context.Background(), "ops", []byte("newpassword"), access)
+	if err == nil || !strings.Contains(err.Error(), "Invalid Message VPN") {
+		t.Fatalf("expected the access level error, got %v", err)
+	}
+	if len(bodies) != 4 || !strings.Contains(bodies[3], "<no><username><name>ops</name>") {
+		t.Errorf("expected the user deleted after the failed access level, got %v", bodies)
+	}
+}
+
+func TestSEMPClient_ChangePasswordWithShutdown(t *testing.T) {
+	var (
+		steps   []string
+		failOn  string
+		replies = map[string]string{
+			"<shutdown/></username>": "shutdown",
+			"<change-password>":      "change",
+			"<no><shutdown/></no>":   "enable",
+		}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		step := "unknown"
+		for fragment, name := range replies {
+			if strings.Contains(string(body), fragment) {
+				step = name
+			}
+		}
+		steps = append(steps, step)
+		if step == failOn {
+			w.Write([]byte(`<rpc-reply><execute-result code="fail"/></rpc-reply>`))
+			return
+		}
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+	client := &SEMPClient{SEMPURL: server.URL, HTTPClient: server.Client()}
+
+	for _, tc := range []struct {
+		failOn    string
+		wantSteps string
+		wantErr   func(error) bool
+	}{
+		{"", "shutdown,change,enable", func(err error) bool { return err == nil }},
+		// A failed change still enables the user again.
+		{"change", "shutdown,change,enable", func(err error) bool { return err != nil && !errors.Is(err, errUserLeftShutdown) }},
+		{"enable", "shutdown,change,enable", func(err error) bool { return errors.Is(err, errUserLeftShutdown) }},
+		// Nothing else is tried when the user cannot be shut down.
+		{"shutdown", "shutdown", func(err error) bool { return err != nil && !errors.Is(err, errUserLeftShutdown) }},
+	} {
+		steps, failOn = nil, tc.failOn
+		err := client.ChangePasswordWithShutdown(context.Background(), "app", []byte("newpassword"))
+		if got := strings.Join(steps, ","); got != tc.wantSteps {
+			t.Errorf("failing %q: steps = %s, want %s", tc.failOn, got, tc.wantSteps)
+		}
+		if !tc.wantErr(err) {
+			t.Errorf("failing %q: unexpected error %v", tc.failOn, err)
+		}
+	}
+}
+
+func TestNewHTTPClient_Timeouts(t *testing.T) {
+	client := newHTTPClient(&BrokerConfig{})
+	if client.Timeout != defaultRequestTimeout {
+		t.Errorf("default Timeout = %s, want %s", client.Timeout, defaultRequestTimeout)
+	}
+
+	client = newHTTPClient(&BrokerConfig{RequestTimeout: 45 * time.Second})
+	if client.Timeout != 45*time.Second {
+		t.Errorf("Timeout = %s, want 45s", client.Timeout)
+	}
+}
+
+func TestSEMPClient_SendsIdentificationHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("User-Agent"); got != sempUserAgent {
+			t.Errorf("User-Agent = %q, want %q", got, sempUserAgent)
+		}
+		if got := r.Header.Get("X-Request-ID"); got != "req-1234" {
+			t.Errorf("X-Request-ID = %q, want req-1234", got)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "adminpass",
+		HTTPClient:    server.Client(),
+	}
+
+	ctx := withSEMPRequestID(context.Background(), "req-1234")
+	if err := client.ChangePassword(ctx, "testuser", []byte("newpassword")); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+}
+
+func TestSEMPClient_RetriesAfterRetryAfter(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "adminpass",
+		HTTPClient:    server.Client(),
+	}
+
+	if err := client.ChangePassword(context.Background(), "testuser", []byte("newpassword")); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestSEMPClient_RetryAfterTooLong(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "adminpass",
+		HTTPClient:    server.Client(),
+	}
+
+	err := client.ChangePassword(context.Background(), "testuser", []byte("newpassword"))
+	if err == nil {
+		t.Fatal("expected error when Retry-After exceeds the maximum wait")
+	}
+	if got := sempRetryAfter(err); got != time.Hour {
+		t.Errorf("RetryAfter = %s, want 1h", got)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if d, ok := parseRetryAfter("120", now); !ok || d != 2*time.Minute {
+		t.Errorf("seconds: got %s, %v", d, ok)
+	}
+	if d, ok := parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now); !ok || d != time.Minute {
+		t.Errorf("http date: got %s, %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("expected invalid value to be rejected")
+	}
+}
+
+func TestNewHTTPClient_TransportTuning(t *testing.T) {
+	client := newHTTPClient(&BrokerConfig{
+		ForceHTTP1:          true,
+		MaxIdleConnsPerHost: 8,
+		TLSHandshakeTimeout: 3 * time.Second,
+	})
+	transport := client.Transport.(*http.Transport)
+	if transport.ForceAttemptHTTP2 {
+		t.Error("ForceAttemptHTTP2 should be false when force_http1 is set")
+	}
+	if transport.TLSNextProto == nil || len(transport.TLSNextProto) != 0 {
+		t.Error("TLSNextProto should be an empty map to disable HTTP/2")
+	}
+	if transport.MaxIdleConnsPerHost != 8 {
+		t.Errorf("MaxIdleConnsPerHost = %d, want 8", transport.MaxIdleConnsPerHost)
+	}
+	if transport.TLSHandshakeTimeout != 3*time.Second {
+		t.Errorf("TLSHandshakeTimeout = %s, want 3s", transport.TLSHandshakeTimeout)
+	}
+
+	transport = newHTTPClient(&BrokerConfig{}).Transport.(*http.Transport)
+	if !transport.ForceAttemptHTTP2 {
+		t.Error("HTTP/2 should be attempted by default")
+	}
+	if transport.TLSHandshakeTimeout != defaultTLSHandshakeTimeout {
+		t.Errorf("default TLSHandshakeTimeout = %s, want %s", transport.TLSHandshakeTimeout, defaultTLSHandshakeTimeout)
+	}
+}
+
+func TestBuildChangePasswordXML_SizedUpFront(t *testing.T) {
+	// A buffer that grows leaves an unwiped copy of the password behind, so
+	// even a worst-case escaping password must fit the initial allocation.
+	password := []byte(strings.Repeat(`"`, maxPasswordLength))
+	body := buildChangePasswordXML("soltr/10_4", strings.Repeat("<", 32), password)
+	initial := newRPCBuffer("soltr/10_4", escapedLen(strings.Repeat("<", 32))+len(password)*6+128)
+	if cap(body) != initial.Cap() {
+		t.Errorf("body capacity = %d, want the initial %d", cap(body), initial.Cap())
+	}
+}
+
+func TestSEMPClient_IsActive(t *testing.T) {
+	for name, tc := range map[string]struct {
+		redundancy string
+		want       bool
+	}{
+		"standalone":     {`<config-status>Disabled</config-status>`, true},
+		"primary active": {`<config-status>Enabled</config-status><virtual-routers><primary><status><activity>Local Active</activity></status></primary><backup><status><activity>Mate Active</activity></status></backup></virtual-routers>`, true},
+		"backup active":  {`<config-status>Enabled</config-status><virtual-routers><primary><status><activity>Mate Active</activity></status></primary><backup><status><activity>Local Active</activity></status></backup></virtual-routers>`, true},
+		"standby":        {`<config-status>Enabled</config-status><virtual-routers><primary><status><activity>Mate Active</activity></status></primary><backup><status><activity>Local Standby</activity></status></backup></virtual-routers>`, false},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`<rpc-reply><rpc><show><redundancy>` + tc.redundancy + `</redundancy></show></rpc><execute-result code="ok"/></rpc-reply>`))
+		}))
+		client := &SEMPClient{SEMPURL: server.URL, AdminUsername: "admin", AdminPassword: "adminpass", HTTPClient: server.Client()}
+		active, err := client.IsActive(context.Background())
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: IsActive: %v", name, err)
+		}
+		if active != tc.want {
+			t.Errorf("%s: IsActive = %v, want %v", name, active, tc.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Where Vault reaches a broker only through a local tunnel, SEMP can go
+// over a Unix domain socket, given as a semp_url of unix:///path/to/socket,
+// or to a TCP address other than semp_url's, such as the local end of an
+// SSH port forward, given as semp_dial_address. With a dial address the
+// request still names semp_url's host, so its TLS certificate is checked
+// against the broker rather than the tunnel.
+
+// unixSocketScheme is the semp_url scheme of a Unix domain socket.
+const unixSocketScheme = "unix"
+
+// dialTarget is where connections for a SEMP URL are actually made.
+type dialTarget struct {
+	network string
+	address string
+}
+
+// sempRequestBase returns the URL SEMP requests to a semp_url are made
+// against. A Unix socket has no host of its own, so it is given one derived
+// from its path, which keeps the connections of an HA pair's two sockets
+// apart in the connection pool.
+func sempRequestBase(sempURL string) string {
+	parsed, err := url.Parse(sempURL)
+	if err != nil || parsed.Scheme != unixSocketScheme {
+		return sempURL
+	}
+	return "http://" + unixSocketHost(parsed.Path)
+}
+
+// unixSocketHost returns the host requests over the socket at path use.
+func unixSocketHost(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return "unix-" + hex.EncodeToString(sum[:8]) + ".invalid"
+}
+
+// sempDialTargets returns, keyed by the host and port requests to a
+// broker's SEMP URLs connect to, the sockets and tunnel addresses those
+// connections go to instead.
+func sempDialTargets(config *BrokerConfig) map[string]dialTarget {
+	targets := make(map[string]dialTarget)
+	for _, endpoint := range []struct{ sempURL, dialAddress string }{
+		{config.SEMPURL, config.SEMPDialAddress},
+		{config.MateSEMPURL, config.MateSEMPDialAddress},
+	} {
+		parsed, err := url.Parse(endpoint.sempURL)
+		if err != nil || endpoint.sempURL == "" {
+			continue
+		}
+		switch {
+		case parsed.Scheme == unixSocketScheme:
+			targets[net.JoinHostPort(unixSocketHost(parsed.Path), "80")] = dialTarget{network: "unix", address: parsed.Path}
+		case endpoint.dialAddress != "":
+			targets[urlHostPort(parsed)] = dialTarget{network: "tcp", address: endpoint.dialAddress}
+		}
+	}
+	return targets
+}
+
+// urlHostPort returns the host and port an HTTP request to a URL connects
+// to, filling in the scheme's default port.
+func urlHostPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return net.JoinHostPort(u.Hostname(), port)
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// redirectingDialer sends connections for the addresses in targets to
+// their sockets or tunnel addresses, and every other connection to the
+// address asked for.
+func redirectingDialer(dialer *net.Dialer, targets map[string]dialTarget) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if len(targets) == 0 {
+		return dialer.DialContext
+	}
+	// A Unix socket is local, so the checks dialer makes on network
+	// addresses, such as allowed_semp_networks, do not apply to it.
+	unixDialer := &net.Dialer{Timeout: dialer.Timeout}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		target, ok := targets[address]
+		switch {
+		case !ok:
+			return dialer.DialContext(ctx, network, address)
+		case target.network == "unix":
+			return unixDialer.DialContext(ctx, "unix", target.address)
+		default:
+			return dialer.DialContext(ctx, network, target.address)
+		}
+	}
+}
+
+// validateSEMPEndpoint checks a semp_url or mate_semp_url, named field, and
+// the dial address that goes with it.
+func validateSEMPEndpoint(field, sempURL, dialAddress string, allowInsecure bool) error {
+	parsed, err := url.Parse(sempURL)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid URL", field)
+	}
+	if parsed.Scheme == unixSocketScheme {
+		if parsed.Host != "" || parsed.Path == "" {
+			return fmt.Errorf("%s of a Unix socket must be an absolute path, like unix:///run/solace/semp.sock", field)
+		}
+		if dialAddress != "" {
+			return fmt.Errorf("%s is a Unix socket, which cannot also have a dial address", field)
+		}
+		return nil
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%s must use the http, https or unix scheme", field)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("%s must include a host", field)
+	}
+	if parsed.Scheme != "https" && !allowInsecure {
+		return fmt.Errorf("%s must use https; set allow_insecure_transport on config/settings to permit http", field)
+	}
+	if dialAddress != "" {
+		host, port, err := net.SplitHostPort(dialAddress)
+		if err != nil || host == "" || port == "" {
+			return errors.New("dial addresses must be a host and port, like localhost:1943")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// hostRecordingServer is a SEMP server that accepts every request and
+// records the Host each one names.
+func hostRecordingServer(listener net.Listener) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var hosts []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		mu.Unlock()
+		w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+	}))
+	if listener != nil {
+		server.Listener.Close()
+		server.Listener = listener
+	}
+	server.Start()
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), hosts...)
+	}
+}
+
+func TestSEMPClient_UnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "semp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "semp.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets are not available: %v", err)
+	}
+	server, hosts := hostRecordingServer(listener)
+	defer server.Close()
+
+	client := NewSEMPClient("tunnel", &BrokerConfig{SEMPURL: "unix://" + socket, AdminUsername: "admin", AdminPassword: "secret"})
+	if err := client.ChangePassword(context.Background(), "app", []byte("new-Password-0123456789")); err != nil {
+		t.Fatalf("ChangePassword over a Unix socket: %v", err)
+	}
+	if got := hosts(); len(got) != 1 || got[0] != unixSocketHost(socket) {
+		t.Errorf("hosts = %v, want %s", got, unixSocketHost(socket))
+	}
+}
+
+func TestSEMPClient_DialAddress(t *testing.T) {
+	server, hosts := hostRecordingServer(nil)
+	defer server.Close()
+
+	// The broker's name does not resolve; only the tunnel reaches it.
+	config := &BrokerConfig{
+		SEMPURL:         "http://broker.invalid:8080",
+		SEMPDialAddress: server.Listener.Addr().String(),
+		AdminUsername:   "admin",
+		AdminPassword:   "secret",
+	}
+	if err := NewSEMPClient("tunnel", config).ChangePassword(context.Background(), "app", []byte("new-Password-0123456789")); err != nil {
+		t.Fatalf("ChangePassword through a dial address: %v", err)
+	}
+	if got := hosts(); len(got) != 1 || got[0] != "broker.invalid:8080" {
+		t.Errorf("hosts = %v, want the request to name semp_url's host", got)
+	}
+}
+
+func TestPathConfigBrokers_TunnelValidation(t *testing.T) {
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		data    map[string]interface{}
+		wantErr bool
+	}{
+		"unix socket":             {map[string]interface{}{"semp_url": "unix:///run/solace/semp.sock"}, false},
+		"dial address":            {map[string]interface{}{"semp_url": "https://broker:943", "semp_dial_address": "localhost:1943"}, false},
+		"relative socket":         {map[string]interface{}{"semp_url": "unix://run/semp.sock"}, true},
+		"socket with dial":        {map[string]interface{}{"semp_url": "unix:///run/semp.sock", "semp_dial_address": "localhost:1943"}, true},
+		"dial address no port":    {map[string]interface{}{"semp_url": "https://broker:943", "semp_dial_address": "localhost"}, true},
+		"mate dial without mate":  {map[string]interface{}{"semp_url": "https://broker:943", "mate_semp_dial_address": "localhost:1944"}, true},
+		"mate on its own socket":  {map[string]interface{}{"semp_url": "unix:///run/a.sock", "mate_semp_url": "unix:///run/b.sock"}, false},
+		"mate on the same socket": {map[string]interface{}{"semp_url": "unix:///run/a.sock", "mate_semp_url": "unix:///run/a.sock"}, true},
+	} {
+		// Each case gets its own broker, since writes merge into an
+		// existing config.
+		tc.data["admin_username"] = "admin"
+		tc.data["admin_password"] = "secret"
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/brokers/tunnel-" + strings.ReplaceAll(name, " ", "-"),
+			Storage:   storage,
+			Data:      tc.data,
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := resp != nil && resp.IsError(); got != tc.wantErr {
+			t.Errorf("%s: error = %v, want %v (resp=%v)", name, got, tc.wantErr, resp)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package solacevaultplugin
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// errSEMPAddressNotAllowed is returned when a broker's SEMP host resolves
+// to an address outside its allowed_semp_networks, as it would if its DNS
+// were hijacked to send the admin credential and new passwords elsewhere.
+var errSEMPAddressNotAllowed = errors.New("address is outside allowed_semp_networks")
+
+// normalizeNetworks checks allowed_semp_networks and returns them as CIDR
+// prefixes. A bare address stands for itself alone.
+func normalizeNetworks(networks []string) ([]string, error) {
+	out := make([]string, 0, len(networks))
+	for _, n := range networks {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(n); err == nil {
+			out = append(out, netip.PrefixFrom(addr, addr.BitLen()).String())
+			continue
+		}
+		prefix, err := netip.ParsePrefix(n)
+		if err != nil {
+			return nil, fmt.Errorf("allowed_semp_networks must be CIDR ranges or addresses, got %q", n)
+		}
+		out = append(out, prefix.Masked().String())
+	}
+	return out, nil
+}
+
+// allowedNetworksControl returns a dialer Control function that refuses to
+// connect outside networks. It sees the address after resolution, so it
+// checks the address actually connected to rather than one looked up
+// beforehand that a later lookup could replace.
+func allowedNetworksControl(networks []string) func(network, address string, c syscall.RawConn) error {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, n := range networks {
+		// The networks were checked when the broker was written.
+		if prefix, err := netip.ParsePrefix(n); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return func(_, address string, _ syscall.RawConn) error {
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return fmt.Errorf("connecting to %s: %w", address, errSEMPAddressNotAllowed)
+		}
+		addr := addrPort.Addr().Unmap()
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return nil
+			}
+		}
+		return fmt.Errorf("connecting to %s: %w", address, errSEMPAddressNotAllowed)
+	}
+}
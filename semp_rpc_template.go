@@ -0,0 +1,127 @@
+package solacevaultplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// A semp_rpc role rotates a credential the plugin does not model by sending
+// a SEMP v1 request built from a template. The template is the body of the
+// request without its <rpc> element, with {{password}} where the new
+// password goes and, optionally, {{username}} for the role's
+// semp_rpc_username. Both are XML-escaped when substituted.
+
+const (
+	rpcPlaceholderUsername = "username"
+	rpcPlaceholderPassword = "password"
+)
+
+// sempRPCOperation names template requests in metrics and broker activity.
+const sempRPCOperation = "custom_rpc"
+
+var rpcPlaceholderPattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// validateRPCTemplate checks that a template uses only known placeholders,
+// sets the password, and is well-formed XML whatever values are substituted
+// into it.
+func validateRPCTemplate(template, username string) error {
+	if strings.TrimSpace(template) == "" {
+		return errors.New("semp_rpc_template is required for semp_rpc roles")
+	}
+	hasPassword := false
+	for _, m := range rpcPlaceholderPattern.FindAllStringSubmatch(template, -1) {
+		switch m[1] {
+		case rpcPlaceholderPassword:
+			hasPassword = true
+		case rpcPlaceholderUsername:
+			if username == "" {
+				return errors.New("semp_rpc_template uses {{username}}, so semp_rpc_username is required")
+			}
+		default:
+			return fmt.Errorf("semp_rpc_template has unknown placeholder %q; only {{username}} and {{password}} are supported", m[0])
+		}
+	}
+	if !hasPassword {
+		return errors.New("semp_rpc_template must contain {{password}}")
+	}
+
+	// Values holding every character XML escapes show up placeholders used
+	// where escaped text is not allowed, such as in an element name.
+	sample := buildSEMPRPCTemplateXML("", template, `u<&"'>`, []byte(`p<&"'>`))
+	decoder := xml.NewDecoder(bytes.NewReader(sample))
+	depth, elements := 0, 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("semp_rpc_template is not well-formed XML: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 1 && t.Name.Local == "rpc" {
+				return errors.New("semp_rpc_template must not include the <rpc> element; it is added for the broker's SEMP version")
+			}
+			if depth == 1 {
+				elements++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.ProcInst, xml.Directive:
+			return errors.New("semp_rpc_template must not contain processing instructions or directives")
+		}
+	}
+	if elements == 0 {
+		return errors.New("semp_rpc_template must contain a SEMP command element")
+	}
+	return nil
+}
+
+// buildSEMPRPCTemplateXML wraps a template in an <rpc> element for the SEMP
+// version and substitutes its placeholders.
+func buildSEMPRPCTemplateXML(sempVersion, template, username string, password []byte) []byte {
+	// Size the buffer for every placeholder being the password or username,
+	// whichever is longer once escaped, so it never grows.
+	placeholders := len(rpcPlaceholderPattern.FindAllStringIndex(template, -1))
+	b := newRPCBuffer(sempVersion, len(template)+placeholders*max(escapedLen(username), len(password)*6)+16)
+	rest := template
+	for {
+		loc := rpcPlaceholderPattern.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			b.WriteString(rest)
+			break
+		}
+		b.WriteString(rest[:loc[0]])
+		switch rest[loc[2]:loc[3]] {
+		case rpcPlaceholderPassword:
+			xml.EscapeText(b, password)
+		case rpcPlaceholderUsername:
+			b.WriteString(escapeXML(username))
+		}
+		rest = rest[loc[1]:]
+	}
+	b.WriteString(`</rpc>`)
+	return b.Bytes()
+}
+
+// ExecuteRPCTemplate sends a semp_rpc role's template with the new password
+// substituted.
+func (c *SEMPClient) ExecuteRPCTemplate(ctx context.Context, template, username string, password []byte) error {
+	release, err := c.ConfigLock.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	body := buildSEMPRPCTemplateXML(c.SEMPVersion, template, username, password)
+	defer wipe(body)
+	return c.executeChange(ctx, sempRPCOperation, username, body)
+}
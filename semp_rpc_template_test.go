@@ -0,0 +1,146 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const testRPCTemplate = `<client-username><name>{{username}}</name><vpn-name>default</vpn-name><password><password>{{password}}</password></password></client-username>`
+
+func TestValidateRPCTemplate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		template, username string
+		wantErr            string
+	}{
+		"valid":                {testRPCTemplate, "app", ""},
+		"spaced placeholders":  {`<a><b>{{ password }}</b></a>`, "", ""},
+		"empty":                {"", "", "required"},
+		"no password":          {`<client-username><name>{{username}}</name></client-username>`, "app", "{{password}}"},
+		"username without one": {testRPCTemplate, "", "semp_rpc_username"},
+		"unknown placeholder":  {`<a>{{password}}{{vpn}}</a>`, "", "unknown placeholder"},
+		"rpc element":          {`<rpc><a>{{password}}</a></rpc>`, "", "<rpc>"},
+		"malformed":            {`<a>{{password}}</b>`, "", "well-formed"},
+		"placeholder as name":  {`<{{password}}/>`, "", "well-formed"},
+		"placeholder in attr":  {`<a b={{password}}/>`, "", "well-formed"},
+		"directive":            {`<!DOCTYPE a><a>{{password}}</a>`, "", "directives"},
+		"no element":           {`{{password}}`, "", "command element"},
+	} {
+		err := validateRPCTemplate(tc.template, tc.username)
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", name, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("%s: error = %v, want one mentioning %q", name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestBuildSEMPRPCTemplateXML(t *testing.T) {
+	body := buildSEMPRPCTemplateXML("soltr/10_4", testRPCTemplate, "a&b", []byte(`p<"w>d`))
+	want := `<rpc semp-version="soltr/10_4"><client-username><name>a&amp;b</name><vpn-name>default</vpn-name>` +
+		`<password><password>p&lt;&#34;w&gt;d</password></password></client-username></rpc>`
+	if string(body) != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+// rpcTemplateBroker is a SEMP v1 server recording the requests it receives.
+type rpcTemplateBroker struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (rb *rpcTemplateBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rb.mu.Lock()
+	rb.bodies = append(rb.bodies, string(body))
+	rb.mu.Unlock()
+	w.Write([]byte(`<rpc-reply><execute-result code="ok"/></rpc-reply>`))
+}
+
+func TestSEMPRPCRole_Rotate(t *testing.T) {
+	rb := &rpcTemplateBroker{}
+	server := httptest.NewServer(rb)
+	t.Cleanup(server.Close)
+
+	b, storage := getTestBackend(t)
+	ctx := context.Background()
+	for _, w := range []struct {
+		path string
+		data map[string]interface{}
+	}{
+		{"config/brokers/test-broker", map[string]interface{}{
+			"semp_url":       server.URL,
+			"admin_username": "admin",
+			"admin_password": "secret",
+		}},
+		{"roles/client", map[string]interface{}{
+			"broker":            "test-broker",
+			"target":            roleTargetSEMPRPC,
+			"semp_rpc_template": testRPCTemplate,
+			"semp_rpc_username": "app",
+		}},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      w.path,
+			Storage:   storage,
+			Data:      w.data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("write %s: err=%v, resp=%v", w.path, err, resp)
+		}
+	}
+
+	resp, err := b.(*solaceBackend).rotateRole(ctx, storage, "client")
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("rotateRole: err=%v, resp=%v", err, resp)
+	}
+
+	rb.mu.Lock()
+	bodies := append([]string(nil), rb.bodies...)
+	rb.mu.Unlock()
+	if len(bodies) != 1 {
+		t.Fatalf("broker received %d requests, want 1: %v", len(bodies), bodies)
+	}
+	var sent struct {
+		ClientUsername struct {
+			Name     string `xml:"name"`
+			Password string `xml:"password>password"`
+		} `xml:"client-username"`
+	}
+	if err := xml.Unmarshal([]byte(bodies[0]), &sent); err != nil {
+		t.Fatalf("parsing request %s: %v", bodies[0], err)
+	}
+
+	creds := readCreds(t, b, storage, "client")
+	if sent.ClientUsername.Name != "app" || creds["semp_rpc_username"] != "app" {
+		t.Errorf("sent username %q, creds %v", sent.ClientUsername.Name, creds)
+	}
+	if sent.ClientUsername.Password == "" || creds["password"] != sent.ClientUsername.Password {
+		t.Error("stored password does not match the one sent to the broker")
+	}
+
+	// A template may not be set on other targets.
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/cli",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"broker":            "test-broker",
+			"cli_username":      "monitor",
+			"semp_rpc_template": testRPCTemplate,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Errorf("cli_user role with a template: err=%v, resp=%v, want an error response", err, resp)
+	}
+}
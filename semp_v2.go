@@ -0,0 +1,234 @@
+package solacevaultplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientUsername is a message VPN client-username as modeled by the SEMP v2
+// config API.
+type ClientUsername struct {
+	ClientUsername    string `json:"clientUsername"`
+	Password          string `json:"password,omitempty"`
+	ClientProfileName string `json:"clientProfileName,omitempty"`
+	ACLProfileName    string `json:"aclProfileName,omitempty"`
+	Enabled           bool   `json:"enabled"`
+}
+
+type sempV2Response struct {
+	Meta struct {
+		ResponseCode int `json:"responseCode"`
+		Error        *struct {
+			Code        int    `json:"code"`
+			Description string `json:"description"`
+			Status      string `json:"status"`
+		} `json:"error,omitempty"`
+	} `json:"meta"`
+}
+
+// CreateClientUsername creates a client-username in the given message VPN.
+func (c *SEMPClient) CreateClientUsername(ctx context.Context, msgVpn string, cu *ClientUsername) error {
+	path := "/msgVpns/" + url.PathEscape(msgVpn) + "/clientUsernames"
+	_, err := c.executeV2(ctx, "create_client_username", http.MethodPost, path, cu)
+	return err
+}
+
+// SetClientUsernameEnabled enables or disables a client-username.
+func (c *SEMPClient) SetClientUsernameEnabled(ctx context.Context, msgVpn, name string, enabled bool) error {
+	path := "/msgVpns/" + url.PathEscape(msgVpn) + "/clientUsernames/" + url.PathEscape(name)
+	_, err := c.executeV2(ctx, "update_client_username", http.MethodPatch, path, map[string]interface{}{"enabled": enabled})
+	return err
+}
+
+// DeleteClientUsername removes a client-username from the given message VPN.
+func (c *SEMPClient) DeleteClientUsername(ctx context.Context, msgVpn, name string) error {
+	path := "/msgVpns/" + url.PathEscape(msgVpn) + "/clientUsernames/" + url.PathEscape(name)
+	_, err := c.executeV2(ctx, "delete_client_username", http.MethodDelete, path, nil)
+	return err
+}
+
+// executeV2 issues a SEMP v2 config API request, records telemetry for the
+// call and returns the raw response body once the broker has reported success.
+// Any request other than a GET is a configuration change: it holds the
+// broker's configuration lock and is recorded in its activity log, with
+// path as the target.
+func (c *SEMPClient) executeV2(ctx context.Context, operation, method, path string, payload interface{}) (respBody []byte, err error) {
+	if method != http.MethodGet {
+		release, lockErr := c.ConfigLock.acquire(ctx)
+		if lockErr != nil {
+			return nil, lockErr
+		}
+		defer release()
+		defer func() {
+			c.recordActivity(ctx, operation, path, err)
+		}()
+	}
+
+	start := time.Now()
+	defer func() {
+		c.finishCall(ctx, operation, start, err)
+	}()
+
+	if err := c.Breaker.allow(c.Broker); err != nil {
+		return nil, err
+	}
+
+	// A pre-encoded payload is sent as is, so a caller holding a secret in
+	// it can wipe the one copy.
+	var encoded []byte
+	switch p := payload.(type) {
+	case nil:
+	case json.RawMessage:
+		encoded = p
+	default:
+		encoded, err = json.Marshal(payload)
+		if err != nil {
+			return nil, &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("encoding SEMP v2 request: %w", err)}
+		}
+	}
+
+	status, respBody, err := c.do(ctx, func() (*http.Request, error) {
+		var body io.Reader
+		if encoded != nil {
+			body = bytes.NewReader(encoded)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, sempRequestBase(c.SEMPURL)+"/SEMP/v2/config"+path, body)
+		if err != nil {
+			return nil, err
+		}
+		if encoded != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed sempV2Response
+	if len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, &parsed); err != nil && status == http.StatusOK {
+			return nil, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("parsing SEMP v2 response: %w", err)}
+		}
+	}
+
+	if status != http.StatusOK {
+		if parsed.Meta.Error != nil {
+			return nil, &SEMPError{Class: sempErrCommand, StatusCode: status, Err: fmt.Errorf("SEMP v2 command failed: %s (%s)", parsed.Meta.Error.Description, parsed.Meta.Error.Status)}
+		}
+		return nil, &SEMPError{Class: sempErrHTTP, StatusCode: status, Err: fmt.Errorf("SEMP v2 returned HTTP %d: %s", status, string(respBody))}
+	}
+
+	return respBody, nil
+}
+
+// RESTConsumer is a REST delivery point's REST consumer as modeled by the
+// SEMP v2 config API, limited to the fields the plugin reads.
+type RESTConsumer struct {
+	RESTConsumerName     string `json:"restConsumerName"`
+	Enabled              bool   `json:"enabled"`
+	AuthenticationScheme string `json:"authenticationScheme"`
+}
+
+func restConsumerPath(msgVpn, rdp, consumer string) string {
+	return "/msgVpns/" + url.PathEscape(msgVpn) + "/restDeliveryPoints/" + url.PathEscape(rdp) + "/restConsumers/" + url.PathEscape(consumer)
+}
+
+// GetRESTConsumer reads a REST consumer of a REST delivery point.
+func (c *SEMPClient) GetRESTConsumer(ctx context.Context, msgVpn, rdp, consumer string) (*RESTConsumer, error) {
+	respBody, err := c.executeV2(ctx, "get_rest_consumer", http.MethodGet, restConsumerPath(msgVpn, rdp, consumer), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data RESTConsumer `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("parsing SEMP v2 REST consumer: %w", err)}
+	}
+	return &resp.Data, nil
+}
+
+// SetRESTConsumerBasicAuth switches a REST consumer to HTTP basic
+// authentication with the given credentials. The encoded request body is
+// wiped once the call returns; password remains the caller's to wipe.
+func (c *SEMPClient) SetRESTConsumerBasicAuth(ctx context.Context, msgVpn, rdp, consumer, username string, password []byte) error {
+	body, err := json.Marshal(map[string]string{
+		"authenticationScheme":            restAuthHTTPBasic,
+		"authenticationHttpBasicUsername": username,
+		"authenticationHttpBasicPassword": string(password),
+	})
+	if err != nil {
+		return &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("encoding SEMP v2 request: %w", err)}
+	}
+	defer wipe(body)
+	_, err = c.executeV2(ctx, "update_rest_consumer", http.MethodPatch, restConsumerPath(msgVpn, rdp, consumer), json.RawMessage(body))
+	return err
+}
+
+// SetRESTConsumerClientCert switches a REST consumer to client certificate
+// authentication. content is the PEM private key followed by the
+// certificate; like a password, it remains the caller's to wipe.
+func (c *SEMPClient) SetRESTConsumerClientCert(ctx context.Context, msgVpn, rdp, consumer string, content []byte) error {
+	body, err := json.Marshal(map[string]string{
+		"authenticationScheme":            restAuthClientCertificate,
+		"authenticationClientCertContent": string(content),
+	})
+	if err != nil {
+		return &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("encoding SEMP v2 request: %w", err)}
+	}
+	defer wipe(body)
+	_, err = c.executeV2(ctx, "update_rest_consumer", http.MethodPatch, restConsumerPath(msgVpn, rdp, consumer), json.RawMessage(body))
+	return err
+}
+
+// OAuthProfile is an OAuth profile as modeled by the SEMP v2 config API,
+// limited to the fields the plugin reads.
+type OAuthProfile struct {
+	OAuthProfileName string `json:"oauthProfileName"`
+	Enabled          bool   `json:"enabled"`
+}
+
+// oauthProfilePath addresses a message VPN's OAuth authentication profile,
+// or a broker-level OAuth profile when msgVpn is empty.
+func oauthProfilePath(msgVpn, profile string) string {
+	if msgVpn == "" {
+		return "/oauthProfiles/" + url.PathEscape(profile)
+	}
+	return "/msgVpns/" + url.PathEscape(msgVpn) + "/authenticationOauthProfiles/" + url.PathEscape(profile)
+}
+
+// GetOAuthProfile reads an OAuth profile; see oauthProfilePath.
+func (c *SEMPClient) GetOAuthProfile(ctx context.Context, msgVpn, profile string) (*OAuthProfile, error) {
+	respBody, err := c.executeV2(ctx, "get_oauth_profile", http.MethodGet, oauthProfilePath(msgVpn, profile), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data OAuthProfile `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, &SEMPError{Class: sempErrParse, Err: fmt.Errorf("parsing SEMP v2 OAuth profile: %w", err)}
+	}
+	return &resp.Data, nil
+}
+
+// SetOAuthProfileClientSecret replaces the client secret of an OAuth
+// profile; see oauthProfilePath. As with REST consumers, only the encoded
+// request body is wiped.
+func (c *SEMPClient) SetOAuthProfileClientSecret(ctx context.Context, msgVpn, profile string, secret []byte) error {
+	body, err := json.Marshal(map[string]string{"clientSecret": string(secret)})
+	if err != nil {
+		return &SEMPError{Class: sempErrTransport, Err: fmt.Errorf("encoding SEMP v2 request: %w", err)}
+	}
+	defer wipe(body)
+	_, err = c.executeV2(ctx, "update_oauth_profile", http.MethodPatch, oauthProfilePath(msgVpn, profile), json.RawMessage(body))
+	return err
+}
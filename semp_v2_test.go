@@ -0,0 +1,104 @@
+package solacevaultplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSEMPClient_CreateClientUsername(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %q, want POST", r.Method)
+		}
+		if r.URL.Path != "/SEMP/v2/config/msgVpns/default/clientUsernames" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		var cu ClientUsername
+		if err := json.NewDecoder(r.Body).Decode(&cu); err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		if cu.ClientUsername != "app1" || cu.ClientProfileName != "cp" || cu.ACLProfileName != "acl" || !cu.Enabled {
+			t.Errorf("unexpected body: %+v", cu)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{},"meta":{"responseCode":200}}`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "adminpass",
+		HTTPClient:    server.Client(),
+	}
+
+	err := client.CreateClientUsername(context.Background(), "default", &ClientUsername{
+		ClientUsername:    "app1",
+		Password:          "secret",
+		ClientProfileName: "cp",
+		ACLProfileName:    "acl",
+		Enabled:           true,
+	})
+	if err != nil {
+		t.Fatalf("CreateClientUsername: %v", err)
+	}
+}
+
+func TestSEMPClient_DeleteClientUsername_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("method = %q, want DELETE", r.Method)
+		}
+		if r.URL.Path != "/SEMP/v2/config/msgVpns/default/clientUsernames/app1" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"meta":{"responseCode":400,"error":{"code":6,"description":"Could not find match for clientUsername","status":"NOT_FOUND"}}}`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "adminpass",
+		HTTPClient:    server.Client(),
+	}
+
+	err := client.DeleteClientUsername(context.Background(), "default", "app1")
+	if err == nil {
+		t.Fatal("expected error for SEMP v2 failure")
+	}
+	if sempErrorClass(err) != sempErrCommand {
+		t.Errorf("error class = %q, want %q", sempErrorClass(err), sempErrCommand)
+	}
+}
+
+func TestSEMPClient_SetClientUsernameEnabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("method = %q, want PATCH", r.Method)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["enabled"] != false {
+			t.Errorf("enabled = %v, want false", body["enabled"])
+		}
+		w.Write([]byte(`{"meta":{"responseCode":200}}`))
+	}))
+	defer server.Close()
+
+	client := &SEMPClient{
+		SEMPURL:       server.URL,
+		AdminUsername: "admin",
+		AdminPassword: "adminpass",
+		HTTPClient:    server.Client(),
+	}
+
+	if err := client.SetClientUsernameEnabled(context.Background(), "default", "app1", false); err != nil {
+		t.Fatalf("SetClientUsernameEnabled: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package solacetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	solacevaultplugin "github.com/solace-vault-plugin"
+)
+
+// NewBackend returns a plugin backend on fresh in-memory storage. Because a
+// Server speaks plain HTTP, the mount is set to allow insecure transport.
+func NewBackend(t testing.TB) (logical.Backend, logical.Storage) {
+	t.Helper()
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := solacevaultplugin.Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("solacetest: creating backend: %v", err)
+	}
+	Write(t, b, config.StorageView, "config/settings", map[string]interface{}{
+		"allow_insecure_transport": true,
+	})
+	return b, config.StorageView
+}
+
+// WriteBroker configures the broker name to use server with its admin
+// credential. data, which may be nil, adds or overrides broker parameters.
+func WriteBroker(t testing.TB, b logical.Backend, s logical.Storage, name string, server *Server, data map[string]interface{}) {
+	t.Helper()
+	fields := map[string]interface{}{
+		"semp_url":       server.URL,
+		"admin_username": AdminUsername,
+		"admin_password": AdminPassword,
+	}
+	for key, value := range data {
+		fields[key] = value
+	}
+	Write(t, b, s, "config/brokers/"+name, fields)
+}
+
+// HandleRequest sends a request to the backend and returns its response, failing
+// the test if the request returns an error. An error response is returned,
+// not failed on, so tests can check for it.
+func HandleRequest(t testing.TB, b logical.Backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("solacetest: %s %s: %v", op, path, err)
+	}
+	return resp
+}
+
+// Write sends an update to the backend and fails the test unless it
+// succeeds.
+func Write(t testing.TB, b logical.Backend, s logical.Storage, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+	resp := HandleRequest(t, b, s, logical.UpdateOperation, path, data)
+	if resp != nil && resp.IsError() {
+		t.Fatalf("solacetest: write %s: %v", path, resp.Error())
+	}
+	return resp
+}
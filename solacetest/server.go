@@ -0,0 +1,351 @@
+// Package solacetest helps test code that depends on the Solace Vault plugin
+// without a real broker. It provides a fake SEMP server that keeps CLI users
+// in memory and can be scripted to fail, and helpers that build a plugin
+// backend wired to it.
+package solacetest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Operations the fake server recognizes in SEMP v1 requests. They match the
+// operation names the plugin reports in its metrics. A SEMP v2 request's
+// operation is its method and the path below /SEMP/v2/config, such as
+// "PATCH /msgVpns/default/clientUsernames/app".
+const (
+	OperationChangePassword       = "change_password"
+	OperationCreateUsername       = "create_username"
+	OperationSetGlobalAccessLevel = "set_global_access_level"
+	OperationSetVPNAccessLevel    = "set_vpn_access_level"
+	OperationCreateVPNException   = "create_vpn_access_level_exception"
+	OperationDeleteUsername       = "delete_username"
+	OperationShutdownUsername     = "shutdown_username"
+	OperationEnableUsername       = "enable_username"
+	OperationShowUsername         = "show_username"
+	OperationShowRedundancy       = "show_redundancy"
+)
+
+// Default admin credential of a Server, as used by WriteBroker.
+const (
+	AdminUsername = "admin"
+	AdminPassword = "admin-password"
+)
+
+const (
+	replyOK   = `<rpc-reply><execute-result code="ok"/></rpc-reply>`
+	replyFail = `<rpc-reply><execute-result code="fail"/></rpc-reply>`
+)
+
+// User is a CLI user on a Server.
+type User struct {
+	Name              string
+	Password          string
+	GlobalAccessLevel string
+
+	// VPNAccessLevel is the user's access level to message VPNs, and
+	// VPNAccessLevelExceptions its access level to particular ones.
+	VPNAccessLevel           string
+	VPNAccessLevelExceptions map[string]string
+
+	// Shutdown reports whether the user is shut down. A shut-down user
+	// cannot log in.
+	Shutdown bool
+}
+
+// Response is a scripted reply to one request.
+type Response struct {
+	// Status is the HTTP status; zero means 200 OK.
+	Status int
+	// Body is sent as is.
+	Body string
+	// Header is added to the reply, e.g. Retry-After.
+	Header http.Header
+}
+
+// CommandFailure is a SEMP v1 reply whose execute-result reports a failed
+// command.
+var CommandFailure = Response{Body: replyFail}
+
+// Request is a request the Server received.
+type Request struct {
+	Operation string
+	// Username is the user the request authenticated as: the admin, or a
+	// CLI user whose password the plugin is checking.
+	Username string
+	// Target is the CLI username a SEMP v1 request names, if any.
+	Target string
+}
+
+// Server is a fake Solace broker answering SEMP v1 requests for CLI users.
+// Password changes, creations, deletions and shutdowns update its users; show
+// commands report them. SEMP v2 requests are answered with an empty
+// success unless scripted. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	users    map[string]*User
+	scripted map[string][]Response
+	requests []Request
+}
+
+// NewServer starts a Server with no users. It is closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{
+		users:    make(map[string]*User),
+		scripted: make(map[string][]Response),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// AddUser creates or replaces a CLI user.
+func (s *Server) AddUser(name, password, globalAccessLevel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[name] = &User{Name: name, Password: password, GlobalAccessLevel: globalAccessLevel}
+}
+
+// User returns a CLI user, and whether it exists.
+func (s *Server) User(name string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[name]
+	if !ok {
+		return User{}, false
+	}
+	copied := *user
+	if user.VPNAccessLevelExceptions != nil {
+		copied.VPNAccessLevelExceptions = make(map[string]string, len(user.VPNAccessLevelExceptions))
+		for vpn, level := range user.VPNAccessLevelExceptions {
+			copied.VPNAccessLevelExceptions[vpn] = level
+		}
+	}
+	return copied, true
+}
+
+// Script queues responses to the next requests for operation, in order.
+// Once they are used up the server answers the operation normally again.
+func (s *Server) Script(operation string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scripted[operation] = append(s.scripted[operation], responses...)
+}
+
+// Requests returns every request received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// rpc is the subset of SEMP v1 requests the server understands.
+type rpc struct {
+	Show *struct {
+		Username *struct {
+			Name string `xml:"name"`
+		} `xml:"username"`
+		Redundancy *struct{} `xml:"redundancy"`
+	} `xml:"show"`
+	Create *struct {
+		Username *struct {
+			Name     string `xml:"name"`
+			Password string `xml:"password"`
+		} `xml:"username"`
+	} `xml:"create"`
+	No *struct {
+		Username *struct {
+			Name string `xml:"name"`
+		} `xml:"username"`
+	} `xml:"no"`
+	Username *struct {
+		Name     string    `xml:"name"`
+		Shutdown *struct{} `xml:"shutdown"`
+		No       *struct {
+			Shutdown *struct{} `xml:"shutdown"`
+		} `xml:"no"`
+		ChangePassword *struct {
+			Password string `xml:"password"`
+		} `xml:"change-password"`
+		GlobalAccessLevel *struct {
+			AccessLevel string `xml:"access-level"`
+		} `xml:"global-access-level"`
+		MessageVPN *struct {
+			DefaultAccessLevel *struct {
+				AccessLevel string `xml:"access-level"`
+			} `xml:"default-access-level"`
+			Create *struct {
+				Exception *struct {
+					VPNName     string `xml:"vpn-name"`
+					AccessLevel string `xml:"access-level"`
+				} `xml:"access-level-exception"`
+			} `xml:"create"`
+		} `xml:"message-vpn"`
+	} `xml:"username"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	username, password, _ := r.BasicAuth()
+	req := Request{Username: username}
+
+	var cmd rpc
+	v2 := strings.HasPrefix(r.URL.Path, "/SEMP/v2/config")
+	if v2 {
+		req.Operation = r.Method + " " + strings.TrimPrefix(r.URL.Path, "/SEMP/v2/config")
+	} else if err := xml.Unmarshal(body, &cmd); err != nil {
+		http.Error(w, "malformed request", http.StatusBadRequest)
+		return
+	} else {
+		req.Operation, req.Target = cmd.operation()
+	}
+	s.requests = append(s.requests, req)
+
+	// The plugin checks a CLI user's password by logging in as that user.
+	if username != AdminUsername || password != AdminPassword {
+		if user, ok := s.users[username]; !ok || user.Password != password || user.Shutdown {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if queued := s.scripted[req.Operation]; len(queued) > 0 {
+		s.scripted[req.Operation] = queued[1:]
+		writeResponse(w, queued[0])
+		return
+	}
+	if v2 {
+		writeResponse(w, Response{Body: `{"data":{},"meta":{"responseCode":200}}`})
+		return
+	}
+	writeResponse(w, Response{Body: s.apply(username, &cmd)})
+}
+
+// operation names a SEMP v1 request and the CLI user it targets.
+func (c *rpc) operation() (string, string) {
+	switch {
+	case c.Show != nil && c.Show.Username != nil:
+		return OperationShowUsername, c.Show.Username.Name
+	case c.Show != nil && c.Show.Redundancy != nil:
+		return OperationShowRedundancy, ""
+	case c.Create != nil && c.Create.Username != nil:
+		return OperationCreateUsername, c.Create.Username.Name
+	case c.No != nil && c.No.Username != nil:
+		return OperationDeleteUsername, c.No.Username.Name
+	case c.Username != nil && c.Username.ChangePassword != nil:
+		return OperationChangePassword, c.Username.Name
+	case c.Username != nil && c.Username.Shutdown != nil:
+		return OperationShutdownUsername, c.Username.Name
+	case c.Username != nil && c.Username.No != nil && c.Username.No.Shutdown != nil:
+		return OperationEnableUsername, c.Username.Name
+	case c.Username != nil && c.Username.GlobalAccessLevel != nil:
+		return OperationSetGlobalAccessLevel, c.Username.Name
+	case c.Username != nil && c.Username.MessageVPN != nil && c.Username.MessageVPN.DefaultAccessLevel != nil:
+		return OperationSetVPNAccessLevel, c.Username.Name
+	case c.Username != nil && c.Username.MessageVPN != nil && c.Username.MessageVPN.Create != nil &&
+		c.Username.MessageVPN.Create.Exception != nil:
+		return OperationCreateVPNException, c.Username.Name
+	}
+	return "unknown", ""
+}
+
+// apply carries out a SEMP v1 request against the server's users and
+// returns the reply. A CLI user logged in to check its password may only
+// run show commands. s.mu must be held.
+func (s *Server) apply(username string, c *rpc) string {
+	op, target := c.operation()
+	if username != AdminUsername && op != OperationShowUsername && op != OperationShowRedundancy {
+		return replyFail
+	}
+
+	switch op {
+	case OperationShowUsername:
+		user, ok := s.users[target]
+		if !ok {
+			return `<rpc-reply><rpc><show><username><usernames/></username></show></rpc><execute-result code="ok"/></rpc-reply>`
+		}
+		var b strings.Builder
+		b.WriteString(`<rpc-reply><rpc><show><username><usernames><username><name>`)
+		xml.EscapeText(&b, []byte(user.Name))
+		fmt.Fprintf(&b, `</name><enabled>%t</enabled><global-access-level>`, !user.Shutdown)
+		xml.EscapeText(&b, []byte(user.GlobalAccessLevel))
+		b.WriteString(`</global-access-level></username></usernames></username></show></rpc><execute-result code="ok"/></rpc-reply>`)
+		return b.String()
+	case OperationShowRedundancy:
+		return `<rpc-reply><rpc><show><redundancy><config-status>Disabled</config-status></redundancy></show></rpc><execute-result code="ok"/></rpc-reply>`
+	case OperationCreateUsername:
+		if _, ok := s.users[target]; ok {
+			return replyFail
+		}
+		s.users[target] = &User{Name: target, Password: c.Create.Username.Password}
+	case OperationDeleteUsername:
+		delete(s.users, target)
+	case OperationChangePassword:
+		user, ok := s.users[target]
+		if !ok {
+			return replyFail
+		}
+		user.Password = c.Username.ChangePassword.Password
+	case OperationShutdownUsername, OperationEnableUsername:
+		user, ok := s.users[target]
+		if !ok {
+			return replyFail
+		}
+		user.Shutdown = op == OperationShutdownUsername
+	case OperationSetGlobalAccessLevel:
+		user, ok := s.users[target]
+		if !ok {
+			return replyFail
+		}
+		user.GlobalAccessLevel = c.Username.GlobalAccessLevel.AccessLevel
+	case OperationSetVPNAccessLevel:
+		user, ok := s.users[target]
+		if !ok {
+			return replyFail
+		}
+		user.VPNAccessLevel = c.Username.MessageVPN.DefaultAccessLevel.AccessLevel
+	case OperationCreateVPNException:
+		user, ok := s.users[target]
+		exception := c.Username.MessageVPN.Create.Exception
+		if !ok || user.VPNAccessLevelExceptions[exception.VPNName] != "" {
+			return replyFail
+		}
+		if user.VPNAccessLevelExceptions == nil {
+			user.VPNAccessLevelExceptions = make(map[string]string)
+		}
+		user.VPNAccessLevelExceptions[exception.VPNName] = exception.AccessLevel
+	default:
+		return replyFail
+	}
+	return replyOK
+}
+
+func writeResponse(w http.ResponseWriter, resp Response) {
+	for name, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	fmt.Fprint(w, resp.Body)
+}
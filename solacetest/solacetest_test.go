@@ -0,0 +1,101 @@
+package solacetest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestServer_RotationAgainstBackend(t *testing.T) {
+	server := NewServer(t)
+	server.AddUser("monitor", "initial", "read-only")
+
+	b, s := NewBackend(t)
+	Write(t, b, s, "config/settings", map[string]interface{}{"min_rotation_interval": 0})
+	WriteBroker(t, b, s, "dev", server, nil)
+	Write(t, b, s, "roles/app", map[string]interface{}{
+		"broker":       "dev",
+		"cli_username": "monitor",
+	})
+
+	Write(t, b, s, "rotate-role/app", nil)
+	creds := HandleRequest(t, b, s, logical.ReadOperation, "creds/app", nil)
+	user, _ := server.User("monitor")
+	if creds == nil || creds.Data["password"] != user.Password {
+		t.Fatalf("creds = %v, broker password = %q", creds, user.Password)
+	}
+	if resp := HandleRequest(t, b, s, logical.ReadOperation, "verify-password/app", nil); resp == nil || resp.Data["matches"] != true {
+		t.Errorf("verify-password = %v, want a match", resp)
+	}
+
+	// A scripted failure is used once, then the server behaves again.
+	server.Script(OperationChangePassword, CommandFailure)
+	if resp := HandleRequest(t, b, s, logical.UpdateOperation, "rotate-role/app", nil); resp == nil || !resp.IsError() {
+		t.Fatalf("rotation against a failing broker = %v, want an error", resp)
+	}
+	if after, _ := server.User("monitor"); after.Password != user.Password {
+		t.Error("failed change updated the password")
+	}
+
+	var changes int
+	for _, req := range server.Requests() {
+		if req.Operation == OperationChangePassword && req.Target == "monitor" {
+			changes++
+		}
+	}
+	if changes != 2 {
+		t.Errorf("saw %d password changes, want 2", changes)
+	}
+}
+
+func TestServer_DisableDuringRotation(t *testing.T) {
+	server := NewServer(t)
+	server.AddUser("app", "initial", "none")
+
+	b, s := NewBackend(t)
+	WriteBroker(t, b, s, "dev", server, nil)
+	Write(t, b, s, "roles/app", map[string]interface{}{
+		"broker":                  "dev",
+		"cli_username":            "app",
+		"disable_during_rotation": true,
+	})
+
+	Write(t, b, s, "rotate-role/app", nil)
+	var ops []string
+	for _, req := range server.Requests() {
+		if req.Target == "app" && req.Operation != OperationShowUsername {
+			ops = append(ops, req.Operation)
+		}
+	}
+	want := []string{OperationShutdownUsername, OperationChangePassword, OperationEnableUsername}
+	if strings.Join(ops, ",") != strings.Join(want, ",") {
+		t.Errorf("operations = %v, want %v", ops, want)
+	}
+	if user, _ := server.User("app"); user.Shutdown || user.Password == "initial" {
+		t.Errorf("user = %+v, want it enabled with a new password", user)
+	}
+}
+
+func TestServer_CreatesMissingUser(t *testing.T) {
+	server := NewServer(t)
+	b, s := NewBackend(t)
+	WriteBroker(t, b, s, "dev", server, nil)
+	Write(t, b, s, "roles/new", map[string]interface{}{
+		"broker":              "dev",
+		"cli_username":        "newuser",
+		"create_if_missing":   true,
+		"global_access_level": "read-only",
+		"vpn_access_level":    "none",
+		"vpn_access_level_exceptions": map[string]interface{}{
+			"prod": "read-write",
+		},
+	})
+
+	Write(t, b, s, "rotate-role/new", nil)
+	user, ok := server.User("newuser")
+	if !ok || user.GlobalAccessLevel != "read-only" || user.Password == "" ||
+		user.VPNAccessLevel != "none" || user.VPNAccessLevelExceptions["prod"] != "read-write" {
+		t.Errorf("user = %+v, exists = %v", user, ok)
+	}
+}
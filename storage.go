@@ -9,7 +9,32 @@ import (
 
 const (
 	brokerStoragePrefix = "config/brokers/"
+	groupStoragePrefix  = "config/broker-groups/"
 	roleStoragePrefix   = "roles/"
+	secretStoragePrefix = "secrets/"
+	recoveryPrefix      = "recovery/"
+	retainedPrefix      = "retained/"
+	activityPrefix      = "activity/"
+	settingsStorageKey  = "config/settings"
+
+	// brokerRoleIndexPrefix holds one empty entry per role under
+	// <prefix><broker>/<role>, so a broker's dependents can be listed
+	// without reading every role.
+	brokerRoleIndexPrefix = "index/broker-roles/"
+	brokerRoleIndexMarker = "index/broker-roles-built"
+
+	// groupRoleIndexPrefix is the same index for roles on a broker group.
+	groupRoleIndexPrefix = "index/group-roles/"
+
+	roleSecretsMigratedMarker = "migrations/role-secrets"
+
+	generationStorageKey = "state/generation"
+	restoreSuspectPrefix = "state/restore-suspect/"
+
+	lastPeriodicRunStorageKey = "state/last-periodic-run"
+
+	driftPrefix          = "state/drift/"
+	driftCheckStorageKey = "state/drift-check"
 )
 
 func getEntry[T any](ctx context.Context, s logical.Storage, path string) (*T, error) {
@@ -51,16 +76,293 @@ func listBrokers(ctx context.Context, s logical.Storage) ([]string, error) {
 	return s.List(ctx, brokerStoragePrefix)
 }
 
+func getBrokerGroup(ctx context.Context, s logical.Storage, name string) (*BrokerGroup, error) {
+	return getEntry[BrokerGroup](ctx, s, groupStoragePrefix+name)
+}
+
+func putBrokerGroup(ctx context.Context, s logical.Storage, name string, group *BrokerGroup) error {
+	return putEntry(ctx, s, groupStoragePrefix+name, group)
+}
+
+func deleteBrokerGroup(ctx context.Context, s logical.Storage, name string) error {
+	return s.Delete(ctx, groupStoragePrefix+name)
+}
+
+func listBrokerGroups(ctx context.Context, s logical.Storage) ([]string, error) {
+	return s.List(ctx, groupStoragePrefix)
+}
+
+// getSettings returns the mount's settings. Defaults fill in any setting
+// that has never been written, including ones added after the entry was
+// stored.
+func getSettings(ctx context.Context, s logical.Storage) (*Settings, error) {
+	settings := defaultSettings()
+	entry, err := s.Get(ctx, settingsStorageKey)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return settings, nil
+	}
+	if err := json.Unmarshal(entry.Value, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func putSettings(ctx context.Context, s logical.Storage, settings *Settings) error {
+	return putEntry(ctx, s, settingsStorageKey, settings)
+}
+
 func getRole(ctx context.Context, s logical.Storage, name string) (*RoleEntry, error) {
 	return getEntry[RoleEntry](ctx, s, roleStoragePrefix+name)
 }
 
+// roleIndexKey returns the index entry linking a role to its broker, or to
+// its broker group.
+func roleIndexKey(name string, role *RoleEntry) string {
+	if role.BrokerGroup != "" {
+		return groupRoleIndexPrefix + role.BrokerGroup + "/" + name
+	}
+	return brokerRoleIndexPrefix + role.Broker + "/" + name
+}
+
+// putRole stores a role and keeps the broker-to-role index in step with it.
 func putRole(ctx context.Context, s logical.Storage, name string, role *RoleEntry) error {
-	return putEntry(ctx, s, roleStoragePrefix+name, role)
+	existing, err := getRole(ctx, s, name)
+	if err != nil {
+		return err
+	}
+	if err := putEntry(ctx, s, roleStoragePrefix+name, role); err != nil {
+		return err
+	}
+	if existing != nil && roleIndexKey(name, existing) == roleIndexKey(name, role) {
+		return nil
+	}
+	if existing != nil {
+		if err := s.Delete(ctx, roleIndexKey(name, existing)); err != nil {
+			return err
+		}
+	}
+	return s.Put(ctx, &logical.StorageEntry{Key: roleIndexKey(name, role)})
 }
 
+// deleteRole removes a role, its secret and restore flag, and its
+// broker-to-role index entry.
 func deleteRole(ctx context.Context, s logical.Storage, name string) error {
-	return s.Delete(ctx, roleStoragePrefix+name)
+	existing, err := getRole(ctx, s, name)
+	if err != nil {
+		return err
+	}
+	if err := s.Delete(ctx, roleStoragePrefix+name); err != nil {
+		return err
+	}
+	if err := s.Delete(ctx, secretStoragePrefix+name); err != nil {
+		return err
+	}
+	if err := deleteRestoreSuspect(ctx, s, name); err != nil {
+		return err
+	}
+	if err := deleteDrift(ctx, s, name); err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+	return s.Delete(ctx, roleIndexKey(name, existing))
+}
+
+func getRoleSecret(ctx context.Context, s logical.Storage, name string) (*RoleSecret, error) {
+	return getEntry[RoleSecret](ctx, s, secretStoragePrefix+name)
+}
+
+func putRoleSecret(ctx context.Context, s logical.Storage, name string, secret *RoleSecret) error {
+	return putEntry(ctx, s, secretStoragePrefix+name, secret)
+}
+
+// migrateRoleSecrets moves passwords out of roles stored by versions that
+// kept them inline into their own secret entries.
+func migrateRoleSecrets(ctx context.Context, s logical.Storage) error {
+	marker, err := s.Get(ctx, roleSecretsMigratedMarker)
+	if err != nil {
+		return err
+	}
+	if marker != nil {
+		return nil
+	}
+
+	roles, err := listRoles(ctx, s)
+	if err != nil {
+		return err
+	}
+	for _, name := range roles {
+		role, err := getRole(ctx, s, name)
+		if err != nil {
+			return err
+		}
+		if role == nil || role.LegacyPassword == "" {
+			continue
+		}
+		if err := putRoleSecret(ctx, s, name, &RoleSecret{Password: role.LegacyPassword}); err != nil {
+			return err
+		}
+		role.LegacyPassword = ""
+		if err := putRole(ctx, s, name, role); err != nil {
+			return err
+		}
+	}
+
+	return s.Put(ctx, &logical.StorageEntry{Key: roleSecretsMigratedMarker, Value: []byte("1")})
+}
+
+func getRecovery(ctx context.Context, s logical.Storage, name string) (*RecoveryEntry, error) {
+	return getEntry[RecoveryEntry](ctx, s, recoveryPrefix+name)
+}
+
+func putRecovery(ctx context.Context, s logical.Storage, name string, entry *RecoveryEntry) error {
+	return putEntry(ctx, s, recoveryPrefix+name, entry)
+}
+
+func deleteRecovery(ctx context.Context, s logical.Storage, name string) error {
+	return s.Delete(ctx, recoveryPrefix+name)
+}
+
+func listRecoveries(ctx context.Context, s logical.Storage) ([]string, error) {
+	return s.List(ctx, recoveryPrefix)
+}
+
+func getRetained(ctx context.Context, s logical.Storage, name string) (*RetainedEntry, error) {
+	return getEntry[RetainedEntry](ctx, s, retainedPrefix+name)
+}
+
+func putRetained(ctx context.Context, s logical.Storage, name string, entry *RetainedEntry) error {
+	return putEntry(ctx, s, retainedPrefix+name, entry)
+}
+
+func deleteRetained(ctx context.Context, s logical.Storage, name string) error {
+	return s.Delete(ctx, retainedPrefix+name)
+}
+
+func listRetained(ctx context.Context, s logical.Storage) ([]string, error) {
+	return s.List(ctx, retainedPrefix)
+}
+
+func getBrokerActivity(ctx context.Context, s logical.Storage, broker string) ([]brokerActivity, error) {
+	activity, err := getEntry[[]brokerActivity](ctx, s, activityPrefix+broker)
+	if err != nil || activity == nil {
+		return nil, err
+	}
+	return *activity, nil
+}
+
+func putBrokerActivity(ctx context.Context, s logical.Storage, broker string, activity []brokerActivity) error {
+	return putEntry(ctx, s, activityPrefix+broker, activity)
+}
+
+func deleteBrokerActivity(ctx context.Context, s logical.Storage, broker string) error {
+	return s.Delete(ctx, activityPrefix+broker)
+}
+
+func getGeneration(ctx context.Context, s logical.Storage) (uint64, error) {
+	gen, err := getEntry[uint64](ctx, s, generationStorageKey)
+	if err != nil || gen == nil {
+		return 0, err
+	}
+	return *gen, nil
+}
+
+func putGeneration(ctx context.Context, s logical.Storage, gen uint64) error {
+	return putEntry(ctx, s, generationStorageKey, gen)
+}
+
+func getRestoreSuspect(ctx context.Context, s logical.Storage, name string) (*restoreSuspect, error) {
+	return getEntry[restoreSuspect](ctx, s, restoreSuspectPrefix+name)
+}
+
+func putRestoreSuspect(ctx context.Context, s logical.Storage, name string, suspect *restoreSuspect) error {
+	return putEntry(ctx, s, restoreSuspectPrefix+name, suspect)
+}
+
+func deleteRestoreSuspect(ctx context.Context, s logical.Storage, name string) error {
+	return s.Delete(ctx, restoreSuspectPrefix+name)
+}
+
+func listRestoreSuspects(ctx context.Context, s logical.Storage) ([]string, error) {
+	return s.List(ctx, restoreSuspectPrefix)
+}
+
+func getDrift(ctx context.Context, s logical.Storage, name string) (*driftEntry, error) {
+	return getEntry[driftEntry](ctx, s, driftPrefix+name)
+}
+
+func putDrift(ctx context.Context, s logical.Storage, name string, drift *driftEntry) error {
+	return putEntry(ctx, s, driftPrefix+name, drift)
+}
+
+func deleteDrift(ctx context.Context, s logical.Storage, name string) error {
+	return s.Delete(ctx, driftPrefix+name)
+}
+
+func listDrift(ctx context.Context, s logical.Storage) ([]string, error) {
+	return s.List(ctx, driftPrefix)
+}
+
+func getDriftCheckState(ctx context.Context, s logical.Storage) (*driftCheckState, error) {
+	return getEntry[driftCheckState](ctx, s, driftCheckStorageKey)
+}
+
+func putDriftCheckState(ctx context.Context, s logical.Storage, state *driftCheckState) error {
+	return putEntry(ctx, s, driftCheckStorageKey, state)
+}
+
+func getLastPeriodicRun(ctx context.Context, s logical.Storage) (*periodicRun, error) {
+	return getEntry[periodicRun](ctx, s, lastPeriodicRunStorageKey)
+}
+
+func putLastPeriodicRun(ctx context.Context, s logical.Storage, run *periodicRun) error {
+	return putEntry(ctx, s, lastPeriodicRunStorageKey, run)
+}
+
+// listBrokerRoles returns the names of the roles that reference a broker.
+func listBrokerRoles(ctx context.Context, s logical.Storage, broker string) ([]string, error) {
+	return s.List(ctx, brokerRoleIndexPrefix+broker+"/")
+}
+
+// listGroupRoles returns the names of the roles that reference a broker
+// group.
+func listGroupRoles(ctx context.Context, s logical.Storage, group string) ([]string, error) {
+	return s.List(ctx, groupRoleIndexPrefix+group+"/")
+}
+
+// buildBrokerRoleIndex populates the broker-to-role index from existing roles
+// the first time a mount runs a version that maintains it.
+func buildBrokerRoleIndex(ctx context.Context, s logical.Storage) error {
+	marker, err := s.Get(ctx, brokerRoleIndexMarker)
+	if err != nil {
+		return err
+	}
+	if marker != nil {
+		return nil
+	}
+
+	roles, err := listRoles(ctx, s)
+	if err != nil {
+		return err
+	}
+	for _, name := range roles {
+		role, err := getRole(ctx, s, name)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			continue
+		}
+		if err := s.Put(ctx, &logical.StorageEntry{Key: brokerRoleIndexPrefix + role.Broker + "/" + name}); err != nil {
+			return err
+		}
+	}
+
+	return s.Put(ctx, &logical.StorageEntry{Key: brokerRoleIndexMarker, Value: []byte("1")})
 }
 
 func listRoles(ctx context.Context, s logical.Storage) ([]string, error) {
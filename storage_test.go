@@ -85,3 +85,93 @@ func TestRoleStorage(t *testing.T) {
 		t.Errorf("listRoles = %v, want [test-role]", names)
 	}
 }
+
+func TestBrokerRoleIndex(t *testing.T) {
+	ctx := context.Background()
+	s := &logical.InmemStorage{}
+
+	putRole(ctx, s, "a", &RoleEntry{Broker: "east", CLIUsername: "a"})
+	putRole(ctx, s, "b", &RoleEntry{Broker: "east", CLIUsername: "b"})
+
+	names, err := listBrokerRoles(ctx, s, "east")
+	if err != nil {
+		t.Fatalf("listBrokerRoles: %v", err)
+	}
+	if len(names) != 2 {
+		t.Errorf("east roles = %v, want [a b]", names)
+	}
+
+	// Moving a role to another broker updates both index entries
+	putRole(ctx, s, "b", &RoleEntry{Broker: "west", CLIUsername: "b"})
+	names, _ = listBrokerRoles(ctx, s, "east")
+	if len(names) != 1 || names[0] != "a" {
+		t.Errorf("east roles after move = %v, want [a]", names)
+	}
+	names, _ = listBrokerRoles(ctx, s, "west")
+	if len(names) != 1 || names[0] != "b" {
+		t.Errorf("west roles after move = %v, want [b]", names)
+	}
+
+	if err := deleteRole(ctx, s, "a"); err != nil {
+		t.Fatalf("deleteRole: %v", err)
+	}
+	names, _ = listBrokerRoles(ctx, s, "east")
+	if len(names) != 0 {
+		t.Errorf("east roles after delete = %v, want []", names)
+	}
+}
+
+func TestBuildBrokerRoleIndex(t *testing.T) {
+	ctx := context.Background()
+	s := &logical.InmemStorage{}
+
+	// Roles written by a version that did not maintain the index
+	putEntry(ctx, s, roleStoragePrefix+"legacy", &RoleEntry{Broker: "east", CLIUsername: "x"})
+
+	if err := buildBrokerRoleIndex(ctx, s); err != nil {
+		t.Fatalf("buildBrokerRoleIndex: %v", err)
+	}
+	names, _ := listBrokerRoles(ctx, s, "east")
+	if len(names) != 1 || names[0] != "legacy" {
+		t.Errorf("east roles = %v, want [legacy]", names)
+	}
+}
+
+func TestMigrateRoleSecrets(t *testing.T) {
+	ctx := context.Background()
+	s := &logical.InmemStorage{}
+
+	// A role written by a version that kept the password inline
+	putEntry(ctx, s, roleStoragePrefix+"legacy", &RoleEntry{Broker: "east", CLIUsername: "x", LegacyPassword: "old-secret"})
+
+	if err := migrateRoleSecrets(ctx, s); err != nil {
+		t.Fatalf("migrateRoleSecrets: %v", err)
+	}
+
+	secret, err := getRoleSecret(ctx, s, "legacy")
+	if err != nil || secret == nil {
+		t.Fatalf("getRoleSecret: secret=%v, err=%v", secret, err)
+	}
+	if secret.Password != "old-secret" {
+		t.Error("password was not moved to secret storage")
+	}
+	role, _ := getRole(ctx, s, "legacy")
+	if role.LegacyPassword != "" {
+		t.Error("password should be removed from the role entry")
+	}
+}
+
+func TestDeleteRoleRemovesSecret(t *testing.T) {
+	ctx := context.Background()
+	s := &logical.InmemStorage{}
+
+	putRole(ctx, s, "r", &RoleEntry{Broker: "east", CLIUsername: "x"})
+	putRoleSecret(ctx, s, "r", &RoleSecret{Password: "pw"})
+
+	if err := deleteRole(ctx, s, "r"); err != nil {
+		t.Fatalf("deleteRole: %v", err)
+	}
+	if secret, _ := getRoleSecret(ctx, s, "r"); secret != nil {
+		t.Error("secret should be deleted with its role")
+	}
+}
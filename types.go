@@ -1,6 +1,9 @@
 package solacevaultplugin
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // BrokerConfig holds connection details for a Solace broker's SEMP v1 interface.
 type BrokerConfig struct {
@@ -9,14 +12,478 @@ type BrokerConfig struct {
 	AdminPassword string `json:"admin_password"`
 	SEMPVersion   string `json:"semp_version,omitempty"`
 	TLSSkipVerify bool   `json:"tls_skip_verify,omitempty"`
+
+	// ConnectTimeout bounds dialing the broker; RequestTimeout bounds the
+	// whole SEMP exchange. Zero means use the default.
+	ConnectTimeout time.Duration `json:"connect_timeout,omitempty"`
+	RequestTimeout time.Duration `json:"request_timeout,omitempty"`
+
+	// Transport tuning for brokers behind proxies that misbehave with Go's
+	// defaults. Zero values mean use the default.
+	ForceHTTP1          bool          `json:"force_http1,omitempty"`
+	MaxIdleConnsPerHost int           `json:"max_idle_conns_per_host,omitempty"`
+	TLSHandshakeTimeout time.Duration `json:"tls_handshake_timeout,omitempty"`
+
+	// MateSEMPURL is the SEMP URL of the other node of an HA pair. When set,
+	// configuration changes go to whichever node reports itself active.
+	MateSEMPURL string `json:"mate_semp_url,omitempty"`
+
+	// SEMPDialAddress and MateSEMPDialAddress, when set, are the host and
+	// port connections to SEMPURL and MateSEMPURL are made to instead of
+	// their own, such as the local end of a tunnel; see semp_dial.go.
+	SEMPDialAddress     string `json:"semp_dial_address,omitempty"`
+	MateSEMPDialAddress string `json:"mate_semp_dial_address,omitempty"`
+
+	// CloudAPIURL and CloudAPIToken reach the Solace Cloud REST API of the
+	// organization hosting a Cloud broker, for cloud_token roles.
+	CloudAPIURL   string `json:"cloud_api_url,omitempty"`
+	CloudAPIToken string `json:"cloud_api_token,omitempty"`
+
+	// NotifyURL is the broker's REST messaging endpoint, and NotifyTopic
+	// the topic a notice of each rotation is published to through it,
+	// authenticating as NotifyUsername when set. Notices are signed with
+	// NotifySigningKey when set.
+	NotifyURL        string `json:"notify_url,omitempty"`
+	NotifyTopic      string `json:"notify_topic,omitempty"`
+	NotifyUsername   string `json:"notify_username,omitempty"`
+	NotifyPassword   string `json:"notify_password,omitempty"`
+	NotifySigningKey string `json:"notify_signing_key,omitempty"`
+
+	// PasswordExcludedChars are the characters the broker rejects in
+	// passwords, replacing Solace's documented exclusions, for brokers
+	// such as those passing logins through to RADIUS that reject others.
+	PasswordExcludedChars string `json:"password_excluded_characters,omitempty"`
+
+	// AllowedSEMPNetworks, when set, are the CIDR ranges the broker's SEMP
+	// hosts may resolve to; connections anywhere else are refused.
+	AllowedSEMPNetworks []string `json:"allowed_semp_networks,omitempty"`
+
+	// RotationLockTTL, when set, makes rotations on the broker take a lock
+	// there first, held for at most this long, so that plugins in other
+	// Vault clusters managing the broker do not rotate the same credential
+	// at once; see rotation_lock.go.
+	RotationLockTTL time.Duration `json:"rotation_lock_ttl,omitempty"`
+}
+
+// excludedPasswordChars returns the characters generated and supplied
+// passwords must not contain on the broker.
+func (c *BrokerConfig) excludedPasswordChars() string {
+	if c.PasswordExcludedChars == "" {
+		return passwordForbidden
+	}
+	return c.PasswordExcludedChars
+}
+
+// BrokerGroup is a set of brokers, such as a DR pair or the nodes of a DMR
+// cluster, that share CLI users. A role on a group gives its CLI user the
+// same password on every member.
+type BrokerGroup struct {
+	Brokers []string `json:"brokers"`
 }
 
-// RoleEntry maps a Vault role to a CLI user on a Solace broker.
+// Role targets: what a role's credential belongs to.
+const (
+	roleTargetCLIUser      = "cli_user"
+	roleTargetRESTConsumer = "rest_consumer"
+	roleTargetOAuthProfile = "oauth_profile"
+	roleTargetCloudToken   = "cloud_token"
+	roleTargetSEMPRPC      = "semp_rpc"
+)
+
+// RoleEntry maps a Vault role to a credential on a Solace broker: by
+// default a CLI user's password.
 type RoleEntry struct {
 	Broker         string        `json:"broker"`
+	BrokerGroup    string        `json:"broker_group,omitempty"`
 	CLIUsername    string        `json:"cli_username"`
 	RotationPeriod time.Duration `json:"rotation_period,omitempty"`
 	PasswordLength int           `json:"password_length,omitempty"`
-	Password       string        `json:"password,omitempty"`
 	LastRotated    time.Time     `json:"last_rotated,omitempty"`
+
+	// UsernameTemplate, when set, is the template CLIUsername was rendered
+	// from.
+	UsernameTemplate string `json:"username_template,omitempty"`
+
+	// MaxPasswordAge, when set, is how old the credential may get before
+	// the role is reported non-compliant, however rotation is going.
+	MaxPasswordAge time.Duration `json:"max_password_age,omitempty"`
+
+	// LastRotationTrigger records whether the last rotation was manual or
+	// periodic. For manual rotations LastRotatedBy and LastRotatedByEntity
+	// are the display name and entity ID of the token that requested it.
+	LastRotationTrigger string `json:"last_rotation_trigger,omitempty"`
+	LastRotatedBy       string `json:"last_rotated_by,omitempty"`
+	LastRotatedByEntity string `json:"last_rotated_by_entity_id,omitempty"`
+
+	// OwnerEntityID is the Vault entity that owns the role, and
+	// ApproverEntityIDs the entities that may approve its manual rotations
+	// besides the owner. With RequireOwnerApproval a manual rotation must
+	// be requested or approved by one of them, and
+	// LastRotationApprovedBy records which.
+	OwnerEntityID          string   `json:"owner_entity_id,omitempty"`
+	ApproverEntityIDs      []string `json:"approver_entity_ids,omitempty"`
+	RequireOwnerApproval   bool     `json:"require_owner_approval,omitempty"`
+	LastRotationApprovedBy string   `json:"last_rotation_approved_by,omitempty"`
+
+	// OwnershipTransferredAt and OwnershipTransferredBy record the last
+	// transfer of the role to a new owner, and the entity that made it.
+	OwnershipTransferredAt time.Time `json:"ownership_transferred_at,omitempty"`
+	OwnershipTransferredBy string    `json:"ownership_transferred_by,omitempty"`
+
+	// LegacyPassword is only set on roles stored before passwords moved to
+	// their own RoleSecret entry; migrateRoleSecrets moves it out.
+	LegacyPassword string `json:"password,omitempty"`
+
+	// CreateIfMissing makes rotation create the CLI user, with
+	// GlobalAccessLevel, when it does not yet exist on the broker. The user
+	// gets VPNAccessLevel to message VPNs, except those named in
+	// VPNAccessLevelExceptions, which map a VPN to its access level.
+	CreateIfMissing          bool              `json:"create_if_missing,omitempty"`
+	GlobalAccessLevel        string            `json:"global_access_level,omitempty"`
+	VPNAccessLevel           string            `json:"vpn_access_level,omitempty"`
+	VPNAccessLevelExceptions map[string]string `json:"vpn_access_level_exceptions,omitempty"`
+
+	// Monitor marks a role issuing a read-only monitoring credential: its
+	// CLI user is created read-only when missing, and is never rotated if it
+	// exists with more access.
+	Monitor bool `json:"monitor,omitempty"`
+
+	// DisableDuringRotation shuts the CLI user down while its password is
+	// changed, ending every session logged in with the old password.
+	DisableDuringRotation bool `json:"disable_during_rotation,omitempty"`
+
+	// TerminateSessions ends the CLI user's sessions once a rotation has
+	// stored its new password, by shutting the user down and enabling it
+	// again, so sessions logged in with the old password do not linger.
+	TerminateSessions bool `json:"terminate_sessions,omitempty"`
+
+	// RotateOnPolicyChange rotates the role as soon as a write changes its
+	// password_length, rather than at its next scheduled rotation.
+	RotateOnPolicyChange bool `json:"rotate_on_policy_change,omitempty"`
+
+	// PropagationDelay is how long after a rotation creds/ keeps serving
+	// the previous credential, for brokers that take time to pass a new
+	// password on to every node.
+	PropagationDelay time.Duration `json:"propagation_delay,omitempty"`
+
+	// PasswordEncoding is how creds/ presents the credential: plain, the
+	// default, stored as empty, or base64 or url for consumers that inject
+	// it into URIs. It never changes the credential itself.
+	PasswordEncoding string `json:"password_encoding,omitempty"`
+
+	// AuditTags are name=value pairs, such as the owning application, that
+	// creds/ and rotation responses carry so audit entries can be routed
+	// by them; see audit_tags.go.
+	AuditTags map[string]string `json:"audit_tags,omitempty"`
+
+	// Target is what the role rotates: a CLI user (the default, stored as
+	// empty) or a REST delivery point's REST consumer, identified by
+	// MsgVPN, RESTDeliveryPoint and RESTConsumer and authenticating with
+	// RESTAuthScheme.
+	Target            string `json:"target,omitempty"`
+	MsgVPN            string `json:"msg_vpn,omitempty"`
+	RESTDeliveryPoint string `json:"rest_delivery_point,omitempty"`
+	RESTConsumer      string `json:"rest_consumer,omitempty"`
+	RESTAuthScheme    string `json:"rest_consumer_auth,omitempty"`
+	RESTUsername      string `json:"rest_consumer_username,omitempty"`
+
+	// OAuthProfile is the OAuth profile whose client secret an
+	// oauth_profile role keeps in sync with its identity provider. It is
+	// a message VPN's authentication profile when MsgVPN is set, and a
+	// broker-level profile otherwise.
+	OAuthProfile string `json:"oauth_profile,omitempty"`
+
+	// CloudTokenID is the Solace Cloud API token a cloud_token role
+	// regenerates through its broker's Cloud REST API.
+	CloudTokenID string `json:"cloud_token_id,omitempty"`
+
+	// SEMPRPCTemplate is the SEMP v1 request body a semp_rpc role sends to
+	// set a new password, and SEMPRPCUsername the value of its {{username}}
+	// placeholder.
+	SEMPRPCTemplate string `json:"semp_rpc_template,omitempty"`
+	SEMPRPCUsername string `json:"semp_rpc_username,omitempty"`
+}
+
+// isCLIUser reports whether the role rotates a CLI user's password. Roles
+// stored before targets existed have no Target and are CLI user roles.
+func (r *RoleEntry) isCLIUser() bool {
+	return r.Target == "" || r.Target == roleTargetCLIUser
+}
+
+// canApprove reports whether entityID may approve the role's manual
+// rotations: it is the role's owner or one of its approvers.
+func (r *RoleEntry) canApprove(entityID string) bool {
+	if entityID == "" {
+		return false
+	}
+	return entityID == r.OwnerEntityID || slices.Contains(r.ApproverEntityIDs, entityID)
+}
+
+// passwordEncoding returns how creds/ presents the role's credential.
+func (r *RoleEntry) passwordEncoding() string {
+	if r.PasswordEncoding == "" {
+		return passwordEncodingPlain
+	}
+	return r.PasswordEncoding
+}
+
+// location returns the broker, or for group roles the broker group, the
+// role's credential lives on, for logs, metrics and events.
+func (r *RoleEntry) location() string {
+	if r.BrokerGroup != "" {
+		return r.BrokerGroup
+	}
+	return r.Broker
+}
+
+// access returns the access a missing CLI user is created with.
+func (r *RoleEntry) access() CLIUserAccess {
+	access := CLIUserAccess{
+		GlobalAccessLevel:        r.GlobalAccessLevel,
+		VPNAccessLevel:           r.VPNAccessLevel,
+		VPNAccessLevelExceptions: r.VPNAccessLevelExceptions,
+	}
+	if r.Monitor {
+		access.GlobalAccessLevel = monitorAccessLevel
+	}
+	return access
+}
+
+// isCloudToken reports whether the role regenerates a Solace Cloud API
+// token.
+func (r *RoleEntry) isCloudToken() bool {
+	return r.Target == roleTargetCloudToken
+}
+
+// isSEMPRPC reports whether the role sets its password with a custom SEMP
+// v1 request template.
+func (r *RoleEntry) isSEMPRPC() bool {
+	return r.Target == roleTargetSEMPRPC
+}
+
+// isOAuthProfile reports whether the role syncs an OAuth profile's client
+// secret.
+func (r *RoleEntry) isOAuthProfile() bool {
+	return r.Target == roleTargetOAuthProfile
+}
+
+// isRESTConsumer reports whether the role rotates a REST consumer's
+// credential rather than a CLI user's password.
+func (r *RoleEntry) isRESTConsumer() bool {
+	return r.Target == roleTargetRESTConsumer
+}
+
+// usesClientCertificate reports whether the role's credential is a client
+// certificate rather than a password.
+func (r *RoleEntry) usesClientCertificate() bool {
+	return r.isRESTConsumer() && r.RESTAuthScheme == restAuthClientCertificate
+}
+
+// generatesPassword reports whether rotation generates the role's password,
+// rather than issuing a certificate or taking the value from elsewhere.
+func (r *RoleEntry) generatesPassword() bool {
+	return !r.usesClientCertificate() && !r.isOAuthProfile() && !r.isCloudToken()
+}
+
+// RoleSecret holds a role's live credential. It is stored apart from the
+// RoleEntry so that writing role configuration can never overwrite it.
+type RoleSecret struct {
+	Password string `json:"password"`
+
+	// Certificate and PrivateKey are the PEM client certificate and key of
+	// REST consumer roles that authenticate with a client certificate.
+	Certificate string `json:"certificate,omitempty"`
+	PrivateKey  string `json:"private_key,omitempty"`
+
+	// Version counts the credentials the role has had: 1 for the first,
+	// and one more for each rotation. Secrets stored before versions were
+	// kept have version 0 until their next rotation.
+	Version int `json:"version,omitempty"`
+
+	// Previous is the credential this one replaced, for roles with a
+	// propagation_delay, and ServedFrom when creds/ switches from it to
+	// this one. Previous never has a Previous of its own.
+	Previous   *RoleSecret `json:"previous,omitempty"`
+	ServedFrom time.Time   `json:"served_from,omitempty"`
+}
+
+// served returns the credential creds/ hands out at now: the previous one
+// while a propagation delay is running, and s otherwise. It is nil when
+// the role has no secret.
+func (s *RoleSecret) served(now time.Time) *RoleSecret {
+	if s != nil && s.Previous != nil && now.Before(s.ServedFrom) {
+		return s.Previous
+	}
+	return s
+}
+
+// nextVersion returns the version of the credential that replaces s.
+func (s *RoleSecret) nextVersion() int {
+	if s == nil {
+		return 1
+	}
+	return s.Version + 1
+}
+
+// version returns the version of s, or 0 when the role has no secret.
+func (s *RoleSecret) version() int {
+	if s == nil {
+		return 0
+	}
+	return s.Version
+}
+
+// empty reports whether the secret holds no credential yet.
+func (s *RoleSecret) empty() bool {
+	return s == nil || (s.Password == "" && s.Certificate == "")
+}
+
+// RetainedEntry keeps the configuration and last credential of a role
+// deleted without purge_history, for forensics, until ExpiresAt.
+type RetainedEntry struct {
+	Role      RoleEntry  `json:"role"`
+	Secret    RoleSecret `json:"secret"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+// RecoveryEntry keeps a password that was set on the broker but could not be
+// stored as the role's secret, so an operator can recover it without it
+// ever being written to the server log.
+type RecoveryEntry struct {
+	Broker      string    `json:"broker"`
+	CLIUsername string    `json:"cli_username"`
+	Password    string    `json:"password"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Certificate and PrivateKey are set instead of Password for REST
+	// consumer roles that authenticate with a client certificate.
+	Certificate string `json:"certificate,omitempty"`
+	PrivateKey  string `json:"private_key,omitempty"`
+}
+
+// restoreSuspect marks a role whose stored password may not match the broker
+// because Vault storage was restored from a snapshot taken before the role's
+// latest rotation.
+type restoreSuspect struct {
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// driftEntry marks a role whose stored password a drift check found out of
+// step with a broker: its CLI user is gone, or the broker rejects the
+// password.
+type driftEntry struct {
+	DetectedAt time.Time `json:"detected_at"`
+	Reason     string    `json:"reason"`
+	Broker     string    `json:"broker"`
+}
+
+// driftCheckState tracks the drift check across periodic passes. A check
+// that runs out of time records the last role it checked in Cursor, and the
+// next pass carries on after it.
+type driftCheckState struct {
+	LastCompleted time.Time `json:"last_completed,omitempty"`
+	Cursor        string    `json:"cursor,omitempty"`
+}
+
+// brokerActivity is one SEMP configuration change recorded in a broker's
+// activity log. It never holds the credential that was set.
+type brokerActivity struct {
+	Time       time.Time `json:"time"`
+	Operation  string    `json:"operation"`
+	Target     string    `json:"target"`
+	Result     string    `json:"result"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+}
+
+// Settings holds mount-wide behavioral options that would otherwise be
+// compiled-in constants.
+type Settings struct {
+	PeriodicConcurrency   int           `json:"periodic_concurrency"`
+	DefaultPasswordLength int           `json:"default_password_length"`
+	MinRotationInterval   time.Duration `json:"min_rotation_interval"`
+	RotationJitter        time.Duration `json:"rotation_jitter,omitempty"`
+	PeriodicTimeBudget    time.Duration `json:"periodic_time_budget"`
+
+	// PeriodicInterval is how often the periodic function checks roles for
+	// rotation. Zero checks on every call from Vault.
+	PeriodicInterval time.Duration `json:"periodic_interval,omitempty"`
+
+	// ClockSkewTolerance is how far past its due time a role rotated by
+	// another node, or before this node started, must be before it is
+	// rotated, so clocks that differ between nodes do not rotate it twice.
+	ClockSkewTolerance time.Duration `json:"clock_skew_tolerance"`
+
+	// StartupCooldown is how long after the mount is set up, or the node
+	// becomes able to rotate, the periodic function waits before its first
+	// pass.
+	StartupCooldown time.Duration `json:"startup_cooldown,omitempty"`
+
+	// DeletedRoleRetention is how long the last credential of a role
+	// deleted with purge_history=false is kept under retained/.
+	DeletedRoleRetention time.Duration `json:"deleted_role_retention,omitempty"`
+
+	// RequireCharacterClasses makes generated passwords contain at least
+	// one lowercase letter, uppercase letter, digit and symbol.
+	RequireCharacterClasses bool `json:"require_character_classes"`
+
+	// PasswordCharset replaces the built-in set of characters passwords are
+	// drawn from.
+	PasswordCharset string `json:"password_charset,omitempty"`
+
+	// AllowInsecureTransport permits broker configs with an http semp_url
+	// or tls_skip_verify set.
+	AllowInsecureTransport bool `json:"allow_insecure_transport,omitempty"`
+
+	// VerifyRotation makes each rotation log in to the broker as the CLI
+	// user to confirm the new password works and the old one no longer
+	// does before the new password is stored.
+	VerifyRotation bool `json:"verify_rotation,omitempty"`
+
+	// AllowSuppliedPasswords lets rotate-role set a password the caller
+	// supplies instead of a generated one.
+	AllowSuppliedPasswords bool `json:"allow_supplied_passwords,omitempty"`
+
+	// MaxRoles and MaxBrokers bound how many roles and broker configs the
+	// mount may hold. Zero means no limit.
+	MaxRoles   int `json:"max_roles,omitempty"`
+	MaxBrokers int `json:"max_brokers,omitempty"`
+
+	// DriftCheckInterval is how often the periodic function checks that
+	// every CLI user role's user still exists and accepts the stored
+	// password. Zero turns the check off.
+	DriftCheckInterval time.Duration `json:"drift_check_interval,omitempty"`
+
+	// BlackoutDates and BlackoutWeekdays are days, in BlackoutTimezone,
+	// on which automatic rotations are held back, as are the events of
+	// the iCalendar feed at BlackoutICalURL. BlackoutPeriods holds those
+	// events, as of the feed's fetch at BlackoutICalFetched.
+	BlackoutDates       []string         `json:"blackout_dates,omitempty"`
+	BlackoutWeekdays    []string         `json:"blackout_weekdays,omitempty"`
+	BlackoutTimezone    string           `json:"blackout_timezone,omitempty"`
+	BlackoutICalURL     string           `json:"blackout_ical_url,omitempty"`
+	BlackoutPeriods     []blackoutPeriod `json:"blackout_periods,omitempty"`
+	BlackoutICalFetched time.Time        `json:"blackout_ical_fetched,omitempty"`
+}
+
+// passwordPolicy returns the password generation policy the settings select.
+func (s *Settings) passwordPolicy() passwordPolicy {
+	return passwordPolicy{
+		Charset:        s.PasswordCharset,
+		RequireClasses: s.RequireCharacterClasses,
+	}
+}
+
+func defaultSettings() *Settings {
+	return &Settings{
+		PeriodicConcurrency:   1,
+		DefaultPasswordLength: defaultPasswordLength,
+		MinRotationInterval:   minRotationInterval,
+		PeriodicTimeBudget:    defaultPeriodicTimeBudget,
+		ClockSkewTolerance:    defaultClockSkewTolerance,
+		DeletedRoleRetention:  defaultDeletedRoleRetention,
+
+		RequireCharacterClasses: true,
+	}
 }
@@ -0,0 +1,40 @@
+package solacevaultplugin
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/vault/sdk/helper/template"
+)
+
+// usernameTemplateData is what a role's username_template is rendered with.
+// DisplayName and EntityID are those of the token writing the role.
+type usernameTemplateData struct {
+	RoleName    string
+	Broker      string
+	BrokerGroup string
+	DisplayName string
+	EntityID    string
+}
+
+// renderUsername renders a username template, which uses the same template
+// language and functions as the username_template of Vault's database
+// engines, such as truncate, lowercase, replace and unix_time.
+func renderUsername(usernameTemplate string, data usernameTemplateData) (string, error) {
+	tmpl, err := template.NewTemplate(template.Template(usernameTemplate))
+	if err != nil {
+		return "", fmt.Errorf("invalid username_template: %w", err)
+	}
+	username, err := tmpl.Generate(data)
+	if err != nil {
+		return "", fmt.Errorf("rendering username_template: %w", err)
+	}
+	if username == "" {
+		return "", fmt.Errorf("username_template rendered an empty username")
+	}
+	if strings.ContainsFunc(username, unicode.IsSpace) {
+		return "", fmt.Errorf("username_template rendered %q, which contains whitespace", username)
+	}
+	return username, nil
+}